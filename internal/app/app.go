@@ -1,19 +1,36 @@
 package app
 
 import (
+	"context"
+	"fmt"
 	"github.com/labstack/echo/v4/middleware"
+	"io/ioutil"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
 	"time"
 
 	handler "github.com/MAVIKE/yad-backend/internal/delivery/http"
 	"github.com/MAVIKE/yad-backend/internal/repository"
 	"github.com/MAVIKE/yad-backend/internal/service"
 	"github.com/MAVIKE/yad-backend/pkg/auth"
+	"github.com/MAVIKE/yad-backend/pkg/hash"
+	"github.com/MAVIKE/yad-backend/pkg/payment"
 	"github.com/labstack/echo/v4"
 	"github.com/spf13/viper"
 )
 
+// minAccessTokenTTL guards against TTLs written in the wrong unit, which
+// would make every access token expire right away.
+const minAccessTokenTTL = time.Minute
+
+// shutdownTimeout is how long the running requests may take to finish
+// once the server is asked to stop.
+const shutdownTimeout = 10 * time.Second
+
 func Run(configPath string) {
 	if err := initConfig(configPath); err != nil {
 		log.Fatalf("error initializing configs: %s", err.Error())
@@ -21,51 +38,244 @@ func Run(configPath string) {
 
 	dbPrefix := viper.GetString("db.name") + "."
 
-	db, err := repository.NewPostgresDB(repository.Config{
+	dbConfig := repository.Config{
 		Host:     viper.GetString(dbPrefix + "host"),
 		Port:     viper.GetString(dbPrefix + "port"),
 		Username: viper.GetString(dbPrefix + "username"),
 		DBName:   viper.GetString(dbPrefix + "dbname"),
 		SSLMode:  viper.GetString(dbPrefix + "sslmode"),
 		Password: viper.GetString(dbPrefix + "password"),
-	})
+	}
 
+	db, err := repository.NewPostgresDB(dbConfig)
 	if err != nil {
 		log.Fatalf("failed to initialize db: %s", err.Error())
 	}
 
+	eventListener, err := repository.NewEventListenerPg(dbConfig)
+	if err != nil {
+		log.Fatalf("failed to listen for events: %s", err.Error())
+	}
+	defer eventListener.Close()
+
 	repos := repository.NewRepository(db)
 
-	signingKey := viper.GetString("token.signing_key")
-	if signingKey == "" {
-		log.Fatalf("failed to get token signing key")
+	accessTokenTTL, err := getTTL("token.access_token_ttl", minAccessTokenTTL)
+	if err != nil {
+		log.Fatalf("failed to get access token TTL: %s", err.Error())
 	}
 
-	accessTokenTTL, err := strconv.Atoi(viper.GetString("token.access_token_ttl"))
-	if err != nil || accessTokenTTL == 0 {
-		log.Fatalf("failed to get access token TTL: %s", err.Error())
+	refreshTokenTTL, err := getTTL("token.refresh_token_ttl", accessTokenTTL)
+	if err != nil {
+		log.Fatalf("failed to get refresh token TTL: %s", err.Error())
 	}
 
-	tokenManager, err := auth.NewManager(signingKey)
+	keyring, err := initKeyring()
+	if err != nil {
+		log.Fatalf("failed to initialize token keys: %s", err.Error())
+	}
+
+	tokenManager, err := auth.NewManager(keyring)
 	if err != nil {
 		log.Fatalf(err.Error())
 	}
 
+	hasher, err := hash.NewBCryptHasher(viper.GetInt("password.bcrypt_cost"))
+	if err != nil {
+		log.Fatalf("failed to initialize password hasher: %s", err.Error())
+	}
+
+	dispatchInterval := viper.GetDuration("dispatch.interval")
+	if dispatchInterval == 0 {
+		log.Fatalf("failed to get dispatch interval")
+	}
+
+	offerTimeout := viper.GetDuration("dispatch.offer_timeout")
+	if offerTimeout == 0 {
+		log.Fatalf("failed to get courier offer timeout")
+	}
+
+	webhooksInterval := viper.GetDuration("webhooks.interval")
+	if webhooksInterval == 0 {
+		log.Fatalf("failed to get webhooks interval")
+	}
+
+	outboxInterval := viper.GetDuration("outbox.interval")
+	if outboxInterval == 0 {
+		log.Fatalf("failed to get outbox interval")
+	}
+
+	scheduleInterval := viper.GetDuration("restaurants.schedule_interval")
+	if scheduleInterval == 0 {
+		log.Fatalf("failed to get opening hours interval")
+	}
+
+	revocationCleanupInterval := viper.GetDuration("token.revocation_cleanup_interval")
+	if revocationCleanupInterval == 0 {
+		log.Fatalf("failed to get revocation cleanup interval")
+	}
+
+	refundsInterval := viper.GetDuration("refunds.interval")
+	if refundsInterval == 0 {
+		log.Fatalf("failed to get refunds interval")
+	}
+
+	cartInterval := viper.GetDuration("cart.interval")
+	if cartInterval == 0 {
+		log.Fatalf("failed to get cart interval")
+	}
+
+	paymentProvider, err := initPaymentProvider()
+	if err != nil {
+		log.Fatalf("failed to initialize payment provider: %s", err.Error())
+	}
+
 	deps := service.Deps{
-		Repos:          repos,
-		TokenManager:   tokenManager,
-		AccessTokenTTL: time.Duration(accessTokenTTL) * time.Hour,
+		Repos:           repos,
+		EventListener:   eventListener,
+		TokenManager:    tokenManager,
+		Hasher:          hasher,
+		AccessTokenTTL:  accessTokenTTL,
+		RefreshTokenTTL: refreshTokenTTL,
+
+		RevocationCacheTTL: viper.GetDuration("token.revocation_cache_ttl"),
+		DeliveryPricing: service.DeliveryPricing{
+			BaseFee:        viper.GetInt("delivery.base_fee"),
+			PerKm:          viper.GetInt("delivery.per_km"),
+			FreeThreshold:  viper.GetInt("delivery.free_threshold"),
+			MinOrderAmount: viper.GetInt("delivery.min_order_amount"),
+		},
+		DispatchOfferTimeout:  offerTimeout,
+		DispatchOfferCooldown: viper.GetDuration("dispatch.offer_cooldown"),
+		DispatchEscalateAfter: viper.GetDuration("dispatch.escalate_after"),
+		LocationRetention:     viper.GetDuration("tracking.retention"),
+		Webhooks: service.WebhookConfig{
+			Timeout:     viper.GetDuration("webhooks.timeout"),
+			Backoff:     viper.GetDuration("webhooks.backoff"),
+			MaxAttempts: viper.GetInt("webhooks.max_attempts"),
+
+			AllowPrivate: viper.GetBool("webhooks.allow_private"),
+		},
+		OutboxRetention: viper.GetDuration("outbox.retention"),
+		PaymentProvider: paymentProvider,
+		CartTTL:         viper.GetDuration("cart.ttl"),
 	}
 
+	// the background workers stop together with the server
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	services := service.NewService(deps)
+	go services.Dispatch.Run(ctx, dispatchInterval)
+	go services.Webhook.Run(ctx, webhooksInterval)
+	go services.Outbox.Run(ctx, outboxInterval)
+	go services.Events.Run(ctx)
+	go services.Refund.Run(ctx, refundsInterval)
+	go services.Cart.Run(ctx, cartInterval)
+	go services.Revocation.Run(ctx, revocationCleanupInterval)
+	go services.Restaurant.Run(ctx, scheduleInterval)
 	handlers := handler.NewHandler(services, tokenManager)
 
 	app := echo.New()
 	app.Use(middleware.Logger())
 	handlers.Init(app)
 
-	if err := app.Start(viper.GetString("port")); err != nil {
-		log.Fatalf("failed to listen: %s", err.Error())
+	go func() {
+		if err := app.Start(viper.GetString("port")); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("failed to listen: %s", err.Error())
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	cancel()
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer shutdownCancel()
+
+	if err := app.Shutdown(shutdownCtx); err != nil {
+		log.Printf("failed to shut down: %s", err.Error())
+	}
+}
+
+type keyConfig struct {
+	Id        string `mapstructure:"id"`
+	Algorithm string `mapstructure:"algorithm"`
+	File      string `mapstructure:"file"`
+}
+
+// initKeyring loads the legacy token.signing_key as the HS256 key with the
+// default id together with every key listed under token.keys.
+func initKeyring() (*auth.Keyring, error) {
+	var configs []keyConfig
+	if err := viper.UnmarshalKey("token.keys", &configs); err != nil {
+		return nil, err
+	}
+
+	var keys []*auth.Key
+
+	if signingKey := viper.GetString("token.signing_key"); signingKey != "" {
+		key, err := auth.ParseKey(auth.DefaultKeyId, "HS256", []byte(signingKey))
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	for _, config := range configs {
+		data, err := ioutil.ReadFile(config.File)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := auth.ParseKey(config.Id, config.Algorithm, data)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	activeKey := viper.GetString("token.active_key")
+	if activeKey == "" {
+		activeKey = auth.DefaultKeyId
+	}
+
+	return auth.NewKeyring(activeKey, keys...)
+}
+
+// getTTL reads a duration with a unit, like 15m or 720h, that is at least min.
+// Bare numbers are rejected: the TTLs used to be set in hours without a
+// unit, and would now be read as nanoseconds.
+func getTTL(key string, min time.Duration) (time.Duration, error) {
+	value := viper.GetString(key)
+	if value == "" {
+		return 0, fmt.Errorf("%s is not set", key)
+	}
+
+	if _, err := strconv.Atoi(value); err == nil {
+		return 0, fmt.Errorf("%s must have a unit, e.g. %sh for hours", key, value)
+	}
+
+	ttl, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+
+	if ttl < min {
+		return 0, fmt.Errorf("%s must be at least %s", key, min)
+	}
+
+	return ttl, nil
+}
+
+func initPaymentProvider() (payment.Provider, error) {
+	switch name := viper.GetString("payment.provider"); name {
+	case payment.MockName:
+		return payment.NewMockProvider(viper.GetString("payment.callback_secret")), nil
+	default:
+		return nil, fmt.Errorf("unknown provider %q", name)
 	}
 }
 
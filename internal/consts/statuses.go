@@ -14,4 +14,61 @@ const (
 	OrderWaitingForCourier = 3
 	OrderEnRoute           = 4
 	OrderDelivered         = 5
+
+	OrderCancelledByUser      = 6
+	OrderRejectedByRestaurant = 7
+	OrderDeliveryFailed       = 8
+	OrderRefunded             = 9
+)
+
+// Courier offer statuses. A pending offer waits for the courier until it
+// expires; the other statuses are final.
+const (
+	OfferPending  = "pending"
+	OfferAccepted = "accepted"
+	OfferDeclined = "declined"
+	OfferExpired  = "expired"
+)
+
+// Webhook delivery statuses. A pending delivery is retried until it is
+// delivered or runs out of attempts and fails.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Payment statuses, following the intent at the provider. The order is paid
+// once the payment is captured. A payment is cancelled when a newer one
+// replaces it or its order can no longer be paid.
+const (
+	PaymentPending    = "pending"
+	PaymentAuthorized = "authorized"
+	PaymentCaptured   = "captured"
+	PaymentFailed     = "failed"
+	PaymentRefunded   = "refunded"
+	PaymentCancelled  = "cancelled"
+)
+
+// Refund statuses. A pending refund holds its amount until the provider
+// returns the money or fails to.
+const (
+	RefundPending   = "pending"
+	RefundSucceeded = "succeeded"
+	RefundFailed    = "failed"
+)
+
+// Refund kinds: the whole order, a removed order item or a goodwill refund
+// made by an admin.
+const (
+	RefundOrder    = "order"
+	RefundItem     = "item"
+	RefundGoodwill = "goodwill"
+)
+
+// Review subjects. A user rates the restaurant and the courier of a
+// delivered order separately.
+const (
+	ReviewRestaurant = "restaurant"
+	ReviewCourier    = "courier"
 )
@@ -59,6 +59,9 @@ func (h *Handler) Init(router *echo.Echo) {
 
 func (h *Handler) initAPI(router *echo.Echo) {
 	handlerV1 := v1.NewHandler(h.services, h.tokenManager)
+	// the open event streams end on shutdown instead of holding it up
+	router.Server.RegisterOnShutdown(handlerV1.Shutdown)
+
 	api := router.Group("/api")
 	{
 		handlerV1.Init(api)
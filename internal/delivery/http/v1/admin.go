@@ -44,10 +44,11 @@ func (h *Handler) adminsSignIn(ctx echo.Context) error {
 
 	token, err := h.services.Admin.SignIn(input.Name, input.Password)
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, tokenResponse{
-		AccessToken: token.AccessToken,
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
 	})
 }
@@ -0,0 +1,269 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/asaskevich/govalidator"
+	"github.com/labstack/echo/v4"
+)
+
+func (h *Handler) initCartRoutes(api *echo.Group) {
+	cart := api.Group("/users/:uid/cart")
+	{
+		cart.Use(h.identity)
+		cart.GET("", h.getCart)
+		cart.DELETE("", h.deleteCart)
+		cart.POST("/items", h.addCartItem)
+		cart.PUT("/items/:id", h.updateCartItem)
+		cart.DELETE("/items/:id", h.deleteCartItem)
+		cart.POST("/checkout", h.checkoutCart)
+	}
+}
+
+type cartItemInput struct {
+	MenuItemId int `json:"menu_item_id"`
+	Count      int `json:"count" valid:"range(1|99)"`
+}
+
+type cartItemUpdateInput struct {
+	Count int `json:"count" valid:"range(1|99)"`
+}
+
+// @Summary Get Cart
+// @Security UserAuth
+// @Tags cart
+// @Description get the cart with the price of the order it would make, including delivery
+// @ModuleID getCart
+// @Accept  json
+// @Produce  json
+// @Param uid path string true "User id"
+// @Success 200 {object} domain.Cart
+// @Failure 400,403 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /users/{uid}/cart [get]
+func (h *Handler) getCart(ctx echo.Context) error {
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	userId, err := strconv.Atoi(ctx.Param("uid"))
+	if err != nil || userId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid userId")
+	}
+
+	cart, err := h.services.Cart.Get(clientId, clientType, userId)
+	if err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, cart)
+}
+
+// @Summary Delete Cart
+// @Security UserAuth
+// @Tags cart
+// @Description empty the cart
+// @ModuleID deleteCart
+// @Accept  json
+// @Produce  json
+// @Param uid path string true "User id"
+// @Success 200 {object} response
+// @Failure 400,403 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /users/{uid}/cart [delete]
+func (h *Handler) deleteCart(ctx echo.Context) error {
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	userId, err := strconv.Atoi(ctx.Param("uid"))
+	if err != nil || userId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid userId")
+	}
+
+	if err := h.services.Cart.Delete(clientId, clientType, userId); err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, nil)
+}
+
+// @Summary Add Cart Item
+// @Security UserAuth
+// @Tags cart
+// @Description add a dish to the cart; dishes of another restaurant replace the cart only with replace=true
+// @ModuleID addCartItem
+// @Accept  json
+// @Produce  json
+// @Param uid path string true "User id"
+// @Param replace query bool false "Replace dishes of another restaurant"
+// @Param input body cartItemInput true "cart item info"
+// @Success 200 {object} idResponse
+// @Failure 400,403,409,422 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /users/{uid}/cart/items [post]
+func (h *Handler) addCartItem(ctx echo.Context) error {
+	var input cartItemInput
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	userId, err := strconv.Atoi(ctx.Param("uid"))
+	if err != nil || userId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid userId")
+	}
+
+	replace := false
+	if value := ctx.QueryParam("replace"); value != "" {
+		replace, err = strconv.ParseBool(value)
+		if err != nil {
+			return newResponse(ctx, http.StatusBadRequest, "Invalid replace")
+		}
+	}
+
+	if err := ctx.Bind(&input); err != nil {
+		return newResponse(ctx, http.StatusBadRequest, err.Error())
+	}
+
+	if _, err := govalidator.ValidateStruct(input); err != nil {
+		return newResponse(ctx, http.StatusBadRequest, err.Error())
+	}
+
+	cartItemId, err := h.services.Cart.AddItem(clientId, clientType, userId, &domain.CartItem{
+		MenuItemId: input.MenuItemId,
+		Count:      input.Count,
+	}, replace)
+	if err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, idResponse{
+		Id: cartItemId,
+	})
+}
+
+// @Summary Update Cart Item
+// @Security UserAuth
+// @Tags cart
+// @Description change the count of a dish in the cart
+// @ModuleID updateCartItem
+// @Accept  json
+// @Produce  json
+// @Param uid path string true "User id"
+// @Param id path string true "Cart item id"
+// @Param input body cartItemUpdateInput true "cart item info"
+// @Success 200 {object} response
+// @Failure 400,403,404 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /users/{uid}/cart/items/{id} [put]
+func (h *Handler) updateCartItem(ctx echo.Context) error {
+	var input cartItemUpdateInput
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	userId, err := strconv.Atoi(ctx.Param("uid"))
+	if err != nil || userId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid userId")
+	}
+
+	cartItemId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || cartItemId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid cartItemId")
+	}
+
+	if err := ctx.Bind(&input); err != nil {
+		return newResponse(ctx, http.StatusBadRequest, err.Error())
+	}
+
+	if _, err := govalidator.ValidateStruct(input); err != nil {
+		return newResponse(ctx, http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.services.Cart.UpdateItem(clientId, clientType, userId, cartItemId, input.Count); err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, nil)
+}
+
+// @Summary Delete Cart Item
+// @Security UserAuth
+// @Tags cart
+// @Description remove a dish from the cart
+// @ModuleID deleteCartItem
+// @Accept  json
+// @Produce  json
+// @Param uid path string true "User id"
+// @Param id path string true "Cart item id"
+// @Success 200 {object} response
+// @Failure 400,403,404 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /users/{uid}/cart/items/{id} [delete]
+func (h *Handler) deleteCartItem(ctx echo.Context) error {
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	userId, err := strconv.Atoi(ctx.Param("uid"))
+	if err != nil || userId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid userId")
+	}
+
+	cartItemId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || cartItemId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid cartItemId")
+	}
+
+	if err := h.services.Cart.DeleteItem(clientId, clientType, userId, cartItemId); err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, nil)
+}
+
+// @Summary Checkout Cart
+// @Security UserAuth
+// @Tags cart
+// @Description create an order with all the dishes of the cart and empty it
+// @ModuleID checkoutCart
+// @Accept  json
+// @Produce  json
+// @Param uid path string true "User id"
+// @Success 200 {object} idResponse
+// @Failure 400,403,409 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /users/{uid}/cart/checkout [post]
+func (h *Handler) checkoutCart(ctx echo.Context) error {
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	userId, err := strconv.Atoi(ctx.Param("uid"))
+	if err != nil || userId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid userId")
+	}
+
+	orderId, err := h.services.Cart.Checkout(clientId, clientType, userId)
+	if err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, idResponse{
+		Id: orderId,
+	})
+}
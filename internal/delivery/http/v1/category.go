@@ -68,7 +68,7 @@ func (h *Handler) createCategory(ctx echo.Context) error {
 
 	categoryId, err := h.services.Category.Create(clientId, clientType, category)
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, idResponse{
@@ -103,7 +103,7 @@ func (h *Handler) getCategories(ctx echo.Context) error {
 
 	categories, err := h.services.Category.GetAll(clientId, clientType, restaurantId)
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, categories)
@@ -142,7 +142,7 @@ func (h *Handler) getCategoryById(ctx echo.Context) error {
 
 	restaurant, err := h.services.Category.GetById(clientId, clientType, restaurantId, categoryId)
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, restaurant)
@@ -181,7 +181,7 @@ func (h *Handler) getMenuByCategoryId(ctx echo.Context) error {
 
 	menuItems, err := h.services.Category.GetAllItems(clientId, clientType, restaurantId, categoryId)
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, menuItems)
@@ -220,7 +220,7 @@ func (h *Handler) deleteCategory(ctx echo.Context) error {
 	err = h.services.Category.DeleteCategory(clientId, clientType, restaurantId, categoryId)
 
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, nil)
@@ -271,7 +271,7 @@ func (h *Handler) updateCategory(ctx echo.Context) error {
 	err = h.services.Category.UpdateCategory(clientId, clientType, restaurantId, categoryId, category)
 
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, nil)
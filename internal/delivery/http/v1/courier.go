@@ -70,7 +70,7 @@ func (h *Handler) couriersSignUp(ctx echo.Context) error {
 
 	id, err := h.services.Courier.SignUp(courier, clientType)
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, map[string]interface{}{
@@ -108,11 +108,12 @@ func (h *Handler) couriersSignIn(ctx echo.Context) error {
 
 	token, err := h.services.Courier.SignIn(input.Phone, input.Password)
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, tokenResponse{
-		AccessToken: token.AccessToken,
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
 	})
 }
 
@@ -143,7 +144,7 @@ func (h *Handler) getCourierById(ctx echo.Context) error {
 
 	courier, err := h.services.Courier.GetById(clientId, clientType, courierId)
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, courier)
@@ -206,7 +207,7 @@ func (h *Handler) updateCourier(ctx echo.Context) error {
 	err = h.services.Courier.Update(clientId, clientType, courierId, update)
 
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, nil)
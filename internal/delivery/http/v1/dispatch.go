@@ -0,0 +1,158 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (h *Handler) initDispatchRoutes(api *echo.Group) {
+	admins := api.Group("/admins")
+	{
+		admins.Use(h.identity)
+		admins.GET("/dispatch", h.getPendingDispatch)
+	}
+
+	couriers := api.Group("/couriers")
+	{
+		couriers.Use(h.identity)
+		couriers.GET("/:cid/offers", h.getCourierOffers)
+		couriers.POST("/:cid/offers/:id/accept", h.acceptCourierOffer)
+		couriers.POST("/:cid/offers/:id/decline", h.declineCourierOffer)
+	}
+}
+
+// @Summary Get Dispatch Queue
+// @Security AdminAuth
+// @Tags dispatch
+// @Description get paid orders waiting for a courier, the longest waiting first
+// @ModuleID getPendingDispatch
+// @Accept  json
+// @Produce  json
+// @Success 200 {array} domain.PendingOrder
+// @Failure 401,403 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /admins/dispatch [get]
+func (h *Handler) getPendingDispatch(ctx echo.Context) error {
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	orders, err := h.services.Dispatch.GetPending(clientId, clientType)
+	if err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, orders)
+}
+
+// @Summary Get Courier Offers
+// @Security CourierAuth
+// @Tags dispatch
+// @Description get orders offered to the courier and not answered yet
+// @ModuleID getCourierOffers
+// @Accept  json
+// @Produce  json
+// @Param cid path string true "Courier id"
+// @Success 200 {array} domain.CourierOffer
+// @Failure 400,401,403 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /couriers/{cid}/offers [get]
+func (h *Handler) getCourierOffers(ctx echo.Context) error {
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	courierId, err := strconv.Atoi(ctx.Param("cid"))
+	if err != nil || courierId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid courierId")
+	}
+
+	offers, err := h.services.Dispatch.GetOffers(clientId, clientType, courierId)
+	if err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, offers)
+}
+
+// @Summary Accept Courier Offer
+// @Security CourierAuth
+// @Tags dispatch
+// @Description take the offered order, the courier starts working on it
+// @ModuleID acceptCourierOffer
+// @Accept  json
+// @Produce  json
+// @Param cid path string true "Courier id"
+// @Param id path string true "Offer id"
+// @Success 200 {object} response
+// @Failure 400,401,403,404,409 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /couriers/{cid}/offers/{id}/accept [post]
+func (h *Handler) acceptCourierOffer(ctx echo.Context) error {
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	courierId, err := strconv.Atoi(ctx.Param("cid"))
+	if err != nil || courierId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid courierId")
+	}
+
+	offerId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || offerId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid offerId")
+	}
+
+	err = h.services.Dispatch.AcceptOffer(clientId, clientType, courierId, offerId)
+	if err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, nil)
+}
+
+// @Summary Decline Courier Offer
+// @Security CourierAuth
+// @Tags dispatch
+// @Description refuse the offered order, it is offered to the next courier
+// @ModuleID declineCourierOffer
+// @Accept  json
+// @Produce  json
+// @Param cid path string true "Courier id"
+// @Param id path string true "Offer id"
+// @Success 200 {object} response
+// @Failure 400,401,403,404,409 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /couriers/{cid}/offers/{id}/decline [post]
+func (h *Handler) declineCourierOffer(ctx echo.Context) error {
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	courierId, err := strconv.Atoi(ctx.Param("cid"))
+	if err != nil || courierId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid courierId")
+	}
+
+	offerId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || offerId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid offerId")
+	}
+
+	err = h.services.Dispatch.DeclineOffer(clientId, clientType, courierId, offerId)
+	if err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, nil)
+}
@@ -0,0 +1,153 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// eventsHeartbeat keeps idle connections from being closed by proxies.
+	eventsHeartbeat = 15 * time.Second
+	// eventsRevocationCheck is how often an open stream checks that its
+	// token hasn't been revoked since.
+	eventsRevocationCheck = 30 * time.Second
+)
+
+func (h *Handler) initEventRoutes(api *echo.Group) {
+	api.POST("/events/tickets", h.createEventTicket, h.identity)
+	api.GET("/events", h.streamEvents, h.eventTicket)
+}
+
+// eventTicket lets clients that can't set headers, like the browser
+// EventSource, open the stream with a ticket in the ticket query parameter.
+// Tickets are used once and expire soon, so unlike an access token there is
+// nothing to steal from the request logs.
+func (h *Handler) eventTicket(next echo.HandlerFunc) echo.HandlerFunc {
+	identity := h.identity(next)
+
+	return func(ctx echo.Context) error {
+		ticket := ctx.QueryParam("ticket")
+		if ticket == "" {
+			return identity(ctx)
+		}
+
+		claims, err := h.services.EventTicket.Redeem(ticket)
+		if err != nil {
+			return newErrorResponse(ctx, err)
+		}
+
+		return h.setClaims(ctx, claims, next)
+	}
+}
+
+// @Summary Create Event Stream Ticket
+// @Security AdminAuth
+// @Security UserAuth
+// @Security CourierAuth
+// @Security RestaurantAuth
+// @Tags events
+// @Description create a single-use ticket to open the event stream with, for clients that can't set
+// @Description the Authorization header; it expires in 30 seconds and the stream ends with the token
+// @ModuleID createEventTicket
+// @Produce  json
+// @Success 200 {object} domain.EventTicket
+// @Failure 401 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /events/tickets [post]
+func (h *Handler) createEventTicket(ctx echo.Context) error {
+	claims, err := h.getClaims(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	ticket, err := h.services.EventTicket.Create(claims)
+	if err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, ticket)
+}
+
+// @Summary Stream Order Events
+// @Security AdminAuth
+// @Security UserAuth
+// @Security CourierAuth
+// @Security RestaurantAuth
+// @Tags events
+// @Description stream order changes of the client as Server-Sent Events: status changes,
+// @Description incoming paid orders for restaurants, offers and assignments for couriers,
+// @Description orders waiting too long for a courier for admins; the stream ends when the token
+// @Description expires or is revoked, or when the server shuts down
+// @ModuleID streamEvents
+// @Produce  text/event-stream
+// @Param ticket query string false "ticket from POST /events/tickets, when the Authorization header can't be set"
+// @Success 200 {object} domain.Event
+// @Failure 401 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /events [get]
+func (h *Handler) streamEvents(ctx echo.Context) error {
+	claims, err := h.getClaims(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	events, cancel := h.services.Events.Subscribe(claims.ClientId, claims.ClientType)
+	defer cancel()
+
+	resp := ctx.Response()
+	resp.Header().Set(echo.HeaderContentType, "text/event-stream")
+	resp.Header().Set("Cache-Control", "no-cache")
+	resp.Header().Set("Connection", "keep-alive")
+	resp.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprint(resp, ": connected\n\n"); err != nil {
+		return nil
+	}
+	resp.Flush()
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+
+	revocationCheck := time.NewTicker(eventsRevocationCheck)
+	defer revocationCheck.Stop()
+
+	expired := time.NewTimer(time.Until(claims.ExpiresAt))
+	defer expired.Stop()
+
+	for {
+		select {
+		case <-ctx.Request().Context().Done():
+			return nil
+		case <-h.streams.Done():
+			return nil
+		case <-expired.C:
+			return nil
+		case <-revocationCheck.C:
+			revoked, err := h.services.Revocation.IsRevoked(claims)
+			if err != nil || revoked {
+				return nil
+			}
+			continue
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(resp, ": ping\n\n"); err != nil {
+				return nil
+			}
+		case event := <-events:
+			data, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+
+			if _, err := fmt.Fprintf(resp, "id: %s\nevent: %s\ndata: %s\n\n", event.Id, event.Type, data); err != nil {
+				return nil
+			}
+		}
+		resp.Flush()
+	}
+}
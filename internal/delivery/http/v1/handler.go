@@ -1,11 +1,13 @@
 package v1
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/MAVIKE/yad-backend/internal/service"
 	"github.com/MAVIKE/yad-backend/pkg/auth"
@@ -15,21 +17,36 @@ import (
 type Handler struct {
 	services     *service.Service
 	tokenManager *auth.Manager
+
+	// streams is done once the server shuts down
+	streams     context.Context
+	stopStreams context.CancelFunc
 }
 
 func NewHandler(services *service.Service, tokenManager *auth.Manager) *Handler {
+	streams, stopStreams := context.WithCancel(context.Background())
+
 	return &Handler{
 		services:     services,
 		tokenManager: tokenManager,
+		streams:      streams,
+		stopStreams:  stopStreams,
 	}
 }
 
+// Shutdown ends the open event streams. The server doesn't cancel the
+// running requests on shutdown, so it would wait for them until it times out.
+func (h *Handler) Shutdown() {
+	h.stopStreams()
+}
+
 func (h *Handler) Init(api *echo.Group) {
 	v1 := api.Group("/v1")
 	{
 		v1.GET("/ping", func(c echo.Context) error {
 			return c.String(http.StatusOK, "pong")
 		})
+		v1.GET("/.well-known/jwks.json", h.getJWKS)
 
 		h.initAdminRoutes(v1)
 		h.initUserRoutes(v1)
@@ -38,6 +55,15 @@ func (h *Handler) Init(api *echo.Group) {
 		h.initCategoryRoutes(v1)
 		h.initMenuRoutes(v1)
 		h.initOrderRoutes(v1)
+		h.initSessionRoutes(v1)
+		h.initDispatchRoutes(v1)
+		h.initTrackingRoutes(v1)
+		h.initEventRoutes(v1)
+		h.initWebhookRoutes(v1)
+		h.initPaymentRoutes(v1)
+		h.initRefundRoutes(v1)
+		h.initCartRoutes(v1)
+		h.initReviewRoutes(v1)
 	}
 }
 
@@ -48,15 +74,58 @@ func (h *Handler) identity(next echo.HandlerFunc) echo.HandlerFunc {
 			return newResponse(ctx, http.StatusUnauthorized, err.Error())
 		}
 
-		userId, clientType, err := h.tokenManager.Parse(token)
+		claims, err := h.tokenManager.Parse(token)
 		if err != nil {
 			return newResponse(ctx, http.StatusUnauthorized, err.Error())
 		}
 
-		ctx.Request().Header.Set(idCtx, strconv.Itoa(userId))
-		ctx.Request().Header.Set(clientTypeCtx, clientType)
-		return next(ctx)
+		return h.setClaims(ctx, claims, next)
+	}
+}
+
+// setClaims passes the claims of a token that isn't revoked on to next.
+func (h *Handler) setClaims(ctx echo.Context, claims *auth.Claims, next echo.HandlerFunc) error {
+	revoked, err := h.services.Revocation.IsRevoked(claims)
+	if err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	if revoked {
+		return newResponse(ctx, http.StatusUnauthorized, "Token revoked")
 	}
+
+	ctx.Request().Header.Set(idCtx, strconv.Itoa(claims.ClientId))
+	ctx.Request().Header.Set(clientTypeCtx, claims.ClientType)
+	ctx.Request().Header.Set(tokenIdCtx, claims.TokenId)
+	ctx.Request().Header.Set(tokenIssuedCtx, strconv.FormatInt(claims.IssuedAt.Unix(), 10))
+	ctx.Request().Header.Set(tokenExpiresCtx, strconv.FormatInt(claims.ExpiresAt.Unix(), 10))
+	return next(ctx)
+}
+
+// getClaims returns the claims of the token the request was made with.
+func (h *Handler) getClaims(ctx echo.Context) (*auth.Claims, error) {
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenId, expiresAt, err := h.getTokenParams(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	issuedAt, err := strconv.ParseInt(ctx.Request().Header.Get(tokenIssuedCtx), 10, 64)
+	if err != nil {
+		return nil, errors.New("token issue time is of invalid type")
+	}
+
+	return &auth.Claims{
+		ClientId:   clientId,
+		ClientType: clientType,
+		TokenId:    tokenId,
+		IssuedAt:   time.Unix(issuedAt, 0),
+		ExpiresAt:  expiresAt,
+	}, nil
 }
 
 func (h *Handler) getClientParams(ctx echo.Context) (int, string, error) {
@@ -78,6 +147,20 @@ func (h *Handler) getClientParams(ctx echo.Context) (int, string, error) {
 	return intId, clientType, nil
 }
 
+func (h *Handler) getTokenParams(ctx echo.Context) (string, time.Time, error) {
+	tokenId := ctx.Request().Header.Get(tokenIdCtx)
+	if tokenId == "" {
+		return "", time.Time{}, errors.New("token id not found")
+	}
+
+	expiresAt, err := strconv.ParseInt(ctx.Request().Header.Get(tokenExpiresCtx), 10, 64)
+	if err != nil {
+		return "", time.Time{}, errors.New("token expiration is of invalid type")
+	}
+
+	return tokenId, time.Unix(expiresAt, 0), nil
+}
+
 func (h *Handler) getToken(ctx echo.Context) (string, error) {
 	header := ctx.Request().Header.Get(authorizationHeader)
 	if header == "" {
@@ -96,6 +179,13 @@ func (h *Handler) getToken(ctx echo.Context) (string, error) {
 	return headerParts[1], nil
 }
 
+const (
+	adminType      = "admin"
+	userType       = "user"
+	courierType    = "courier"
+	restaurantType = "restaurant"
+)
+
 type locationInput struct {
 	Latitude  float64 `json:"latitude" valid:"required,latitude"`
 	Longitude float64 `json:"longitude" valid:"required,longitude"`
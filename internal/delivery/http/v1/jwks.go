@@ -0,0 +1,20 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// @Summary Get JWKS
+// @Tags auth
+// @Description public keys access tokens can be verified with
+// @ModuleID getJWKS
+// @Produce  json
+// @Success 200 {object} auth.JWKS
+// @Failure default {object} response
+// @Router /.well-known/jwks.json [get]
+func (h *Handler) getJWKS(ctx echo.Context) error {
+	ctx.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return ctx.JSON(http.StatusOK, h.tokenManager.JWKS())
+}
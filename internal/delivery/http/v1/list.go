@@ -0,0 +1,97 @@
+package v1
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MAVIKE/yad-backend/internal/consts"
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/labstack/echo/v4"
+)
+
+// getListParams reads the cursor, the page size and the sort of a list
+// from the query.
+func getListParams(ctx echo.Context) (domain.ListParams, error) {
+	limit, err := queryInt(ctx, "limit")
+	if err != nil {
+		return domain.ListParams{}, err
+	}
+
+	if limit == 0 {
+		limit = domain.DefaultListLimit
+	}
+
+	return domain.ListParams{
+		Cursor: ctx.QueryParam("cursor"),
+		Limit:  limit,
+		Sort:   ctx.QueryParam("sort"),
+	}, nil
+}
+
+// getOrderFilter reads the filter of a list of orders from the query. The
+// status is "active" for the orders on their way or a comma separated list
+// of statuses, the dates are RFC 3339.
+func getOrderFilter(ctx echo.Context) (*domain.OrderFilter, error) {
+	params, err := getListParams(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := &domain.OrderFilter{ListParams: params}
+
+	switch value := ctx.QueryParam("status"); value {
+	case "":
+	case "active":
+		filter.Statuses = []int{consts.OrderPaid, consts.OrderPreparing, consts.OrderWaitingForCourier, consts.OrderEnRoute}
+	default:
+		for _, part := range strings.Split(value, ",") {
+			status, err := strconv.Atoi(part)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid status")
+			}
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+
+	if filter.From, err = queryTime(ctx, "from"); err != nil {
+		return nil, err
+	}
+
+	if filter.To, err = queryTime(ctx, "to"); err != nil {
+		return nil, err
+	}
+
+	return filter, nil
+}
+
+// queryInt returns the integer query parameter, zero if it is missing.
+func queryInt(ctx echo.Context, name string) (int, error) {
+	value := ctx.QueryParam(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid %s", name)
+	}
+
+	return number, nil
+}
+
+// queryTime returns the RFC 3339 query parameter, nil if it is missing.
+func queryTime(ctx echo.Context, name string) (*time.Time, error) {
+	value := ctx.QueryParam(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid %s", name)
+	}
+
+	return &t, nil
+}
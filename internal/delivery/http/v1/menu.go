@@ -37,8 +37,13 @@ func (h *Handler) initMenuRoutes(api *echo.Group) {
 // @Accept  json
 // @Produce  json
 // @Param rid path string true "Restaurant id"
-// @Success 200 {array} domain.MenuItem
-// @Failure 400,403,404 {object} response
+// @Param cursor query string false "Next cursor of the previous page"
+// @Param limit query int false "Page size, 20 by default"
+// @Param sort query string false "id, title or price, - for descending"
+// @Param min_price query int false "Min price"
+// @Param max_price query int false "Max price"
+// @Success 200 {object} domain.MenuPage
+// @Failure 400,403,404,422 {object} response
 // @Failure 500 {object} response
 // @Failure default {object} response
 // @Router /restaurants/{rid}/menu/ [get]
@@ -53,12 +58,26 @@ func (h *Handler) getRestaurantMenu(ctx echo.Context) error {
 		return newResponse(ctx, http.StatusBadRequest, "Invalid restaurantId")
 	}
 
-	menu, err := h.services.Restaurant.GetMenu(clientId, clientType, restaurantId)
+	params, err := getListParams(ctx)
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newResponse(ctx, http.StatusBadRequest, err.Error())
+	}
+
+	filter := &domain.MenuFilter{ListParams: params}
+	if filter.MinPrice, err = queryInt(ctx, "min_price"); err != nil {
+		return newResponse(ctx, http.StatusBadRequest, err.Error())
+	}
+
+	if filter.MaxPrice, err = queryInt(ctx, "max_price"); err != nil {
+		return newResponse(ctx, http.StatusBadRequest, err.Error())
 	}
 
-	return ctx.JSON(http.StatusOK, menu)
+	page, err := h.services.Restaurant.GetMenu(clientId, clientType, restaurantId, filter)
+	if err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, page)
 }
 
 // @Summary Get Menu Item By Id
@@ -92,7 +111,7 @@ func (h *Handler) getMenuItemById(ctx echo.Context) error {
 
 	menuItem, err := h.services.MenuItem.GetById(clientId, clientType, menuItemId, restaurantId)
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, menuItem)
@@ -156,7 +175,7 @@ func (h *Handler) updateMenuItem(ctx echo.Context) error {
 	err = h.services.MenuItem.UpdateMenuItem(clientId, clientType, restaurantId, menuItemId, input.CategoryId, update)
 
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, nil)
@@ -214,7 +233,7 @@ func (h *Handler) createMenuItem(ctx echo.Context) error {
 
 	menuItemId, err := h.services.MenuItem.Create(clientId, clientType, menuItem, input.CategoryId)
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, idResponse{
@@ -286,7 +305,7 @@ func (h *Handler) updateMenuItemImage(ctx echo.Context) error {
 
 	_, err = h.services.MenuItem.GetById(clientId, clientType, menuItemId, restaurantId)
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	file, err := ctx.FormFile("file")
@@ -305,7 +324,7 @@ func (h *Handler) updateMenuItemImage(ctx echo.Context) error {
 
 	menuItem, err := h.services.MenuItem.UpdateImage(clientId, clientType, restaurantId, menuItemId, fileName)
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, menuItem)
@@ -343,7 +362,7 @@ func (h *Handler) deleteMenuItem(ctx echo.Context) error {
 
 	err = h.services.MenuItem.Delete(clientId, clientType, restaurantId, menuItemId)
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, nil)
@@ -17,6 +17,9 @@ func (h *Handler) initOrderRoutes(api *echo.Group) {
 		orders.GET("/:oid", h.getOrderById)
 		orders.DELETE("/:oid", h.deleteOrder)
 		orders.PUT("/:oid", h.updateOrder)
+		orders.GET("/:oid/transitions", h.getOrderTransitions)
+		orders.GET("/:oid/timeline", h.getOrderTimeline)
+		orders.GET("/:oid/receipt", h.getOrderReceipt)
 
 		orderItems := orders.Group("/:oid/items")
 		{
@@ -59,7 +62,7 @@ type orderInput struct {
 // @Produce  json
 // @Param input body orderInput true "order input info"
 // @Success 200 {object} idResponse
-// @Failure 400,403,404 {object} response
+// @Failure 400,403,404,409,422 {object} response
 // @Failure 500 {object} response
 // @Failure default {object} response
 // @Router /orders/ [post]
@@ -84,7 +87,7 @@ func (h *Handler) createOrder(ctx echo.Context) error {
 
 	orderId, err := h.services.Order.Create(clientId, clientType, order)
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, idResponse{
@@ -120,7 +123,7 @@ func (h *Handler) getOrderItems(ctx echo.Context) error {
 
 	orderItems, err := h.services.Order.GetAllItems(clientId, clientType, orderId)
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, orderItems)
@@ -154,7 +157,7 @@ func (h *Handler) getOrderById(ctx echo.Context) error {
 
 	orderItem, err := h.services.Order.GetById(clientId, clientType, orderId)
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, orderItem)
@@ -187,17 +190,18 @@ func (h *Handler) deleteOrder(ctx echo.Context) error {
 
 	err = h.services.Order.Delete(clientId, clientType, orderId)
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, nil)
 }
 
 type orderUpdate struct {
-	Status int `json:"status" valid:"range(0|5)"`
+	Status int `json:"status" valid:"range(0|9)"`
 }
 
 // @Summary Update Order
+// @Security AdminAuth
 // @Security UserAuth
 // @Security RestaurantAuth
 // @Security CourierAuth
@@ -209,7 +213,7 @@ type orderUpdate struct {
 // @Param oid path string true "Order id"
 // @Param input body orderUpdate true "order update info"
 // @Success 200 {object} response
-// @Failure 400,403,404 {object} response
+// @Failure 400,403,404,409,422 {object} response
 // @Failure 500 {object} response
 // @Failure default {object} response
 // @Router /orders/{oid} [put]
@@ -239,12 +243,117 @@ func (h *Handler) updateOrder(ctx echo.Context) error {
 
 	err = h.services.Order.Update(clientId, clientType, orderId, update)
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, nil)
 }
 
+// @Summary Get Order Transitions
+// @Security AdminAuth
+// @Security UserAuth
+// @Security RestaurantAuth
+// @Security CourierAuth
+// @Tags orders
+// @Description get statuses the caller may move the order to
+// @ModuleID getOrderTransitions
+// @Accept  json
+// @Produce  json
+// @Param oid path string true "Order id"
+// @Success 200 {array} domain.OrderTransition
+// @Failure 400,403,404 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /orders/{oid}/transitions [get]
+func (h *Handler) getOrderTransitions(ctx echo.Context) error {
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	orderId, err := strconv.Atoi(ctx.Param("oid"))
+	if err != nil || orderId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid orderId")
+	}
+
+	transitions, err := h.services.Order.GetTransitions(clientId, clientType, orderId)
+	if err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, transitions)
+}
+
+// @Summary Get Order Timeline
+// @Security AdminAuth
+// @Security UserAuth
+// @Security RestaurantAuth
+// @Security CourierAuth
+// @Tags orders
+// @Description get status changes of the order, oldest first
+// @ModuleID getOrderTimeline
+// @Accept  json
+// @Produce  json
+// @Param oid path string true "Order id"
+// @Success 200 {array} domain.OrderEvent
+// @Failure 400,403,404 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /orders/{oid}/timeline [get]
+func (h *Handler) getOrderTimeline(ctx echo.Context) error {
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	orderId, err := strconv.Atoi(ctx.Param("oid"))
+	if err != nil || orderId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid orderId")
+	}
+
+	events, err := h.services.Order.GetTimeline(clientId, clientType, orderId)
+	if err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, events)
+}
+
+// @Summary Get Order Receipt
+// @Security AdminAuth
+// @Security UserAuth
+// @Security RestaurantAuth
+// @Security CourierAuth
+// @Tags orders
+// @Description get the order items with the prices they were added at, the delivery price and the total
+// @ModuleID getOrderReceipt
+// @Accept  json
+// @Produce  json
+// @Param oid path string true "Order id"
+// @Success 200 {object} domain.Receipt
+// @Failure 400,403,404 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /orders/{oid}/receipt [get]
+func (h *Handler) getOrderReceipt(ctx echo.Context) error {
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	orderId, err := strconv.Atoi(ctx.Param("oid"))
+	if err != nil || orderId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid orderId")
+	}
+
+	receipt, err := h.services.Order.GetReceipt(clientId, clientType, orderId)
+	if err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, receipt)
+}
+
 type orderItemInput struct {
 	MenuItemId int `json:"menu_item_id"`
 	Count      int `json:"count" valid:"range(1|99)"`
@@ -260,7 +369,7 @@ type orderItemInput struct {
 // @Param oid path string true "Order id"
 // @Param input body orderItemInput true "order item create info"
 // @Success 200 {object} idResponse
-// @Failure 400,403,404 {object} response
+// @Failure 400,403,404,409,422 {object} response
 // @Failure 500 {object} response
 // @Failure default {object} response
 // @Router /orders/{oid}/items/ [post]
@@ -292,7 +401,7 @@ func (h *Handler) createOrderItem(ctx echo.Context) error {
 
 	orderItemId, err := h.services.Order.CreateItem(clientId, clientType, orderItem)
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, idResponse{
@@ -334,7 +443,7 @@ func (h *Handler) getOrderItemById(ctx echo.Context) error {
 
 	orderItem, err := h.services.Order.GetItemById(clientId, clientType, orderId, orderItemId)
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, orderItem)
@@ -342,15 +451,16 @@ func (h *Handler) getOrderItemById(ctx echo.Context) error {
 
 // @Summary Delete Order Item
 // @Security UserAuth
+// @Security RestaurantAuth
 // @Tags orders
-// @Description delete order item
+// @Description delete order item, the restaurant removing an item from a paid order refunds its price
 // @ModuleID deleteOrderItem
 // @Accept  json
 // @Produce  json
 // @Param oid path string true "Order id"
 // @Param id path string true "Order item id"
 // @Success 200 {object} response
-// @Failure 400,403,404 {object} response
+// @Failure 400,403,404,409 {object} response
 // @Failure 500 {object} response
 // @Failure default {object} response
 // @Router /orders/{oid}/items/{id} [delete]
@@ -372,7 +482,7 @@ func (h *Handler) deleteOrderItem(ctx echo.Context) error {
 
 	err = h.services.Order.DeleteItem(clientId, clientType, orderId, orderItemId)
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, nil)
@@ -393,7 +503,7 @@ type orderItemUpdate struct {
 // @Param id path string true "Order item id"
 // @Param input body orderItemUpdate true "order item update info"
 // @Success 200 {object} response
-// @Failure 400,403,404 {object} response
+// @Failure 400,403,404,409 {object} response
 // @Failure 500 {object} response
 // @Failure default {object} response
 // @Router /orders/{oid}/items/{id} [put]
@@ -425,7 +535,7 @@ func (h *Handler) updateOrderItem(ctx echo.Context) error {
 	err = h.services.Order.UpdateItem(clientId, clientType, orderId, orderItemId, input.Count)
 
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, nil)
@@ -434,13 +544,19 @@ func (h *Handler) updateOrderItem(ctx echo.Context) error {
 // @Summary Get All Active Orders For Restaurant
 // @Security RestaurantAuth
 // @Tags orders
-// @Description get all active orders for restaurant
+// @Description get a page of the orders of the restaurant, the paid ones unless a status is given
 // @ModuleID getActiveRestaurantOrders
 // @Accept  json
 // @Produce  json
 // @Param rid path string true "Restaurant id"
-// @Success 200 {array} domain.Order
-// @Failure 400,403,404 {object} response
+// @Param cursor query string false "Next cursor of the previous page"
+// @Param limit query int false "Page size, 20 by default"
+// @Param sort query string false "created_at or total_price, - for descending, -created_at by default"
+// @Param status query string false "active or comma separated statuses"
+// @Param from query string false "Created at or after, RFC 3339"
+// @Param to query string false "Created before, RFC 3339"
+// @Success 200 {object} domain.OrderPage
+// @Failure 400,403,404,422 {object} response
 // @Failure 500 {object} response
 // @Failure default {object} response
 // @Router /restaurants/{rid}/orders/ [get]
@@ -455,12 +571,17 @@ func (h *Handler) getActiveRestaurantOrders(ctx echo.Context) error {
 		return newResponse(ctx, http.StatusBadRequest, "Invalid restaurantId")
 	}
 
-	orders, err := h.services.Order.GetActiveRestaurantOrders(clientId, clientType, restaurantId)
+	filter, err := getOrderFilter(ctx)
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newResponse(ctx, http.StatusBadRequest, err.Error())
+	}
+
+	page, err := h.services.Order.GetRestaurantOrders(clientId, clientType, restaurantId, filter)
+	if err != nil {
+		return newErrorResponse(ctx, err)
 	}
 
-	return ctx.JSON(http.StatusOK, orders)
+	return ctx.JSON(http.StatusOK, page)
 }
 
 // @Summary Get All Orders
@@ -472,9 +593,14 @@ func (h *Handler) getActiveRestaurantOrders(ctx echo.Context) error {
 // @Accept  json
 // @Produce  json
 // @Param uid path string true "User id"
-// @Param status query string false "Status"
-// @Success 200 {array} domain.Order
-// @Failure 400,403,404 {object} response
+// @Param cursor query string false "Next cursor of the previous page"
+// @Param limit query int false "Page size, 20 by default"
+// @Param sort query string false "created_at or total_price, - for descending, -created_at by default"
+// @Param status query string false "active or comma separated statuses"
+// @Param from query string false "Created at or after, RFC 3339"
+// @Param to query string false "Created before, RFC 3339"
+// @Success 200 {object} domain.OrderPage
+// @Failure 400,403,404,422 {object} response
 // @Failure 500 {object} response
 // @Failure default {object} response
 // @Router /users/{uid}/orders [get]
@@ -489,18 +615,17 @@ func (h *Handler) usersGetAllOrders(ctx echo.Context) error {
 		return newResponse(ctx, http.StatusBadRequest, "Invalid userId")
 	}
 
-	orderStatus := ctx.QueryParam("status")
-	activeOrdersFlag := false
-	if orderStatus == "active" {
-		activeOrdersFlag = true
+	filter, err := getOrderFilter(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusBadRequest, err.Error())
 	}
 
-	orders, err := h.services.User.GetAllOrders(clientId, clientType, userId, activeOrdersFlag)
+	page, err := h.services.User.GetAllOrders(clientId, clientType, userId, filter)
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
-	return ctx.JSON(http.StatusOK, orders)
+	return ctx.JSON(http.StatusOK, page)
 }
 
 // @Summary Get Active Order
@@ -529,7 +654,7 @@ func (h *Handler) getActiveCourierOrder(ctx echo.Context) error {
 
 	order, err := h.services.Order.GetActiveCourierOrder(clientId, clientType, courierId)
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, order)
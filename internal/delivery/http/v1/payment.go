@@ -0,0 +1,143 @@
+package v1
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (h *Handler) initPaymentRoutes(api *echo.Group) {
+	orders := api.Group("/orders")
+	{
+		orders.Use(h.identity)
+		orders.POST("/:oid/payment", h.createPayment)
+		orders.GET("/:oid/payment", h.getPayment)
+		orders.POST("/:oid/payment/confirm", h.confirmPayment)
+	}
+
+	// the provider authenticates callbacks with a signature
+	api.POST("/payments/callback", h.paymentCallback)
+}
+
+// @Summary Create Payment
+// @Security UserAuth
+// @Tags payments
+// @Description start paying the total price of the order
+// @ModuleID createPayment
+// @Accept  json
+// @Produce  json
+// @Param oid path string true "Order id"
+// @Success 200 {object} domain.Payment
+// @Failure 400,401,403,404,409 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /orders/{oid}/payment [post]
+func (h *Handler) createPayment(ctx echo.Context) error {
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	orderId, err := strconv.Atoi(ctx.Param("oid"))
+	if err != nil || orderId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid orderId")
+	}
+
+	payment, err := h.services.Payment.Create(clientId, clientType, orderId)
+	if err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, payment)
+}
+
+// @Summary Get Payment
+// @Security UserAuth
+// @Security AdminAuth
+// @Tags payments
+// @Description get the latest payment of the order
+// @ModuleID getPayment
+// @Accept  json
+// @Produce  json
+// @Param oid path string true "Order id"
+// @Success 200 {object} domain.Payment
+// @Failure 400,401,403,404 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /orders/{oid}/payment [get]
+func (h *Handler) getPayment(ctx echo.Context) error {
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	orderId, err := strconv.Atoi(ctx.Param("oid"))
+	if err != nil || orderId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid orderId")
+	}
+
+	payment, err := h.services.Payment.Get(clientId, clientType, orderId)
+	if err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, payment)
+}
+
+// @Summary Confirm Payment
+// @Security UserAuth
+// @Tags payments
+// @Description confirm the latest payment of the order, the order is paid once the provider captures it
+// @ModuleID confirmPayment
+// @Accept  json
+// @Produce  json
+// @Param oid path string true "Order id"
+// @Success 200 {object} domain.Payment
+// @Failure 400,401,403,404,409 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /orders/{oid}/payment/confirm [post]
+func (h *Handler) confirmPayment(ctx echo.Context) error {
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	orderId, err := strconv.Atoi(ctx.Param("oid"))
+	if err != nil || orderId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid orderId")
+	}
+
+	payment, err := h.services.Payment.Confirm(clientId, clientType, orderId)
+	if err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, payment)
+}
+
+// @Summary Payment Callback
+// @Tags payments
+// @Description status change of a payment, sent by the payment provider
+// @ModuleID paymentCallback
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} response
+// @Failure 400,404 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /payments/callback [post]
+func (h *Handler) paymentCallback(ctx echo.Context) error {
+	body, err := ioutil.ReadAll(ctx.Request().Body)
+	if err != nil {
+		return newResponse(ctx, http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.services.Payment.HandleCallback(ctx.Request().Header, body); err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, nil)
+}
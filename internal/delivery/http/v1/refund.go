@@ -0,0 +1,106 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/asaskevich/govalidator"
+	"github.com/labstack/echo/v4"
+)
+
+func (h *Handler) initRefundRoutes(api *echo.Group) {
+	refunds := api.Group("/orders/:oid/refunds")
+	{
+		refunds.Use(h.identity)
+		refunds.POST("", h.createRefund)
+		refunds.GET("", h.getRefunds)
+	}
+}
+
+type refundInput struct {
+	Amount int    `json:"amount" valid:"required"`
+	Reason string `json:"reason" valid:"required,length(1|500)"`
+}
+
+// @Summary Create Refund
+// @Security AdminAuth
+// @Tags refunds
+// @Description return a part of the paid order to the user as a goodwill gesture; a refund the payment
+// @Description provider fails stays pending and is retried
+// @ModuleID createRefund
+// @Accept  json
+// @Produce  json
+// @Param oid path string true "Order id"
+// @Param input body refundInput true "refund input info"
+// @Success 200 {object} idResponse
+// @Failure 400,401,403,404,409 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /orders/{oid}/refunds [post]
+func (h *Handler) createRefund(ctx echo.Context) error {
+	var input refundInput
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	orderId, err := strconv.Atoi(ctx.Param("oid"))
+	if err != nil || orderId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid orderId")
+	}
+
+	if err := ctx.Bind(&input); err != nil {
+		return newResponse(ctx, http.StatusBadRequest, err.Error())
+	}
+
+	if _, err := govalidator.ValidateStruct(input); err != nil {
+		return newResponse(ctx, http.StatusBadRequest, err.Error())
+	}
+
+	refundId, err := h.services.Refund.Create(clientId, clientType, orderId, &domain.Refund{
+		Amount: input.Amount,
+		Reason: input.Reason,
+	})
+	if err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, idResponse{
+		Id: refundId,
+	})
+}
+
+// @Summary Get Refunds
+// @Security AdminAuth
+// @Security UserAuth
+// @Security RestaurantAuth
+// @Tags refunds
+// @Description get the refunds of the order
+// @ModuleID getRefunds
+// @Accept  json
+// @Produce  json
+// @Param oid path string true "Order id"
+// @Success 200 {array} domain.Refund
+// @Failure 400,401,403,404 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /orders/{oid}/refunds [get]
+func (h *Handler) getRefunds(ctx echo.Context) error {
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	orderId, err := strconv.Atoi(ctx.Param("oid"))
+	if err != nil || orderId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid orderId")
+	}
+
+	refunds, err := h.services.Refund.GetAll(clientId, clientType, orderId)
+	if err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, refunds)
+}
@@ -1,23 +1,65 @@
 package v1
 
 import (
+	"net/http"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/gommon/log"
 )
 
 type response struct {
+	Code    string `json:"code,omitempty"`
 	Message string `json:"message"`
 }
 
 type tokenResponse struct {
-	AccessToken string `json:"token"`
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
 }
 
 type idResponse struct {
 	Id int `json:"id"`
 }
 
+// statusCodes maps domain error codes onto HTTP statuses.
+var statusCodes = map[string]int{
+	domain.CodeNotFound:               http.StatusNotFound,
+	domain.CodeUnauthorized:           http.StatusUnauthorized,
+	domain.CodeForbidden:              http.StatusForbidden,
+	domain.CodeConflict:               http.StatusConflict,
+	domain.CodeValidation:             http.StatusUnprocessableEntity,
+	domain.CodeInvalidStateTransition: http.StatusConflict,
+	domain.CodeOrderNotEditable:       http.StatusConflict,
+	domain.CodeForeignMenuItem:        http.StatusUnprocessableEntity,
+	domain.CodeRestaurantClosed:       http.StatusConflict,
+	domain.CodeOutsideDeliveryZone:    http.StatusUnprocessableEntity,
+}
+
+// errorCodes are used for responses that don't come from a domain error.
+var errorCodes = map[int]string{
+	http.StatusBadRequest:          "bad_request",
+	http.StatusUnauthorized:        domain.CodeUnauthorized,
+	http.StatusForbidden:           domain.CodeForbidden,
+	http.StatusNotFound:            domain.CodeNotFound,
+	http.StatusInternalServerError: "internal_error",
+}
+
 func newResponse(ctx echo.Context, statusCode int, message string) error {
 	log.Error(message)
-	return ctx.JSON(statusCode, response{message})
+	return ctx.JSON(statusCode, response{errorCodes[statusCode], message})
+}
+
+// newErrorResponse picks the status code from the kind of err,
+// errors of unknown kind are internal server errors.
+func newErrorResponse(ctx echo.Context, err error) error {
+	code := domain.ErrorCode(err)
+
+	statusCode, ok := statusCodes[code]
+	if !ok {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	log.Error(err.Error())
+	return ctx.JSON(statusCode, response{code, err.Error()})
 }
@@ -21,10 +21,15 @@ func (h *Handler) initRestaurantRoutes(api *echo.Group) {
 		restaurants.Use(h.identity)
 		restaurants.POST("/sign-up", h.restaurantsSignUp)
 		restaurants.GET("/", h.getRestaurants)
+		restaurants.GET("/search", h.searchRestaurants)
 		restaurants.GET("/:rid", h.getRestaurantById)
 		restaurants.GET("/image", h.getRestaurantImage)
 		restaurants.PUT("/:rid/image", h.updateRestaurantImage, middleware.BodyLimit("10M"))
 		restaurants.PUT("/:rid", h.updateRestaurant)
+		restaurants.GET("/:rid/hours", h.getRestaurantSchedule)
+		restaurants.PUT("/:rid/hours", h.updateRestaurantSchedule)
+		restaurants.GET("/:rid/zone", h.getDeliveryZone)
+		restaurants.PUT("/:rid/zone", h.updateDeliveryZone)
 	}
 }
 
@@ -58,10 +63,11 @@ func (h *Handler) restaurantsSignIn(ctx echo.Context) error {
 
 	token, err := h.services.Restaurant.SignIn(input.Phone, input.Password)
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 	return ctx.JSON(http.StatusOK, tokenResponse{
-		AccessToken: token.AccessToken,
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
 	})
 }
 
@@ -115,7 +121,7 @@ func (h *Handler) restaurantsSignUp(ctx echo.Context) error {
 
 	id, err := h.services.Restaurant.SignUp(restaurant, clientType)
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, map[string]interface{}{
@@ -127,12 +133,16 @@ func (h *Handler) restaurantsSignUp(ctx echo.Context) error {
 // @Security UserAuth
 // @Security RestaurantAuth
 // @Tags restaurants
-// @Description get all restaurants for user
+// @Description get a page of the restaurants delivering to the user, the nearest first by default
 // @ModuleID getAllRestaurants
 // @Accept  json
 // @Produce  json
-// @Success 200 {array} domain.Restaurant
-// @Failure 400,403,404 {object} response
+// @Param cursor query string false "Next cursor of the previous page"
+// @Param limit query int false "Page size, 20 by default"
+// @Param sort query string false "distance, name or rating, - for descending"
+// @Param working_status query int false "Working status"
+// @Success 200 {object} domain.RestaurantPage
+// @Failure 400,403,404,422 {object} response
 // @Failure 500 {object} response
 // @Failure default {object} response
 // @Router /restaurants/ [get]
@@ -142,12 +152,63 @@ func (h *Handler) getRestaurants(ctx echo.Context) error {
 		return newResponse(ctx, http.StatusInternalServerError, err.Error())
 	}
 
-	restaurants, err := h.services.Restaurant.GetAll(clientId, clientType)
+	params, err := getListParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusBadRequest, err.Error())
+	}
+
+	workingStatus, err := queryInt(ctx, "working_status")
+	if err != nil {
+		return newResponse(ctx, http.StatusBadRequest, err.Error())
+	}
+
+	page, err := h.services.Restaurant.GetAll(clientId, clientType, &domain.RestaurantFilter{
+		ListParams:    params,
+		WorkingStatus: workingStatus,
+	})
+	if err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, page)
+}
+
+// @Summary Search Restaurants
+// @Security UserAuth
+// @Tags restaurants
+// @Description search the restaurants delivering to the user by their name, dishes and menu categories, the best match first by default
+// @ModuleID searchRestaurants
+// @Accept  json
+// @Produce  json
+// @Param q query string true "Search query"
+// @Param cursor query string false "Next cursor of the previous page"
+// @Param limit query int false "Page size, 20 by default"
+// @Param sort query string false "score or distance, - for descending"
+// @Success 200 {object} domain.SearchPage
+// @Failure 400,403,422 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /restaurants/search [get]
+func (h *Handler) searchRestaurants(ctx echo.Context) error {
+	clientId, clientType, err := h.getClientParams(ctx)
 	if err != nil {
 		return newResponse(ctx, http.StatusInternalServerError, err.Error())
 	}
 
-	return ctx.JSON(http.StatusOK, restaurants)
+	params, err := getListParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusBadRequest, err.Error())
+	}
+
+	page, err := h.services.Restaurant.Search(clientId, clientType, &domain.SearchFilter{
+		ListParams: params,
+		Query:      ctx.QueryParam("q"),
+	})
+	if err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, page)
 }
 
 // @Summary Get Restaurant By Id
@@ -177,7 +238,7 @@ func (h *Handler) getRestaurantById(ctx echo.Context) error {
 
 	restaurant, err := h.services.Restaurant.GetById(clientId, clientType, restaurantId)
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, restaurant)
@@ -241,7 +302,7 @@ func (h *Handler) updateRestaurantImage(ctx echo.Context) error {
 
 	_, err = h.services.Restaurant.GetById(clientId, clientType, restaurantId)
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	file, err := ctx.FormFile("file")
@@ -260,7 +321,7 @@ func (h *Handler) updateRestaurantImage(ctx echo.Context) error {
 
 	restaurant, err := h.services.Restaurant.UpdateImage(clientId, clientType, restaurantId, fileName)
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, restaurant)
@@ -276,14 +337,15 @@ type restaurantUpdateInput struct {
 // @Summary Update Restaurant
 // @Security RestaurantAuth
 // @Tags restaurants
-// @Description update restaurant
+// @Description update restaurant; the working status of a restaurant with opening hours follows them
+// @Description and can't be set, a zero working status keeps the current one
 // @ModuleID updateRestaurant
 // @Accept  json
 // @Produce  json
 // @Param rid path string true "Restaurant id"
 // @Param input body restaurantUpdateInput true "restaurant update info"
 // @Success 200 {object} response
-// @Failure 400,403,404 {object} response
+// @Failure 400,403,404,422 {object} response
 // @Failure 500 {object} response
 // @Failure default {object} response
 // @Router /restaurants/{rid} [put]
@@ -320,9 +382,222 @@ func (h *Handler) updateRestaurant(ctx echo.Context) error {
 
 	err = h.services.Restaurant.Update(clientId, clientType, restaurantId, update)
 
+	if err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, nil)
+}
+
+type openingHoursInput struct {
+	Weekday  int    `json:"weekday" valid:"range(1|7)"`
+	OpensAt  string `json:"opens_at" valid:"required"`
+	ClosesAt string `json:"closes_at" valid:"required"`
+}
+
+type holidayInput struct {
+	Date     string  `json:"date" valid:"required"`
+	OpensAt  *string `json:"opens_at"`
+	ClosesAt *string `json:"closes_at"`
+	Reason   string  `json:"reason" valid:"length(0|100)"`
+}
+
+type scheduleInput struct {
+	TimeZone string              `json:"time_zone" valid:"required,length(1|50)"`
+	Hours    []openingHoursInput `json:"hours"`
+	Holidays []holidayInput      `json:"holidays"`
+}
+
+// @Summary Get Restaurant Opening Hours
+// @Security UserAuth
+// @Security RestaurantAuth
+// @Security AdminAuth
+// @Tags restaurants
+// @Description get the weekly opening hours and the upcoming holidays of the restaurant
+// @ModuleID getRestaurantSchedule
+// @Accept  json
+// @Produce  json
+// @Param rid path string true "Restaurant id"
+// @Success 200 {object} domain.Schedule
+// @Failure 400,403,404 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /restaurants/{rid}/hours [get]
+func (h *Handler) getRestaurantSchedule(ctx echo.Context) error {
+	clientId, clientType, err := h.getClientParams(ctx)
 	if err != nil {
 		return newResponse(ctx, http.StatusInternalServerError, err.Error())
 	}
 
+	restaurantId, err := strconv.Atoi(ctx.Param("rid"))
+	if err != nil || restaurantId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid restaurantId")
+	}
+
+	schedule, err := h.services.Restaurant.GetSchedule(clientId, clientType, restaurantId)
+	if err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, schedule)
+}
+
+// @Summary Update Restaurant Opening Hours
+// @Security RestaurantAuth
+// @Security AdminAuth
+// @Tags restaurants
+// @Description replace the opening hours and the holidays of the restaurant; weekdays go from 1, Monday, to 7, Sunday, times are HH:MM in the restaurant time zone and hours closing before they open end the next day
+// @ModuleID updateRestaurantSchedule
+// @Accept  json
+// @Produce  json
+// @Param rid path string true "Restaurant id"
+// @Param input body scheduleInput true "opening hours"
+// @Success 200 {object} response
+// @Failure 400,403,404,422 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /restaurants/{rid}/hours [put]
+func (h *Handler) updateRestaurantSchedule(ctx echo.Context) error {
+	var input scheduleInput
+
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	restaurantId, err := strconv.Atoi(ctx.Param("rid"))
+	if err != nil || restaurantId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid restaurantId")
+	}
+
+	if err := ctx.Bind(&input); err != nil {
+		return newResponse(ctx, http.StatusBadRequest, err.Error())
+	}
+
+	if _, err := govalidator.ValidateStruct(input); err != nil {
+		return newResponse(ctx, http.StatusBadRequest, err.Error())
+	}
+
+	schedule := &domain.Schedule{
+		TimeZone: input.TimeZone,
+		Hours:    make([]*domain.OpeningHours, 0, len(input.Hours)),
+		Holidays: make([]*domain.Holiday, 0, len(input.Holidays)),
+	}
+
+	for _, hours := range input.Hours {
+		schedule.Hours = append(schedule.Hours, &domain.OpeningHours{
+			Weekday:  hours.Weekday,
+			OpensAt:  hours.OpensAt,
+			ClosesAt: hours.ClosesAt,
+		})
+	}
+
+	for _, holiday := range input.Holidays {
+		schedule.Holidays = append(schedule.Holidays, &domain.Holiday{
+			Date:     holiday.Date,
+			OpensAt:  holiday.OpensAt,
+			ClosesAt: holiday.ClosesAt,
+			Reason:   holiday.Reason,
+		})
+	}
+
+	if err := h.services.Restaurant.UpdateSchedule(clientId, clientType, restaurantId, schedule); err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, nil)
+}
+
+type deliveryZoneInput struct {
+	Radius  *float64        `json:"radius"`
+	Polygon []locationInput `json:"polygon"`
+}
+
+// @Summary Get Delivery Zone
+// @Security UserAuth
+// @Security RestaurantAuth
+// @Security AdminAuth
+// @Tags restaurants
+// @Description get the delivery zone of the restaurant
+// @ModuleID getDeliveryZone
+// @Accept  json
+// @Produce  json
+// @Param rid path string true "Restaurant id"
+// @Success 200 {object} domain.DeliveryZone
+// @Failure 400,403,404 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /restaurants/{rid}/zone [get]
+func (h *Handler) getDeliveryZone(ctx echo.Context) error {
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	restaurantId, err := strconv.Atoi(ctx.Param("rid"))
+	if err != nil || restaurantId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid restaurantId")
+	}
+
+	zone, err := h.services.Restaurant.GetDeliveryZone(clientId, clientType, restaurantId)
+	if err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, zone)
+}
+
+// @Summary Update Delivery Zone
+// @Security RestaurantAuth
+// @Security AdminAuth
+// @Tags restaurants
+// @Description replace the delivery zone of the restaurant with a radius in km or a polygon; an empty zone delivers anywhere
+// @ModuleID updateDeliveryZone
+// @Accept  json
+// @Produce  json
+// @Param rid path string true "Restaurant id"
+// @Param input body deliveryZoneInput true "delivery zone"
+// @Success 200 {object} response
+// @Failure 400,403,404,422 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /restaurants/{rid}/zone [put]
+func (h *Handler) updateDeliveryZone(ctx echo.Context) error {
+	var input deliveryZoneInput
+
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	restaurantId, err := strconv.Atoi(ctx.Param("rid"))
+	if err != nil || restaurantId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid restaurantId")
+	}
+
+	if err := ctx.Bind(&input); err != nil {
+		return newResponse(ctx, http.StatusBadRequest, err.Error())
+	}
+
+	if _, err := govalidator.ValidateStruct(input); err != nil {
+		return newResponse(ctx, http.StatusBadRequest, err.Error())
+	}
+
+	zone := &domain.DeliveryZone{
+		Radius:  input.Radius,
+		Polygon: make([]*domain.Location, 0, len(input.Polygon)),
+	}
+
+	for _, point := range input.Polygon {
+		zone.Polygon = append(zone.Polygon, &domain.Location{
+			Latitude:  point.Latitude,
+			Longitude: point.Longitude,
+		})
+	}
+
+	if err := h.services.Restaurant.UpdateDeliveryZone(clientId, clientType, restaurantId, zone); err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
 	return ctx.JSON(http.StatusOK, nil)
 }
@@ -0,0 +1,260 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/asaskevich/govalidator"
+	"github.com/labstack/echo/v4"
+)
+
+func (h *Handler) initReviewRoutes(api *echo.Group) {
+	orderReviews := api.Group("/orders/:oid/reviews")
+	{
+		orderReviews.Use(h.identity)
+		orderReviews.POST("", h.createReview)
+		orderReviews.GET("", h.getOrderReviews)
+	}
+
+	restaurantReviews := api.Group("/restaurants/:rid/reviews")
+	{
+		restaurantReviews.Use(h.identity)
+		restaurantReviews.GET("", h.getRestaurantReviews)
+		restaurantReviews.PUT("/:id/reply", h.replyToReview)
+	}
+
+	reviews := api.Group("/reviews")
+	{
+		reviews.Use(h.identity)
+		reviews.PUT("/:id/moderation", h.moderateReview)
+	}
+}
+
+type reviewInput struct {
+	Subject string `json:"subject" valid:"required,in(restaurant|courier)"`
+	Rating  int    `json:"rating" valid:"required"`
+	Comment string `json:"comment"`
+}
+
+type replyInput struct {
+	Reply string `json:"reply" valid:"required"`
+}
+
+type moderationInput struct {
+	Hidden *bool `json:"hidden"`
+}
+
+// @Summary Create Review
+// @Security UserAuth
+// @Tags reviews
+// @Description rate the restaurant or the courier of the delivered order from 1 to 5
+// @ModuleID createReview
+// @Accept  json
+// @Produce  json
+// @Param oid path string true "Order id"
+// @Param input body reviewInput true "review input info"
+// @Success 200 {object} idResponse
+// @Failure 400,401,403,404,409,422 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /orders/{oid}/reviews [post]
+func (h *Handler) createReview(ctx echo.Context) error {
+	var input reviewInput
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	orderId, err := strconv.Atoi(ctx.Param("oid"))
+	if err != nil || orderId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid orderId")
+	}
+
+	if err := ctx.Bind(&input); err != nil {
+		return newResponse(ctx, http.StatusBadRequest, err.Error())
+	}
+
+	if _, err := govalidator.ValidateStruct(input); err != nil {
+		return newResponse(ctx, http.StatusBadRequest, err.Error())
+	}
+
+	reviewId, err := h.services.Review.Create(clientId, clientType, orderId, &domain.Review{
+		Subject: input.Subject,
+		Rating:  input.Rating,
+		Comment: input.Comment,
+	})
+	if err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, idResponse{
+		Id: reviewId,
+	})
+}
+
+// @Summary Get Order Reviews
+// @Security AdminAuth
+// @Security UserAuth
+// @Security CourierAuth
+// @Security RestaurantAuth
+// @Tags reviews
+// @Description get the reviews of the order, the restaurant and the courier get the reviews about them
+// @ModuleID getOrderReviews
+// @Accept  json
+// @Produce  json
+// @Param oid path string true "Order id"
+// @Success 200 {array} domain.Review
+// @Failure 400,401,403,404 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /orders/{oid}/reviews [get]
+func (h *Handler) getOrderReviews(ctx echo.Context) error {
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	orderId, err := strconv.Atoi(ctx.Param("oid"))
+	if err != nil || orderId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid orderId")
+	}
+
+	reviews, err := h.services.Review.GetByOrder(clientId, clientType, orderId)
+	if err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, reviews)
+}
+
+// @Summary Get Restaurant Reviews
+// @Security AdminAuth
+// @Security UserAuth
+// @Security RestaurantAuth
+// @Tags reviews
+// @Description get a page of the reviews of the restaurant, the newest first by default
+// @ModuleID getRestaurantReviews
+// @Accept  json
+// @Produce  json
+// @Param rid path string true "Restaurant id"
+// @Param cursor query string false "Next cursor of the previous page"
+// @Param limit query int false "Page size, 20 by default"
+// @Param sort query string false "created_at or rating, - for descending"
+// @Success 200 {object} domain.ReviewPage
+// @Failure 400,401,403,422 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /restaurants/{rid}/reviews [get]
+func (h *Handler) getRestaurantReviews(ctx echo.Context) error {
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	restaurantId, err := strconv.Atoi(ctx.Param("rid"))
+	if err != nil || restaurantId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid restaurantId")
+	}
+
+	params, err := getListParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusBadRequest, err.Error())
+	}
+
+	page, err := h.services.Review.GetRestaurantReviews(clientId, clientType, restaurantId,
+		&domain.ReviewFilter{ListParams: params})
+	if err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, page)
+}
+
+// @Summary Reply To Review
+// @Security RestaurantAuth
+// @Tags reviews
+// @Description reply to a review of the restaurant publicly, replacing the previous reply
+// @ModuleID replyToReview
+// @Accept  json
+// @Produce  json
+// @Param rid path string true "Restaurant id"
+// @Param id path string true "Review id"
+// @Param input body replyInput true "reply input info"
+// @Success 200 {object} response
+// @Failure 400,401,403,404,422 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /restaurants/{rid}/reviews/{id}/reply [put]
+func (h *Handler) replyToReview(ctx echo.Context) error {
+	var input replyInput
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	restaurantId, err := strconv.Atoi(ctx.Param("rid"))
+	if err != nil || restaurantId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid restaurantId")
+	}
+
+	reviewId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || reviewId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid reviewId")
+	}
+
+	if err := ctx.Bind(&input); err != nil {
+		return newResponse(ctx, http.StatusBadRequest, err.Error())
+	}
+
+	if _, err := govalidator.ValidateStruct(input); err != nil {
+		return newResponse(ctx, http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.services.Review.Reply(clientId, clientType, restaurantId, reviewId, input.Reply); err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, nil)
+}
+
+// @Summary Moderate Review
+// @Security AdminAuth
+// @Tags reviews
+// @Description hide an abusive review, hidden reviews don't count towards the rating
+// @ModuleID moderateReview
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Review id"
+// @Param input body moderationInput true "moderation input info"
+// @Success 200 {object} response
+// @Failure 400,401,403,404 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /reviews/{id}/moderation [put]
+func (h *Handler) moderateReview(ctx echo.Context) error {
+	var input moderationInput
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	reviewId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || reviewId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid reviewId")
+	}
+
+	if err := ctx.Bind(&input); err != nil {
+		return newResponse(ctx, http.StatusBadRequest, err.Error())
+	}
+
+	if input.Hidden == nil {
+		return newResponse(ctx, http.StatusBadRequest, "hidden: non zero value required")
+	}
+
+	if err := h.services.Review.Moderate(clientId, clientType, reviewId, *input.Hidden); err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, nil)
+}
@@ -0,0 +1,265 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/labstack/echo/v4"
+)
+
+func (h *Handler) initSessionRoutes(api *echo.Group) {
+	admins := api.Group("/admins")
+	{
+		admins.POST("/refresh", h.refreshTokens(adminType))
+		admins.Use(h.identity)
+		admins.POST("/sign-out", h.signOut)
+		admins.POST("/revocations", h.revokeClient)
+	}
+
+	users := api.Group("/users")
+	{
+		users.POST("/refresh", h.refreshTokens(userType))
+		users.Use(h.identity)
+		users.POST("/sign-out", h.signOut)
+		users.GET("/:uid/sessions", h.getSessions(userType, "uid"))
+		users.DELETE("/:uid/sessions/:sid", h.deleteSession(userType, "uid"))
+	}
+
+	couriers := api.Group("/couriers")
+	{
+		couriers.POST("/refresh", h.refreshTokens(courierType))
+		couriers.Use(h.identity)
+		couriers.POST("/sign-out", h.signOut)
+		couriers.GET("/:id/sessions", h.getSessions(courierType, "id"))
+		couriers.DELETE("/:id/sessions/:sid", h.deleteSession(courierType, "id"))
+	}
+
+	restaurants := api.Group("/restaurants")
+	{
+		restaurants.POST("/refresh", h.refreshTokens(restaurantType))
+		restaurants.Use(h.identity)
+		restaurants.POST("/sign-out", h.signOut)
+		restaurants.GET("/:rid/sessions", h.getSessions(restaurantType, "rid"))
+		restaurants.DELETE("/:rid/sessions/:sid", h.deleteSession(restaurantType, "rid"))
+	}
+}
+
+type refreshTokenInput struct {
+	RefreshToken string `json:"refresh_token" valid:"required"`
+}
+
+// @Summary Refresh Tokens
+// @Tags sessions
+// @Description exchange a refresh token for a new token pair
+// @ModuleID refreshTokens
+// @Accept  json
+// @Produce  json
+// @Param input body refreshTokenInput true "refresh token"
+// @Success 200 {object} tokenResponse
+// @Failure 400,401 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /admins/refresh [post]
+// @Router /users/refresh [post]
+// @Router /couriers/refresh [post]
+// @Router /restaurants/refresh [post]
+func (h *Handler) refreshTokens(clientType string) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		var input refreshTokenInput
+
+		if err := ctx.Bind(&input); err != nil {
+			return newResponse(ctx, http.StatusBadRequest, err.Error())
+		}
+
+		if _, err := govalidator.ValidateStruct(input); err != nil {
+			return newResponse(ctx, http.StatusBadRequest, err.Error())
+		}
+
+		token, err := h.services.Session.Refresh(clientType, input.RefreshToken)
+		if err != nil {
+			return newErrorResponse(ctx, err)
+		}
+
+		return ctx.JSON(http.StatusOK, tokenResponse{
+			AccessToken:  token.AccessToken,
+			RefreshToken: token.RefreshToken,
+		})
+	}
+}
+
+// @Summary Sign Out
+// @Security AdminAuth
+// @Security UserAuth
+// @Security CourierAuth
+// @Security RestaurantAuth
+// @Tags sessions
+// @Description close the session of the given refresh token
+// @ModuleID signOut
+// @Accept  json
+// @Produce  json
+// @Param input body refreshTokenInput true "refresh token"
+// @Success 200 {object} response
+// @Failure 400,401,403 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /admins/sign-out [post]
+// @Router /users/sign-out [post]
+// @Router /couriers/sign-out [post]
+// @Router /restaurants/sign-out [post]
+func (h *Handler) signOut(ctx echo.Context) error {
+	var input refreshTokenInput
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	if err := ctx.Bind(&input); err != nil {
+		return newResponse(ctx, http.StatusBadRequest, err.Error())
+	}
+
+	if _, err := govalidator.ValidateStruct(input); err != nil {
+		return newResponse(ctx, http.StatusBadRequest, err.Error())
+	}
+
+	tokenId, expiresAt, err := h.getTokenParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	err = h.services.Session.SignOut(clientId, clientType, input.RefreshToken)
+	if err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	if err := h.services.Revocation.RevokeToken(tokenId, expiresAt); err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, nil)
+}
+
+type revokeClientInput struct {
+	ClientId   int    `json:"client_id" valid:"required"`
+	ClientType string `json:"client_type" valid:"required"`
+}
+
+// @Summary Revoke Client Tokens
+// @Security AdminAuth
+// @Tags sessions
+// @Description revoke all tokens and sessions of a client
+// @ModuleID revokeClient
+// @Accept  json
+// @Produce  json
+// @Param input body revokeClientInput true "client to revoke"
+// @Success 200 {object} response
+// @Failure 400,401,403 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /admins/revocations [post]
+func (h *Handler) revokeClient(ctx echo.Context) error {
+	var input revokeClientInput
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	if err := ctx.Bind(&input); err != nil {
+		return newResponse(ctx, http.StatusBadRequest, err.Error())
+	}
+
+	if _, err := govalidator.ValidateStruct(input); err != nil {
+		return newResponse(ctx, http.StatusBadRequest, err.Error())
+	}
+
+	err = h.services.Revocation.RevokeClient(clientId, clientType, input.ClientId, input.ClientType)
+	if err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, nil)
+}
+
+// @Summary Get Active Sessions
+// @Security AdminAuth
+// @Security UserAuth
+// @Security CourierAuth
+// @Security RestaurantAuth
+// @Tags sessions
+// @Description get active sessions of a user, courier or restaurant
+// @ModuleID getSessions
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Client id"
+// @Success 200 {array} domain.Session
+// @Failure 400,403,404 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /users/{id}/sessions [get]
+// @Router /couriers/{id}/sessions [get]
+// @Router /restaurants/{id}/sessions [get]
+func (h *Handler) getSessions(ownerType, idParam string) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		clientId, clientType, err := h.getClientParams(ctx)
+		if err != nil {
+			return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		}
+
+		ownerId, err := strconv.Atoi(ctx.Param(idParam))
+		if err != nil || ownerId == 0 {
+			return newResponse(ctx, http.StatusBadRequest, "Invalid "+ownerType+"Id")
+		}
+
+		sessions, err := h.services.Session.GetAll(clientId, clientType, ownerId, ownerType)
+		if err != nil {
+			return newErrorResponse(ctx, err)
+		}
+
+		return ctx.JSON(http.StatusOK, sessions)
+	}
+}
+
+// @Summary Revoke Session
+// @Security AdminAuth
+// @Security UserAuth
+// @Security CourierAuth
+// @Security RestaurantAuth
+// @Tags sessions
+// @Description revoke a session of a user, courier or restaurant
+// @ModuleID deleteSession
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Client id"
+// @Param sid path string true "Session id"
+// @Success 200 {object} response
+// @Failure 400,403,404 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /users/{id}/sessions/{sid} [delete]
+// @Router /couriers/{id}/sessions/{sid} [delete]
+// @Router /restaurants/{id}/sessions/{sid} [delete]
+func (h *Handler) deleteSession(ownerType, idParam string) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		clientId, clientType, err := h.getClientParams(ctx)
+		if err != nil {
+			return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		}
+
+		ownerId, err := strconv.Atoi(ctx.Param(idParam))
+		if err != nil || ownerId == 0 {
+			return newResponse(ctx, http.StatusBadRequest, "Invalid "+ownerType+"Id")
+		}
+
+		sessionId, err := strconv.Atoi(ctx.Param("sid"))
+		if err != nil || sessionId == 0 {
+			return newResponse(ctx, http.StatusBadRequest, "Invalid sessionId")
+		}
+
+		err = h.services.Session.Delete(clientId, clientType, ownerId, ownerType, sessionId)
+		if err != nil {
+			return newErrorResponse(ctx, err)
+		}
+
+		return ctx.JSON(http.StatusOK, nil)
+	}
+}
@@ -0,0 +1,103 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/asaskevich/govalidator"
+	"github.com/labstack/echo/v4"
+)
+
+func (h *Handler) initTrackingRoutes(api *echo.Group) {
+	couriers := api.Group("/couriers")
+	{
+		couriers.Use(h.identity)
+		couriers.POST("/:cid/location", h.addCourierLocation)
+	}
+
+	orders := api.Group("/orders")
+	{
+		orders.Use(h.identity)
+		orders.GET("/:oid/courier-location", h.getOrderCourierLocation)
+	}
+}
+
+// @Summary Add Courier Location
+// @Security CourierAuth
+// @Tags tracking
+// @Description report the current position of the courier
+// @ModuleID addCourierLocation
+// @Accept  json
+// @Produce  json
+// @Param cid path string true "Courier id"
+// @Param input body locationInput true "current position"
+// @Success 200 {object} response
+// @Failure 400,401,403 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /couriers/{cid}/location [post]
+func (h *Handler) addCourierLocation(ctx echo.Context) error {
+	var input locationInput
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	courierId, err := strconv.Atoi(ctx.Param("cid"))
+	if err != nil || courierId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid courierId")
+	}
+
+	if err := ctx.Bind(&input); err != nil {
+		return newResponse(ctx, http.StatusBadRequest, err.Error())
+	}
+
+	if _, err := govalidator.ValidateStruct(input); err != nil {
+		return newResponse(ctx, http.StatusBadRequest, err.Error())
+	}
+
+	location := &domain.Location{
+		Latitude:  input.Latitude,
+		Longitude: input.Longitude,
+	}
+
+	err = h.services.Tracking.AddLocation(clientId, clientType, courierId, location)
+	if err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, nil)
+}
+
+// @Summary Get Order Courier Location
+// @Security UserAuth
+// @Tags tracking
+// @Description get the current position of the courier delivering the order
+// @ModuleID getOrderCourierLocation
+// @Accept  json
+// @Produce  json
+// @Param oid path string true "Order id"
+// @Success 200 {object} domain.CourierLocation
+// @Failure 400,401,403,404,409 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /orders/{oid}/courier-location [get]
+func (h *Handler) getOrderCourierLocation(ctx echo.Context) error {
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	orderId, err := strconv.Atoi(ctx.Param("oid"))
+	if err != nil || orderId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid orderId")
+	}
+
+	location, err := h.services.Tracking.GetOrderCourierLocation(clientId, clientType, orderId)
+	if err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, location)
+}
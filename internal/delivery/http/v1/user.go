@@ -13,6 +13,9 @@ const (
 	authorizationHeader = "Authorization"
 	idCtx               = "id"
 	clientTypeCtx       = "client_type"
+	tokenIdCtx          = "token_id"
+	tokenIssuedCtx      = "token_issued"
+	tokenExpiresCtx     = "token_expires"
 )
 
 func (h *Handler) initUserRoutes(api *echo.Group) {
@@ -70,7 +73,7 @@ func (h *Handler) usersSignUp(ctx echo.Context) error {
 
 	id, err := h.services.User.SignUp(user)
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, map[string]interface{}{
@@ -108,11 +111,12 @@ func (h *Handler) usersSignIn(ctx echo.Context) error {
 
 	token, err := h.services.User.SignIn(input.Phone, input.Password)
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, tokenResponse{
-		AccessToken: token.AccessToken,
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
 	})
 }
 
@@ -170,7 +174,7 @@ func (h *Handler) userUpdate(ctx echo.Context) error {
 	err = h.services.User.Update(clientId, clientType, userId, update)
 
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, nil)
@@ -204,7 +208,7 @@ func (h *Handler) getUserById(ctx echo.Context) error {
 
 	user, err := h.services.User.GetById(clientId, clientType, userId)
 	if err != nil {
-		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+		return newErrorResponse(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, user)
@@ -0,0 +1,186 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/asaskevich/govalidator"
+	"github.com/labstack/echo/v4"
+)
+
+func (h *Handler) initWebhookRoutes(api *echo.Group) {
+	webhooks := api.Group("/restaurants/:rid/webhooks")
+	{
+		webhooks.Use(h.identity)
+		webhooks.POST("", h.createWebhook)
+		webhooks.GET("", h.getWebhooks)
+		webhooks.DELETE("/:wid", h.deleteWebhook)
+		webhooks.GET("/:wid/deliveries", h.getWebhookDeliveries)
+	}
+}
+
+type webhookInput struct {
+	Url    string   `json:"url" valid:"required,requrl"`
+	Secret string   `json:"secret" valid:"required,length(16|100)"`
+	Events []string `json:"events"`
+}
+
+// @Summary Create Webhook
+// @Security RestaurantAuth
+// @Tags webhooks
+// @Description subscribe an url to order events of the restaurant
+// @ModuleID createWebhook
+// @Accept  json
+// @Produce  json
+// @Param rid path string true "Restaurant id"
+// @Param input body webhookInput true "webhook input info"
+// @Success 200 {object} idResponse
+// @Failure 400,401,403 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /restaurants/{rid}/webhooks [post]
+func (h *Handler) createWebhook(ctx echo.Context) error {
+	var input webhookInput
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	restaurantId, err := strconv.Atoi(ctx.Param("rid"))
+	if err != nil || restaurantId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid restaurantId")
+	}
+
+	if err := ctx.Bind(&input); err != nil {
+		return newResponse(ctx, http.StatusBadRequest, err.Error())
+	}
+
+	if _, err := govalidator.ValidateStruct(input); err != nil {
+		return newResponse(ctx, http.StatusBadRequest, err.Error())
+	}
+
+	webhook := &domain.Webhook{
+		RestaurantId: restaurantId,
+		Url:          input.Url,
+		Secret:       input.Secret,
+		Events:       input.Events,
+	}
+
+	webhookId, err := h.services.Webhook.Create(clientId, clientType, webhook)
+	if err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, idResponse{
+		Id: webhookId,
+	})
+}
+
+// @Summary Get Webhooks
+// @Security RestaurantAuth
+// @Tags webhooks
+// @Description get webhooks of the restaurant
+// @ModuleID getWebhooks
+// @Accept  json
+// @Produce  json
+// @Param rid path string true "Restaurant id"
+// @Success 200 {array} domain.Webhook
+// @Failure 400,401,403 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /restaurants/{rid}/webhooks [get]
+func (h *Handler) getWebhooks(ctx echo.Context) error {
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	restaurantId, err := strconv.Atoi(ctx.Param("rid"))
+	if err != nil || restaurantId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid restaurantId")
+	}
+
+	webhooks, err := h.services.Webhook.GetAll(clientId, clientType, restaurantId)
+	if err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, webhooks)
+}
+
+// @Summary Delete Webhook
+// @Security RestaurantAuth
+// @Tags webhooks
+// @Description delete webhook of the restaurant
+// @ModuleID deleteWebhook
+// @Accept  json
+// @Produce  json
+// @Param rid path string true "Restaurant id"
+// @Param wid path string true "Webhook id"
+// @Success 200 {object} response
+// @Failure 400,401,403,404 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /restaurants/{rid}/webhooks/{wid} [delete]
+func (h *Handler) deleteWebhook(ctx echo.Context) error {
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	restaurantId, err := strconv.Atoi(ctx.Param("rid"))
+	if err != nil || restaurantId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid restaurantId")
+	}
+
+	webhookId, err := strconv.Atoi(ctx.Param("wid"))
+	if err != nil || webhookId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid webhookId")
+	}
+
+	err = h.services.Webhook.Delete(clientId, clientType, restaurantId, webhookId)
+	if err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, nil)
+}
+
+// @Summary Get Webhook Deliveries
+// @Security RestaurantAuth
+// @Tags webhooks
+// @Description get delivery log of the webhook, newest first
+// @ModuleID getWebhookDeliveries
+// @Accept  json
+// @Produce  json
+// @Param rid path string true "Restaurant id"
+// @Param wid path string true "Webhook id"
+// @Success 200 {array} domain.WebhookDelivery
+// @Failure 400,401,403,404 {object} response
+// @Failure 500 {object} response
+// @Failure default {object} response
+// @Router /restaurants/{rid}/webhooks/{wid}/deliveries [get]
+func (h *Handler) getWebhookDeliveries(ctx echo.Context) error {
+	clientId, clientType, err := h.getClientParams(ctx)
+	if err != nil {
+		return newResponse(ctx, http.StatusInternalServerError, err.Error())
+	}
+
+	restaurantId, err := strconv.Atoi(ctx.Param("rid"))
+	if err != nil || restaurantId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid restaurantId")
+	}
+
+	webhookId, err := strconv.Atoi(ctx.Param("wid"))
+	if err != nil || webhookId == 0 {
+		return newResponse(ctx, http.StatusBadRequest, "Invalid webhookId")
+	}
+
+	deliveries, err := h.services.Webhook.GetDeliveries(clientId, clientType, restaurantId, webhookId)
+	if err != nil {
+		return newErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, deliveries)
+}
@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+// Cart holds the dishes a user is going to order from one restaurant.
+// The prices are the current menu prices, they are fixed on checkout.
+type Cart struct {
+	Id            int         `json:"id" db:"id"`
+	UserId        int         `json:"user_id" db:"user_id"`
+	RestaurantId  int         `json:"restaurant_id" db:"restaurant_id"`
+	Items         []*CartItem `json:"items" db:"-"`
+	ItemsPrice    int         `json:"items_price" db:"-"`
+	DeliveryPrice int         `json:"delivery_price" db:"-"`
+	TotalPrice    int         `json:"total_price" db:"-"`
+	UpdatedAt     time.Time   `json:"updated_at" db:"updated_at"`
+}
+
+type CartItem struct {
+	Id         int    `json:"id" db:"id"`
+	CartId     int    `json:"cart_id" db:"cart_id"`
+	MenuItemId int    `json:"menu_item_id" db:"menu_item_id"`
+	Count      int    `json:"count" db:"count"`
+	Title      string `json:"title" db:"title"`
+	Price      int    `json:"price" db:"price"`
+}
@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+// PendingOrder is a paid order still waiting for a courier. Offered is set
+// while a courier is considering the order.
+type PendingOrder struct {
+	OrderId     int        `json:"order_id" db:"order_id"`
+	UserId      int        `json:"user_id" db:"user_id"`
+	Status      int        `json:"status" db:"status"`
+	Offered     bool       `json:"offered" db:"offered"`
+	QueuedAt    time.Time  `json:"queued_at" db:"queued_at"`
+	EscalatedAt *time.Time `json:"escalated_at" db:"escalated_at"`
+}
+
+// CourierOffer asks a courier to deliver an order.
+type CourierOffer struct {
+	Id          int        `json:"id" db:"id"`
+	OrderId     int        `json:"order_id" db:"order_id"`
+	CourierId   int        `json:"courier_id" db:"courier_id"`
+	Status      string     `json:"status" db:"status"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	ExpiresAt   time.Time  `json:"expires_at" db:"expires_at"`
+	RespondedAt *time.Time `json:"responded_at" db:"responded_at"`
+}
@@ -0,0 +1,112 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error codes are part of the API: they are returned next to the message
+// so that clients don't have to parse it.
+const (
+	CodeNotFound               = "not_found"
+	CodeUnauthorized           = "unauthorized"
+	CodeForbidden              = "forbidden"
+	CodeConflict               = "conflict"
+	CodeValidation             = "validation_error"
+	CodeInvalidStateTransition = "invalid_state_transition"
+	CodeOrderNotEditable       = "order_not_editable"
+	CodeForeignMenuItem        = "foreign_menu_item"
+	CodeRestaurantClosed       = "restaurant_closed"
+	CodeOutsideDeliveryZone    = "outside_delivery_zone"
+)
+
+// Error is an error of a known kind. It lives in domain rather than in
+// service so that repositories can return it too.
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is reports errors of the same kind as equal, so errors.Is(err, ErrNotFound)
+// holds for any not found error whatever its message.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrNotFound               = &Error{Code: CodeNotFound, Message: "not found"}
+	ErrUnauthorized           = &Error{Code: CodeUnauthorized, Message: "unauthorized"}
+	ErrForbidden              = &Error{Code: CodeForbidden, Message: "forbidden"}
+	ErrConflict               = &Error{Code: CodeConflict, Message: "conflict"}
+	ErrValidation             = &Error{Code: CodeValidation, Message: "validation error"}
+	ErrInvalidStateTransition = &Error{Code: CodeInvalidStateTransition, Message: "invalid state transition"}
+	ErrOrderNotEditable       = &Error{Code: CodeOrderNotEditable, Message: "order not editable"}
+	ErrForeignMenuItem        = &Error{Code: CodeForeignMenuItem, Message: "foreign menu item"}
+	ErrRestaurantClosed       = &Error{Code: CodeRestaurantClosed, Message: "restaurant closed"}
+	ErrOutsideDeliveryZone    = &Error{Code: CodeOutsideDeliveryZone, Message: "outside delivery zone"}
+)
+
+func NewNotFoundError(format string, a ...interface{}) error {
+	return &Error{Code: CodeNotFound, Message: fmt.Sprintf(format, a...)}
+}
+
+// NewUnauthorizedError is returned when the client fails to prove who it
+// is, e.g. with a wrong password or a stale refresh token.
+func NewUnauthorizedError(format string, a ...interface{}) error {
+	return &Error{Code: CodeUnauthorized, Message: fmt.Sprintf(format, a...)}
+}
+
+func NewForbiddenError(format string, a ...interface{}) error {
+	return &Error{Code: CodeForbidden, Message: fmt.Sprintf(format, a...)}
+}
+
+func NewConflictError(format string, a ...interface{}) error {
+	return &Error{Code: CodeConflict, Message: fmt.Sprintf(format, a...)}
+}
+
+func NewValidationError(format string, a ...interface{}) error {
+	return &Error{Code: CodeValidation, Message: fmt.Sprintf(format, a...)}
+}
+
+func NewInvalidStateTransitionError(format string, a ...interface{}) error {
+	return &Error{Code: CodeInvalidStateTransition, Message: fmt.Sprintf(format, a...)}
+}
+
+// NewOrderNotEditableError is returned when the items of an order are
+// changed after it has left the state that allows it.
+func NewOrderNotEditableError(format string, a ...interface{}) error {
+	return &Error{Code: CodeOrderNotEditable, Message: fmt.Sprintf(format, a...)}
+}
+
+// NewForeignMenuItemError is returned when a menu item is added to an order
+// of another restaurant.
+func NewForeignMenuItemError(format string, a ...interface{}) error {
+	return &Error{Code: CodeForeignMenuItem, Message: fmt.Sprintf(format, a...)}
+}
+
+// NewRestaurantClosedError is returned when an order is made or paid
+// outside the opening hours of the restaurant.
+func NewRestaurantClosedError(format string, a ...interface{}) error {
+	return &Error{Code: CodeRestaurantClosed, Message: fmt.Sprintf(format, a...)}
+}
+
+// NewOutsideDeliveryZoneError is returned when an order is made by a user
+// the restaurant doesn't deliver to.
+func NewOutsideDeliveryZoneError(format string, a ...interface{}) error {
+	return &Error{Code: CodeOutsideDeliveryZone, Message: fmt.Sprintf(format, a...)}
+}
+
+// ErrorCode returns the code of a typed error, or an empty string.
+func ErrorCode(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+
+	return ""
+}
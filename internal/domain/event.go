@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// Event types pushed to clients.
+const (
+	// EventOrderStatusChanged goes to the user, the restaurant and the courier of the order.
+	EventOrderStatusChanged = "order.status_changed"
+	// EventOrderIncoming goes to the restaurant once the order is paid.
+	EventOrderIncoming = "order.incoming"
+	// EventOrderOffered goes to the courier the order is offered to.
+	EventOrderOffered = "order.offered"
+	// EventCourierAssigned goes to the user, the restaurant and the courier
+	// once the courier has accepted the order.
+	EventCourierAssigned = "order.courier_assigned"
+	// EventOrderEscalated goes to admins once the order has waited too long
+	// for a courier.
+	EventOrderEscalated = "order.escalated"
+
+	// Events restaurants may subscribe their webhooks to.
+	EventOrderCreated   = "order.created"
+	EventOrderPaid      = "order.paid"
+	EventOrderCancelled = "order.cancelled"
+
+	// Working status changes of couriers and restaurants.
+	EventCourierStatusChanged    = "courier.status_changed"
+	EventRestaurantStatusChanged = "restaurant.status_changed"
+)
+
+// Event is a change of an order, a courier or a restaurant. The ids tell
+// whom it concerns. Id is unique per event, so a consumer that gets the
+// same event twice can drop the duplicate.
+type Event struct {
+	Id           string    `json:"id"`
+	Type         string    `json:"type"`
+	OrderId      int       `json:"order_id"`
+	UserId       int       `json:"user_id"`
+	RestaurantId int       `json:"restaurant_id"`
+	CourierId    int       `json:"courier_id"`
+	Status       int       `json:"status"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func NewEvent(eventType string, order *Order) *Event {
+	return &Event{
+		Id:           newEventId(),
+		Type:         eventType,
+		OrderId:      order.Id,
+		UserId:       order.UserId,
+		RestaurantId: order.RestaurantId,
+		CourierId:    order.CourierId,
+		Status:       order.Status,
+		CreatedAt:    time.Now(),
+	}
+}
+
+func NewCourierEvent(eventType string, courierId, status int) *Event {
+	return &Event{
+		Id:        newEventId(),
+		Type:      eventType,
+		CourierId: courierId,
+		Status:    status,
+		CreatedAt: time.Now(),
+	}
+}
+
+func NewRestaurantEvent(eventType string, restaurantId, status int) *Event {
+	return &Event{
+		Id:           newEventId(),
+		Type:         eventType,
+		RestaurantId: restaurantId,
+		Status:       status,
+		CreatedAt:    time.Now(),
+	}
+}
+
+func newEventId() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+// EventTicket lets a client that can't set headers, like the browser
+// EventSource, open the event stream once within a short time. It carries
+// the access token it was issued for, so the stream ends with the token.
+type EventTicket struct {
+	Ticket         string    `json:"ticket" db:"ticket_hash"`
+	ClientId       int       `json:"-" db:"client_id"`
+	ClientType     string    `json:"-" db:"client_type"`
+	TokenId        string    `json:"-" db:"token_id"`
+	TokenIssuedAt  time.Time `json:"-" db:"token_issued_at"`
+	TokenExpiresAt time.Time `json:"-" db:"token_expires_at"`
+	ExpiresAt      time.Time `json:"expires_at" db:"expires_at"`
+}
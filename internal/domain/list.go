@@ -0,0 +1,64 @@
+package domain
+
+import "time"
+
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+)
+
+// ListParams select a page of a list. Cursor is the next cursor of the
+// previous page, empty for the first page. Sort is one of the orders the
+// list allows, a leading "-" makes it descending; empty is the default
+// order of the list.
+type ListParams struct {
+	Cursor string
+	Limit  int
+	Sort   string
+}
+
+// RestaurantFilter narrows the restaurants delivering to the user. Zero
+// fields don't filter.
+type RestaurantFilter struct {
+	ListParams
+	WorkingStatus int
+}
+
+// MenuFilter narrows the menu of a restaurant to a price range. Zero
+// fields don't filter.
+type MenuFilter struct {
+	ListParams
+	MinPrice int
+	MaxPrice int
+}
+
+// OrderFilter narrows the orders of a user or of a restaurant. Orders are
+// created in [From, To). Zero fields don't filter.
+type OrderFilter struct {
+	ListParams
+	UserId       int
+	RestaurantId int
+	Statuses     []int
+	From         *time.Time
+	To           *time.Time
+}
+
+// The pages carry the cursor of the next page, it is empty on the last one.
+
+// Cursors holds the cursor that continues after each restaurant, for the
+// pages filtered further once read.
+type RestaurantPage struct {
+	Items      []*Restaurant `json:"items"`
+	NextCursor string        `json:"next_cursor"`
+	Cursors    []string      `json:"-"`
+}
+
+type MenuPage struct {
+	Items      []*MenuItem `json:"items"`
+	NextCursor string      `json:"next_cursor"`
+}
+
+type OrderPage struct {
+	Items      []*Order `json:"items"`
+	NextCursor string   `json:"next_cursor"`
+}
@@ -1,6 +1,16 @@
 package domain
 
+import "time"
+
 type Location struct {
 	Latitude  float64 `json:"latitude" db:"latitude"`
 	Longitude float64 `json:"longitude" db:"longitude"`
 }
+
+// CourierLocation is a position reported by a courier.
+type CourierLocation struct {
+	CourierId int       `json:"courier_id" db:"courier_id"`
+	Latitude  float64   `json:"latitude" db:"latitude"`
+	Longitude float64   `json:"longitude" db:"longitude"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
@@ -11,4 +11,30 @@ type Order struct {
 	TotalPrice    int        `json:"total_price" db:"total_price"`
 	Status        int        `json:"status" db:"status"`
 	Paid          *time.Time `json:"paid" db:"paid"`
+	Discount      int        `json:"discount" db:"discount"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+}
+
+type OrderTransition struct {
+	Status int    `json:"status"`
+	Name   string `json:"name"`
+}
+
+// Receipt itemizes an order. The total is the items price plus delivery
+// minus the refunded discount.
+type Receipt struct {
+	OrderId       int            `json:"order_id"`
+	Items         []*ReceiptLine `json:"items"`
+	ItemsPrice    int            `json:"items_price"`
+	DeliveryPrice int            `json:"delivery_price"`
+	Discount      int            `json:"discount"`
+	TotalPrice    int            `json:"total_price"`
+}
+
+type ReceiptLine struct {
+	MenuItemId int    `json:"menu_item_id"`
+	Title      string `json:"title"`
+	Price      int    `json:"price"`
+	Count      int    `json:"count"`
+	Amount     int    `json:"amount"`
 }
@@ -0,0 +1,15 @@
+package domain
+
+import "time"
+
+// OrderEvent is a status change of an order. OldStatus is nil for the event
+// recorded when the order is created.
+type OrderEvent struct {
+	Id        int       `json:"id" db:"id"`
+	OrderId   int       `json:"order_id" db:"order_id"`
+	ActorId   int       `json:"actor_id" db:"actor_id"`
+	ActorType string    `json:"actor_type" db:"actor_type"`
+	OldStatus *int      `json:"old_status" db:"old_status"`
+	NewStatus int       `json:"new_status" db:"new_status"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
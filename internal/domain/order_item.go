@@ -1,8 +1,13 @@
 package domain
 
+// OrderItem keeps the title and the unit price the menu item had when it
+// was added, so later menu changes don't change the order. The menu item
+// is zero once the dish is deleted from the menu, the order keeps the line.
 type OrderItem struct {
-	Id         int `json:"id" db:"id"`
-	OrderId    int `json:"order_id" db:"order_id"`
-	MenuItemId int `json:"menu_item_id" db:"menu_item_id"`
-	Count      int `json:"count" db:"count"`
+	Id         int    `json:"id" db:"id"`
+	OrderId    int    `json:"order_id" db:"order_id"`
+	MenuItemId int    `json:"menu_item_id" db:"menu_item_id"`
+	Count      int    `json:"count" db:"count"`
+	Title      string `json:"title" db:"title"`
+	Price      int    `json:"price" db:"price"`
 }
@@ -0,0 +1,17 @@
+package domain
+
+import "time"
+
+// OutboxMessage is an event written together with the change it describes
+// and waiting to be relayed to the sinks.
+type OutboxMessage struct {
+	Id            int64      `json:"id" db:"id"`
+	EventId       string     `json:"event_id" db:"event_id"`
+	EventType     string     `json:"event_type" db:"event_type"`
+	Payload       string     `json:"payload" db:"payload"`
+	Attempts      int        `json:"attempts" db:"attempts"`
+	LastError     *string    `json:"last_error" db:"last_error"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" db:"next_attempt_at"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	PublishedAt   *time.Time `json:"published_at" db:"published_at"`
+}
@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+// Payment is an intent at the payment provider to take the total price
+// of an order.
+type Payment struct {
+	Id        int       `json:"id" db:"id"`
+	OrderId   int       `json:"order_id" db:"order_id"`
+	Provider  string    `json:"provider" db:"provider"`
+	IntentId  string    `json:"intent_id" db:"intent_id"`
+	Amount    int       `json:"amount" db:"amount"`
+	Status    string    `json:"status" db:"status"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+// Refund returns a part or all of a captured payment to the user. A pending
+// refund is made at the payment provider, retried until it succeeds or
+// runs out of attempts.
+type Refund struct {
+	Id               int       `json:"id" db:"id"`
+	OrderId          int       `json:"order_id" db:"order_id"`
+	PaymentId        int       `json:"payment_id" db:"payment_id"`
+	Kind             string    `json:"kind" db:"kind"`
+	Amount           int       `json:"amount" db:"amount"`
+	Reason           string    `json:"reason" db:"reason"`
+	OrderItemId      *int      `json:"order_item_id" db:"order_item_id"`
+	Status           string    `json:"status" db:"status"`
+	ProviderRefundId *string   `json:"provider_refund_id" db:"provider_refund_id"`
+	Attempts         int       `json:"attempts" db:"attempts"`
+	LastError        *string   `json:"last_error" db:"last_error"`
+	NextAttemptAt    time.Time `json:"next_attempt_at" db:"next_attempt_at"`
+	ActorId          int       `json:"actor_id" db:"actor_id"`
+	ActorType        string    `json:"actor_type" db:"actor_type"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+}
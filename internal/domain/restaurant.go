@@ -1,5 +1,7 @@
 package domain
 
+import "time"
+
 type Restaurant struct {
 	Id            int       `json:"id" db:"id"`
 	Name          string    `json:"name" db:"name"`
@@ -8,4 +10,17 @@ type Restaurant struct {
 	WorkingStatus int       `json:"working_status" db:"working_status"`
 	Address       *Location `json:"location" db:"location"`
 	Image         string    `json:"image" db:"image"`
+	TimeZone      string    `json:"time_zone" db:"time_zone"`
+	// Rating is the average of the visible reviews, 0 without any.
+	Rating      float64 `json:"rating" db:"rating"`
+	RatingCount int     `json:"rating_count" db:"rating_count"`
+	// NextOpening is set for a restaurant closed by its opening hours.
+	NextOpening *time.Time `json:"next_opening,omitempty" db:"-"`
+}
+
+// DeliveryZone is where a restaurant delivers: within Radius kilometres of
+// it or inside Polygon. A restaurant without either delivers anywhere.
+type DeliveryZone struct {
+	Radius  *float64    `json:"radius"`
+	Polygon []*Location `json:"polygon"`
 }
@@ -0,0 +1,33 @@
+package domain
+
+import "time"
+
+// Review rates the restaurant or the courier of a delivered order, the
+// subject. Restaurants reply to their reviews publicly; hidden reviews are
+// taken down by a moderator and don't count towards the rating.
+type Review struct {
+	Id        int        `json:"id" db:"id"`
+	OrderId   int        `json:"order_id" db:"order_id"`
+	UserId    int        `json:"user_id" db:"user_id"`
+	Subject   string     `json:"subject" db:"subject"`
+	SubjectId int        `json:"subject_id" db:"subject_id"`
+	Rating    int        `json:"rating" db:"rating"`
+	Comment   string     `json:"comment" db:"comment"`
+	Reply     *string    `json:"reply" db:"reply"`
+	RepliedAt *time.Time `json:"replied_at" db:"replied_at"`
+	Hidden    bool       `json:"hidden" db:"hidden"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// ReviewFilter selects the reviews of a restaurant. Hidden reviews are
+// only listed for moderators.
+type ReviewFilter struct {
+	ListParams
+	RestaurantId int
+	WithHidden   bool
+}
+
+type ReviewPage struct {
+	Items      []*Review `json:"items"`
+	NextCursor string    `json:"next_cursor"`
+}
@@ -0,0 +1,14 @@
+package domain
+
+import "time"
+
+type RevokedToken struct {
+	TokenId   string    `json:"token_id" db:"token_id"`
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+}
+
+type RevokedClient struct {
+	ClientId      int       `json:"client_id" db:"client_id"`
+	ClientType    string    `json:"client_type" db:"client_type"`
+	RevokedBefore time.Time `json:"revoked_before" db:"revoked_before"`
+}
@@ -0,0 +1,28 @@
+package domain
+
+// Schedule is when a restaurant takes orders. The times are wall clock
+// times in the time zone of the restaurant.
+type Schedule struct {
+	TimeZone string          `json:"time_zone"`
+	Hours    []*OpeningHours `json:"hours"`
+	Holidays []*Holiday      `json:"holidays"`
+}
+
+// OpeningHours are the hours of a day of the week, 1 being Monday and
+// 7 Sunday. Hours closing at or before the time they open end the next day.
+type OpeningHours struct {
+	RestaurantId int    `json:"-" db:"restaurant_id"`
+	Weekday      int    `json:"weekday" db:"weekday"`
+	OpensAt      string `json:"opens_at" db:"opens_at"`
+	ClosesAt     string `json:"closes_at" db:"closes_at"`
+}
+
+// Holiday replaces the opening hours on a date. The restaurant is closed
+// all day unless the holiday has hours of its own.
+type Holiday struct {
+	RestaurantId int     `json:"-" db:"restaurant_id"`
+	Date         string  `json:"date" db:"date"`
+	OpensAt      *string `json:"opens_at" db:"opens_at"`
+	ClosesAt     *string `json:"closes_at" db:"closes_at"`
+	Reason       string  `json:"reason" db:"reason"`
+}
@@ -0,0 +1,20 @@
+package domain
+
+// SearchFilter is a full-text search over the restaurants delivering to the
+// user, their dishes and the categories of their menus.
+type SearchFilter struct {
+	ListParams
+	Query string
+}
+
+// SearchResult is a restaurant matching the search, by its name or by its
+// menu, with the dishes that match it, the best match first.
+type SearchResult struct {
+	Restaurant *Restaurant `json:"restaurant"`
+	Dishes     []*MenuItem `json:"dishes"`
+}
+
+type SearchPage struct {
+	Items      []*SearchResult `json:"items"`
+	NextCursor string          `json:"next_cursor"`
+}
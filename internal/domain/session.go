@@ -0,0 +1,13 @@
+package domain
+
+import "time"
+
+type Session struct {
+	Id           int        `json:"id" db:"id"`
+	ClientId     int        `json:"client_id" db:"client_id"`
+	ClientType   string     `json:"client_type" db:"client_type"`
+	RefreshToken string     `json:"-" db:"refresh_token_hash"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	RefreshedAt  *time.Time `json:"refreshed_at" db:"refreshed_at"`
+	ExpiresAt    time.Time  `json:"expires_at" db:"expires_at"`
+}
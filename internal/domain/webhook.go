@@ -0,0 +1,31 @@
+package domain
+
+import "time"
+
+// Webhook pushes the subscribed order events of a restaurant to its URL.
+// The secret signs the deliveries and is never returned by the API.
+type Webhook struct {
+	Id           int       `json:"id" db:"id"`
+	RestaurantId int       `json:"restaurant_id" db:"restaurant_id"`
+	Url          string    `json:"url" db:"url"`
+	Secret       string    `json:"-" db:"secret"`
+	Events       []string  `json:"events" db:"events"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// WebhookDelivery is one event sent to a webhook, with the outcome
+// of the latest attempt.
+type WebhookDelivery struct {
+	Id            int        `json:"id" db:"id"`
+	WebhookId     int        `json:"webhook_id" db:"webhook_id"`
+	Event         string     `json:"event" db:"event"`
+	EventId       string     `json:"event_id" db:"event_id"`
+	Payload       string     `json:"payload" db:"payload"`
+	Status        string     `json:"status" db:"status"`
+	Attempts      int        `json:"attempts" db:"attempts"`
+	ResponseCode  *int       `json:"response_code" db:"response_code"`
+	LastError     *string    `json:"last_error" db:"last_error"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" db:"next_attempt_at"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	DeliveredAt   *time.Time `json:"delivered_at" db:"delivered_at"`
+}
@@ -16,13 +16,19 @@ func NewAdminPg(db *sqlx.DB) *AdminPg {
 	}
 }
 
-func (r *AdminPg) GetByCredentials(name, password string) (*domain.Admin, error) {
+func (r *AdminPg) GetByName(name string) (*domain.Admin, error) {
 	admin := new(domain.Admin)
 
-	query := fmt.Sprintf(`SELECT * FROM %s AS a WHERE a.name = $1 AND a.password_hash = $2`, adminsTable)
-	if err := r.db.Get(admin, query, name, password); err != nil {
-		return nil, err
+	query := fmt.Sprintf(`SELECT * FROM %s AS a WHERE a.name = $1`, adminsTable)
+	if err := r.db.Get(admin, query, name); err != nil {
+		return nil, pgError(err)
 	}
 
 	return admin, nil
 }
+
+func (r *AdminPg) UpdatePassword(adminId int, passwordHash string) error {
+	query := fmt.Sprintf(`UPDATE %s SET password_hash = $1 WHERE id = $2`, adminsTable)
+	_, err := r.db.Exec(query, passwordHash, adminId)
+	return pgError(err)
+}
@@ -0,0 +1,267 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+type CartPg struct {
+	db *sqlx.DB
+}
+
+func NewCartPg(db *sqlx.DB) *CartPg {
+	return &CartPg{
+		db: db,
+	}
+}
+
+func (r *CartPg) Get(userId int) (*domain.Cart, error) {
+	cart := new(domain.Cart)
+
+	query := fmt.Sprintf(`SELECT id, user_id, restaurant_id, updated_at FROM %s WHERE user_id = $1`, cartsTable)
+	err := r.db.Get(cart, query, userId)
+
+	return cart, pgError(err)
+}
+
+// GetItems returns the cart items with the current title and price of
+// their menu items.
+func (r *CartPg) GetItems(cartId int) ([]*domain.CartItem, error) {
+	var items []*domain.CartItem
+
+	query := fmt.Sprintf(
+		`SELECT ci.id, ci.cart_id, ci.menu_item_id, ci.count, mi.title, mi.price
+		FROM %s AS ci
+			INNER JOIN %s AS mi ON mi.id = ci.menu_item_id
+		WHERE ci.cart_id = $1 ORDER BY ci.id`, cartItemsTable, menuItemsTable)
+	err := r.db.Select(&items, query, cartId)
+
+	return items, pgError(err)
+}
+
+// AddItem puts the menu item into the cart of the user, creating the cart
+// if there is none. Adding a dish that is already there adds to its count.
+// A cart with dishes of another restaurant is only emptied and switched
+// to the restaurant when replace is set.
+func (r *CartPg) AddItem(userId, restaurantId int, item *domain.CartItem, replace bool) (int, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return 0, pgError(err)
+	}
+
+	var cartId, cartRestaurantId int
+
+	// the upsert locks the cart row until the transaction ends
+	query := fmt.Sprintf(
+		`INSERT INTO %s (user_id, restaurant_id) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET updated_at = now()
+		RETURNING id, restaurant_id`, cartsTable)
+
+	row := tx.QueryRow(query, userId, restaurantId)
+	if err := row.Scan(&cartId, &cartRestaurantId); err != nil {
+		_ = tx.Rollback()
+		return 0, pgError(err)
+	}
+
+	if cartRestaurantId != restaurantId {
+		if err := switchCartRestaurant(tx, cartId, restaurantId, replace); err != nil {
+			_ = tx.Rollback()
+			return 0, pgError(err)
+		}
+	}
+
+	var cartItemId int
+
+	query = fmt.Sprintf(
+		`INSERT INTO %s (cart_id, menu_item_id, count) VALUES ($1, $2, $3)
+		ON CONFLICT (cart_id, menu_item_id) DO UPDATE SET count = %s.count + EXCLUDED.count
+		RETURNING id`, cartItemsTable, cartItemsTable)
+
+	row = tx.QueryRow(query, cartId, item.MenuItemId, item.Count)
+	if err := row.Scan(&cartItemId); err != nil {
+		_ = tx.Rollback()
+		return 0, pgError(err)
+	}
+
+	return cartItemId, pgError(tx.Commit())
+}
+
+func switchCartRestaurant(tx *sqlx.Tx, cartId, restaurantId int, replace bool) error {
+	var items int
+
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE cart_id = $1`, cartItemsTable)
+	if err := tx.Get(&items, query, cartId); err != nil {
+		return err
+	}
+
+	if items > 0 && !replace {
+		return domain.NewConflictError("Cart has dishes of another restaurant, confirm replacing them")
+	}
+
+	query = fmt.Sprintf(`DELETE FROM %s WHERE cart_id = $1`, cartItemsTable)
+	if _, err := tx.Exec(query, cartId); err != nil {
+		return err
+	}
+
+	query = fmt.Sprintf(`UPDATE %s SET restaurant_id = $1 WHERE id = $2`, cartsTable)
+	_, err := tx.Exec(query, restaurantId, cartId)
+
+	return err
+}
+
+func (r *CartPg) UpdateItem(cartId, cartItemId, count int) error {
+	query := fmt.Sprintf(
+		`WITH item AS (UPDATE %s SET count = $1 WHERE id = $2 AND cart_id = $3 RETURNING cart_id)
+		UPDATE %s SET updated_at = now() WHERE id IN (SELECT cart_id FROM item)`,
+		cartItemsTable, cartsTable)
+
+	return r.touch(query, count, cartItemId, cartId)
+}
+
+func (r *CartPg) DeleteItem(cartId, cartItemId int) error {
+	query := fmt.Sprintf(
+		`WITH item AS (DELETE FROM %s WHERE id = $1 AND cart_id = $2 RETURNING cart_id)
+		UPDATE %s SET updated_at = now() WHERE id IN (SELECT cart_id FROM item)`,
+		cartItemsTable, cartsTable)
+
+	return r.touch(query, cartItemId, cartId)
+}
+
+// touch runs a query changing a cart item and the cart updated_at,
+// it fails with not found if there is no such item.
+func (r *CartPg) touch(query string, args ...interface{}) error {
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return pgError(err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return pgError(err)
+	}
+
+	if rows == 0 {
+		return domain.NewNotFoundError("Cart item not found")
+	}
+
+	return nil
+}
+
+func (r *CartPg) Delete(userId int) error {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE user_id = $1`, cartsTable)
+	_, err := r.db.Exec(query, userId)
+	return pgError(err)
+}
+
+// Checkout creates the order with all the dishes of the cart and deletes
+// the cart in one transaction. Neither the cart nor the prices of its dishes
+// must have changed since it was read, so the order is the one the user has
+// seen priced.
+func (r *CartPg) Checkout(cart *domain.Cart, order *domain.Order, event *domain.OrderEvent, outbox ...*domain.Event) (int, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return 0, pgError(err)
+	}
+
+	var cartId int
+
+	query := fmt.Sprintf(`SELECT id FROM %s WHERE id = $1 AND updated_at = $2 FOR UPDATE`, cartsTable)
+	if err := tx.Get(&cartId, query, cart.Id, cart.UpdatedAt); err != nil {
+		_ = tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, domain.NewConflictError("Cart has changed, review it before checkout")
+		}
+		return 0, pgError(err)
+	}
+
+	// the menu items stay locked until the order items are copied from them
+	var items []*domain.CartItem
+
+	query = fmt.Sprintf(
+		`SELECT ci.id, ci.cart_id, ci.menu_item_id, ci.count, mi.title, mi.price
+		FROM %s AS ci
+			INNER JOIN %s AS mi ON mi.id = ci.menu_item_id
+		WHERE ci.cart_id = $1 AND mi.restaurant_id = $2
+		ORDER BY ci.id
+		FOR SHARE OF mi`, cartItemsTable, menuItemsTable)
+	if err := tx.Select(&items, query, cart.Id, order.RestaurantId); err != nil {
+		_ = tx.Rollback()
+		return 0, pgError(err)
+	}
+
+	if !sameCartItems(items, cart.Items) {
+		_ = tx.Rollback()
+		return 0, domain.NewConflictError("Menu prices have changed, review the cart before checkout")
+	}
+
+	orderId, err := createOrder(tx, order, event, outbox)
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, pgError(err)
+	}
+
+	query = fmt.Sprintf(
+		`INSERT INTO %s (order_id, menu_item_id, count, title, price)
+		SELECT $1, ci.menu_item_id, ci.count, mi.title, mi.price
+		FROM %s AS ci
+			INNER JOIN %s AS mi ON mi.id = ci.menu_item_id
+		WHERE ci.cart_id = $2 AND mi.restaurant_id = $3
+		ORDER BY ci.id`, orderItemsTable, cartItemsTable, menuItemsTable)
+
+	result, err := tx.Exec(query, orderId, cart.Id, order.RestaurantId)
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, pgError(err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, pgError(err)
+	}
+
+	if rows == 0 {
+		_ = tx.Rollback()
+		return 0, domain.NewConflictError("Cart is empty")
+	}
+
+	query = fmt.Sprintf(`DELETE FROM %s WHERE id = $1`, cartsTable)
+	if _, err := tx.Exec(query, cart.Id); err != nil {
+		_ = tx.Rollback()
+		return 0, pgError(err)
+	}
+
+	return orderId, pgError(tx.Commit())
+}
+
+func sameCartItems(a, b []*domain.CartItem) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i].Id != b[i].Id || a[i].MenuItemId != b[i].MenuItemId || a[i].Count != b[i].Count ||
+			a[i].Title != b[i].Title || a[i].Price != b[i].Price {
+			return false
+		}
+	}
+
+	return true
+}
+
+// DeleteExpired drops the carts that haven't changed since before.
+func (r *CartPg) DeleteExpired(before time.Time) (int64, error) {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE updated_at < $1`, cartsTable)
+	result, err := r.db.Exec(query, before)
+	if err != nil {
+		return 0, pgError(err)
+	}
+
+	rows, err := result.RowsAffected()
+	return rows, pgError(err)
+}
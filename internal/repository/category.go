@@ -2,7 +2,6 @@ package repository
 
 import (
 	"database/sql"
-	"errors"
 	"fmt"
 
 	"github.com/MAVIKE/yad-backend/internal/domain"
@@ -29,7 +28,7 @@ func (r *CategoryPg) Create(category *domain.Category) (int, error) {
 	row := r.db.QueryRow(query, category.RestaurantId, category.Title)
 	err := row.Scan(&categoryId)
 
-	return categoryId, err
+	return categoryId, pgError(err)
 }
 
 func (r *CategoryPg) GetAll(restaurantId int) ([]*domain.Category, error) {
@@ -42,7 +41,7 @@ func (r *CategoryPg) GetAll(restaurantId int) ([]*domain.Category, error) {
 
 	err := r.db.Select(&categories, query, restaurantId)
 
-	return categories, err
+	return categories, pgError(err)
 }
 
 func (r *CategoryPg) GetById(categoryId int) (*domain.Category, error) {
@@ -58,7 +57,7 @@ func (r *CategoryPg) GetById(categoryId int) (*domain.Category, error) {
 
 	err := row.Scan(&category.Id, &category.RestaurantId, &category.Title)
 
-	return category, err
+	return category, pgError(err)
 }
 
 func (r *CategoryPg) GetAllItems(categoryId int) ([]*domain.MenuItem, error) {
@@ -74,13 +73,13 @@ func (r *CategoryPg) GetAllItems(categoryId int) ([]*domain.MenuItem, error) {
 		WHERE ci.category_id = $1`, menuItemsTable, categoryItemsTable)
 	err := r.db.Select(&items, query, categoryId)
 
-	return items, err
+	return items, pgError(err)
 }
 
 func (r *CategoryPg) DeleteCategory(restaurantId int, categoryId int) error {
 	tx, err := r.db.Begin()
 	if err != nil {
-		return err
+		return pgError(err)
 	}
 
 	id := 0
@@ -90,10 +89,10 @@ func (r *CategoryPg) DeleteCategory(restaurantId int, categoryId int) error {
 	if err != nil {
 		if err == sql.ErrNoRows {
 			_ = tx.Rollback()
-			return errors.New("category does not belong to this restaurant")
+			return domain.NewNotFoundError("no such category for this restaurant")
 		} else {
 			_ = tx.Rollback()
-			return err
+			return pgError(err)
 		}
 	}
 
@@ -101,14 +100,14 @@ func (r *CategoryPg) DeleteCategory(restaurantId int, categoryId int) error {
 	_, err = r.db.Exec(query, categoryId)
 	if err != nil {
 		_ = tx.Rollback()
-		return err
+		return pgError(err)
 	}
 
 	query = fmt.Sprintf(`DELETE FROM %s WHERE id = $1`, categoriesTable)
 	_, err = r.db.Exec(query, categoryId)
 	if err != nil {
 		_ = tx.Rollback()
-		return err
+		return pgError(err)
 	}
 
 	return tx.Commit()
@@ -117,7 +116,7 @@ func (r *CategoryPg) DeleteCategory(restaurantId int, categoryId int) error {
 func (r *CategoryPg) UpdateCategory(restaurantId int, categoryId int, input *domain.Category) error {
 	tx, err := r.db.Begin()
 	if err != nil {
-		return err
+		return pgError(err)
 	}
 
 	id := 0
@@ -127,11 +126,11 @@ func (r *CategoryPg) UpdateCategory(restaurantId int, categoryId int, input *dom
 	if err != nil {
 		if err == sql.ErrNoRows {
 			_ = tx.Rollback()
-			return errors.New("category does not belong to this restaurant")
+			return domain.NewNotFoundError("no such category for this restaurant")
 		}
 
 		_ = tx.Rollback()
-		return err
+		return pgError(err)
 	}
 
 	if input.Title != "" {
@@ -139,7 +138,7 @@ func (r *CategoryPg) UpdateCategory(restaurantId int, categoryId int, input *dom
 		_, err = r.db.Exec(query, input.Title, categoryId)
 		if err != nil {
 			_ = tx.Rollback()
-			return err
+			return pgError(err)
 		}
 	}
 
@@ -20,7 +20,7 @@ func NewCourierPg(db *sqlx.DB) *CourierPg {
 func (r *CourierPg) Create(courier *domain.Courier) (int, error) {
 	tx, err := r.db.Begin()
 	if err != nil {
-		return 0, err
+		return 0, pgError(err)
 	}
 
 	var addressId int
@@ -31,7 +31,7 @@ func (r *CourierPg) Create(courier *domain.Courier) (int, error) {
 	locationRow := tx.QueryRow(createLocationQuery, courier.Address.Latitude, courier.Address.Longitude)
 	if err = locationRow.Scan(&addressId); err != nil {
 		_ = tx.Rollback()
-		return 0, err
+		return 0, pgError(err)
 	}
 
 	createCourierQuery := fmt.Sprintf(
@@ -42,25 +42,31 @@ func (r *CourierPg) Create(courier *domain.Courier) (int, error) {
 	userRow := tx.QueryRow(createCourierQuery, courier.Name, courier.Phone, courier.Password, courier.Email, addressId, courier.WorkingStatus)
 	if err = userRow.Scan(&courierId); err != nil {
 		_ = tx.Rollback()
-		return 0, err
+		return 0, pgError(err)
 	}
 
 	return courierId, tx.Commit()
 }
 
-func (r *CourierPg) GetByCredentials(phone, password string) (*domain.Courier, error) {
+func (r *CourierPg) GetByPhone(phone string) (*domain.Courier, error) {
 	courier := new(domain.Courier)
 	address := new(domain.Location)
 
 	query := fmt.Sprintf(
 		`SELECT u.id, u.name, u.phone, u.password_hash, u.email, l.latitude, l.longitude, u.working_status
  				FROM %s AS u JOIN %s AS l ON u.address_id = l.id
- 				WHERE u.phone = $1 AND u.password_hash = $2`, couriersTable, locationsTable)
-	row := r.db.QueryRow(query, phone, password)
+ 				WHERE u.phone = $1`, couriersTable, locationsTable)
+	row := r.db.QueryRow(query, phone)
 	err := row.Scan(&courier.Id, &courier.Name, &courier.Phone, &courier.Password, &courier.Email, &address.Latitude, &address.Longitude, &courier.WorkingStatus)
 	courier.Address = address
 
-	return courier, err
+	return courier, pgError(err)
+}
+
+func (r *CourierPg) UpdatePassword(courierId int, passwordHash string) error {
+	query := fmt.Sprintf(`UPDATE %s SET password_hash = $1 WHERE id = $2`, couriersTable)
+	_, err := r.db.Exec(query, passwordHash, courierId)
+	return pgError(err)
 }
 
 func (r *CourierPg) GetById(courierId int) (*domain.Courier, error) {
@@ -81,13 +87,13 @@ func (r *CourierPg) GetById(courierId int) (*domain.Courier, error) {
 		&location.Latitude, &location.Longitude)
 	courier.Address = location
 
-	return courier, err
+	return courier, pgError(err)
 }
 
-func (r *CourierPg) Update(courierId int, input *domain.Courier) error {
-	tx, err := r.db.Begin()
+func (r *CourierPg) Update(courierId int, input *domain.Courier, outbox ...*domain.Event) error {
+	tx, err := r.db.Beginx()
 	if err != nil {
-		return err
+		return pgError(err)
 	}
 
 	setValues := make([]string, 0)
@@ -130,7 +136,7 @@ func (r *CourierPg) Update(courierId int, input *domain.Courier) error {
 		_, err := r.db.Exec(query, input.Address.Latitude, input.Address.Longitude, courierId)
 		if err != nil {
 			_ = tx.Rollback()
-			return err
+			return pgError(err)
 		}
 	}
 
@@ -141,7 +147,12 @@ func (r *CourierPg) Update(courierId int, input *domain.Courier) error {
 
 	if _, err = tx.Exec(query, args...); err != nil {
 		_ = tx.Rollback()
-		return err
+		return pgError(err)
+	}
+
+	if err := writeOutbox(tx, outbox); err != nil {
+		_ = tx.Rollback()
+		return pgError(err)
 	}
 
 	return tx.Commit()
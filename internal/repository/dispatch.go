@@ -0,0 +1,280 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/MAVIKE/yad-backend/internal/consts"
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+type DispatchPg struct {
+	db *sqlx.DB
+}
+
+func NewDispatchPg(db *sqlx.DB) *DispatchPg {
+	return &DispatchPg{
+		db: db,
+	}
+}
+
+// GetPending returns the queued orders, the longest waiting first.
+func (r *DispatchPg) GetPending() ([]*domain.PendingOrder, error) {
+	var orders []*domain.PendingOrder
+
+	query := fmt.Sprintf(
+		`SELECT q.order_id, o.user_id, o.status, q.queued_at, q.escalated_at,
+			EXISTS (
+				SELECT 1 FROM %s AS f WHERE f.order_id = q.order_id AND f.status = $1
+			) AS offered
+		FROM %s AS q
+			INNER JOIN %s AS o ON q.order_id = o.id
+		ORDER BY q.queued_at, q.order_id`,
+		courierOffersTable, dispatchQueueTable, ordersTable)
+	err := r.db.Select(&orders, query, consts.OfferPending)
+
+	return orders, pgError(err)
+}
+
+// Dequeue takes the order off the queue and withdraws its pending offer.
+func (r *DispatchPg) Dequeue(orderId int) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return pgError(err)
+	}
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE order_id = $1`, dispatchQueueTable)
+	if _, err := tx.Exec(query, orderId); err != nil {
+		_ = tx.Rollback()
+		return pgError(err)
+	}
+
+	query = fmt.Sprintf(
+		`UPDATE %s SET status = $1 WHERE order_id = $2 AND status = $3`, courierOffersTable)
+	if _, err := tx.Exec(query, consts.OfferExpired, orderId, consts.OfferPending); err != nil {
+		_ = tx.Rollback()
+		return pgError(err)
+	}
+
+	return pgError(tx.Commit())
+}
+
+// Escalate marks the queued order as escalated and writes the outbox events.
+// An order is escalated once: nothing is written when another instance has
+// already escalated it.
+func (r *DispatchPg) Escalate(orderId int, outbox ...*domain.Event) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return pgError(err)
+	}
+
+	query := fmt.Sprintf(
+		`UPDATE %s SET escalated_at = now() WHERE order_id = $1 AND escalated_at IS NULL`, dispatchQueueTable)
+	result, err := tx.Exec(query, orderId)
+	if err != nil {
+		_ = tx.Rollback()
+		return pgError(err)
+	}
+
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		_ = tx.Rollback()
+		return pgError(err)
+	}
+
+	if err := writeOutbox(tx, outbox); err != nil {
+		_ = tx.Rollback()
+		return pgError(err)
+	}
+
+	return pgError(tx.Commit())
+}
+
+// GetNearestCourierId returns the waiting courier nearest to the user of the
+// order. The courier position is the latest location ping, or the courier
+// address for couriers who have never sent one. Couriers busy with another
+// order or offer are skipped, and so are the couriers who declined or missed
+// an offer of this order after answeredAfter.
+func (r *DispatchPg) GetNearestCourierId(orderId int, answeredAfter time.Time) (int, error) {
+	var courierId int
+
+	query := fmt.Sprintf(
+		`SELECT c.id
+		FROM %s AS c
+			INNER JOIN %s AS l ON c.address_id = l.id
+			LEFT JOIN LATERAL (
+				SELECT p.latitude, p.longitude FROM %s AS p
+				WHERE p.courier_id = c.id
+				ORDER BY p.created_at DESC
+				LIMIT 1
+			) AS p ON true,
+			(
+				SELECT ul.latitude, ul.longitude
+				FROM %s AS o
+					INNER JOIN %s AS u ON o.user_id = u.id
+					INNER JOIN %s AS ul ON u.address_id = ul.id
+				WHERE o.id = $1
+			) AS ua
+		WHERE c.working_status = $2
+			AND NOT EXISTS (
+				SELECT 1 FROM %s AS o
+				WHERE o.courier_id = c.id AND o.status IN ($3, $4, $5, $6)
+			)
+			AND NOT EXISTS (
+				SELECT 1 FROM %s AS f
+				WHERE f.courier_id = c.id AND (f.status = $7
+					OR f.order_id = $1 AND COALESCE(f.responded_at, f.expires_at) > $8)
+			)
+		ORDER BY get_distance(COALESCE(p.latitude, l.latitude), COALESCE(p.longitude, l.longitude),
+			ua.latitude, ua.longitude)
+		LIMIT 1`,
+		couriersTable, locationsTable, courierLocationsTable, ordersTable, usersTable, locationsTable,
+		ordersTable, courierOffersTable)
+
+	row := r.db.QueryRow(query, orderId, consts.CourierWaiting,
+		consts.OrderPaid, consts.OrderPreparing, consts.OrderWaitingForCourier, consts.OrderEnRoute,
+		consts.OfferPending, answeredAfter)
+	err := row.Scan(&courierId)
+
+	return courierId, pgError(err)
+}
+
+func (r *DispatchPg) CreateOffer(orderId, courierId int, expiresAt time.Time, outbox ...*domain.Event) (int, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return 0, pgError(err)
+	}
+
+	var offerId int
+
+	query := fmt.Sprintf(
+		`INSERT INTO %s (order_id, courier_id, status, expires_at)
+		VALUES ($1, $2, $3, $4) RETURNING id`, courierOffersTable)
+
+	row := tx.QueryRow(query, orderId, courierId, consts.OfferPending, expiresAt)
+	if err := row.Scan(&offerId); err != nil {
+		_ = tx.Rollback()
+		return 0, pgError(err)
+	}
+
+	if err := writeOutbox(tx, outbox); err != nil {
+		_ = tx.Rollback()
+		return 0, pgError(err)
+	}
+
+	return offerId, pgError(tx.Commit())
+}
+
+func (r *DispatchPg) GetOfferById(offerId int) (*domain.CourierOffer, error) {
+	offer := new(domain.CourierOffer)
+
+	query := fmt.Sprintf(`SELECT * FROM %s WHERE id = $1`, courierOffersTable)
+	err := r.db.Get(offer, query, offerId)
+
+	return offer, pgError(err)
+}
+
+// GetOffers returns the pending offers of the courier that have not expired yet.
+func (r *DispatchPg) GetOffers(courierId int) ([]*domain.CourierOffer, error) {
+	var offers []*domain.CourierOffer
+
+	query := fmt.Sprintf(
+		`SELECT * FROM %s
+		WHERE courier_id = $1 AND status = $2 AND expires_at > now()
+		ORDER BY created_at`, courierOffersTable)
+	err := r.db.Select(&offers, query, courierId, consts.OfferPending)
+
+	return offers, pgError(err)
+}
+
+// ExpireOffers closes the pending offers the couriers didn't answer in time.
+func (r *DispatchPg) ExpireOffers() error {
+	query := fmt.Sprintf(
+		`UPDATE %s SET status = $1 WHERE status = $2 AND expires_at <= now()`, courierOffersTable)
+	_, err := r.db.Exec(query, consts.OfferExpired, consts.OfferPending)
+	return pgError(err)
+}
+
+// AcceptOffer gives the order to the courier, marks the courier as working,
+// takes the order off the queue and writes the outbox events in one transaction.
+func (r *DispatchPg) AcceptOffer(offerId int, outbox ...*domain.Event) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return pgError(err)
+	}
+
+	var orderId, courierId int
+
+	query := fmt.Sprintf(
+		`UPDATE %s SET status = $1, responded_at = now()
+		WHERE id = $2 AND status = $3 AND expires_at > now()
+		RETURNING order_id, courier_id`, courierOffersTable)
+	row := tx.QueryRow(query, consts.OfferAccepted, offerId, consts.OfferPending)
+	if err := row.Scan(&orderId, &courierId); err != nil {
+		_ = tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.NewConflictError("offer is no longer available")
+		}
+		return pgError(err)
+	}
+
+	query = fmt.Sprintf(
+		`UPDATE %s SET courier_id = $1
+		WHERE id = $2 AND courier_id IS NULL AND status IN ($3, $4, $5)`, ordersTable)
+	result, err := tx.Exec(query, courierId, orderId,
+		consts.OrderPaid, consts.OrderPreparing, consts.OrderWaitingForCourier)
+	if err != nil {
+		_ = tx.Rollback()
+		return pgError(err)
+	}
+
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		_ = tx.Rollback()
+		if err != nil {
+			return pgError(err)
+		}
+		return domain.NewConflictError("order is no longer available")
+	}
+
+	query = fmt.Sprintf(`UPDATE %s SET working_status = $1 WHERE id = $2`, couriersTable)
+	if _, err := tx.Exec(query, consts.CourierWorking, courierId); err != nil {
+		_ = tx.Rollback()
+		return pgError(err)
+	}
+
+	query = fmt.Sprintf(`DELETE FROM %s WHERE order_id = $1`, dispatchQueueTable)
+	if _, err := tx.Exec(query, orderId); err != nil {
+		_ = tx.Rollback()
+		return pgError(err)
+	}
+
+	if err := writeOutbox(tx, outbox); err != nil {
+		_ = tx.Rollback()
+		return pgError(err)
+	}
+
+	return pgError(tx.Commit())
+}
+
+func (r *DispatchPg) DeclineOffer(offerId int) error {
+	query := fmt.Sprintf(
+		`UPDATE %s SET status = $1, responded_at = now()
+		WHERE id = $2 AND status = $3 AND expires_at > now()`, courierOffersTable)
+	result, err := r.db.Exec(query, consts.OfferDeclined, offerId, consts.OfferPending)
+	if err != nil {
+		return pgError(err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return pgError(err)
+	}
+
+	if rows == 0 {
+		return domain.NewConflictError("offer is no longer available")
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/lib/pq"
+)
+
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+	checkViolation      = "23514"
+)
+
+// pgError translates database errors the services care about into
+// domain errors and passes the rest through unchanged.
+func pgError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	switch pqErr.Code {
+	case uniqueViolation:
+		return domain.NewConflictError("%s", errorDetail(pqErr))
+	case foreignKeyViolation:
+		// the row is referenced by others on delete, or refers to
+		// a missing row on insert and update
+		if strings.Contains(pqErr.Detail, "still referenced") {
+			return domain.NewConflictError("%s", errorDetail(pqErr))
+		}
+		return domain.NewValidationError("%s", errorDetail(pqErr))
+	case checkViolation:
+		return domain.NewValidationError("%s", errorDetail(pqErr))
+	}
+
+	return err
+}
+
+// isUniqueViolation reports whether the error is a violation of the unique
+// constraint or index.
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation && pqErr.Constraint == constraint
+}
+
+func errorDetail(err *pq.Error) string {
+	if err.Detail != "" {
+		return err.Detail
+	}
+
+	return err.Message
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/lib/pq"
+)
+
+const (
+	// eventsChannel is the channel the events written to the outbox are
+	// notified on.
+	eventsChannel = "events"
+	// listenerPing is how often an idle listener checks its connection.
+	listenerPing = 90 * time.Second
+)
+
+// EventListenerPg receives the events committed by any instance. It has a
+// connection of its own, since a pooled one can't wait for notifications.
+type EventListenerPg struct {
+	listener *pq.Listener
+}
+
+// NewEventListenerPg connects and starts listening, so the events committed
+// once it returns are received. The connection is reestablished when lost;
+// the events committed meanwhile are missed.
+func NewEventListenerPg(cfg Config) (*EventListenerPg, error) {
+	listener := pq.NewListener(cfg.dataSource(), time.Second, time.Minute, nil)
+	if err := listener.Listen(eventsChannel); err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+
+	return &EventListenerPg{
+		listener: listener,
+	}, nil
+}
+
+// Listen passes the events to handle until the context is done.
+func (r *EventListenerPg) Listen(ctx context.Context, handle func(event *domain.Event)) error {
+	ping := time.NewTicker(listenerPing)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ping.C:
+			// a broken connection is noticed and reestablished
+			_ = r.listener.Ping()
+		case notification := <-r.listener.Notify:
+			// nil after the connection has been reestablished
+			if notification == nil {
+				continue
+			}
+
+			event := new(domain.Event)
+			if err := json.Unmarshal([]byte(notification.Extra), event); err != nil {
+				return fmt.Errorf("invalid payload: %s", err.Error())
+			}
+			handle(event)
+		}
+	}
+}
+
+func (r *EventListenerPg) Close() error {
+	return r.listener.Close()
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+type EventTicketPg struct {
+	db *sqlx.DB
+}
+
+func NewEventTicketPg(db *sqlx.DB) *EventTicketPg {
+	return &EventTicketPg{
+		db: db,
+	}
+}
+
+// Create stores the ticket and drops the expired ones.
+func (r *EventTicketPg) Create(ticket *domain.EventTicket) error {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE expires_at <= now()`, eventTicketsTable)
+	if _, err := r.db.Exec(query); err != nil {
+		return pgError(err)
+	}
+
+	query = fmt.Sprintf(
+		`INSERT INTO %s (ticket_hash, client_id, client_type, token_id, token_issued_at, token_expires_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`, eventTicketsTable)
+	_, err := r.db.Exec(query, ticket.Ticket, ticket.ClientId, ticket.ClientType, ticket.TokenId,
+		ticket.TokenIssuedAt, ticket.TokenExpiresAt, ticket.ExpiresAt)
+
+	return pgError(err)
+}
+
+// Take deletes the ticket and returns it, so it is used once.
+func (r *EventTicketPg) Take(ticketHash string) (*domain.EventTicket, error) {
+	ticket := new(domain.EventTicket)
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE ticket_hash = $1 RETURNING *`, eventTicketsTable)
+	err := r.db.Get(ticket, query, ticketHash)
+
+	return ticket, pgError(err)
+}
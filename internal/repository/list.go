@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+)
+
+// listSorts whitelists the columns a list can be sorted by, each with the
+// type it is compared as. A list without a sort uses its default one.
+type listSorts struct {
+	defaultSort string
+	columns     map[string]string
+}
+
+// listOrder is the order of a page. Rows are ordered by the column and then
+// by id in the same direction, which makes the order total so that a
+// cursor always falls between two rows.
+type listOrder struct {
+	sort   string
+	column string
+	typ    string
+	desc   bool
+}
+
+// listCursor points right after the last row of a page: the sort the page
+// was taken with, the sort column of the row as text and its id.
+type listCursor struct {
+	Sort string `json:"s"`
+	Key  string `json:"k"`
+	Id   int    `json:"id"`
+}
+
+func (s listSorts) order(sort string) (*listOrder, error) {
+	if sort == "" {
+		sort = s.defaultSort
+	}
+
+	column := strings.TrimPrefix(sort, "-")
+	typ, ok := s.columns[column]
+	if !ok {
+		return nil, domain.NewValidationError("Invalid sort %q", sort)
+	}
+
+	return &listOrder{sort: sort, column: column, typ: typ, desc: column != sort}, nil
+}
+
+func (o *listOrder) decodeCursor(value string) (*listCursor, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	cursor := new(listCursor)
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || json.Unmarshal(data, cursor) != nil || cursor.Sort != o.sort {
+		return nil, domain.NewValidationError("Invalid cursor")
+	}
+
+	return cursor, nil
+}
+
+func (o *listOrder) encodeCursor(key string, id int) string {
+	data, _ := json.Marshal(&listCursor{Sort: o.sort, Key: key, Id: id})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// pageQuery selects the columns of the page of the rows of inner that comes
+// after the cursor. The rows of inner need an id and the sort column. The
+// query takes one row more than the limit to tell if there is a next page,
+// and adds the sort column as text as sort_key for its cursor.
+func pageQuery(columns, inner string, params *domain.ListParams, sorts listSorts, args []interface{}) (string, []interface{}, *listOrder, error) {
+	order, err := sorts.order(params.Sort)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	cursor, err := order.decodeCursor(params.Cursor)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	direction, operator := "ASC", ">"
+	if order.desc {
+		direction, operator = "DESC", "<"
+	}
+
+	after := ""
+	if cursor != nil {
+		args = append(args, cursor.Key, cursor.Id)
+		after = fmt.Sprintf("WHERE (%s, id) %s ($%d::%s, $%d)", order.column, operator, len(args)-1, order.typ, len(args))
+	}
+
+	args = append(args, params.Limit+1)
+	query := fmt.Sprintf(
+		`SELECT %s, %s::text AS sort_key
+		FROM (%s) AS list
+		%s
+		ORDER BY %s %s, id %s
+		LIMIT $%d`,
+		columns, order.column, inner, after, order.column, direction, direction, len(args))
+
+	return query, args, order, nil
+}
+
+// whereClause joins the conditions of a list query.
+func whereClause(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND ")
+}
@@ -2,7 +2,6 @@ package repository
 
 import (
 	"database/sql"
-	"errors"
 	"fmt"
 	"strings"
 
@@ -20,15 +19,54 @@ func NewMenuItem(db *sqlx.DB) *MenuItemPg {
 	}
 }
 
-func (r *RestaurantPg) GetMenu(restarauntId int) ([]*domain.MenuItem, error) {
-	var items []*domain.MenuItem
+// menuSorts are the orders of a menu, the order of creation by default.
+var menuSorts = listSorts{
+	defaultSort: "id",
+	columns:     map[string]string{"id": "int", "title": "text", "price": "int"},
+}
+
+// GetMenu returns a page of the menu of the restaurant.
+func (r *RestaurantPg) GetMenu(restarauntId int, filter *domain.MenuFilter) (*domain.MenuPage, error) {
+	var rows []struct {
+		domain.MenuItem
+		SortKey string `db:"sort_key"`
+	}
+
+	conditions := []string{"m.restaurant_id = $1"}
+	args := []interface{}{restarauntId}
+
+	if filter.MinPrice != 0 {
+		args = append(args, filter.MinPrice)
+		conditions = append(conditions, fmt.Sprintf("m.price >= $%d", len(args)))
+	}
+
+	if filter.MaxPrice != 0 {
+		args = append(args, filter.MaxPrice)
+		conditions = append(conditions, fmt.Sprintf("m.price <= $%d", len(args)))
+	}
 
-	query := fmt.Sprintf(`SELECT * FROM %s AS m WHERE m.restaurant_id = $1`, menuItemsTable)
-	if err := r.db.Select(&items, query, restarauntId); err != nil {
+	inner := fmt.Sprintf(`SELECT * FROM %s AS m %s`, menuItemsTable, whereClause(conditions))
+	query, args, order, err := pageQuery(`id, restaurant_id, title, image, description, price`,
+		inner, &filter.ListParams, menuSorts, args)
+	if err != nil {
 		return nil, err
 	}
 
-	return items, nil
+	if err := r.db.Select(&rows, query, args...); err != nil {
+		return nil, pgError(err)
+	}
+
+	page := &domain.MenuPage{Items: make([]*domain.MenuItem, 0, len(rows))}
+	for i := range rows {
+		if i == filter.Limit {
+			page.NextCursor = order.encodeCursor(rows[i-1].SortKey, rows[i-1].Id)
+			break
+		}
+
+		page.Items = append(page.Items, &rows[i].MenuItem)
+	}
+
+	return page, nil
 }
 
 func (r *MenuItemPg) GetById(menuItemId int) (*domain.MenuItem, error) {
@@ -45,13 +83,13 @@ func (r *MenuItemPg) GetById(menuItemId int) (*domain.MenuItem, error) {
 	err := row.Scan(&menuItem.Id, &menuItem.RestaurantId, &menuItem.Title, &menuItem.Image,
 		&menuItem.Description, &menuItem.Price)
 
-	return menuItem, err
+	return menuItem, pgError(err)
 }
 
 func (r *MenuItemPg) UpdateMenuItem(restaurantId int, menuItemId int, categoryId int, input *domain.MenuItem) error {
 	tx, err := r.db.Begin()
 	if err != nil {
-		return err
+		return pgError(err)
 	}
 
 	setValues := make([]string, 0)
@@ -64,7 +102,7 @@ func (r *MenuItemPg) UpdateMenuItem(restaurantId int, menuItemId int, categoryId
 	err = row.Scan(&id)
 	if err == sql.ErrNoRows {
 		_ = tx.Rollback()
-		return errors.New("menu item does not belong to this restaurant")
+		return domain.NewNotFoundError("no such menu item for this restaurant")
 	}
 
 	if categoryId != 0 {
@@ -74,20 +112,20 @@ func (r *MenuItemPg) UpdateMenuItem(restaurantId int, menuItemId int, categoryId
 		err := row.Scan(&id)
 		if err == sql.ErrNoRows {
 			_ = tx.Rollback()
-			return errors.New("category does not belong to this restaurant")
+			return domain.NewNotFoundError("no such category for this restaurant")
 		}
 
 		query = fmt.Sprintf(`DELETE FROM %s WHERE menu_item_id = $1`, categoryItemsTable)
 		_, err = r.db.Exec(query, menuItemId)
 		if err != nil {
 			_ = tx.Rollback()
-			return err
+			return pgError(err)
 		}
 		query = fmt.Sprintf(`INSERT INTO %s (category_id, menu_item_id) VALUES($1, $2)`, categoryItemsTable)
 		_, err = r.db.Exec(query, categoryId, menuItemId)
 		if err != nil {
 			_ = tx.Rollback()
-			return err
+			return pgError(err)
 		}
 	}
 
@@ -122,7 +160,7 @@ func (r *MenuItemPg) UpdateMenuItem(restaurantId int, menuItemId int, categoryId
 
 	if _, err = tx.Exec(query, args...); err != nil {
 		_ = tx.Rollback()
-		return err
+		return pgError(err)
 	}
 
 	return tx.Commit()
@@ -131,7 +169,7 @@ func (r *MenuItemPg) UpdateMenuItem(restaurantId int, menuItemId int, categoryId
 func (r *MenuItemPg) Create(menuItem *domain.MenuItem, categoryId int) (int, error) {
 	tx, err := r.db.Begin()
 	if err != nil {
-		return 0, err
+		return 0, pgError(err)
 	}
 
 	var menuItemId int
@@ -144,7 +182,7 @@ func (r *MenuItemPg) Create(menuItem *domain.MenuItem, categoryId int) (int, err
 	err = row.Scan(&menuItemId)
 	if err != nil {
 		_ = tx.Rollback()
-		return 0, err
+		return 0, pgError(err)
 	}
 
 	var categoryItemsId int
@@ -155,7 +193,7 @@ func (r *MenuItemPg) Create(menuItem *domain.MenuItem, categoryId int) (int, err
 	err = row.Scan(&categoryItemsId)
 	if err != nil {
 		_ = tx.Rollback()
-		return 0, err
+		return 0, pgError(err)
 	}
 
 	return menuItemId, tx.Commit()
@@ -164,27 +202,27 @@ func (r *MenuItemPg) Create(menuItem *domain.MenuItem, categoryId int) (int, err
 func (r *MenuItemPg) UpdateImage(menuItemId int, image string) error {
 	query := fmt.Sprintf(`UPDATE %s AS r SET image = $1 WHERE r.id = $2`, menuItemsTable)
 	_, err := r.db.Exec(query, image, menuItemId)
-	return err
+	return pgError(err)
 }
 
 func (r *MenuItemPg) DeleteItem(menuItemId int) error {
 	tx, err := r.db.Begin()
 	if err != nil {
-		return err
+		return pgError(err)
 	}
 
 	query := fmt.Sprintf(`DELETE FROM %s WHERE menu_item_id = $1`, categoryItemsTable)
 	_, err = r.db.Exec(query, menuItemId)
 	if err != nil {
 		_ = tx.Rollback()
-		return err
+		return pgError(err)
 	}
 
 	query = fmt.Sprintf(`DELETE FROM %s WHERE id = $1`, menuItemsTable)
 	_, err = r.db.Exec(query, menuItemId)
 	if err != nil {
 		_ = tx.Rollback()
-		return err
+		return pgError(err)
 	}
 
 	return tx.Commit()
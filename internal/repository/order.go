@@ -1,12 +1,16 @@
 package repository
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/MAVIKE/yad-backend/internal/consts"
 	"github.com/MAVIKE/yad-backend/internal/domain"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type OrderPg struct {
@@ -19,27 +23,64 @@ func NewOrderPg(db *sqlx.DB) *OrderPg {
 	}
 }
 
-func (r *OrderPg) Create(order *domain.Order) (int, error) {
+// Create inserts the order, records the event and writes the outbox events
+// in one transaction. The outbox events get the id of the new order.
+func (r *OrderPg) Create(order *domain.Order, event *domain.OrderEvent, outbox ...*domain.Event) (int, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return 0, pgError(err)
+	}
+
+	orderId, err := createOrder(tx, order, event, outbox)
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, pgError(err)
+	}
+
+	return orderId, tx.Commit()
+}
+
+func createOrder(tx *sqlx.Tx, order *domain.Order, event *domain.OrderEvent, outbox []*domain.Event) (int, error) {
 	var orderId int
 
 	query := fmt.Sprintf(
 		`INSERT INTO %s (user_id, restaurant_id, delivery_price, total_price, status)
 		VALUES ($1, $2, $3, $4, $5) RETURNING id`, ordersTable)
 
-	row := r.db.QueryRow(query, order.UserId, order.RestaurantId, order.DeliveryPrice,
+	row := tx.QueryRow(query, order.UserId, order.RestaurantId, order.DeliveryPrice,
 		order.TotalPrice, order.Status)
-	err := row.Scan(&orderId)
+	if err := row.Scan(&orderId); err != nil {
+		return 0, err
+	}
+
+	event.OrderId = orderId
+	if err := createOrderEvent(tx, event); err != nil {
+		return 0, err
+	}
+
+	for _, e := range outbox {
+		e.OrderId = orderId
+	}
+
+	if err := writeOutbox(tx, outbox); err != nil {
+		return 0, err
+	}
 
-	return orderId, err
+	return orderId, nil
 }
 
+// orderItemColumns read an order item from its snapshot. The menu item is
+// zero once the dish is deleted from the menu.
+const orderItemColumns = `oi.id, oi.order_id, COALESCE(oi.menu_item_id, 0) AS menu_item_id, oi.count, oi.title, oi.price`
+
 func (r *OrderPg) GetAllItems(orderId int) ([]*domain.OrderItem, error) {
 	var items []*domain.OrderItem
 
-	query := fmt.Sprintf(`SELECT * FROM %s AS oi WHERE oi.order_id = $1`, orderItemsTable)
+	query := fmt.Sprintf(`SELECT %s FROM %s AS oi WHERE oi.order_id = $1 ORDER BY oi.id`,
+		orderItemColumns, orderItemsTable)
 	err := r.db.Select(&items, query, orderId)
 
-	return items, err
+	return items, pgError(err)
 }
 
 func (r *OrderPg) GetById(orderId int) (*domain.Order, error) {
@@ -47,20 +88,24 @@ func (r *OrderPg) GetById(orderId int) (*domain.Order, error) {
 
 	query := fmt.Sprintf(
 		`SELECT id, user_id, restaurant_id, COALESCE(courier_id, 0) AS courier_id,
-			delivery_price, total_price, status, paid 
+			delivery_price, total_price, status, paid, discount, created_at
 		FROM %s WHERE id = $1`, ordersTable)
 	err := r.db.Get(order, query, orderId)
 
-	return order, err
+	return order, pgError(err)
 }
 
 func (r *OrderPg) Delete(orderId int) error {
 	query := fmt.Sprintf(`DELETE FROM %s WHERE id = $1`, ordersTable)
 	_, err := r.db.Exec(query, orderId)
-	return err
+	return pgError(err)
 }
 
-func (r *OrderPg) Update(orderId int, input *domain.Order) error {
+// Update changes the order, records the event and writes the outbox events
+// in one transaction. The order is only changed while it is still in
+// event.OldStatus, so two concurrent transitions from the same status
+// can't both succeed.
+func (r *OrderPg) Update(orderId int, input *domain.Order, event *domain.OrderEvent, outbox ...*domain.Event) error {
 	setValues := make([]string, 0)
 	args := make([]interface{}, 0)
 	argId := 1
@@ -83,97 +128,308 @@ func (r *OrderPg) Update(orderId int, input *domain.Order) error {
 		argId++
 	}
 
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return pgError(err)
+	}
+
 	setQuery := strings.Join(setValues, ", ")
-	query := fmt.Sprintf(`UPDATE %s SET %s WHERE id=$%d`,
-		ordersTable, setQuery, argId)
-	args = append(args, orderId)
-	_, err := r.db.Exec(query, args...)
+	query := fmt.Sprintf(`UPDATE %s SET %s WHERE id=$%d AND status=$%d`,
+		ordersTable, setQuery, argId, argId+1)
+	args = append(args, orderId, event.OldStatus)
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		_ = tx.Rollback()
+		return pgError(err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		_ = tx.Rollback()
+		return pgError(err)
+	}
+
+	if rows == 0 {
+		_ = tx.Rollback()
+		return domain.NewConflictError("order status has changed")
+	}
+
+	event.OrderId = orderId
+	if err := createOrderEvent(tx, event); err != nil {
+		_ = tx.Rollback()
+		return pgError(err)
+	}
+
+	if err := writeOutbox(tx, outbox); err != nil {
+		_ = tx.Rollback()
+		return pgError(err)
+	}
 
-	return err
+	return tx.Commit()
 }
 
-func (r *OrderPg) GetActiveRestaurantOrders(restaurantId int) ([]*domain.Order, error) {
-	var orders []*domain.Order
+// MarkPaid moves the new order to paid and queues it for dispatch in one
+// transaction. The order is only paid while its total is still the captured
+// amount, so an order changed since the payment was checked is a conflict.
+func (r *OrderPg) MarkPaid(orderId, amount int, paid time.Time, event *domain.OrderEvent,
+	outbox ...*domain.Event) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return pgError(err)
+	}
+
+	query := fmt.Sprintf(
+		`UPDATE %s SET status = $1, paid = $2 WHERE id = $3 AND status = $4 AND total_price = $5`, ordersTable)
+	result, err := tx.Exec(query, consts.OrderPaid, paid, orderId, consts.OrderCreated, amount)
+	if err != nil {
+		_ = tx.Rollback()
+		return pgError(err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		_ = tx.Rollback()
+		return pgError(err)
+	}
+
+	if rows == 0 {
+		_ = tx.Rollback()
+		return domain.NewConflictError("order has changed")
+	}
+
+	event.OrderId = orderId
+	if err := createOrderEvent(tx, event); err != nil {
+		_ = tx.Rollback()
+		return pgError(err)
+	}
 
+	if err := writeOutbox(tx, outbox); err != nil {
+		_ = tx.Rollback()
+		return pgError(err)
+	}
+
+	query = fmt.Sprintf(
+		`INSERT INTO %s (order_id) VALUES ($1) ON CONFLICT (order_id) DO NOTHING`, dispatchQueueTable)
+	if _, err := tx.Exec(query, orderId); err != nil {
+		_ = tx.Rollback()
+		return pgError(err)
+	}
+
+	return pgError(tx.Commit())
+}
+
+func (r *OrderPg) GetEvents(orderId int) ([]*domain.OrderEvent, error) {
+	var events []*domain.OrderEvent
+
+	query := fmt.Sprintf(`SELECT * FROM %s WHERE order_id = $1 ORDER BY created_at, id`, orderEventsTable)
+	err := r.db.Select(&events, query, orderId)
+
+	return events, pgError(err)
+}
+
+func createOrderEvent(tx *sqlx.Tx, event *domain.OrderEvent) error {
 	query := fmt.Sprintf(
-		`SELECT * FROM %s 
-		WHERE restaurant_id = $1 AND status = %d`, ordersTable, consts.OrderPaid)
-	err := r.db.Select(&orders, query, restaurantId)
+		`INSERT INTO %s (order_id, actor_id, actor_type, old_status, new_status)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`, orderEventsTable)
+
+	row := tx.QueryRow(query, event.OrderId, event.ActorId, event.ActorType, event.OldStatus, event.NewStatus)
+	return row.Scan(&event.Id, &event.CreatedAt)
+}
 
-	return orders, err
+// orderSorts are the orders of the lists of orders, the newest first by
+// default.
+var orderSorts = listSorts{
+	defaultSort: "-created_at",
+	columns:     map[string]string{"created_at": "timestamptz", "total_price": "int"},
 }
 
+// GetAll returns a page of the orders of the user or of the restaurant of
+// the filter.
+func (r *OrderPg) GetAll(filter *domain.OrderFilter) (*domain.OrderPage, error) {
+	var rows []struct {
+		domain.Order
+		SortKey string `db:"sort_key"`
+	}
+
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+
+	if filter.UserId != 0 {
+		args = append(args, filter.UserId)
+		conditions = append(conditions, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+
+	if filter.RestaurantId != 0 {
+		args = append(args, filter.RestaurantId)
+		conditions = append(conditions, fmt.Sprintf("restaurant_id = $%d", len(args)))
+	}
+
+	if len(filter.Statuses) > 0 {
+		args = append(args, pq.Array(filter.Statuses))
+		conditions = append(conditions, fmt.Sprintf("status = ANY($%d)", len(args)))
+	}
+
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+
+	inner := fmt.Sprintf(
+		`SELECT id, user_id, restaurant_id, COALESCE(courier_id, 0) AS courier_id,
+			delivery_price, total_price, status, paid, discount, created_at
+		FROM %s %s`, ordersTable, whereClause(conditions))
+
+	query, args, order, err := pageQuery(`*`, inner, &filter.ListParams, orderSorts, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.db.Select(&rows, query, args...); err != nil {
+		return nil, pgError(err)
+	}
+
+	page := &domain.OrderPage{Items: make([]*domain.Order, 0, len(rows))}
+	for i := range rows {
+		if i == filter.Limit {
+			page.NextCursor = order.encodeCursor(rows[i-1].SortKey, rows[i-1].Id)
+			break
+		}
+
+		page.Items = append(page.Items, &rows[i].Order)
+	}
+
+	return page, nil
+}
+
+// errOrderNotEditable is returned when the order has been paid meanwhile.
+var errOrderNotEditable = domain.NewOrderNotEditableError("Order is no longer editable, its items can only be changed before it is paid")
+
+// orderEditable is the condition of the item writes: the items can only be
+// changed until the order is paid, checked by the write itself, so a
+// payment made meanwhile can't be followed by a change.
+var orderEditable = fmt.Sprintf(`EXISTS (SELECT 1 FROM %s AS o WHERE o.id = $1 AND o.status = %d)`,
+	ordersTable, consts.OrderCreated)
+
+// CreateItem adds the menu item to the order with a snapshot of its current
+// title and price.
 func (r *OrderPg) CreateItem(orderItem *domain.OrderItem) (int, error) {
 	var orderItemId int
 
 	query := fmt.Sprintf(
-		`INSERT INTO %s (order_id, menu_item_id, count)
-		VALUES ($1, $2, $3) RETURNING id`, orderItemsTable)
+		`INSERT INTO %s (order_id, menu_item_id, count, title, price)
+		SELECT $1, mi.id, $3, mi.title, mi.price FROM %s AS mi WHERE mi.id = $2 AND %s
+		RETURNING id`, orderItemsTable, menuItemsTable, orderEditable)
 
 	row := r.db.QueryRow(query, orderItem.OrderId, orderItem.MenuItemId, orderItem.Count)
-	err := row.Scan(&orderItemId)
+	if err := row.Scan(&orderItemId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, errOrderNotEditable
+		}
+		return 0, pgError(err)
+	}
 
-	return orderItemId, err
+	return orderItemId, nil
 }
 
 func (r *OrderPg) GetItemById(orderItemId int) (*domain.OrderItem, error) {
 	item := new(domain.OrderItem)
 
-	query := fmt.Sprintf(`SELECT * FROM %s AS i WHERE i.id = $1`, orderItemsTable)
+	query := fmt.Sprintf(`SELECT %s FROM %s AS oi WHERE oi.id = $1`, orderItemColumns, orderItemsTable)
 	err := r.db.Get(item, query, orderItemId)
 
-	return item, err
+	return item, pgError(err)
 }
 
 func (r *OrderPg) DeleteItem(orderId int, orderItemId int) error {
-	query := fmt.Sprintf(`DELETE FROM %s AS i WHERE i.order_id = $1 AND i.id = $2`, orderItemsTable)
-	_, err := r.db.Exec(query, orderId, orderItemId)
+	query := fmt.Sprintf(`DELETE FROM %s AS i WHERE i.order_id = $1 AND i.id = $2 AND %s`,
+		orderItemsTable, orderEditable)
+	result, err := r.db.Exec(query, orderId, orderItemId)
+
+	return itemWritten(result, err)
+}
 
-	return err
+func (r *OrderPg) UpdateItem(orderId, orderItemId, menuItemsCount int) error {
+	query := fmt.Sprintf(`UPDATE %s SET count = $3 WHERE order_id = $1 AND id = $2 AND %s`,
+		orderItemsTable, orderEditable)
+	result, err := r.db.Exec(query, orderId, orderItemId, menuItemsCount)
+
+	return itemWritten(result, err)
 }
 
-func (r *OrderPg) UpdateItem(orderItemId, menuItemsCount int) error {
-	query := fmt.Sprintf(`UPDATE %s SET count = $1 WHERE id = $2`, orderItemsTable)
-	_, err := r.db.Exec(query, menuItemsCount, orderItemId)
+// itemWritten maps a conditional item write that has changed nothing to
+// errOrderNotEditable, the item itself having been checked before.
+func itemWritten(result sql.Result, err error) error {
+	if err != nil {
+		return pgError(err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return pgError(err)
+	}
+
+	if rows == 0 {
+		return errOrderNotEditable
+	}
 
-	return err
+	return nil
 }
 
 func (r *OrderPg) GetActiveCourierOrder(courierId int) (*domain.Order, error) {
 	order := new(domain.Order)
 
 	query := fmt.Sprintf(`SELECT * FROM %s AS o 
-						WHERE o.status = $1 OR o.status = $2 OR o.status = $3 OR o.status = $4 AND o.courier_id = $5`, ordersTable)
+						WHERE o.status IN ($1, $2, $3, $4) AND o.courier_id = $5`, ordersTable)
 	row := r.db.QueryRow(query, consts.OrderPaid, consts.OrderPreparing, consts.OrderWaitingForCourier, consts.OrderEnRoute, courierId)
-	err := row.Scan(&order.Id, &order.UserId, &order.RestaurantId, &order.CourierId, &order.DeliveryPrice, &order.TotalPrice, &order.Status, &order.Paid)
+	err := row.Scan(&order.Id, &order.UserId, &order.RestaurantId, &order.CourierId, &order.DeliveryPrice, &order.TotalPrice, &order.Status, &order.Paid, &order.Discount, &order.CreatedAt)
 
-	return order, err
+	return order, pgError(err)
 }
 
-// TODO: стоит вынести в репозиторий курьера
-func (r *OrderPg) GetNearestCourierId(userId int) (int, error) {
-	var courierId int
+// GetDeliveryDistance returns the distance in kilometres between the user
+// address and the restaurant address.
+func (r *OrderPg) GetDeliveryDistance(userId, restaurantId int) (float64, error) {
+	var distance float64
 
 	query := fmt.Sprintf(
-		`SELECT tmp.id FROM
-		(
-			SELECT c.id, get_distance(l.latitude, l.longitude, ua.latitude, ua.longitude) AS distance
-			FROM %s AS c
-				INNER JOIN %s AS l ON c.address_id = l.id,
-				(
-					SELECT ul.latitude, ul.longitude
-					FROM %s AS u
-						INNER JOIN %s AS ul ON u.address_id = ul.id
-					WHERE u.id = $1
-				) AS ua
-			WHERE c.working_status = $2
-			ORDER BY distance
-		) AS tmp
-		LIMIT 1`,
-		couriersTable, locationsTable, usersTable, locationsTable)
-
-	row := r.db.QueryRow(query, userId, consts.CourierWaiting)
-	err := row.Scan(&courierId)
-
-	return courierId, err
+		`SELECT get_distance(ul.latitude, ul.longitude, rl.latitude, rl.longitude)
+		FROM %s AS u
+			INNER JOIN %s AS ul ON u.address_id = ul.id,
+			%s AS r
+			INNER JOIN %s AS rl ON r.address_id = rl.id
+		WHERE u.id = $1 AND r.id = $2`,
+		usersTable, locationsTable, restaurantsTable, locationsTable)
+
+	row := r.db.QueryRow(query, userId, restaurantId)
+	err := row.Scan(&distance)
+
+	return distance, pgError(err)
+}
+
+// UpdateDeliveryPrice changes the delivery price of a new order. An order
+// paid meanwhile keeps the price the user has paid.
+func (r *OrderPg) UpdateDeliveryPrice(orderId, deliveryPrice int) error {
+	query := fmt.Sprintf(
+		`UPDATE %s SET delivery_price = $1, total_price = total_price - delivery_price + $1
+		WHERE id = $2 AND status = $3`, ordersTable)
+	_, err := r.db.Exec(query, deliveryPrice, orderId, consts.OrderCreated)
+
+	return pgError(err)
+}
+
+func (r *OrderPg) GetUnpaidUserOrders(userId int) ([]*domain.Order, error) {
+	var orders []*domain.Order
+
+	query := fmt.Sprintf(
+		`SELECT id, user_id, restaurant_id, COALESCE(courier_id, 0) AS courier_id,
+			delivery_price, total_price, status, paid, discount, created_at
+		FROM %s WHERE user_id = $1 AND status = $2`, ordersTable)
+	err := r.db.Select(&orders, query, userId, consts.OrderCreated)
+
+	return orders, pgError(err)
 }
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+type OutboxPg struct {
+	db *sqlx.DB
+}
+
+func NewOutboxPg(db *sqlx.DB) *OutboxPg {
+	return &OutboxPg{
+		db: db,
+	}
+}
+
+// writeOutbox adds the events to the outbox within the transaction of the
+// change they describe, so they are stored if and only if it is committed.
+// Every instance listening for events is notified of them on commit too.
+func writeOutbox(tx *sqlx.Tx, events []*domain.Event) error {
+	query := fmt.Sprintf(
+		`INSERT INTO %s (event_id, event_type, payload) VALUES ($1, $2, $3)`, outboxTable)
+
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(query, event.Id, event.Type, string(payload)); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(`SELECT pg_notify($1, $2)`, eventsChannel, string(payload)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Claim returns up to limit unpublished messages that are due, the oldest
+// first, and postpones them by lease. A message that isn't published or
+// failed within the lease, e.g. because the relay has crashed, is claimed
+// again. Concurrent relays never claim the same message at once.
+func (r *OutboxPg) Claim(limit int, lease time.Duration) ([]*domain.OutboxMessage, error) {
+	var messages []*domain.OutboxMessage
+
+	query := fmt.Sprintf(
+		`UPDATE %[1]s SET next_attempt_at = $1
+		WHERE id IN (
+			SELECT id FROM %[1]s
+			WHERE published_at IS NULL AND next_attempt_at <= now()
+			ORDER BY id LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, outboxTable)
+	err := r.db.Select(&messages, query, time.Now().Add(lease), limit)
+
+	return messages, pgError(err)
+}
+
+func (r *OutboxPg) MarkPublished(messageId int64) error {
+	query := fmt.Sprintf(`UPDATE %s SET published_at = now() WHERE id = $1`, outboxTable)
+	_, err := r.db.Exec(query, messageId)
+	return pgError(err)
+}
+
+func (r *OutboxPg) MarkFailed(messageId int64, lastError string, nextAttemptAt time.Time) error {
+	query := fmt.Sprintf(
+		`UPDATE %s SET attempts = attempts + 1, last_error = $1, next_attempt_at = $2
+		WHERE id = $3`, outboxTable)
+	_, err := r.db.Exec(query, lastError, nextAttemptAt, messageId)
+	return pgError(err)
+}
+
+// DeletePublished removes the messages published before the given time.
+func (r *OutboxPg) DeletePublished(before time.Time) error {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE published_at < $1`, outboxTable)
+	_, err := r.db.Exec(query, before)
+	return pgError(err)
+}
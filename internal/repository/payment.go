@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/MAVIKE/yad-backend/internal/consts"
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+type PaymentPg struct {
+	db *sqlx.DB
+}
+
+func NewPaymentPg(db *sqlx.DB) *PaymentPg {
+	return &PaymentPg{
+		db: db,
+	}
+}
+
+func (r *PaymentPg) Create(payment *domain.Payment) (int, error) {
+	var paymentId int
+
+	query := fmt.Sprintf(
+		`INSERT INTO %s (order_id, provider, intent_id, amount, status)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id`, paymentsTable)
+
+	row := r.db.QueryRow(query, payment.OrderId, payment.Provider, payment.IntentId,
+		payment.Amount, payment.Status)
+	err := row.Scan(&paymentId)
+
+	return paymentId, pgError(err)
+}
+
+// GetLatest returns the last payment created for the order.
+func (r *PaymentPg) GetById(paymentId int) (*domain.Payment, error) {
+	payment := new(domain.Payment)
+
+	query := fmt.Sprintf(`SELECT * FROM %s WHERE id = $1`, paymentsTable)
+	err := r.db.Get(payment, query, paymentId)
+
+	return payment, pgError(err)
+}
+
+func (r *PaymentPg) GetLatest(orderId int) (*domain.Payment, error) {
+	payment := new(domain.Payment)
+
+	query := fmt.Sprintf(`SELECT * FROM %s WHERE order_id = $1 ORDER BY id DESC LIMIT 1`, paymentsTable)
+	err := r.db.Get(payment, query, orderId)
+
+	return payment, pgError(err)
+}
+
+func (r *PaymentPg) GetByIntentId(provider, intentId string) (*domain.Payment, error) {
+	payment := new(domain.Payment)
+
+	query := fmt.Sprintf(`SELECT * FROM %s WHERE provider = $1 AND intent_id = $2`, paymentsTable)
+	err := r.db.Get(payment, query, provider, intentId)
+
+	return payment, pgError(err)
+}
+
+// GetOpen returns the payments of the order that wait for the payer or hold
+// the money.
+func (r *PaymentPg) GetOpen(orderId int) ([]*domain.Payment, error) {
+	var payments []*domain.Payment
+
+	query := fmt.Sprintf(`SELECT * FROM %s WHERE order_id = $1 AND status IN ($2, $3) ORDER BY id`, paymentsTable)
+	err := r.db.Select(&payments, query, orderId, consts.PaymentPending, consts.PaymentAuthorized)
+
+	return payments, pgError(err)
+}
+
+func (r *PaymentPg) UpdateStatus(paymentId int, status string) error {
+	query := fmt.Sprintf(`UPDATE %s SET status = $1, updated_at = now() WHERE id = $2`, paymentsTable)
+	_, err := r.db.Exec(query, status, paymentId)
+	return pgError(err)
+}
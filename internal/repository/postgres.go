@@ -8,16 +8,35 @@ import (
 )
 
 const (
-	adminsTable        = "admins"
-	usersTable         = "users"
-	couriersTable      = "couriers"
-	locationsTable     = "locations"
-	restaurantsTable   = "restaurants"
-	categoriesTable    = "categories"
-	menuItemsTable     = "menu_items"
-	ordersTable        = "orders"
-	orderItemsTable    = "order_items"
-	categoryItemsTable = "category_items"
+	adminsTable            = "admins"
+	usersTable             = "users"
+	couriersTable          = "couriers"
+	locationsTable         = "locations"
+	restaurantsTable       = "restaurants"
+	categoriesTable        = "categories"
+	menuItemsTable         = "menu_items"
+	ordersTable            = "orders"
+	orderItemsTable        = "order_items"
+	orderEventsTable       = "order_events"
+	categoryItemsTable     = "category_items"
+	sessionsTable          = "sessions"
+	revokedTokensTable     = "revoked_tokens"
+	revokedClientsTable    = "revoked_clients"
+	eventTicketsTable      = "event_tickets"
+	dispatchQueueTable     = "dispatch_queue"
+	courierOffersTable     = "courier_offers"
+	courierLocationsTable  = "courier_locations"
+	webhooksTable          = "webhooks"
+	webhookDeliveriesTable = "webhook_deliveries"
+	outboxTable            = "outbox"
+	paymentsTable          = "payments"
+	refundsTable           = "refunds"
+	cartsTable             = "carts"
+	cartItemsTable         = "cart_items"
+	openingHoursTable      = "restaurant_hours"
+	holidaysTable          = "restaurant_holidays"
+	zonePointsTable        = "delivery_zone_points"
+	reviewsTable           = "reviews"
 )
 
 type Config struct {
@@ -29,9 +48,13 @@ type Config struct {
 	SSLMode  string
 }
 
+func (cfg Config) dataSource() string {
+	return fmt.Sprintf("host=%s port=%s user=%s dbname=%s password=%s sslmode=%s",
+		cfg.Host, cfg.Port, cfg.Username, cfg.DBName, cfg.Password, cfg.SSLMode)
+}
+
 func NewPostgresDB(cfg Config) (*sqlx.DB, error) {
-	db, err := sqlx.Open("postgres", fmt.Sprintf("host=%s port=%s user=%s dbname=%s password=%s sslmode=%s",
-		cfg.Host, cfg.Port, cfg.Username, cfg.DBName, cfg.Password, cfg.SSLMode))
+	db, err := sqlx.Open("postgres", cfg.dataSource())
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,265 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/MAVIKE/yad-backend/internal/consts"
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+// refundsItemIndex keeps one refund of an item that hasn't failed.
+const refundsItemIndex = "refunds_item_idx"
+
+type RefundPg struct {
+	db *sqlx.DB
+}
+
+func NewRefundPg(db *sqlx.DB) *RefundPg {
+	return &RefundPg{
+		db: db,
+	}
+}
+
+// Create records a pending refund. The payment is locked while the refunds
+// are summed, so concurrent refunds can't return more than was captured.
+// An item is refunded once: another refund of it is a conflict until the
+// first one fails.
+func (r *RefundPg) Create(refund *domain.Refund) (int, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return 0, pgError(err)
+	}
+
+	left, err := refundableAmount(tx, refund.PaymentId)
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, pgError(err)
+	}
+
+	if refund.Amount > left {
+		_ = tx.Rollback()
+		return 0, domain.NewConflictError("Refund exceeds the paid amount, %d is left", left)
+	}
+
+	refundId, err := createRefund(tx, refund)
+	if err != nil {
+		_ = tx.Rollback()
+		if isUniqueViolation(err, refundsItemIndex) {
+			return 0, domain.NewConflictError("Item is already being refunded")
+		}
+		return 0, pgError(err)
+	}
+
+	return refundId, pgError(tx.Commit())
+}
+
+// CreateForOrder moves the order to the status of the event and records a
+// pending refund of what is left of the payment in one transaction, so an
+// order is never cancelled without its money being on the way back. It
+// returns 0 when nothing is left to refund.
+func (r *RefundPg) CreateForOrder(refund *domain.Refund, event *domain.OrderEvent, outbox ...*domain.Event) (int, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return 0, pgError(err)
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET status = $1 WHERE id = $2 AND status = $3`, ordersTable)
+	result, err := tx.Exec(query, event.NewStatus, refund.OrderId, event.OldStatus)
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, pgError(err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, pgError(err)
+	}
+
+	if rows == 0 {
+		_ = tx.Rollback()
+		return 0, domain.NewConflictError("order status has changed")
+	}
+
+	event.OrderId = refund.OrderId
+	if err := createOrderEvent(tx, event); err != nil {
+		_ = tx.Rollback()
+		return 0, pgError(err)
+	}
+
+	if err := writeOutbox(tx, outbox); err != nil {
+		_ = tx.Rollback()
+		return 0, pgError(err)
+	}
+
+	refund.Amount, err = refundableAmount(tx, refund.PaymentId)
+	if err != nil {
+		_ = tx.Rollback()
+		return 0, pgError(err)
+	}
+
+	var refundId int
+	if refund.Amount > 0 {
+		refundId, err = createRefund(tx, refund)
+		if err != nil {
+			_ = tx.Rollback()
+			return 0, pgError(err)
+		}
+	}
+
+	return refundId, pgError(tx.Commit())
+}
+
+// refundableAmount locks the payment and returns what is left of it.
+func refundableAmount(tx *sqlx.Tx, paymentId int) (int, error) {
+	var captured int
+
+	query := fmt.Sprintf(`SELECT amount FROM %s WHERE id = $1 FOR UPDATE`, paymentsTable)
+	if err := tx.Get(&captured, query, paymentId); err != nil {
+		return 0, err
+	}
+
+	refunded, err := refundedAmount(tx, paymentId)
+	if err != nil {
+		return 0, err
+	}
+
+	return captured - refunded, nil
+}
+
+func createRefund(tx *sqlx.Tx, refund *domain.Refund) (int, error) {
+	var refundId int
+
+	query := fmt.Sprintf(
+		`INSERT INTO %s (order_id, payment_id, kind, amount, reason, order_item_id, status, next_attempt_at,
+			actor_id, actor_type)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`, refundsTable)
+
+	row := tx.QueryRow(query, refund.OrderId, refund.PaymentId, refund.Kind, refund.Amount, refund.Reason,
+		refund.OrderItemId, consts.RefundPending, refund.NextAttemptAt, refund.ActorId, refund.ActorType)
+	err := row.Scan(&refundId)
+
+	return refundId, err
+}
+
+// ClaimPending returns up to limit pending refunds that are due, the oldest
+// first, and postpones them by lease, so every attempt is made by one
+// instance.
+func (r *RefundPg) ClaimPending(limit int, lease time.Duration) ([]*domain.Refund, error) {
+	var refunds []*domain.Refund
+
+	query := fmt.Sprintf(
+		`UPDATE %[1]s SET next_attempt_at = $1
+		WHERE id IN (
+			SELECT id FROM %[1]s
+			WHERE status = $2 AND next_attempt_at <= now()
+			ORDER BY id LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, refundsTable)
+	err := r.db.Select(&refunds, query, time.Now().Add(lease), consts.RefundPending, limit)
+
+	return refunds, pgError(err)
+}
+
+func (r *RefundPg) GetAll(orderId int) ([]*domain.Refund, error) {
+	var refunds []*domain.Refund
+
+	query := fmt.Sprintf(`SELECT * FROM %s WHERE order_id = $1 ORDER BY id`, refundsTable)
+	err := r.db.Select(&refunds, query, orderId)
+
+	return refunds, pgError(err)
+}
+
+// GetRefundedAmount returns the amount of the payment that is refunded
+// or being refunded.
+func (r *RefundPg) GetRefundedAmount(paymentId int) (int, error) {
+	amount, err := refundedAmount(r.db, paymentId)
+	return amount, pgError(err)
+}
+
+func refundedAmount(q sqlx.Queryer, paymentId int) (int, error) {
+	var amount int
+
+	query := fmt.Sprintf(
+		`SELECT COALESCE(SUM(amount), 0) FROM %s WHERE payment_id = $1 AND status <> $2`, refundsTable)
+	err := sqlx.Get(q, &amount, query, paymentId, consts.RefundFailed)
+
+	return amount, err
+}
+
+// Complete marks the refund succeeded and takes it off the order total in
+// one transaction. A removed item is deleted, which reprices the order;
+// other refunds are added to the order discount. The payment is refunded
+// once nothing of it is left. A refund that is no longer pending is left
+// as it is.
+func (r *RefundPg) Complete(refund *domain.Refund, providerRefundId string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return pgError(err)
+	}
+
+	query := fmt.Sprintf(
+		`UPDATE %s SET status = $1, provider_refund_id = $2, updated_at = now() WHERE id = $3 AND status = $4`,
+		refundsTable)
+	result, err := tx.Exec(query, consts.RefundSucceeded, providerRefundId, refund.Id, consts.RefundPending)
+	if err != nil {
+		_ = tx.Rollback()
+		return pgError(err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		_ = tx.Rollback()
+		return pgError(err)
+	}
+
+	if rows == 0 {
+		_ = tx.Rollback()
+		return nil
+	}
+
+	if refund.OrderItemId != nil {
+		query = fmt.Sprintf(`DELETE FROM %s WHERE id = $1 AND order_id = $2`, orderItemsTable)
+		_, err = tx.Exec(query, *refund.OrderItemId, refund.OrderId)
+	} else {
+		query = fmt.Sprintf(
+			`UPDATE %s SET discount = discount + $1, total_price = total_price - $1 WHERE id = $2`, ordersTable)
+		_, err = tx.Exec(query, refund.Amount, refund.OrderId)
+	}
+	if err != nil {
+		_ = tx.Rollback()
+		return pgError(err)
+	}
+
+	query = fmt.Sprintf(
+		`UPDATE %s SET status = $1, updated_at = now()
+		WHERE id = $2 AND amount = (SELECT SUM(amount) FROM %s WHERE payment_id = $2 AND status = $3)`,
+		paymentsTable, refundsTable)
+	if _, err := tx.Exec(query, consts.PaymentRefunded, refund.PaymentId, consts.RefundSucceeded); err != nil {
+		_ = tx.Rollback()
+		return pgError(err)
+	}
+
+	return pgError(tx.Commit())
+}
+
+// Retry records a failed attempt of a pending refund and when to make the
+// next one.
+func (r *RefundPg) Retry(refundId int, lastError string, nextAttemptAt time.Time) error {
+	query := fmt.Sprintf(
+		`UPDATE %s SET attempts = attempts + 1, last_error = $1, next_attempt_at = $2, updated_at = now()
+		WHERE id = $3 AND status = $4`, refundsTable)
+	_, err := r.db.Exec(query, lastError, nextAttemptAt, refundId, consts.RefundPending)
+	return pgError(err)
+}
+
+func (r *RefundPg) Fail(refundId int, lastError string) error {
+	query := fmt.Sprintf(
+		`UPDATE %s SET status = $1, attempts = attempts + 1, last_error = $2, updated_at = now()
+		WHERE id = $3 AND status = $4`, refundsTable)
+	_, err := r.db.Exec(query, consts.RefundFailed, lastError, refundId, consts.RefundPending)
+	return pgError(err)
+}
@@ -1,17 +1,22 @@
 package repository
 
 import (
+	"context"
+	"time"
+
 	"github.com/MAVIKE/yad-backend/internal/domain"
 	"github.com/jmoiron/sqlx"
 )
 
 type Admin interface {
-	GetByCredentials(name, password string) (*domain.Admin, error)
+	GetByName(name string) (*domain.Admin, error)
+	UpdatePassword(adminId int, passwordHash string) error
 }
 
 type User interface {
 	Create(user *domain.User) (int, error)
-	GetByCredentials(phone, password string) (*domain.User, error)
+	GetByPhone(phone string) (*domain.User, error)
+	UpdatePassword(userId int, passwordHash string) error
 	GetAllOrders(userId int, activeOrdersFlag bool) ([]*domain.Order, error)
 	Update(userId int, input *domain.User) error
 	GetById(userId int) (*domain.User, error)
@@ -19,19 +24,29 @@ type User interface {
 
 type Courier interface {
 	Create(courier *domain.Courier) (int, error)
-	GetByCredentials(phone, password string) (*domain.Courier, error)
+	GetByPhone(phone string) (*domain.Courier, error)
+	UpdatePassword(courierId int, passwordHash string) error
 	GetById(courierId int) (*domain.Courier, error)
-	Update(courierId int, input *domain.Courier) error
+	Update(courierId int, input *domain.Courier, outbox ...*domain.Event) error
 }
 
 type Restaurant interface {
-	GetByCredentials(phone, password string) (*domain.Restaurant, error)
-	GetAll(userId int) ([]*domain.Restaurant, error)
+	GetByPhone(phone string) (*domain.Restaurant, error)
+	UpdatePassword(restaurantId int, passwordHash string) error
+	GetAll(userId int, params *domain.ListParams) (*domain.RestaurantPage, error)
+	Search(userId int, filter *domain.SearchFilter) (*domain.SearchPage, error)
 	GetById(restaurantId int) (*domain.Restaurant, error)
-	GetMenu(restaurantId int) ([]*domain.MenuItem, error)
+	GetMenu(restaurantId int, filter *domain.MenuFilter) (*domain.MenuPage, error)
 	Create(restaurant *domain.Restaurant) (int, error)
 	UpdateImage(restaurantId int, image string) error
-	Update(restaurantId int, input *domain.Restaurant) error
+	Update(restaurantId int, input *domain.Restaurant, outbox ...*domain.Event) error
+	GetSchedules(restaurantIds []int) (map[int]*domain.Schedule, error)
+	UpdateSchedule(restaurantId int, schedule *domain.Schedule) error
+	GetScheduledIds() ([]int, error)
+	SetWorkingStatus(restaurantId, status int, outbox ...*domain.Event) error
+	GetDeliveryZone(restaurantId int) (*domain.DeliveryZone, error)
+	UpdateDeliveryZone(restaurantId int, zone *domain.DeliveryZone) error
+	DeliversTo(restaurantId, userId int) (bool, error)
 }
 
 type Category interface {
@@ -44,18 +59,22 @@ type Category interface {
 }
 
 type Order interface {
-	Create(order *domain.Order) (int, error)
+	Create(order *domain.Order, event *domain.OrderEvent, outbox ...*domain.Event) (int, error)
 	GetById(orderId int) (*domain.Order, error)
 	Delete(orderId int) error
-	Update(orderId int, input *domain.Order) error
-	GetActiveRestaurantOrders(restaurantId int) ([]*domain.Order, error)
+	Update(orderId int, input *domain.Order, event *domain.OrderEvent, outbox ...*domain.Event) error
+	MarkPaid(orderId, amount int, paid time.Time, event *domain.OrderEvent, outbox ...*domain.Event) error
+	GetEvents(orderId int) ([]*domain.OrderEvent, error)
+	GetAll(filter *domain.OrderFilter) (*domain.OrderPage, error)
 	CreateItem(orderItem *domain.OrderItem) (int, error)
 	GetAllItems(orderId int) ([]*domain.OrderItem, error)
 	GetItemById(orderItemId int) (*domain.OrderItem, error)
-	UpdateItem(orderItemId, menuItemsCount int) error
-	DeleteItem(orderItemId int, orderId int) error
+	UpdateItem(orderId, orderItemId, menuItemsCount int) error
+	DeleteItem(orderId, orderItemId int) error
 	GetActiveCourierOrder(courierId int) (*domain.Order, error)
-	GetNearestCourierId(userId int) (int, error)
+	GetDeliveryDistance(userId, restaurantId int) (float64, error)
+	UpdateDeliveryPrice(orderId, deliveryPrice int) error
+	GetUnpaidUserOrders(userId int) ([]*domain.Order, error)
 }
 
 type MenuItem interface {
@@ -66,6 +85,110 @@ type MenuItem interface {
 	DeleteItem(menuItemId int) error
 }
 
+type Session interface {
+	Create(session *domain.Session) (int, error)
+	GetByRefreshToken(refreshToken string) (*domain.Session, error)
+	GetById(sessionId int) (*domain.Session, error)
+	GetAll(clientId int, clientType string) ([]*domain.Session, error)
+	Rotate(sessionId int, oldRefreshToken, newRefreshToken string, expiresAt time.Time) error
+	Delete(sessionId int) error
+	DeleteAll(clientId int, clientType string) error
+}
+
+type Revocation interface {
+	RevokeToken(tokenId string, expiresAt time.Time) error
+	RevokeClient(clientId int, clientType string, revokedBefore time.Time) error
+	GetRevokedTokens() ([]*domain.RevokedToken, error)
+	GetRevokedClients() ([]*domain.RevokedClient, error)
+	DeleteExpired(clientsRevokedBefore time.Time) error
+}
+
+type EventListener interface {
+	Listen(ctx context.Context, handle func(event *domain.Event)) error
+}
+
+type EventTicket interface {
+	Create(ticket *domain.EventTicket) error
+	Take(ticketHash string) (*domain.EventTicket, error)
+}
+
+type Dispatch interface {
+	GetPending() ([]*domain.PendingOrder, error)
+	Dequeue(orderId int) error
+	Escalate(orderId int, outbox ...*domain.Event) error
+	GetNearestCourierId(orderId int, answeredAfter time.Time) (int, error)
+	CreateOffer(orderId, courierId int, expiresAt time.Time, outbox ...*domain.Event) (int, error)
+	GetOfferById(offerId int) (*domain.CourierOffer, error)
+	GetOffers(courierId int) ([]*domain.CourierOffer, error)
+	ExpireOffers() error
+	AcceptOffer(offerId int, outbox ...*domain.Event) error
+	DeclineOffer(offerId int) error
+}
+
+type Tracking interface {
+	AddLocation(courierId int, location *domain.Location, keepAfter time.Time) error
+	GetLatestLocation(courierId int) (*domain.CourierLocation, error)
+}
+
+type Webhook interface {
+	Create(webhook *domain.Webhook) (int, error)
+	GetById(webhookId int) (*domain.Webhook, error)
+	GetAll(restaurantId int) ([]*domain.Webhook, error)
+	GetSubscribed(restaurantId int, event string) ([]*domain.Webhook, error)
+	Delete(webhookId int) error
+	CreateDelivery(delivery *domain.WebhookDelivery) (int, error)
+	ClaimDueDeliveries(limit int, lease time.Duration) ([]*domain.WebhookDelivery, error)
+	GetDeliveries(webhookId int) ([]*domain.WebhookDelivery, error)
+	UpdateDelivery(delivery *domain.WebhookDelivery) error
+}
+
+type Outbox interface {
+	Claim(limit int, lease time.Duration) ([]*domain.OutboxMessage, error)
+	MarkPublished(messageId int64) error
+	MarkFailed(messageId int64, lastError string, nextAttemptAt time.Time) error
+	DeletePublished(before time.Time) error
+}
+
+type Payment interface {
+	Create(payment *domain.Payment) (int, error)
+	GetById(paymentId int) (*domain.Payment, error)
+	GetLatest(orderId int) (*domain.Payment, error)
+	GetByIntentId(provider, intentId string) (*domain.Payment, error)
+	GetOpen(orderId int) ([]*domain.Payment, error)
+	UpdateStatus(paymentId int, status string) error
+}
+
+type Refund interface {
+	Create(refund *domain.Refund) (int, error)
+	CreateForOrder(refund *domain.Refund, event *domain.OrderEvent, outbox ...*domain.Event) (int, error)
+	GetAll(orderId int) ([]*domain.Refund, error)
+	GetRefundedAmount(paymentId int) (int, error)
+	ClaimPending(limit int, lease time.Duration) ([]*domain.Refund, error)
+	Complete(refund *domain.Refund, providerRefundId string) error
+	Retry(refundId int, lastError string, nextAttemptAt time.Time) error
+	Fail(refundId int, lastError string) error
+}
+
+type Cart interface {
+	Get(userId int) (*domain.Cart, error)
+	GetItems(cartId int) ([]*domain.CartItem, error)
+	AddItem(userId, restaurantId int, item *domain.CartItem, replace bool) (int, error)
+	UpdateItem(cartId, cartItemId, count int) error
+	DeleteItem(cartId, cartItemId int) error
+	Delete(userId int) error
+	Checkout(cart *domain.Cart, order *domain.Order, event *domain.OrderEvent, outbox ...*domain.Event) (int, error)
+	DeleteExpired(before time.Time) (int64, error)
+}
+
+type Review interface {
+	Create(review *domain.Review) (int, error)
+	GetById(reviewId int) (*domain.Review, error)
+	GetByOrder(orderId int) ([]*domain.Review, error)
+	GetAll(filter *domain.ReviewFilter) (*domain.ReviewPage, error)
+	Reply(reviewId int, reply string) error
+	SetHidden(reviewId int, hidden bool) error
+}
+
 type Repository struct {
 	Admin
 	User
@@ -74,16 +197,38 @@ type Repository struct {
 	Category
 	Order
 	MenuItem
+	Session
+	Revocation
+	EventTicket
+	Dispatch
+	Tracking
+	Webhook
+	Outbox
+	Payment
+	Refund
+	Cart
+	Review
 }
 
 func NewRepository(db *sqlx.DB) *Repository {
 	return &Repository{
-		Admin:      NewAdminPg(db),
-		User:       NewUserPg(db),
-		Courier:    NewCourierPg(db),
-		Restaurant: NewRestaurantPg(db),
-		Category:   NewCategoryPg(db),
-		Order:      NewOrderPg(db),
-		MenuItem:   NewMenuItem(db),
+		Admin:       NewAdminPg(db),
+		User:        NewUserPg(db),
+		Courier:     NewCourierPg(db),
+		Restaurant:  NewRestaurantPg(db),
+		Category:    NewCategoryPg(db),
+		Order:       NewOrderPg(db),
+		MenuItem:    NewMenuItem(db),
+		Session:     NewSessionPg(db),
+		Revocation:  NewRevocationPg(db),
+		EventTicket: NewEventTicketPg(db),
+		Dispatch:    NewDispatchPg(db),
+		Tracking:    NewTrackingPg(db),
+		Webhook:     NewWebhookPg(db),
+		Outbox:      NewOutboxPg(db),
+		Payment:     NewPaymentPg(db),
+		Refund:      NewRefundPg(db),
+		Cart:        NewCartPg(db),
+		Review:      NewReviewPg(db),
 	}
 }
@@ -1,11 +1,13 @@
 package repository
 
 import (
+	"database/sql"
 	"fmt"
 	"strings"
 
 	"github.com/MAVIKE/yad-backend/internal/domain"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type RestaurantPg struct {
@@ -18,71 +20,104 @@ func NewRestaurantPg(db *sqlx.DB) *RestaurantPg {
 	}
 }
 
-func (r *RestaurantPg) GetByCredentials(phone, password string) (*domain.Restaurant, error) {
+func (r *RestaurantPg) GetByPhone(phone string) (*domain.Restaurant, error) {
 	restaurant := new(domain.Restaurant)
 	address := new(domain.Location)
 
 	query := fmt.Sprintf(
 		`SELECT u.id, u.name, u.phone, u.password_hash, l.latitude, l.longitude, u.working_status, u.image
  				FROM %s AS u JOIN %s AS l ON u.address_id = l.id
- 				WHERE u.phone = $1 AND u.password_hash = $2`, restaurantsTable, locationsTable)
-	row := r.db.QueryRow(query, phone, password)
+ 				WHERE u.phone = $1`, restaurantsTable, locationsTable)
+	row := r.db.QueryRow(query, phone)
 	err := row.Scan(&restaurant.Id, &restaurant.Name, &restaurant.Phone, &restaurant.Password, &address.Latitude, &address.Longitude, &restaurant.WorkingStatus, &restaurant.Image)
 	restaurant.Address = address
 
-	return restaurant, err
+	return restaurant, pgError(err)
 }
 
-func (r *RestaurantPg) GetAll(userId int) ([]*domain.Restaurant, error) {
-	var restaurants []*domain.Restaurant
+func (r *RestaurantPg) UpdatePassword(restaurantId int, passwordHash string) error {
+	query := fmt.Sprintf(`UPDATE %s SET password_hash = $1 WHERE id = $2`, restaurantsTable)
+	_, err := r.db.Exec(query, passwordHash, restaurantId)
+	return pgError(err)
+}
 
-	query := fmt.Sprintf(
-		`SELECT tmp.id, tmp.name, tmp.phone, tmp.working_status, 
-			tmp.latitude, tmp.longitude, tmp.image
-		FROM
-		(
-			SELECT r.id, r.name, r.phone, r.working_status, 
-				l.latitude, l.longitude, r.image, 
-				get_distance(l.latitude, l.longitude, ua.latitude, ua.longitude) AS distance
-			FROM %s AS r
-				INNER JOIN %s AS l ON r.address_id = l.id,
-				(
-					SELECT ul.latitude, ul.longitude
-						FROM %s AS u
-					INNER JOIN %s AS ul ON u.address_id = ul.id
-						WHERE u.id = $1
-				) AS ua
-			ORDER BY distance
-		) AS tmp`,
+// restaurantSorts are the orders of the restaurants, the nearest first by
+// default.
+var restaurantSorts = listSorts{
+	defaultSort: "distance",
+	columns:     map[string]string{"distance": "float8", "name": "text", "rating": "float8"},
+}
+
+// GetAll returns a page of the restaurants that deliver to the user.
+func (r *RestaurantPg) GetAll(userId int, params *domain.ListParams) (*domain.RestaurantPage, error) {
+	inner := fmt.Sprintf(
+		`SELECT r.id, r.name, r.phone, r.working_status, 
+			l.latitude, l.longitude, r.image, r.time_zone, r.rating, r.rating_count,
+			get_distance(l.latitude, l.longitude, ua.latitude, ua.longitude) AS distance
+		FROM %s AS r
+			INNER JOIN %s AS l ON r.address_id = l.id,
+			(
+				SELECT ul.latitude, ul.longitude
+					FROM %s AS u
+				INNER JOIN %s AS ul ON u.address_id = ul.id
+					WHERE u.id = $1
+			) AS ua
+		WHERE in_delivery_zone(r.id, ua.latitude, ua.longitude)`,
 		restaurantsTable, locationsTable, usersTable, locationsTable)
 
-	rows, err := r.db.Query(query, userId)
+	query, args, order, err := pageQuery(restaurantPageColumns, inner, params, restaurantSorts, []interface{}{userId})
 	if err != nil {
 		return nil, err
 	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, pgError(err)
+	}
 	defer rows.Close()
 
+	return scanRestaurantPage(rows, order, params.Limit)
+}
+
+// restaurantPageColumns are the columns scanRestaurantPage expects from a
+// page query.
+const restaurantPageColumns = `id, name, phone, working_status, latitude, longitude, image, time_zone,
+	rating, rating_count`
+
+func scanRestaurantPage(rows *sql.Rows, order *listOrder, limit int) (*domain.RestaurantPage, error) {
+	page := &domain.RestaurantPage{Items: make([]*domain.Restaurant, 0, limit)}
+	lastKey := ""
 	for rows.Next() {
 		restaurant := &domain.Restaurant{}
 		location := &domain.Location{}
+		var sortKey string
 
 		err := rows.Scan(&restaurant.Id, &restaurant.Name, &restaurant.Phone,
 			&restaurant.WorkingStatus, &location.Latitude,
-			&location.Longitude, &restaurant.Image)
+			&location.Longitude, &restaurant.Image, &restaurant.TimeZone,
+			&restaurant.Rating, &restaurant.RatingCount, &sortKey)
 
 		if err != nil {
-			return nil, err
+			return nil, pgError(err)
+		}
+
+		if len(page.Items) == limit {
+			last := page.Items[len(page.Items)-1]
+			page.NextCursor = order.encodeCursor(lastKey, last.Id)
+			break
 		}
 
 		restaurant.Address = location
-		restaurants = append(restaurants, restaurant)
+		page.Items = append(page.Items, restaurant)
+		page.Cursors = append(page.Cursors, order.encodeCursor(sortKey, restaurant.Id))
+		lastKey = sortKey
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, pgError(err)
 	}
 
-	return restaurants, err
+	return page, nil
 }
 
 func (r *RestaurantPg) GetById(restaurantId int) (*domain.Restaurant, error) {
@@ -91,7 +126,7 @@ func (r *RestaurantPg) GetById(restaurantId int) (*domain.Restaurant, error) {
 
 	query := fmt.Sprintf(
 		`SELECT r.id, r.name, r.phone, r.working_status, 
-			l.latitude, l.longitude, r.image 
+			l.latitude, l.longitude, r.image, r.time_zone, r.rating, r.rating_count
 		FROM %s AS r
 			INNER JOIN %s AS l ON r.address_id = l.id
 		WHERE r.id = $1`,
@@ -100,16 +135,17 @@ func (r *RestaurantPg) GetById(restaurantId int) (*domain.Restaurant, error) {
 	row := r.db.QueryRow(query, restaurantId)
 
 	err := row.Scan(&restaurant.Id, &restaurant.Name, &restaurant.Phone, &restaurant.WorkingStatus,
-		&location.Latitude, &location.Longitude, &restaurant.Image)
+		&location.Latitude, &location.Longitude, &restaurant.Image, &restaurant.TimeZone,
+		&restaurant.Rating, &restaurant.RatingCount)
 	restaurant.Address = location
 
-	return restaurant, err
+	return restaurant, pgError(err)
 }
 
 func (r *RestaurantPg) Create(restaurant *domain.Restaurant) (int, error) {
 	tx, err := r.db.Begin()
 	if err != nil {
-		return 0, err
+		return 0, pgError(err)
 	}
 
 	var addressId int
@@ -120,7 +156,7 @@ func (r *RestaurantPg) Create(restaurant *domain.Restaurant) (int, error) {
 	locationRow := tx.QueryRow(createLocationQuery, restaurant.Address.Latitude, restaurant.Address.Longitude)
 	if err = locationRow.Scan(&addressId); err != nil {
 		_ = tx.Rollback()
-		return 0, err
+		return 0, pgError(err)
 	}
 
 	createRestaurantQuery := fmt.Sprintf(
@@ -131,7 +167,7 @@ func (r *RestaurantPg) Create(restaurant *domain.Restaurant) (int, error) {
 	restaurantRow := tx.QueryRow(createRestaurantQuery, restaurant.Name, restaurant.Phone, restaurant.Password, addressId, restaurant.WorkingStatus, restaurant.Image)
 	if err = restaurantRow.Scan(&restaurantId); err != nil {
 		_ = tx.Rollback()
-		return 0, err
+		return 0, pgError(err)
 	}
 
 	return restaurantId, tx.Commit()
@@ -140,13 +176,13 @@ func (r *RestaurantPg) Create(restaurant *domain.Restaurant) (int, error) {
 func (r *RestaurantPg) UpdateImage(restaurantId int, image string) error {
 	query := fmt.Sprintf(`UPDATE %s AS r SET image = $1 WHERE r.id = $2`, restaurantsTable)
 	_, err := r.db.Exec(query, image, restaurantId)
-	return err
+	return pgError(err)
 }
 
-func (r *RestaurantPg) Update(restaurantId int, input *domain.Restaurant) error {
-	tx, err := r.db.Begin()
+func (r *RestaurantPg) Update(restaurantId int, input *domain.Restaurant, outbox ...*domain.Event) error {
+	tx, err := r.db.Beginx()
 	if err != nil {
-		return err
+		return pgError(err)
 	}
 
 	setValues := make([]string, 0)
@@ -179,7 +215,7 @@ func (r *RestaurantPg) Update(restaurantId int, input *domain.Restaurant) error
 		_, err := r.db.Exec(query, input.Address.Latitude, input.Address.Longitude, restaurantId)
 		if err != nil {
 			_ = tx.Rollback()
-			return err
+			return pgError(err)
 		}
 	}
 
@@ -190,8 +226,211 @@ func (r *RestaurantPg) Update(restaurantId int, input *domain.Restaurant) error
 
 	if _, err = tx.Exec(query, args...); err != nil {
 		_ = tx.Rollback()
-		return err
+		return pgError(err)
+	}
+
+	if err := writeOutbox(tx, outbox); err != nil {
+		_ = tx.Rollback()
+		return pgError(err)
 	}
 
 	return tx.Commit()
 }
+
+// GetSchedules returns the schedules of the restaurants by their id.
+// Holidays that are over are left out.
+func (r *RestaurantPg) GetSchedules(restaurantIds []int) (map[int]*domain.Schedule, error) {
+	var restaurants []*domain.Restaurant
+
+	query := fmt.Sprintf(`SELECT id, time_zone FROM %s WHERE id = ANY($1)`, restaurantsTable)
+	if err := r.db.Select(&restaurants, query, pq.Array(restaurantIds)); err != nil {
+		return nil, pgError(err)
+	}
+
+	schedules := make(map[int]*domain.Schedule, len(restaurants))
+	for _, restaurant := range restaurants {
+		schedules[restaurant.Id] = &domain.Schedule{
+			TimeZone: restaurant.TimeZone,
+			Hours:    []*domain.OpeningHours{},
+			Holidays: []*domain.Holiday{},
+		}
+	}
+
+	var hours []*domain.OpeningHours
+
+	query = fmt.Sprintf(
+		`SELECT restaurant_id, weekday, to_char(opens_at, 'HH24:MI') AS opens_at, to_char(closes_at, 'HH24:MI') AS closes_at
+		FROM %s WHERE restaurant_id = ANY($1) ORDER BY restaurant_id, weekday, opens_at`, openingHoursTable)
+	if err := r.db.Select(&hours, query, pq.Array(restaurantIds)); err != nil {
+		return nil, pgError(err)
+	}
+
+	for _, h := range hours {
+		schedule := schedules[h.RestaurantId]
+		schedule.Hours = append(schedule.Hours, h)
+	}
+
+	var holidays []*domain.Holiday
+
+	// a day of slack keeps the holidays of restaurants ahead of UTC
+	query = fmt.Sprintf(
+		`SELECT restaurant_id, to_char(date, 'YYYY-MM-DD') AS date,
+			to_char(opens_at, 'HH24:MI') AS opens_at, to_char(closes_at, 'HH24:MI') AS closes_at, reason
+		FROM %s WHERE restaurant_id = ANY($1) AND date >= CURRENT_DATE - 1 ORDER BY restaurant_id, date`, holidaysTable)
+	if err := r.db.Select(&holidays, query, pq.Array(restaurantIds)); err != nil {
+		return nil, pgError(err)
+	}
+
+	for _, h := range holidays {
+		schedule := schedules[h.RestaurantId]
+		schedule.Holidays = append(schedule.Holidays, h)
+	}
+
+	return schedules, nil
+}
+
+// GetScheduledIds returns the ids of the restaurants with opening hours.
+func (r *RestaurantPg) GetScheduledIds() ([]int, error) {
+	var restaurantIds []int
+
+	query := fmt.Sprintf(`SELECT DISTINCT restaurant_id FROM %s ORDER BY restaurant_id`, openingHoursTable)
+	err := r.db.Select(&restaurantIds, query)
+
+	return restaurantIds, pgError(err)
+}
+
+// SetWorkingStatus changes the working status of the restaurant and writes
+// the outbox events in one transaction. Nothing is written when the
+// restaurant already has the status.
+func (r *RestaurantPg) SetWorkingStatus(restaurantId, status int, outbox ...*domain.Event) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return pgError(err)
+	}
+
+	query := fmt.Sprintf(
+		`UPDATE %s SET working_status = $1 WHERE id = $2 AND working_status <> $1`, restaurantsTable)
+	result, err := tx.Exec(query, status, restaurantId)
+	if err != nil {
+		_ = tx.Rollback()
+		return pgError(err)
+	}
+
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		_ = tx.Rollback()
+		return pgError(err)
+	}
+
+	if err := writeOutbox(tx, outbox); err != nil {
+		_ = tx.Rollback()
+		return pgError(err)
+	}
+
+	return pgError(tx.Commit())
+}
+
+// UpdateSchedule replaces the time zone, the opening hours and the
+// holidays of the restaurant.
+func (r *RestaurantPg) UpdateSchedule(restaurantId int, schedule *domain.Schedule) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return pgError(err)
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET time_zone = $1 WHERE id = $2`, restaurantsTable)
+	if _, err := tx.Exec(query, schedule.TimeZone, restaurantId); err != nil {
+		_ = tx.Rollback()
+		return pgError(err)
+	}
+
+	for _, table := range []string{openingHoursTable, holidaysTable} {
+		query = fmt.Sprintf(`DELETE FROM %s WHERE restaurant_id = $1`, table)
+		if _, err := tx.Exec(query, restaurantId); err != nil {
+			_ = tx.Rollback()
+			return pgError(err)
+		}
+	}
+
+	query = fmt.Sprintf(`INSERT INTO %s (restaurant_id, weekday, opens_at, closes_at) VALUES ($1, $2, $3, $4)`,
+		openingHoursTable)
+	for _, h := range schedule.Hours {
+		if _, err := tx.Exec(query, restaurantId, h.Weekday, h.OpensAt, h.ClosesAt); err != nil {
+			_ = tx.Rollback()
+			return pgError(err)
+		}
+	}
+
+	query = fmt.Sprintf(`INSERT INTO %s (restaurant_id, date, opens_at, closes_at, reason) VALUES ($1, $2, $3, $4, $5)`,
+		holidaysTable)
+	for _, h := range schedule.Holidays {
+		if _, err := tx.Exec(query, restaurantId, h.Date, h.OpensAt, h.ClosesAt, h.Reason); err != nil {
+			_ = tx.Rollback()
+			return pgError(err)
+		}
+	}
+
+	return pgError(tx.Commit())
+}
+
+func (r *RestaurantPg) GetDeliveryZone(restaurantId int) (*domain.DeliveryZone, error) {
+	zone := &domain.DeliveryZone{Polygon: []*domain.Location{}}
+
+	query := fmt.Sprintf(`SELECT delivery_radius FROM %s WHERE id = $1`, restaurantsTable)
+	if err := r.db.Get(&zone.Radius, query, restaurantId); err != nil {
+		return nil, pgError(err)
+	}
+
+	query = fmt.Sprintf(`SELECT latitude, longitude FROM %s WHERE restaurant_id = $1 ORDER BY seq`, zonePointsTable)
+	if err := r.db.Select(&zone.Polygon, query, restaurantId); err != nil {
+		return nil, pgError(err)
+	}
+
+	return zone, nil
+}
+
+// UpdateDeliveryZone replaces the delivery radius and the polygon of the
+// restaurant.
+func (r *RestaurantPg) UpdateDeliveryZone(restaurantId int, zone *domain.DeliveryZone) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return pgError(err)
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET delivery_radius = $1 WHERE id = $2`, restaurantsTable)
+	if _, err := tx.Exec(query, zone.Radius, restaurantId); err != nil {
+		_ = tx.Rollback()
+		return pgError(err)
+	}
+
+	query = fmt.Sprintf(`DELETE FROM %s WHERE restaurant_id = $1`, zonePointsTable)
+	if _, err := tx.Exec(query, restaurantId); err != nil {
+		_ = tx.Rollback()
+		return pgError(err)
+	}
+
+	query = fmt.Sprintf(`INSERT INTO %s (restaurant_id, seq, latitude, longitude) VALUES ($1, $2, $3, $4)`,
+		zonePointsTable)
+	for i, point := range zone.Polygon {
+		if _, err := tx.Exec(query, restaurantId, i, point.Latitude, point.Longitude); err != nil {
+			_ = tx.Rollback()
+			return pgError(err)
+		}
+	}
+
+	return pgError(tx.Commit())
+}
+
+// DeliversTo reports whether the address of the user is in the delivery
+// zone of the restaurant.
+func (r *RestaurantPg) DeliversTo(restaurantId, userId int) (bool, error) {
+	var delivers bool
+
+	query := fmt.Sprintf(
+		`SELECT COALESCE(in_delivery_zone($1, l.latitude, l.longitude), false)
+		FROM %s AS u
+			INNER JOIN %s AS l ON u.address_id = l.id
+		WHERE u.id = $2`, usersTable, locationsTable)
+	err := r.db.Get(&delivers, query, restaurantId, userId)
+
+	return delivers, pgError(err)
+}
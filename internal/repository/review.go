@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/MAVIKE/yad-backend/internal/consts"
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+type ReviewPg struct {
+	db *sqlx.DB
+}
+
+func NewReviewPg(db *sqlx.DB) *ReviewPg {
+	return &ReviewPg{
+		db: db,
+	}
+}
+
+// reviewSorts are the orders of the reviews of a restaurant, the newest
+// first by default.
+var reviewSorts = listSorts{
+	defaultSort: "-created_at",
+	columns:     map[string]string{"created_at": "timestamptz", "rating": "int"},
+}
+
+const reviewColumns = `id, order_id, user_id, subject, subject_id, rating, comment, reply, replied_at, hidden, created_at`
+
+func (r *ReviewPg) Create(review *domain.Review) (int, error) {
+	var reviewId int
+
+	query := fmt.Sprintf(
+		`INSERT INTO %s (order_id, user_id, subject, subject_id, rating, comment)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`, reviewsTable)
+
+	row := r.db.QueryRow(query, review.OrderId, review.UserId, review.Subject, review.SubjectId,
+		review.Rating, review.Comment)
+	err := row.Scan(&reviewId)
+
+	return reviewId, pgError(err)
+}
+
+func (r *ReviewPg) GetById(reviewId int) (*domain.Review, error) {
+	review := new(domain.Review)
+
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE id = $1`, reviewColumns, reviewsTable)
+	err := r.db.Get(review, query, reviewId)
+
+	return review, pgError(err)
+}
+
+func (r *ReviewPg) GetByOrder(orderId int) ([]*domain.Review, error) {
+	var reviews []*domain.Review
+
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE order_id = $1 ORDER BY id`, reviewColumns, reviewsTable)
+	err := r.db.Select(&reviews, query, orderId)
+
+	return reviews, pgError(err)
+}
+
+// GetAll returns a page of the reviews of the restaurant.
+func (r *ReviewPg) GetAll(filter *domain.ReviewFilter) (*domain.ReviewPage, error) {
+	var rows []struct {
+		domain.Review
+		SortKey string `db:"sort_key"`
+	}
+
+	conditions := []string{"rv.subject = $1", "rv.subject_id = $2"}
+	if !filter.WithHidden {
+		conditions = append(conditions, "NOT rv.hidden")
+	}
+
+	inner := fmt.Sprintf(`SELECT * FROM %s AS rv %s`, reviewsTable, whereClause(conditions))
+	query, args, order, err := pageQuery(reviewColumns, inner, &filter.ListParams, reviewSorts,
+		[]interface{}{consts.ReviewRestaurant, filter.RestaurantId})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.db.Select(&rows, query, args...); err != nil {
+		return nil, pgError(err)
+	}
+
+	page := &domain.ReviewPage{Items: make([]*domain.Review, 0, len(rows))}
+	for i := range rows {
+		if i == filter.Limit {
+			page.NextCursor = order.encodeCursor(rows[i-1].SortKey, rows[i-1].Id)
+			break
+		}
+
+		page.Items = append(page.Items, &rows[i].Review)
+	}
+
+	return page, nil
+}
+
+// Reply sets the public reply to the review, replacing the previous one.
+func (r *ReviewPg) Reply(reviewId int, reply string) error {
+	query := fmt.Sprintf(`UPDATE %s SET reply = $1, replied_at = now() WHERE id = $2`, reviewsTable)
+	_, err := r.db.Exec(query, reply, reviewId)
+	return pgError(err)
+}
+
+// SetHidden hides the review or shows it again. The rating of the
+// restaurant is recounted by a trigger.
+func (r *ReviewPg) SetHidden(reviewId int, hidden bool) error {
+	query := fmt.Sprintf(`UPDATE %s SET hidden = $1 WHERE id = $2`, reviewsTable)
+	_, err := r.db.Exec(query, hidden, reviewId)
+	return pgError(err)
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+type RevocationPg struct {
+	db *sqlx.DB
+}
+
+func NewRevocationPg(db *sqlx.DB) *RevocationPg {
+	return &RevocationPg{
+		db: db,
+	}
+}
+
+func (r *RevocationPg) RevokeToken(tokenId string, expiresAt time.Time) error {
+	query := fmt.Sprintf(
+		`INSERT INTO %s (token_id, expires_at) VALUES ($1, $2)
+		ON CONFLICT (token_id) DO NOTHING`, revokedTokensTable)
+	_, err := r.db.Exec(query, tokenId, expiresAt)
+	return pgError(err)
+}
+
+func (r *RevocationPg) RevokeClient(clientId int, clientType string, revokedBefore time.Time) error {
+	query := fmt.Sprintf(
+		`INSERT INTO %s (client_id, client_type, revoked_before) VALUES ($1, $2, $3)
+		ON CONFLICT (client_id, client_type) DO UPDATE SET revoked_before = EXCLUDED.revoked_before`,
+		revokedClientsTable)
+	_, err := r.db.Exec(query, clientId, clientType, revokedBefore)
+	return pgError(err)
+}
+
+func (r *RevocationPg) GetRevokedTokens() ([]*domain.RevokedToken, error) {
+	var tokens []*domain.RevokedToken
+
+	query := fmt.Sprintf(`SELECT * FROM %s WHERE expires_at > now()`, revokedTokensTable)
+	err := r.db.Select(&tokens, query)
+
+	return tokens, pgError(err)
+}
+
+func (r *RevocationPg) GetRevokedClients() ([]*domain.RevokedClient, error) {
+	var clients []*domain.RevokedClient
+
+	query := fmt.Sprintf(`SELECT * FROM %s`, revokedClientsTable)
+	err := r.db.Select(&clients, query)
+
+	return clients, pgError(err)
+}
+
+// DeleteExpired drops the revoked tokens that have expired and the client
+// revocations older than clientsRevokedBefore.
+func (r *RevocationPg) DeleteExpired(clientsRevokedBefore time.Time) error {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE expires_at <= now()`, revokedTokensTable)
+	if _, err := r.db.Exec(query); err != nil {
+		return pgError(err)
+	}
+
+	query = fmt.Sprintf(`DELETE FROM %s WHERE revoked_before < $1`, revokedClientsTable)
+	_, err := r.db.Exec(query, clientsRevokedBefore)
+	return pgError(err)
+}
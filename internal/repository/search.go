@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/lib/pq"
+)
+
+// searchDistanceScale is the distance in kilometres at which a restaurant
+// ranks half as high as the same match next door.
+const searchDistanceScale = 10
+
+// searchSorts are the orders of the search results, the best match first by
+// default.
+var searchSorts = listSorts{
+	defaultSort: "-score",
+	columns:     map[string]string{"score": "float8", "distance": "float8"},
+}
+
+// Search returns a page of the restaurants delivering to the user that match
+// the query by their name, a dish or a menu category, with the dishes that
+// match. A restaurant scores its best match weighed down by the distance.
+func (r *RestaurantPg) Search(userId int, filter *domain.SearchFilter) (*domain.SearchPage, error) {
+	inner := fmt.Sprintf(
+		`SELECT s.*, s.relevance * %d / (%d + s.distance) AS score
+		FROM
+		(
+			SELECT r.id, r.name, r.phone, r.working_status,
+				l.latitude, l.longitude, r.image, r.time_zone, r.rating, r.rating_count,
+				get_distance(l.latitude, l.longitude, ua.latitude, ua.longitude) AS distance,
+				GREATEST(
+					ts_rank(r.search_vector, q.query),
+					(SELECT MAX(ts_rank(m.search_vector, q.query)) FROM %s AS m
+						WHERE m.restaurant_id = r.id AND m.search_vector @@ q.query),
+					(SELECT MAX(ts_rank(c.search_vector, q.query)) FROM %s AS c
+						WHERE c.restaurant_id = r.id AND c.search_vector @@ q.query)
+				) AS relevance
+			FROM %s AS r
+				INNER JOIN %s AS l ON r.address_id = l.id,
+				(
+					SELECT ul.latitude, ul.longitude
+						FROM %s AS u
+					INNER JOIN %s AS ul ON u.address_id = ul.id
+						WHERE u.id = $1
+				) AS ua,
+				search_query($2) AS q(query)
+			WHERE in_delivery_zone(r.id, ua.latitude, ua.longitude)
+				AND (r.search_vector @@ q.query
+					OR EXISTS (SELECT 1 FROM %s AS m WHERE m.restaurant_id = r.id AND m.search_vector @@ q.query)
+					OR EXISTS (SELECT 1 FROM %s AS c WHERE c.restaurant_id = r.id AND c.search_vector @@ q.query))
+		) AS s`,
+		searchDistanceScale, searchDistanceScale, menuItemsTable, categoriesTable,
+		restaurantsTable, locationsTable, usersTable, locationsTable, menuItemsTable, categoriesTable)
+
+	query, args, order, err := pageQuery(restaurantPageColumns, inner, &filter.ListParams, searchSorts,
+		[]interface{}{userId, filter.Query})
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, pgError(err)
+	}
+	defer rows.Close()
+
+	restaurants, err := scanRestaurantPage(rows, order, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.SearchPage{
+		Items:      make([]*domain.SearchResult, 0, len(restaurants.Items)),
+		NextCursor: restaurants.NextCursor,
+	}
+
+	results := make(map[int]*domain.SearchResult, len(restaurants.Items))
+	restaurantIds := make([]int, 0, len(restaurants.Items))
+	for _, restaurant := range restaurants.Items {
+		result := &domain.SearchResult{Restaurant: restaurant, Dishes: make([]*domain.MenuItem, 0)}
+		page.Items = append(page.Items, result)
+		results[restaurant.Id] = result
+		restaurantIds = append(restaurantIds, restaurant.Id)
+	}
+
+	dishes, err := r.searchDishes(restaurantIds, filter.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dish := range dishes {
+		results[dish.RestaurantId].Dishes = append(results[dish.RestaurantId].Dishes, dish)
+	}
+
+	return page, nil
+}
+
+// searchDishes returns the dishes of the restaurants that match the query
+// or are in a menu category that does, the best match first.
+func (r *RestaurantPg) searchDishes(restaurantIds []int, searchQuery string) ([]*domain.MenuItem, error) {
+	var dishes []*domain.MenuItem
+
+	if len(restaurantIds) == 0 {
+		return dishes, nil
+	}
+
+	query := fmt.Sprintf(
+		`SELECT m.id, m.restaurant_id, m.title, m.image, COALESCE(m.description, '') AS description, m.price
+		FROM %s AS m, search_query($2) AS q(query)
+		WHERE m.restaurant_id = ANY($1)
+			AND (m.search_vector @@ q.query OR EXISTS (
+				SELECT 1 FROM %s AS ci
+					INNER JOIN %s AS c ON ci.category_id = c.id
+				WHERE ci.menu_item_id = m.id AND c.search_vector @@ q.query))
+		ORDER BY ts_rank(m.search_vector, q.query) DESC, m.id`,
+		menuItemsTable, categoryItemsTable, categoriesTable)
+
+	err := r.db.Select(&dishes, query, pq.Array(restaurantIds), searchQuery)
+
+	return dishes, pgError(err)
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+type SessionPg struct {
+	db *sqlx.DB
+}
+
+func NewSessionPg(db *sqlx.DB) *SessionPg {
+	return &SessionPg{
+		db: db,
+	}
+}
+
+func (r *SessionPg) Create(session *domain.Session) (int, error) {
+	var sessionId int
+
+	query := fmt.Sprintf(
+		`INSERT INTO %s (client_id, client_type, refresh_token_hash, expires_at)
+		VALUES ($1, $2, $3, $4) RETURNING id`, sessionsTable)
+
+	row := r.db.QueryRow(query, session.ClientId, session.ClientType, session.RefreshToken, session.ExpiresAt)
+	err := row.Scan(&sessionId)
+
+	return sessionId, pgError(err)
+}
+
+func (r *SessionPg) GetByRefreshToken(refreshToken string) (*domain.Session, error) {
+	session := new(domain.Session)
+
+	query := fmt.Sprintf(`SELECT * FROM %s WHERE refresh_token_hash = $1`, sessionsTable)
+	err := r.db.Get(session, query, refreshToken)
+
+	return session, pgError(err)
+}
+
+func (r *SessionPg) GetById(sessionId int) (*domain.Session, error) {
+	session := new(domain.Session)
+
+	query := fmt.Sprintf(`SELECT * FROM %s WHERE id = $1`, sessionsTable)
+	err := r.db.Get(session, query, sessionId)
+
+	return session, pgError(err)
+}
+
+func (r *SessionPg) GetAll(clientId int, clientType string) ([]*domain.Session, error) {
+	var sessions []*domain.Session
+
+	query := fmt.Sprintf(
+		`SELECT * FROM %s
+		WHERE client_id = $1 AND client_type = $2 AND expires_at > now()
+		ORDER BY created_at DESC`, sessionsTable)
+	err := r.db.Select(&sessions, query, clientId, clientType)
+
+	return sessions, pgError(err)
+}
+
+// Rotate replaces the refresh token only if it has not been rotated yet,
+// so two concurrent refreshes with the same token can't both succeed.
+func (r *SessionPg) Rotate(sessionId int, oldRefreshToken, newRefreshToken string, expiresAt time.Time) error {
+	query := fmt.Sprintf(
+		`UPDATE %s SET refresh_token_hash = $1, expires_at = $2, refreshed_at = now()
+		WHERE id = $3 AND refresh_token_hash = $4`, sessionsTable)
+
+	res, err := r.db.Exec(query, newRefreshToken, expiresAt, sessionId, oldRefreshToken)
+	if err != nil {
+		return pgError(err)
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return pgError(err)
+	}
+
+	if count == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *SessionPg) Delete(sessionId int) error {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE id = $1`, sessionsTable)
+	_, err := r.db.Exec(query, sessionId)
+	return pgError(err)
+}
+
+func (r *SessionPg) DeleteAll(clientId int, clientType string) error {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE client_id = $1 AND client_type = $2`, sessionsTable)
+	_, err := r.db.Exec(query, clientId, clientType)
+	return pgError(err)
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+type TrackingPg struct {
+	db *sqlx.DB
+}
+
+func NewTrackingPg(db *sqlx.DB) *TrackingPg {
+	return &TrackingPg{
+		db: db,
+	}
+}
+
+// AddLocation stores the courier position and drops the positions
+// reported before keepAfter.
+func (r *TrackingPg) AddLocation(courierId int, location *domain.Location, keepAfter time.Time) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return pgError(err)
+	}
+
+	query := fmt.Sprintf(
+		`INSERT INTO %s (courier_id, latitude, longitude) VALUES ($1, $2, $3)`, courierLocationsTable)
+	if _, err := tx.Exec(query, courierId, location.Latitude, location.Longitude); err != nil {
+		_ = tx.Rollback()
+		return pgError(err)
+	}
+
+	query = fmt.Sprintf(`DELETE FROM %s WHERE courier_id = $1 AND created_at < $2`, courierLocationsTable)
+	if _, err := tx.Exec(query, courierId, keepAfter); err != nil {
+		_ = tx.Rollback()
+		return pgError(err)
+	}
+
+	return pgError(tx.Commit())
+}
+
+func (r *TrackingPg) GetLatestLocation(courierId int) (*domain.CourierLocation, error) {
+	location := new(domain.CourierLocation)
+
+	query := fmt.Sprintf(
+		`SELECT courier_id, latitude, longitude, created_at FROM %s
+		WHERE courier_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1`, courierLocationsTable)
+	err := r.db.Get(location, query, courierId)
+
+	return location, pgError(err)
+}
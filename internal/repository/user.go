@@ -23,7 +23,7 @@ func NewUserPg(db *sqlx.DB) *UserPg {
 func (r *UserPg) Create(user *domain.User) (int, error) {
 	tx, err := r.db.Begin()
 	if err != nil {
-		return 0, err
+		return 0, pgError(err)
 	}
 
 	var addressId int
@@ -34,7 +34,7 @@ func (r *UserPg) Create(user *domain.User) (int, error) {
 	locationRow := tx.QueryRow(createLocationQuery, user.Address.Latitude, user.Address.Longitude)
 	if err = locationRow.Scan(&addressId); err != nil {
 		_ = tx.Rollback()
-		return 0, err
+		return 0, pgError(err)
 	}
 
 	createUserQuery := fmt.Sprintf(
@@ -45,25 +45,31 @@ func (r *UserPg) Create(user *domain.User) (int, error) {
 	userRow := tx.QueryRow(createUserQuery, user.Name, user.Phone, user.Password, user.Email, addressId)
 	if err = userRow.Scan(&userId); err != nil {
 		_ = tx.Rollback()
-		return 0, err
+		return 0, pgError(err)
 	}
 
 	return userId, tx.Commit()
 }
 
-func (r *UserPg) GetByCredentials(phone, password string) (*domain.User, error) {
+func (r *UserPg) GetByPhone(phone string) (*domain.User, error) {
 	user := new(domain.User)
 	address := new(domain.Location)
 
 	query := fmt.Sprintf(
 		`SELECT u.id, u.name, u.phone, u.password_hash, u.email, l.latitude, l.longitude
 				FROM %s AS u JOIN %s AS l ON u.address_id = l.id
-				WHERE u.phone = $1 AND u.password_hash = $2`, usersTable, locationsTable)
-	row := r.db.QueryRow(query, phone, password)
+				WHERE u.phone = $1`, usersTable, locationsTable)
+	row := r.db.QueryRow(query, phone)
 	err := row.Scan(&user.Id, &user.Name, &user.Phone, &user.Password, &user.Email, &address.Latitude, &address.Longitude)
 	user.Address = address
 
-	return user, err
+	return user, pgError(err)
+}
+
+func (r *UserPg) UpdatePassword(userId int, passwordHash string) error {
+	query := fmt.Sprintf(`UPDATE %s SET password_hash = $1 WHERE id = $2`, usersTable)
+	_, err := r.db.Exec(query, passwordHash, userId)
+	return pgError(err)
 }
 
 func (r *UserPg) GetAllOrders(userId int, activeOrdersFlag bool) ([]*domain.Order, error) {
@@ -75,18 +81,18 @@ func (r *UserPg) GetAllOrders(userId int, activeOrdersFlag bool) ([]*domain.Orde
 
 	if activeOrdersFlag {
 		query = fmt.Sprintf(`SELECT id, user_id, restaurant_id, COALESCE(courier_id, 0) AS courier_id,
-			delivery_price, total_price, status, paid 
+			delivery_price, total_price, status, paid, discount
 		FROM %s WHERE user_id = $1 and status BETWEEN $2 AND $3`, ordersTable)
 		rows, err = r.db.Query(query, userId, consts.OrderPaid, consts.OrderEnRoute)
 	} else {
 		query = fmt.Sprintf(`SELECT id, user_id, restaurant_id, COALESCE(courier_id, 0) AS courier_id,
-			delivery_price, total_price, status, paid 
+			delivery_price, total_price, status, paid, discount
 		FROM %s WHERE user_id = $1`, ordersTable)
 		rows, err = r.db.Query(query, userId)
 	}
 
 	if err != nil {
-		return nil, err
+		return nil, pgError(err)
 	}
 	defer rows.Close()
 
@@ -95,26 +101,26 @@ func (r *UserPg) GetAllOrders(userId int, activeOrdersFlag bool) ([]*domain.Orde
 
 		err := rows.Scan(&order.Id, &order.UserId, &order.RestaurantId,
 			&order.CourierId, &order.DeliveryPrice,
-			&order.TotalPrice, &order.Status, &order.Paid)
+			&order.TotalPrice, &order.Status, &order.Paid, &order.Discount)
 
 		if err != nil {
-			return nil, err
+			return nil, pgError(err)
 		}
 
 		orders = append(orders, order)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, pgError(err)
 	}
 
-	return orders, err
+	return orders, pgError(err)
 }
 
 func (r *UserPg) Update(userId int, input *domain.User) error {
 	tx, err := r.db.Begin()
 	if err != nil {
-		return err
+		return pgError(err)
 	}
 
 	setValues := make([]string, 0)
@@ -147,7 +153,7 @@ func (r *UserPg) Update(userId int, input *domain.User) error {
 		_, err := r.db.Exec(query, input.Address.Latitude, input.Address.Longitude, userId)
 		if err != nil {
 			_ = tx.Rollback()
-			return err
+			return pgError(err)
 		}
 	}
 
@@ -158,7 +164,7 @@ func (r *UserPg) Update(userId int, input *domain.User) error {
 
 	if _, err = tx.Exec(query, args...); err != nil {
 		_ = tx.Rollback()
-		return err
+		return pgError(err)
 	}
 
 	return tx.Commit()
@@ -182,5 +188,5 @@ func (r *UserPg) GetById(userId int) (*domain.User, error) {
 		&location.Latitude, &location.Longitude)
 	user.Address = location
 
-	return user, err
+	return user, pgError(err)
 }
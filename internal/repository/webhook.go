@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/MAVIKE/yad-backend/internal/consts"
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type WebhookPg struct {
+	db *sqlx.DB
+}
+
+func NewWebhookPg(db *sqlx.DB) *WebhookPg {
+	return &WebhookPg{
+		db: db,
+	}
+}
+
+const webhookColumns = `id, restaurant_id, url, secret, events, created_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanWebhook(row rowScanner) (*domain.Webhook, error) {
+	webhook := new(domain.Webhook)
+	err := row.Scan(&webhook.Id, &webhook.RestaurantId, &webhook.Url, &webhook.Secret,
+		pq.Array(&webhook.Events), &webhook.CreatedAt)
+
+	return webhook, err
+}
+
+func (r *WebhookPg) selectWebhooks(query string, args ...interface{}) ([]*domain.Webhook, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, pgError(err)
+	}
+	defer rows.Close()
+
+	var webhooks []*domain.Webhook
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, pgError(err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, pgError(rows.Err())
+}
+
+func (r *WebhookPg) Create(webhook *domain.Webhook) (int, error) {
+	var webhookId int
+
+	query := fmt.Sprintf(
+		`INSERT INTO %s (restaurant_id, url, secret, events) VALUES ($1, $2, $3, $4) RETURNING id`,
+		webhooksTable)
+
+	row := r.db.QueryRow(query, webhook.RestaurantId, webhook.Url, webhook.Secret, pq.Array(webhook.Events))
+	err := row.Scan(&webhookId)
+
+	return webhookId, pgError(err)
+}
+
+func (r *WebhookPg) GetById(webhookId int) (*domain.Webhook, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE id = $1`, webhookColumns, webhooksTable)
+	webhook, err := scanWebhook(r.db.QueryRow(query, webhookId))
+
+	return webhook, pgError(err)
+}
+
+func (r *WebhookPg) GetAll(restaurantId int) ([]*domain.Webhook, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE restaurant_id = $1 ORDER BY id`, webhookColumns, webhooksTable)
+	return r.selectWebhooks(query, restaurantId)
+}
+
+// GetSubscribed returns the webhooks of the restaurant subscribed to the event.
+func (r *WebhookPg) GetSubscribed(restaurantId int, event string) ([]*domain.Webhook, error) {
+	query := fmt.Sprintf(
+		`SELECT %s FROM %s WHERE restaurant_id = $1 AND $2 = ANY(events) ORDER BY id`,
+		webhookColumns, webhooksTable)
+	return r.selectWebhooks(query, restaurantId, event)
+}
+
+func (r *WebhookPg) Delete(webhookId int) error {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE id = $1`, webhooksTable)
+	_, err := r.db.Exec(query, webhookId)
+	return pgError(err)
+}
+
+// CreateDelivery queues the event for the webhook. An event that has already
+// been queued for the webhook is skipped and 0 is returned.
+func (r *WebhookPg) CreateDelivery(delivery *domain.WebhookDelivery) (int, error) {
+	var deliveryId int
+
+	query := fmt.Sprintf(
+		`INSERT INTO %s (webhook_id, event, event_id, payload, status) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (webhook_id, event_id) DO NOTHING RETURNING id`,
+		webhookDeliveriesTable)
+
+	row := r.db.QueryRow(query, delivery.WebhookId, delivery.Event, delivery.EventId,
+		delivery.Payload, consts.DeliveryPending)
+	err := row.Scan(&deliveryId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+
+	return deliveryId, pgError(err)
+}
+
+// ClaimDueDeliveries returns up to limit pending deliveries whose next
+// attempt is due, the oldest first, and hides them from other workers for
+// lease, so every attempt is made by one instance.
+func (r *WebhookPg) ClaimDueDeliveries(limit int, lease time.Duration) ([]*domain.WebhookDelivery, error) {
+	var deliveries []*domain.WebhookDelivery
+
+	query := fmt.Sprintf(
+		`UPDATE %[1]s SET next_attempt_at = $1
+		WHERE id IN (
+			SELECT id FROM %[1]s
+			WHERE status = $2 AND next_attempt_at <= now()
+			ORDER BY next_attempt_at, id LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, webhookDeliveriesTable)
+	err := r.db.Select(&deliveries, query, time.Now().Add(lease), consts.DeliveryPending, limit)
+
+	return deliveries, pgError(err)
+}
+
+func (r *WebhookPg) GetDeliveries(webhookId int) ([]*domain.WebhookDelivery, error) {
+	var deliveries []*domain.WebhookDelivery
+
+	query := fmt.Sprintf(`SELECT * FROM %s WHERE webhook_id = $1 ORDER BY id DESC`, webhookDeliveriesTable)
+	err := r.db.Select(&deliveries, query, webhookId)
+
+	return deliveries, pgError(err)
+}
+
+// UpdateDelivery saves the outcome of an attempt.
+func (r *WebhookPg) UpdateDelivery(delivery *domain.WebhookDelivery) error {
+	query := fmt.Sprintf(
+		`UPDATE %s SET status = $1, attempts = $2, response_code = $3, last_error = $4,
+			next_attempt_at = $5, delivered_at = $6
+		WHERE id = $7`, webhookDeliveriesTable)
+	_, err := r.db.Exec(query, delivery.Status, delivery.Attempts, delivery.ResponseCode, delivery.LastError,
+		delivery.NextAttemptAt, delivery.DeliveredAt, delivery.Id)
+
+	return pgError(err)
+}
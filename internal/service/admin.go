@@ -2,34 +2,39 @@ package service
 
 import (
 	"github.com/MAVIKE/yad-backend/internal/repository"
-	"github.com/MAVIKE/yad-backend/pkg/auth"
-	"time"
+	"github.com/MAVIKE/yad-backend/pkg/hash"
 )
 
 type AdminService struct {
-	repo           repository.Admin
-	tokenManager   auth.TokenManager
-	accessTokenTTL time.Duration
+	repo     repository.Admin
+	hasher   hash.PasswordHasher
+	sessions Session
 }
 
-func NewAdminService(repo repository.Admin, tokenManager auth.TokenManager, accessTokenTTL time.Duration) *AdminService {
+func NewAdminService(repo repository.Admin, hasher hash.PasswordHasher, sessions Session) *AdminService {
 	return &AdminService{
-		repo:           repo,
-		tokenManager:   tokenManager,
-		accessTokenTTL: accessTokenTTL,
+		repo:     repo,
+		hasher:   hasher,
+		sessions: sessions,
 	}
 }
 
 func (s *AdminService) SignIn(name, password string) (*Tokens, error) {
-	admin, err := s.repo.GetByCredentials(name, password)
+	admin, err := s.repo.GetByName(name)
 	if err != nil {
-		return nil, err
+		return nil, credentialsError(err)
 	}
 
-	token, err := s.tokenManager.NewJWT(admin.Id, adminType, s.accessTokenTTL)
+	newHash, err := checkPassword(s.hasher, admin.Password, password)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Tokens{AccessToken: token}, nil
+	if newHash != "" {
+		if err := s.repo.UpdatePassword(admin.Id, newHash); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.sessions.Create(admin.Id, adminType)
 }
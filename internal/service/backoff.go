@@ -0,0 +1,19 @@
+package service
+
+import "time"
+
+// backoff returns the delay before the attempt following the given one:
+// base after the first attempt, doubled after every next one, but no more
+// than limit.
+func backoff(base, limit time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < limit; i++ {
+		delay *= 2
+	}
+
+	if delay > limit {
+		return limit
+	}
+
+	return delay
+}
@@ -0,0 +1,25 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		delay    time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{4, 4 * time.Minute},
+		{20, time.Hour},
+		{1000, time.Hour},
+	}
+
+	for _, tt := range tests {
+		if delay := backoff(30*time.Second, time.Hour, tt.attempts); delay != tt.delay {
+			t.Errorf("attempt %d: expected %s, got %s", tt.attempts, tt.delay, delay)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/MAVIKE/yad-backend/internal/consts"
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/MAVIKE/yad-backend/internal/repository"
+)
+
+// CartService keeps a cart per user until it is checked out into an order.
+// Carts nobody has touched for ttl are dropped.
+type CartService struct {
+	repo           repository.Cart
+	menuRepo       repository.MenuItem
+	restaurantRepo repository.Restaurant
+	pricer         *deliveryPricer
+	hours          *openingHours
+	ttl            time.Duration
+}
+
+func NewCartService(repo repository.Cart, menuRepo repository.MenuItem, orderRepo repository.Order,
+	restaurantRepo repository.Restaurant, pricing DeliveryPricing, ttl time.Duration) *CartService {
+	return &CartService{
+		repo:           repo,
+		menuRepo:       menuRepo,
+		restaurantRepo: restaurantRepo,
+		pricer:         &deliveryPricer{repo: orderRepo, pricing: pricing},
+		hours:          &openingHours{repo: restaurantRepo},
+		ttl:            ttl,
+	}
+}
+
+// Get returns the cart with a preview of the order price. A user without
+// a cart gets an empty one.
+func (s *CartService) Get(clientId int, clientType string, userId int) (*domain.Cart, error) {
+	if err := authorize(clientId, clientType, resourceCart, actionRead, ownedBy(userType, userId)); err != nil {
+		return nil, err
+	}
+
+	cart, err := s.repo.Get(userId)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return &domain.Cart{UserId: userId, Items: []*domain.CartItem{}}, nil
+		}
+		return nil, err
+	}
+
+	cart.Items, err = s.repo.GetItems(cart.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.price(cart); err != nil {
+		return nil, err
+	}
+
+	return cart, nil
+}
+
+func (s *CartService) price(cart *domain.Cart) error {
+	cart.ItemsPrice = 0
+	for _, item := range cart.Items {
+		cart.ItemsPrice += item.Price * item.Count
+	}
+
+	if len(cart.Items) == 0 {
+		cart.DeliveryPrice = 0
+		cart.TotalPrice = 0
+		return nil
+	}
+
+	deliveryPrice, err := s.pricer.price(&domain.Order{
+		UserId:       cart.UserId,
+		RestaurantId: cart.RestaurantId,
+		TotalPrice:   cart.ItemsPrice,
+	})
+	if err != nil {
+		return err
+	}
+
+	cart.DeliveryPrice = deliveryPrice
+	cart.TotalPrice = cart.ItemsPrice + deliveryPrice
+
+	return nil
+}
+
+// AddItem puts the menu item into the cart. Dishes of another restaurant
+// replace the ones in the cart only when replace is set.
+func (s *CartService) AddItem(clientId int, clientType string, userId int, item *domain.CartItem, replace bool) (int, error) {
+	if err := authorize(clientId, clientType, resourceCart, actionUpdate, ownedBy(userType, userId)); err != nil {
+		return 0, err
+	}
+
+	if item.Count < 1 || item.Count > 99 {
+		return 0, domain.NewValidationError("Menu items count must be greater than 0")
+	}
+
+	menuItem, err := s.menuRepo.GetById(item.MenuItemId)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return 0, domain.NewValidationError("Invalid menuItemId")
+		}
+		return 0, err
+	}
+
+	return s.repo.AddItem(userId, menuItem.RestaurantId, item, replace)
+}
+
+func (s *CartService) UpdateItem(clientId int, clientType string, userId, cartItemId, count int) error {
+	cart, err := s.getCart(clientId, clientType, actionUpdate, userId)
+	if err != nil {
+		return err
+	}
+
+	if count < 1 || count > 99 {
+		return domain.NewValidationError("Menu items count must be greater than 0")
+	}
+
+	return s.repo.UpdateItem(cart.Id, cartItemId, count)
+}
+
+func (s *CartService) DeleteItem(clientId int, clientType string, userId, cartItemId int) error {
+	cart, err := s.getCart(clientId, clientType, actionUpdate, userId)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.DeleteItem(cart.Id, cartItemId)
+}
+
+func (s *CartService) Delete(clientId int, clientType string, userId int) error {
+	if err := authorize(clientId, clientType, resourceCart, actionDelete, ownedBy(userType, userId)); err != nil {
+		return err
+	}
+
+	return s.repo.Delete(userId)
+}
+
+// Checkout turns the cart into a new order with all its dishes at once
+// and empties the cart.
+func (s *CartService) Checkout(clientId int, clientType string, userId int) (int, error) {
+	if err := authorize(clientId, clientType, resourceOrder, actionCreate, target{}); err != nil {
+		return 0, err
+	}
+
+	cart, err := s.Get(clientId, clientType, userId)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(cart.Items) == 0 {
+		return 0, domain.NewConflictError("Cart is empty")
+	}
+
+	if err := s.hours.check(cart.RestaurantId); err != nil {
+		return 0, err
+	}
+
+	if err := checkDeliveryZone(s.restaurantRepo, cart.RestaurantId, userId); err != nil {
+		return 0, err
+	}
+
+	order := &domain.Order{
+		UserId:        userId,
+		RestaurantId:  cart.RestaurantId,
+		DeliveryPrice: cart.DeliveryPrice,
+		TotalPrice:    cart.TotalPrice,
+		Status:        consts.OrderCreated,
+	}
+
+	return s.repo.Checkout(cart, order, &domain.OrderEvent{
+		ActorId:   clientId,
+		ActorType: clientType,
+		NewStatus: order.Status,
+	}, domain.NewEvent(domain.EventOrderCreated, order))
+}
+
+// Run drops expired carts every interval until ctx is done.
+func (s *CartService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Expire(); err != nil {
+			log.Printf("carts: %s", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Expire drops the carts that haven't changed for ttl.
+func (s *CartService) Expire() error {
+	if s.ttl == 0 {
+		return nil
+	}
+
+	_, err := s.repo.DeleteExpired(time.Now().Add(-s.ttl))
+	return err
+}
+
+func (s *CartService) getCart(clientId int, clientType string, act action, userId int) (*domain.Cart, error) {
+	if err := authorize(clientId, clientType, resourceCart, act, ownedBy(userType, userId)); err != nil {
+		return nil, err
+	}
+
+	cart, err := s.repo.Get(userId)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, domain.NewNotFoundError("Cart not found")
+		}
+		return nil, err
+	}
+
+	return cart, nil
+}
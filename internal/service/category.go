@@ -1,8 +1,6 @@
 package service
 
 import (
-	"errors"
-
 	"github.com/MAVIKE/yad-backend/internal/domain"
 	"github.com/MAVIKE/yad-backend/internal/repository"
 )
@@ -18,24 +16,24 @@ func NewCategoryService(repo repository.Category) *CategoryService {
 }
 
 func (s *CategoryService) Create(clientId int, clientType string, category *domain.Category) (int, error) {
-	if !(clientType == restaurantType && category.RestaurantId == clientId) {
-		return 0, errors.New("Forbidden")
+	if err := authorize(clientId, clientType, resourceCategory, actionCreate, ownedBy(restaurantType, category.RestaurantId)); err != nil {
+		return 0, err
 	}
 
 	return s.repo.Create(category)
 }
 
 func (s *CategoryService) GetAll(clientId int, clientType string, restaurantId int) ([]*domain.Category, error) {
-	if !(clientType == userType || clientType == restaurantType && restaurantId == clientId) {
-		return nil, errors.New("forbidden")
+	if err := authorize(clientId, clientType, resourceCategory, actionList, ownedBy(restaurantType, restaurantId)); err != nil {
+		return nil, err
 	}
 
 	return s.repo.GetAll(restaurantId)
 }
 
 func (s *CategoryService) GetById(clientId int, clientType string, restaurantId int, categoryId int) (*domain.Category, error) {
-	if !(clientType == userType || clientType == restaurantType && restaurantId == clientId) {
-		return nil, errors.New("forbidden")
+	if err := authorize(clientId, clientType, resourceCategory, actionRead, ownedBy(restaurantType, restaurantId)); err != nil {
+		return nil, err
 	}
 
 	category, err := s.repo.GetById(categoryId)
@@ -44,14 +42,14 @@ func (s *CategoryService) GetById(clientId int, clientType string, restaurantId
 	}
 
 	if category.RestaurantId != restaurantId {
-		return nil, errors.New("no such category for this restaurant")
+		return nil, domain.NewNotFoundError("no such category for this restaurant")
 	}
 	return category, nil
 }
 
 func (s *CategoryService) GetAllItems(clientId int, clientType string, restaurantId int, categoryId int) ([]*domain.MenuItem, error) {
-	if !(clientType == userType || clientType == restaurantType && restaurantId == clientId) {
-		return nil, errors.New("forbidden")
+	if err := authorize(clientId, clientType, resourceMenuItem, actionList, ownedBy(restaurantType, restaurantId)); err != nil {
+		return nil, err
 	}
 
 	category, err := s.repo.GetById(categoryId)
@@ -60,7 +58,7 @@ func (s *CategoryService) GetAllItems(clientId int, clientType string, restauran
 	}
 
 	if category.RestaurantId != restaurantId {
-		return nil, errors.New("no such category for this restaurant")
+		return nil, domain.NewNotFoundError("no such category for this restaurant")
 	}
 
 	menu, err := s.repo.GetAllItems(categoryId)
@@ -72,16 +70,16 @@ func (s *CategoryService) GetAllItems(clientId int, clientType string, restauran
 }
 
 func (s *CategoryService) DeleteCategory(clientId int, clientType string, restaurantId int, categoryId int) error {
-	if !(clientType == restaurantType && restaurantId == clientId) {
-		return errors.New("forbidden")
+	if err := authorize(clientId, clientType, resourceCategory, actionDelete, ownedBy(restaurantType, restaurantId)); err != nil {
+		return err
 	}
 
 	return s.repo.DeleteCategory(restaurantId, categoryId)
 }
 
 func (s *CategoryService) UpdateCategory(clientId int, clientType string, restaurantId int, categoryId int, input *domain.Category) error {
-	if !(clientType == restaurantType && restaurantId == clientId) {
-		return errors.New("forbidden")
+	if err := authorize(clientId, clientType, resourceCategory, actionUpdate, ownedBy(restaurantType, restaurantId)); err != nil {
+		return err
 	}
 
 	return s.repo.UpdateCategory(restaurantId, categoryId, input)
@@ -1,65 +1,83 @@
 package service
 
 import (
-	"errors"
 	"github.com/MAVIKE/yad-backend/internal/consts"
 	"github.com/MAVIKE/yad-backend/internal/domain"
 	"github.com/MAVIKE/yad-backend/internal/repository"
-	"github.com/MAVIKE/yad-backend/pkg/auth"
-	"time"
+	"github.com/MAVIKE/yad-backend/pkg/hash"
 )
 
 type CourierService struct {
-	repo           repository.Courier
-	orderRepo      repository.Order
-	tokenManager   auth.TokenManager
-	accessTokenTTL time.Duration
+	repo      repository.Courier
+	orderRepo repository.Order
+	hasher    hash.PasswordHasher
+	sessions  Session
+	dispatch  Dispatch
 }
 
-func NewCourierService(repo repository.Courier, orderRepo repository.Order, tokenManager auth.TokenManager, accessTokenTTL time.Duration) *CourierService {
+func NewCourierService(repo repository.Courier, orderRepo repository.Order, hasher hash.PasswordHasher,
+	sessions Session, dispatch Dispatch) *CourierService {
 	return &CourierService{
-		repo:           repo,
-		orderRepo:      orderRepo,
-		tokenManager:   tokenManager,
-		accessTokenTTL: accessTokenTTL,
+		repo:      repo,
+		orderRepo: orderRepo,
+		hasher:    hasher,
+		sessions:  sessions,
+		dispatch:  dispatch,
 	}
 }
 
 func (s *CourierService) SignUp(courier *domain.Courier, clientType string) (int, error) {
-	if clientType != adminType {
-		return 0, errors.New("forbidden")
+	if err := authorize(0, clientType, resourceCourier, actionCreate, target{}); err != nil {
+		return 0, err
 	}
+
+	passwordHash, err := s.hasher.Hash(courier.Password)
+	if err != nil {
+		return 0, err
+	}
+	courier.Password = passwordHash
+
 	return s.repo.Create(courier)
 }
 
 func (s *CourierService) SignIn(phone, password string) (*Tokens, error) {
-	courier, err := s.repo.GetByCredentials(phone, password)
+	courier, err := s.repo.GetByPhone(phone)
 	if err != nil {
-		return nil, err
+		return nil, credentialsError(err)
 	}
 
-	token, err := s.tokenManager.NewJWT(courier.Id, courierType, s.accessTokenTTL)
+	newHash, err := checkPassword(s.hasher, courier.Password, password)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Tokens{AccessToken: token}, nil
+	if newHash != "" {
+		if err := s.repo.UpdatePassword(courier.Id, newHash); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.sessions.Create(courier.Id, courierType)
 }
 
 func (s *CourierService) GetById(clientId int, clientType string, courierId int) (*domain.Courier, error) {
-	if !(clientType == userType || clientType == restaurantType || clientId == courierId) {
-		return nil, errors.New("Forbidden")
+	if err := authorize(clientId, clientType, resourceCourier, actionRead, ownedBy(courierType, courierId)); err != nil {
+		return nil, err
 	}
 
 	return s.repo.GetById(courierId)
 }
 
 func (s *CourierService) Update(clientId int, clientType string, courierId int, input *domain.Courier) error {
+	if err := authorize(clientId, clientType, resourceCourier, actionUpdate, ownedBy(courierType, courierId)); err != nil {
+		return err
+	}
+
 	switch input.WorkingStatus {
 	case consts.CourierUnable, consts.CourierWaiting, consts.CourierWorking:
 		break
 	default:
-		return errors.New("working_status input error")
+		return domain.NewValidationError("working_status input error")
 	}
 
 	courier, err := s.repo.GetById(courierId)
@@ -69,24 +87,46 @@ func (s *CourierService) Update(clientId int, clientType string, courierId int,
 
 	diff := courier.WorkingStatus - input.WorkingStatus
 	if diff == 2 || diff == -2 {
-		return errors.New("jump over states")
+		return domain.NewInvalidStateTransitionError("jump over states")
 	}
 
 	_, err = s.orderRepo.GetActiveCourierOrder(courierId)
 	if err == nil && input.WorkingStatus != consts.CourierWorking {
-		return errors.New("courier still have a order")
+		return domain.NewConflictError("courier still have a order")
 	} else if err != nil && input.WorkingStatus == consts.CourierWorking {
-		return errors.New("can't found order for this courier")
+		return domain.NewConflictError("can't found order for this courier")
 	}
 
-	if clientType == courierType && courierId == clientId {
+	// couriers may only change their working status
+	if clientType == courierType {
 		input.Email = ""
 		input.Name = ""
 		input.Phone = ""
 		input.Password = ""
-	} else if clientType != adminType {
-		return errors.New("forbidden")
 	}
 
-	return s.repo.Update(courierId, input)
+	if input.Password != "" {
+		passwordHash, err := s.hasher.Hash(input.Password)
+		if err != nil {
+			return err
+		}
+		input.Password = passwordHash
+	}
+
+	var events []*domain.Event
+	if input.WorkingStatus != courier.WorkingStatus {
+		events = append(events,
+			domain.NewCourierEvent(domain.EventCourierStatusChanged, courierId, input.WorkingStatus))
+	}
+
+	if err := s.repo.Update(courierId, input, events...); err != nil {
+		return err
+	}
+
+	// a free courier may take an order from the dispatch queue
+	if input.WorkingStatus == consts.CourierWaiting {
+		s.dispatch.Notify()
+	}
+
+	return nil
 }
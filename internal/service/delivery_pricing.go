@@ -0,0 +1,100 @@
+package service
+
+import (
+	"math"
+
+	"github.com/MAVIKE/yad-backend/internal/consts"
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/MAVIKE/yad-backend/internal/repository"
+)
+
+// DeliveryPricing holds the delivery tariff. All amounts are in the same
+// units as menu item prices; zero values turn the matching rule off.
+type DeliveryPricing struct {
+	BaseFee        int
+	PerKm          int
+	FreeThreshold  int
+	MinOrderAmount int
+}
+
+// Price returns the delivery price for the distance in kilometres between
+// the user and the restaurant and for the price of the ordered items.
+func (p DeliveryPricing) Price(distance float64, itemsPrice int) int {
+	if p.FreeThreshold > 0 && itemsPrice >= p.FreeThreshold {
+		return 0
+	}
+
+	return p.BaseFee + int(math.Ceil(distance*float64(p.PerKm)))
+}
+
+// CheckMinOrder returns a validation error when the items are too cheap
+// for the order to be paid.
+func (p DeliveryPricing) CheckMinOrder(itemsPrice int) error {
+	if itemsPrice < p.MinOrderAmount {
+		return domain.NewValidationError("Minimum order amount is %d", p.MinOrderAmount)
+	}
+
+	return nil
+}
+
+// deliveryPricer keeps the delivery price of unpaid orders up to date.
+// Both the order and the user services need it: the price depends on the
+// items and on the user address.
+type deliveryPricer struct {
+	repo    repository.Order
+	pricing DeliveryPricing
+}
+
+// itemsPrice is the price of the order items. The refunded discount is
+// taken off the total, not off the items.
+func itemsPrice(order *domain.Order) int {
+	return order.TotalPrice - order.DeliveryPrice + order.Discount
+}
+
+func (p *deliveryPricer) price(order *domain.Order) (int, error) {
+	distance, err := p.repo.GetDeliveryDistance(order.UserId, order.RestaurantId)
+	if err != nil {
+		return 0, err
+	}
+
+	return p.pricing.Price(distance, itemsPrice(order)), nil
+}
+
+// reprice recomputes the delivery price of the order. Paid orders keep
+// the price the user has paid.
+func (p *deliveryPricer) reprice(orderId int) error {
+	order, err := p.repo.GetById(orderId)
+	if err != nil {
+		return err
+	}
+
+	if order.Status != consts.OrderCreated {
+		return nil
+	}
+
+	deliveryPrice, err := p.price(order)
+	if err != nil {
+		return err
+	}
+
+	if deliveryPrice == order.DeliveryPrice {
+		return nil
+	}
+
+	return p.repo.UpdateDeliveryPrice(orderId, deliveryPrice)
+}
+
+func (p *deliveryPricer) repriceUserOrders(userId int) error {
+	orders, err := p.repo.GetUnpaidUserOrders(userId)
+	if err != nil {
+		return err
+	}
+
+	for _, order := range orders {
+		if err := p.reprice(order.Id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+)
+
+func TestDeliveryPricing_Price(t *testing.T) {
+	pricing := DeliveryPricing{BaseFee: 99, PerKm: 20, FreeThreshold: 1500}
+
+	tests := []struct {
+		name       string
+		pricing    DeliveryPricing
+		distance   float64
+		itemsPrice int
+		price      int
+	}{
+		{"next door", pricing, 0, 500, 99},
+		{"partial km is rounded up", pricing, 2.01, 500, 99 + 41},
+		{"free over the threshold", pricing, 10, 1500, 0},
+		{"just under the threshold", pricing, 10, 1499, 99 + 200},
+		{"no threshold", DeliveryPricing{BaseFee: 50}, 3, 100000, 50},
+		{"zero tariff", DeliveryPricing{}, 3, 100, 0},
+	}
+
+	for _, tt := range tests {
+		if price := tt.pricing.Price(tt.distance, tt.itemsPrice); price != tt.price {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.price, price)
+		}
+	}
+}
+
+func TestDeliveryPricing_CheckMinOrder(t *testing.T) {
+	pricing := DeliveryPricing{MinOrderAmount: 300}
+
+	if err := pricing.CheckMinOrder(299); domain.ErrorCode(err) != domain.CodeValidation {
+		t.Errorf("expected a validation error under the minimum, got %v", err)
+	}
+
+	if err := pricing.CheckMinOrder(300); err != nil {
+		t.Errorf("expected no error at the minimum, got %v", err)
+	}
+
+	if err := (DeliveryPricing{}).CheckMinOrder(0); err != nil {
+		t.Errorf("expected no minimum by default, got %v", err)
+	}
+}
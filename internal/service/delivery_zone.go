@@ -0,0 +1,51 @@
+package service
+
+import (
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/MAVIKE/yad-backend/internal/repository"
+)
+
+const (
+	maxZoneRadius = 100
+	maxZonePoints = 100
+)
+
+// checkDeliveryZone returns an error if the restaurant doesn't deliver to
+// the address of the user.
+func checkDeliveryZone(repo repository.Restaurant, restaurantId, userId int) error {
+	delivers, err := repo.DeliversTo(restaurantId, userId)
+	if err != nil {
+		return err
+	}
+
+	if !delivers {
+		return domain.NewOutsideDeliveryZoneError("Restaurant doesn't deliver to your address")
+	}
+
+	return nil
+}
+
+// validateDeliveryZone checks the zone before it replaces the one of the
+// restaurant. A zone is either a radius or a polygon, an empty zone lets
+// the restaurant deliver anywhere.
+func validateDeliveryZone(zone *domain.DeliveryZone) error {
+	if zone.Radius != nil && len(zone.Polygon) > 0 {
+		return domain.NewValidationError("Delivery zone is either a radius or a polygon")
+	}
+
+	if zone.Radius != nil && (*zone.Radius <= 0 || *zone.Radius > maxZoneRadius) {
+		return domain.NewValidationError("Delivery radius must be greater than 0 and at most %d km", maxZoneRadius)
+	}
+
+	if len(zone.Polygon) > 0 && (len(zone.Polygon) < 3 || len(zone.Polygon) > maxZonePoints) {
+		return domain.NewValidationError("Delivery polygon must have from 3 to %d points", maxZonePoints)
+	}
+
+	for _, point := range zone.Polygon {
+		if point == nil || point.Latitude < -90 || point.Latitude > 90 || point.Longitude < -180 || point.Longitude > 180 {
+			return domain.NewValidationError("Invalid delivery polygon point")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+)
+
+func TestValidateDeliveryZone(t *testing.T) {
+	radius := func(km float64) *float64 {
+		return &km
+	}
+
+	triangle := []*domain.Location{
+		{Latitude: 55, Longitude: 82},
+		{Latitude: 55, Longitude: 83},
+		{Latitude: 56, Longitude: 83},
+	}
+
+	tests := []struct {
+		name  string
+		zone  *domain.DeliveryZone
+		valid bool
+	}{
+		{"anywhere", &domain.DeliveryZone{}, true},
+		{"radius", &domain.DeliveryZone{Radius: radius(7.5)}, true},
+		{"polygon", &domain.DeliveryZone{Polygon: triangle}, true},
+		{"radius and polygon", &domain.DeliveryZone{Radius: radius(7.5), Polygon: triangle}, false},
+		{"zero radius", &domain.DeliveryZone{Radius: radius(0)}, false},
+		{"too wide", &domain.DeliveryZone{Radius: radius(maxZoneRadius + 1)}, false},
+		{"segment", &domain.DeliveryZone{Polygon: triangle[:2]}, false},
+		{"point off the globe", &domain.DeliveryZone{Polygon: append([]*domain.Location{{Latitude: 91}}, triangle...)}, false},
+	}
+
+	for _, tt := range tests {
+		err := validateDeliveryZone(tt.zone)
+		if tt.valid && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+
+		if !tt.valid && domain.ErrorCode(err) != domain.CodeValidation {
+			t.Errorf("%s: expected a validation error, got %v", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/MAVIKE/yad-backend/internal/consts"
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/MAVIKE/yad-backend/internal/repository"
+)
+
+// DispatchService finds couriers for paid orders. Orders wait in a queue,
+// oldest first, and are offered to the nearest waiting courier one at a time.
+// An offer the courier declines or leaves unanswered for offerTimeout goes to
+// the next courier; the order may come back to that courier after
+// offerCooldown, once the others have had their chance. Orders waiting longer than escalateAfter are escalated to
+// admins, who get an EventOrderEscalated and see them in the dispatch queue.
+type DispatchService struct {
+	repo          repository.Dispatch
+	orderRepo     repository.Order
+	offerTimeout  time.Duration
+	offerCooldown time.Duration
+	escalateAfter time.Duration
+	wake          chan struct{}
+}
+
+func NewDispatchService(repo repository.Dispatch, orderRepo repository.Order,
+	offerTimeout, offerCooldown, escalateAfter time.Duration) *DispatchService {
+	return &DispatchService{
+		repo:          repo,
+		orderRepo:     orderRepo,
+		offerTimeout:  offerTimeout,
+		offerCooldown: offerCooldown,
+		escalateAfter: escalateAfter,
+		wake:          make(chan struct{}, 1),
+	}
+}
+
+// Run offers queued orders every interval and whenever Notify is called,
+// until the context is done.
+func (s *DispatchService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.AssignPending(); err != nil {
+			log.Printf("dispatch: %s", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// Notify wakes the worker up, e.g. when an order is paid or a courier
+// becomes free or declines an offer.
+func (s *DispatchService) Notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *DispatchService) AssignPending() error {
+	if err := s.repo.ExpireOffers(); err != nil {
+		return err
+	}
+
+	orders, err := s.repo.GetPending()
+	if err != nil {
+		return err
+	}
+
+	for _, order := range orders {
+		if !awaitsCourier(order.Status) {
+			if err := s.repo.Dequeue(order.OrderId); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !order.Offered {
+			if err := s.offer(order.OrderId); err != nil {
+				return err
+			}
+		}
+
+		if err := s.escalate(order); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *DispatchService) GetPending(clientId int, clientType string) ([]*domain.PendingOrder, error) {
+	if err := authorize(clientId, clientType, resourceDispatch, actionList, target{}); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetPending()
+}
+
+func (s *DispatchService) GetOffers(clientId int, clientType string, courierId int) ([]*domain.CourierOffer, error) {
+	if err := authorize(clientId, clientType, resourceCourierOffer, actionList, ownedBy(courierType, courierId)); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetOffers(courierId)
+}
+
+func (s *DispatchService) AcceptOffer(clientId int, clientType string, courierId, offerId int) error {
+	offer, err := s.getOffer(clientId, clientType, courierId, offerId)
+	if err != nil {
+		return err
+	}
+
+	assigned, err := s.orderEvent(domain.EventCourierAssigned, offer.OrderId, courierId)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.AcceptOffer(offerId, assigned,
+		domain.NewCourierEvent(domain.EventCourierStatusChanged, courierId, consts.CourierWorking))
+}
+
+func (s *DispatchService) DeclineOffer(clientId int, clientType string, courierId, offerId int) error {
+	if _, err := s.getOffer(clientId, clientType, courierId, offerId); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeclineOffer(offerId); err != nil {
+		return err
+	}
+
+	s.Notify()
+
+	return nil
+}
+
+func (s *DispatchService) getOffer(clientId int, clientType string, courierId, offerId int) (*domain.CourierOffer, error) {
+	if err := authorize(clientId, clientType, resourceCourierOffer, actionUpdate, ownedBy(courierType, courierId)); err != nil {
+		return nil, err
+	}
+
+	offer, err := s.repo.GetOfferById(offerId)
+	if err != nil {
+		return nil, err
+	}
+
+	if offer.CourierId != courierId {
+		return nil, domain.NewNotFoundError("Offer not found")
+	}
+
+	return offer, nil
+}
+
+// offer sends the order to the nearest courier who hasn't turned it down
+// within offerCooldown.
+func (s *DispatchService) offer(orderId int) error {
+	courierId, err := s.repo.GetNearestCourierId(orderId, time.Now().Add(-s.offerCooldown))
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	offered, err := s.orderEvent(domain.EventOrderOffered, orderId, courierId)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.repo.CreateOffer(orderId, courierId, time.Now().Add(s.offerTimeout), offered)
+	if err != nil {
+		// another instance has just offered the order
+		if errors.Is(err, domain.ErrConflict) {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// orderEvent returns the event of the order concerning the courier.
+func (s *DispatchService) orderEvent(eventType string, orderId, courierId int) (*domain.Event, error) {
+	order, err := s.orderRepo.GetById(orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	order.CourierId = courierId
+
+	return domain.NewEvent(eventType, order), nil
+}
+
+func (s *DispatchService) escalate(order *domain.PendingOrder) error {
+	if s.escalateAfter == 0 || order.EscalatedAt != nil || time.Since(order.QueuedAt) < s.escalateAfter {
+		return nil
+	}
+
+	log.Printf("dispatch: order %d has been waiting for a courier since %s",
+		order.OrderId, order.QueuedAt.Format(time.RFC3339))
+
+	escalated, err := s.orderEvent(domain.EventOrderEscalated, order.OrderId, 0)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.Escalate(order.OrderId, escalated)
+}
+
+// awaitsCourier reports whether a paid order may still get a courier.
+// Cancelled and rejected orders are dropped from the queue.
+func awaitsCourier(status int) bool {
+	switch status {
+	case consts.OrderPaid, consts.OrderPreparing, consts.OrderWaitingForCourier:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/MAVIKE/yad-backend/internal/repository"
+	"github.com/MAVIKE/yad-backend/pkg/auth"
+)
+
+// eventTicketTTL is how long a ticket waits to open the event stream.
+const eventTicketTTL = 30 * time.Second
+
+var errInvalidEventTicket = domain.NewUnauthorizedError("Invalid ticket")
+
+type EventTicketService struct {
+	repo         repository.EventTicket
+	tokenManager auth.TokenManager
+}
+
+func NewEventTicketService(repo repository.EventTicket, tokenManager auth.TokenManager) *EventTicketService {
+	return &EventTicketService{
+		repo:         repo,
+		tokenManager: tokenManager,
+	}
+}
+
+// Create issues a ticket for the access token of the claims. Only the hash
+// of the ticket is stored, like a refresh token.
+func (s *EventTicketService) Create(claims *auth.Claims) (*domain.EventTicket, error) {
+	ticket, err := s.tokenManager.NewRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(eventTicketTTL)
+	if claims.ExpiresAt.Before(expiresAt) {
+		expiresAt = claims.ExpiresAt
+	}
+
+	eventTicket := &domain.EventTicket{
+		Ticket:         hashRefreshToken(ticket),
+		ClientId:       claims.ClientId,
+		ClientType:     claims.ClientType,
+		TokenId:        claims.TokenId,
+		TokenIssuedAt:  claims.IssuedAt,
+		TokenExpiresAt: claims.ExpiresAt,
+		ExpiresAt:      expiresAt,
+	}
+
+	if err := s.repo.Create(eventTicket); err != nil {
+		return nil, err
+	}
+
+	eventTicket.Ticket = ticket
+	return eventTicket, nil
+}
+
+// Redeem uses up the ticket and returns the claims of the access token it
+// was issued for.
+func (s *EventTicketService) Redeem(ticket string) (*auth.Claims, error) {
+	eventTicket, err := s.repo.Take(hashRefreshToken(ticket))
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, errInvalidEventTicket
+		}
+		return nil, err
+	}
+
+	if !eventTicket.ExpiresAt.After(time.Now()) {
+		return nil, errInvalidEventTicket
+	}
+
+	return &auth.Claims{
+		ClientId:   eventTicket.ClientId,
+		ClientType: eventTicket.ClientType,
+		TokenId:    eventTicket.TokenId,
+		IssuedAt:   eventTicket.TokenIssuedAt,
+		ExpiresAt:  eventTicket.TokenExpiresAt,
+	}, nil
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/MAVIKE/yad-backend/internal/repository"
+)
+
+// subscriberBuffer is how many events a slow subscriber may lag behind
+// before new events are dropped for it.
+const subscriberBuffer = 16
+
+// eventAudience lists the roles every event type is pushed to. Within a role
+// only the client the order belongs to gets the event, except for admins,
+// who get every event addressed to them.
+var eventAudience = map[string][]string{
+	domain.EventOrderStatusChanged: {userType, restaurantType, courierType},
+	domain.EventOrderIncoming:      {restaurantType},
+	domain.EventOrderOffered:       {courierType},
+	domain.EventCourierAssigned:    {userType, restaurantType, courierType},
+	domain.EventOrderEscalated:     {adminType},
+}
+
+type subscriber struct {
+	clientId   int
+	clientType string
+	events     chan *domain.Event
+}
+
+// EventBus is an in-process publish/subscribe hub for order events. Publish
+// never blocks: a subscriber whose buffer is full misses the event.
+type EventBus struct {
+	listener    repository.EventListener
+	mu          sync.RWMutex
+	subscribers map[*subscriber]struct{}
+}
+
+func NewEventBus(listener repository.EventListener) *EventBus {
+	return &EventBus{
+		listener:    listener,
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+// Subscribe returns the events for the client and a function that
+// cancels the subscription and closes the channel.
+func (b *EventBus) Subscribe(clientId int, clientType string) (<-chan *domain.Event, func()) {
+	sub := &subscriber{
+		clientId:   clientId,
+		clientType: clientType,
+		events:     make(chan *domain.Event, subscriberBuffer),
+	}
+
+	b.mu.Lock()
+	b.subscribers[sub] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, sub)
+			b.mu.Unlock()
+			close(sub.events)
+		})
+	}
+
+	return sub.events, cancel
+}
+
+func (b *EventBus) Publish(event *domain.Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub := range b.subscribers {
+		if !addressedTo(event, sub.clientId, sub.clientType) {
+			continue
+		}
+
+		select {
+		case sub.events <- event:
+		default:
+		}
+	}
+}
+
+// Run publishes the events committed by any instance, as they are written
+// to the outbox, until the context is done.
+func (b *EventBus) Run(ctx context.Context) {
+	for {
+		err := b.listener.Listen(ctx, b.Publish)
+		if err == nil {
+			return
+		}
+		log.Printf("events: %s", err.Error())
+	}
+}
+
+func addressedTo(event *domain.Event, clientId int, clientType string) bool {
+	t := target{
+		UserId:       event.UserId,
+		RestaurantId: event.RestaurantId,
+		CourierId:    event.CourierId,
+	}
+
+	for _, role := range eventAudience[event.Type] {
+		if role == clientType && (clientType == adminType || owner(clientId, clientType, t)) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+)
+
+func TestEventBus_Audience(t *testing.T) {
+	order := &domain.Order{Id: 1, UserId: ownClientId, RestaurantId: ownClientId, CourierId: ownClientId}
+
+	tests := []struct {
+		eventType string
+		roles     []string
+	}{
+		{domain.EventOrderStatusChanged, []string{userType, restaurantType, courierType}},
+		{domain.EventOrderIncoming, []string{restaurantType}},
+		{domain.EventOrderOffered, []string{courierType}},
+		{domain.EventCourierAssigned, []string{userType, restaurantType, courierType}},
+		{domain.EventOrderEscalated, []string{adminType}},
+	}
+
+	for _, tt := range tests {
+		bus := NewEventBus(nil)
+
+		own := make(map[string]<-chan *domain.Event)
+		foreign := make(map[string]<-chan *domain.Event)
+		for _, role := range roles {
+			events, cancel := bus.Subscribe(ownClientId, role)
+			defer cancel()
+			own[role] = events
+
+			events, cancel = bus.Subscribe(foreignClientId, role)
+			defer cancel()
+			foreign[role] = events
+		}
+
+		bus.Publish(domain.NewEvent(tt.eventType, order))
+
+		for _, role := range roles {
+			if got := len(own[role]) == 1; got != contains(tt.roles, role) {
+				t.Errorf("%s: %s of the order received the event: %v", tt.eventType, role, got)
+			}
+			if role != adminType && len(foreign[role]) != 0 {
+				t.Errorf("%s: %s of another order must not receive the event", tt.eventType, role)
+			}
+		}
+	}
+}
+
+func TestEventBus_SlowSubscriber(t *testing.T) {
+	bus := NewEventBus(nil)
+	order := &domain.Order{Id: 1, UserId: ownClientId}
+
+	events, cancel := bus.Subscribe(ownClientId, userType)
+
+	for i := 0; i < subscriberBuffer+1; i++ {
+		bus.Publish(domain.NewEvent(domain.EventOrderStatusChanged, order))
+	}
+
+	if len(events) != subscriberBuffer {
+		t.Errorf("expected %d buffered events, got %d", subscriberBuffer, len(events))
+	}
+
+	cancel()
+	cancel()
+
+	bus.Publish(domain.NewEvent(domain.EventOrderStatusChanged, order))
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"github.com/MAVIKE/yad-backend/internal/consts"
+	"github.com/MAVIKE/yad-backend/internal/domain"
+)
+
+// validateListParams checks the page size. The sort and the cursor are
+// checked by the repository, which knows the list.
+func validateListParams(params *domain.ListParams) error {
+	if params.Limit < 1 || params.Limit > domain.MaxListLimit {
+		return domain.NewValidationError("Limit must be from 1 to %d", domain.MaxListLimit)
+	}
+
+	return nil
+}
+
+func validateRestaurantFilter(filter *domain.RestaurantFilter) error {
+	switch filter.WorkingStatus {
+	case 0, consts.RestaurantUnable, consts.RestaurantWorking:
+	default:
+		return domain.NewValidationError("Invalid working status %d", filter.WorkingStatus)
+	}
+
+	return validateListParams(&filter.ListParams)
+}
+
+func validateMenuFilter(filter *domain.MenuFilter) error {
+	if filter.MinPrice < 0 || filter.MaxPrice < 0 {
+		return domain.NewValidationError("Price must not be negative")
+	}
+
+	if filter.MaxPrice != 0 && filter.MaxPrice < filter.MinPrice {
+		return domain.NewValidationError("Max price must not be less than min price")
+	}
+
+	return validateListParams(&filter.ListParams)
+}
+
+func validateOrderFilter(filter *domain.OrderFilter) error {
+	for _, status := range filter.Statuses {
+		if status < consts.OrderCreated || status > consts.OrderRefunded {
+			return domain.NewValidationError("Invalid order status %d", status)
+		}
+	}
+
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return domain.NewValidationError("Date range must end after it starts")
+	}
+
+	return validateListParams(&filter.ListParams)
+}
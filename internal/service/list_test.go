@@ -0,0 +1,67 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+)
+
+func TestValidateOrderFilter(t *testing.T) {
+	page := domain.ListParams{Limit: domain.DefaultListLimit}
+	from := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 1)
+
+	tests := []struct {
+		name   string
+		filter *domain.OrderFilter
+		valid  bool
+	}{
+		{"no filter", &domain.OrderFilter{ListParams: page}, true},
+		{"statuses and dates", &domain.OrderFilter{ListParams: page, Statuses: []int{0, 9}, From: &from, To: &to}, true},
+		{"open date range", &domain.OrderFilter{ListParams: page, From: &to}, true},
+		{"no limit", &domain.OrderFilter{}, false},
+		{"limit too big", &domain.OrderFilter{ListParams: domain.ListParams{Limit: domain.MaxListLimit + 1}}, false},
+		{"unknown status", &domain.OrderFilter{ListParams: page, Statuses: []int{10}}, false},
+		{"empty date range", &domain.OrderFilter{ListParams: page, From: &from, To: &from}, false},
+		{"inverted date range", &domain.OrderFilter{ListParams: page, From: &to, To: &from}, false},
+	}
+
+	for _, tt := range tests {
+		err := validateOrderFilter(tt.filter)
+		if tt.valid && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+
+		if !tt.valid && domain.ErrorCode(err) != domain.CodeValidation {
+			t.Errorf("%s: expected a validation error, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestValidateMenuFilter(t *testing.T) {
+	page := domain.ListParams{Limit: domain.DefaultListLimit}
+
+	tests := []struct {
+		name   string
+		filter *domain.MenuFilter
+		valid  bool
+	}{
+		{"no filter", &domain.MenuFilter{ListParams: page}, true},
+		{"price range", &domain.MenuFilter{ListParams: page, MinPrice: 100, MaxPrice: 100}, true},
+		{"min price only", &domain.MenuFilter{ListParams: page, MinPrice: 100}, true},
+		{"negative price", &domain.MenuFilter{ListParams: page, MinPrice: -1}, false},
+		{"inverted price range", &domain.MenuFilter{ListParams: page, MinPrice: 200, MaxPrice: 100}, false},
+	}
+
+	for _, tt := range tests {
+		err := validateMenuFilter(tt.filter)
+		if tt.valid && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+
+		if !tt.valid && domain.ErrorCode(err) != domain.CodeValidation {
+			t.Errorf("%s: expected a validation error, got %v", tt.name, err)
+		}
+	}
+}
@@ -1,9 +1,6 @@
 package service
 
 import (
-	"errors"
-	"fmt"
-
 	"github.com/MAVIKE/yad-backend/internal/domain"
 	"github.com/MAVIKE/yad-backend/internal/repository"
 )
@@ -20,17 +17,21 @@ func NewMenuItemService(repo repository.MenuItem, categoryRepo repository.Catego
 	}
 }
 
-func (s *RestaurantService) GetMenu(clientId int, clientType string, restaurantId int) ([]*domain.MenuItem, error) {
-	if !(clientType == userType || clientType == restaurantType && restaurantId == clientId) {
-		return nil, errors.New("Forbidden")
+func (s *RestaurantService) GetMenu(clientId int, clientType string, restaurantId int, filter *domain.MenuFilter) (*domain.MenuPage, error) {
+	if err := authorize(clientId, clientType, resourceMenuItem, actionList, ownedBy(restaurantType, restaurantId)); err != nil {
+		return nil, err
+	}
+
+	if err := validateMenuFilter(filter); err != nil {
+		return nil, err
 	}
 
-	return s.repo.GetMenu(restaurantId)
+	return s.repo.GetMenu(restaurantId, filter)
 }
 
 func (s *MenuItemService) GetById(clientId int, clientType string, menuItemId int, restaurantId int) (*domain.MenuItem, error) {
-	if !(clientType == userType || clientType == restaurantType && restaurantId == clientId) {
-		return nil, errors.New("Forbidden")
+	if err := authorize(clientId, clientType, resourceMenuItem, actionRead, ownedBy(restaurantType, restaurantId)); err != nil {
+		return nil, err
 	}
 
 	menuItem, err := s.repo.GetById(menuItemId)
@@ -40,36 +41,40 @@ func (s *MenuItemService) GetById(clientId int, clientType string, menuItemId in
 	}
 
 	if menuItem.RestaurantId != restaurantId {
-		return nil, errors.New("No such menu item for this restaurant")
+		return nil, domain.NewNotFoundError("No such menu item for this restaurant")
 	}
 
 	return menuItem, nil
 }
 
 func (s *MenuItemService) UpdateMenuItem(clientId int, clientType string, restaurantId int, menuItemId int, categoryId int, input *domain.MenuItem) error {
-	if !(clientType == restaurantType && restaurantId == clientId) {
-		return errors.New("forbidden")
+	if err := authorize(clientId, clientType, resourceMenuItem, actionUpdate, ownedBy(restaurantType, restaurantId)); err != nil {
+		return err
 	}
 
 	return s.repo.UpdateMenuItem(restaurantId, menuItemId, categoryId, input)
 }
 
 func (s *MenuItemService) Create(clientId int, clientType string, menuItem *domain.MenuItem, categoryId int) (int, error) {
-	if !(clientType == restaurantType && menuItem.RestaurantId == clientId) {
-		return 0, errors.New("forbidden")
+	if err := authorize(clientId, clientType, resourceMenuItem, actionCreate, ownedBy(restaurantType, menuItem.RestaurantId)); err != nil {
+		return 0, err
 	}
 
 	category, err := s.categoryRepo.GetById(categoryId)
-	if clientId != category.RestaurantId || err != nil {
-		return 0, errors.New("forbidden")
+	if err != nil {
+		return 0, err
+	}
+
+	if category.RestaurantId != menuItem.RestaurantId {
+		return 0, domain.NewNotFoundError("no such category for this restaurant")
 	}
 
 	return s.repo.Create(menuItem, categoryId)
 }
 
 func (s *MenuItemService) UpdateImage(clientId int, clientType string, restaurantId int, menuItemId int, image string) (*domain.MenuItem, error) {
-	if clientType != restaurantType || restaurantId != clientId {
-		return nil, errors.New("Forbidden")
+	if err := authorize(clientId, clientType, resourceMenuItem, actionUpdate, ownedBy(restaurantType, restaurantId)); err != nil {
+		return nil, err
 	}
 
 	menuItem, err := s.repo.GetById(menuItemId)
@@ -77,7 +82,7 @@ func (s *MenuItemService) UpdateImage(clientId int, clientType string, restauran
 		return nil, err
 	}
 	if menuItem.RestaurantId != restaurantId {
-		return nil, errors.New("No such menu item for this restaurant")
+		return nil, domain.NewNotFoundError("No such menu item for this restaurant")
 	}
 
 	if err := s.repo.UpdateImage(menuItemId, image); err != nil {
@@ -88,14 +93,17 @@ func (s *MenuItemService) UpdateImage(clientId int, clientType string, restauran
 }
 
 func (s *MenuItemService) Delete(clientId int, clientType string, restaurantId int, menuItemId int) error {
+	if err := authorize(clientId, clientType, resourceMenuItem, actionDelete, ownedBy(restaurantType, restaurantId)); err != nil {
+		return err
+	}
+
 	menuItem, err := s.repo.GetById(menuItemId)
 	if err != nil {
 		return err
 	}
 
-	if !(clientType == restaurantType && menuItem.RestaurantId == clientId && restaurantId == clientId) {
-		errMessage := fmt.Sprintf("Forbidden for %s", clientType)
-		return errors.New(errMessage)
+	if menuItem.RestaurantId != restaurantId {
+		return domain.NewNotFoundError("No such menu item for this restaurant")
 	}
 
 	// TODO : добавить проверку на то, что данное блюдо есть в активных заказах
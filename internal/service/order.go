@@ -1,10 +1,7 @@
 package service
 
 import (
-	"database/sql"
 	"errors"
-	"fmt"
-	"time"
 
 	"github.com/MAVIKE/yad-backend/internal/consts"
 	"github.com/MAVIKE/yad-backend/internal/domain"
@@ -12,43 +9,65 @@ import (
 )
 
 type OrderService struct {
-	repo repository.Order
+	repo           repository.Order
+	menuRepo       repository.MenuItem
+	restaurantRepo repository.Restaurant
+	pricer         *deliveryPricer
+	refunds        Refund
+	hours          *openingHours
 }
 
-func NewOrderService(repo repository.Order) *OrderService {
+func NewOrderService(repo repository.Order, menuRepo repository.MenuItem, restaurantRepo repository.Restaurant,
+	pricing DeliveryPricing, refunds Refund) *OrderService {
 	return &OrderService{
-		repo: repo,
+		repo:           repo,
+		menuRepo:       menuRepo,
+		restaurantRepo: restaurantRepo,
+		pricer:         &deliveryPricer{repo: repo, pricing: pricing},
+		refunds:        refunds,
+		hours:          &openingHours{repo: restaurantRepo},
 	}
 }
 
 func (s *OrderService) Create(clientId int, clientType string, order *domain.Order) (int, error) {
-	if clientType != userType {
-		return 0, errors.New("Forbidden")
+	if err := authorize(clientId, clientType, resourceOrder, actionCreate, target{}); err != nil {
+		return 0, err
 	}
 
 	order.UserId = clientId
-	// TODO: установить статус
-	// TODO: вычислить и установить стоимость доставки
+	order.Status = consts.OrderCreated
 
-	return s.repo.Create(order)
-}
+	if err := s.hours.check(order.RestaurantId); err != nil {
+		return 0, err
+	}
 
-func (s *OrderService) GetAllItems(clientId int, clientType string, orderId int) ([]*domain.OrderItem, error) {
-	order, err := s.repo.GetById(orderId)
+	deliveryPrice, err := s.pricer.price(order)
 	if err != nil {
-		return nil, err
+		if errors.Is(err, domain.ErrNotFound) {
+			return 0, domain.NewValidationError("Invalid restaurantId")
+		}
+		return 0, err
 	}
+	order.DeliveryPrice = deliveryPrice
+	order.TotalPrice = deliveryPrice
 
-	if !(clientType == userType && order.UserId == clientId ||
-		clientType == restaurantType && order.RestaurantId == clientId ||
-		clientType == courierType && order.CourierId == clientId) {
-		errMessage := fmt.Sprintf("Forbidden for %s", clientType)
-		return nil, errors.New(errMessage)
+	if err := checkDeliveryZone(s.restaurantRepo, order.RestaurantId, order.UserId); err != nil {
+		return 0, err
 	}
 
-	items, err := s.repo.GetAllItems(orderId)
+	return s.repo.Create(order, &domain.OrderEvent{
+		ActorId:   clientId,
+		ActorType: clientType,
+		NewStatus: order.Status,
+	}, domain.NewEvent(domain.EventOrderCreated, order))
+}
 
-	return items, err
+func (s *OrderService) GetAllItems(clientId int, clientType string, orderId int) ([]*domain.OrderItem, error) {
+	if _, err := s.getItemsOrder(clientId, clientType, actionList, orderId); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetAllItems(orderId)
 }
 
 func (s *OrderService) GetById(clientId int, clientType string, orderId int) (*domain.Order, error) {
@@ -58,11 +77,8 @@ func (s *OrderService) GetById(clientId int, clientType string, orderId int) (*d
 		return nil, err
 	}
 
-	if !(clientType == userType && order.UserId == clientId ||
-		clientType == restaurantType && order.RestaurantId == clientId ||
-		clientType == courierType && order.CourierId == clientId) {
-		errMessage := fmt.Sprintf("Forbidden for %s", clientType)
-		return nil, errors.New(errMessage)
+	if err := authorize(clientId, clientType, resourceOrder, actionRead, orderTarget(order)); err != nil {
+		return nil, err
 	}
 
 	return order, nil
@@ -71,18 +87,18 @@ func (s *OrderService) GetById(clientId int, clientType string, orderId int) (*d
 func (s *OrderService) Delete(clientId int, clientType string, orderId int) error {
 	order, err := s.repo.GetById(orderId)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return errors.New("Order not found")
+		if errors.Is(err, domain.ErrNotFound) {
+			return domain.NewNotFoundError("Order not found")
 		}
 		return err
 	}
 
-	if !(clientType == userType && order.UserId == clientId) {
-		return errors.New("Forbidden")
+	if err := authorize(clientId, clientType, resourceOrder, actionDelete, orderTarget(order)); err != nil {
+		return err
 	}
 
 	if order.Status != consts.OrderCreated {
-		return errors.New("You can't delete a paid order")
+		return domain.NewConflictError("You can't delete a paid order")
 	}
 
 	return s.repo.Delete(orderId)
@@ -92,143 +108,285 @@ func (s *OrderService) Delete(clientId int, clientType string, orderId int) erro
 func (s *OrderService) Update(clientId int, clientType string, orderId int, input *domain.Order) error {
 	order, err := s.repo.GetById(orderId)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return errors.New("Order not found")
+		if errors.Is(err, domain.ErrNotFound) {
+			return domain.NewNotFoundError("Order not found")
 		}
 		return err
 	}
 
-	if (input.Status - order.Status) != 1 {
-		return errors.New("Invalid new order status")
+	if err := checkOrderTransition(clientId, clientType, order, input.Status); err != nil {
+		return err
 	}
 
-	switch input.Status {
-	case consts.OrderPaid:
-		if !(clientType == userType && order.UserId == clientId) {
-			errMessage := fmt.Sprintf("Forbidden for %s", clientType)
-			return errors.New(errMessage)
-		}
+	oldStatus := order.Status
+	order.Status = input.Status
 
-		curTime := time.Now()
-		input.Paid = &curTime
+	event := &domain.OrderEvent{
+		ActorId:   clientId,
+		ActorType: clientType,
+		OldStatus: &oldStatus,
+		NewStatus: input.Status,
+	}
 
-		courierId, err := s.repo.GetNearestCourierId(order.UserId)
-		if err != nil {
-			// TODO: свободного курьера может не быть - что делать?
-			if err == sql.ErrNoRows {
-				return errors.New("Free courier not found")
-			}
-			return err
-		}
-		input.CourierId = courierId
-	case consts.OrderPreparing, consts.OrderWaitingForCourier:
-		if !(clientType == restaurantType && order.RestaurantId == clientId) {
-			errMessage := fmt.Sprintf("Forbidden for %s", clientType)
-			return errors.New(errMessage)
-		}
-	case consts.OrderEnRoute, consts.OrderDelivered:
-		if !(clientType == courierType && order.CourierId == clientId) {
-			errMessage := fmt.Sprintf("Forbidden for %s", clientType)
-			return errors.New(errMessage)
-		}
-	default:
-		return errors.New("Order status input error")
+	// the money of a paid order goes back once it is cancelled or rejected
+	if isPaid(order) && (order.Status == consts.OrderCancelledByUser || order.Status == consts.OrderRejectedByRestaurant) {
+		return s.refunds.RefundOrder(order, event)
 	}
 
-	return s.repo.Update(orderId, input)
+	return s.repo.Update(orderId, input, event, orderStatusEvents(order)...)
 }
 
-func (s *OrderService) GetActiveRestaurantOrders(clientId int, clientType string, restaurantId int) ([]*domain.Order, error) {
-	if !(clientType == userType || clientType == restaurantType && restaurantId == clientId) {
-		return nil, errors.New("Forbidden")
-	}
+// orderStatusEvents returns the events of the order having moved to its
+// current status.
+func orderStatusEvents(order *domain.Order) []*domain.Event {
+	events := []*domain.Event{domain.NewEvent(domain.EventOrderStatusChanged, order)}
 
-	orders, err := s.repo.GetActiveRestaurantOrders(restaurantId)
+	switch order.Status {
+	case consts.OrderPaid:
+		events = append(events,
+			domain.NewEvent(domain.EventOrderIncoming, order),
+			domain.NewEvent(domain.EventOrderPaid, order))
+	case consts.OrderCancelledByUser:
+		events = append(events, domain.NewEvent(domain.EventOrderCancelled, order))
+	}
 
-	return orders, err
+	return events
 }
 
-func (s *OrderService) CreateItem(clientId int, clientType string, orderItem *domain.OrderItem) (int, error) {
-	if clientType != userType {
-		return 0, errors.New("Forbidden")
+func (s *OrderService) GetTimeline(clientId int, clientType string, orderId int) ([]*domain.OrderEvent, error) {
+	order, err := s.repo.GetById(orderId)
+	if err != nil {
+		return nil, err
 	}
 
-	if orderItem.Count < 1 || orderItem.Count > 99 {
-		return 0, errors.New("Menu items count must be greater than 0")
+	if err := authorize(clientId, clientType, resourceOrder, actionRead, orderTarget(order)); err != nil {
+		return nil, err
 	}
 
-	return s.repo.CreateItem(orderItem)
+	return s.repo.GetEvents(orderId)
 }
 
-func (s *OrderService) GetItemById(clientId int, clientType string, orderId, orderItemId int) (*domain.OrderItem, error) {
+// GetReceipt itemizes the order with the prices the items had when they
+// were added.
+func (s *OrderService) GetReceipt(clientId int, clientType string, orderId int) (*domain.Receipt, error) {
 	order, err := s.repo.GetById(orderId)
 	if err != nil {
 		return nil, err
 	}
 
-	if !(clientType == userType && order.UserId == clientId ||
-		clientType == restaurantType && order.RestaurantId == clientId ||
-		clientType == courierType && order.CourierId == clientId) {
-		errMessage := fmt.Sprintf("Forbidden for %s", clientType)
-		return nil, errors.New(errMessage)
+	if err := authorize(clientId, clientType, resourceOrder, actionRead, orderTarget(order)); err != nil {
+		return nil, err
 	}
 
-	orderItem, err := s.repo.GetItemById(orderItemId)
+	items, err := s.repo.GetAllItems(orderId)
 	if err != nil {
 		return nil, err
 	}
 
-	if orderItem.OrderId != orderId {
-		return nil, errors.New("no such orderItem for this order")
+	receipt := &domain.Receipt{
+		OrderId:       order.Id,
+		Items:         make([]*domain.ReceiptLine, 0, len(items)),
+		DeliveryPrice: order.DeliveryPrice,
+		Discount:      order.Discount,
+		TotalPrice:    order.TotalPrice,
+	}
+
+	for _, item := range items {
+		line := &domain.ReceiptLine{
+			MenuItemId: item.MenuItemId,
+			Title:      item.Title,
+			Price:      item.Price,
+			Count:      item.Count,
+			Amount:     item.Price * item.Count,
+		}
+		receipt.Items = append(receipt.Items, line)
+		receipt.ItemsPrice += line.Amount
 	}
 
-	return orderItem, err
+	return receipt, nil
 }
 
-func (s *OrderService) UpdateItem(clientId int, clientType string, orderId, orderItemId, menuItemsCount int) error {
+func (s *OrderService) GetTransitions(clientId int, clientType string, orderId int) ([]*domain.OrderTransition, error) {
 	order, err := s.repo.GetById(orderId)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if !(clientType == userType && order.UserId == clientId) {
-		return errors.New("Forbidden")
+	if err := authorize(clientId, clientType, resourceOrder, actionRead, orderTarget(order)); err != nil {
+		return nil, err
 	}
 
-	orderItem, err := s.repo.GetItemById(orderItemId)
+	return allowedOrderTransitions(clientId, clientType, order), nil
+}
+
+// GetRestaurantOrders returns a page of the orders of the restaurant, the
+// paid orders waiting for it unless the filter asks for other statuses.
+func (s *OrderService) GetRestaurantOrders(clientId int, clientType string, restaurantId int, filter *domain.OrderFilter) (*domain.OrderPage, error) {
+	if err := authorize(clientId, clientType, resourceOrder, actionList, ownedBy(restaurantType, restaurantId)); err != nil {
+		return nil, err
+	}
+
+	if err := validateOrderFilter(filter); err != nil {
+		return nil, err
+	}
+
+	if len(filter.Statuses) == 0 {
+		filter.Statuses = []int{consts.OrderPaid}
+	}
+	filter.RestaurantId = restaurantId
+
+	return s.repo.GetAll(filter)
+}
+
+func (s *OrderService) CreateItem(clientId int, clientType string, orderItem *domain.OrderItem) (int, error) {
+	order, err := s.getItemsOrder(clientId, clientType, actionCreate, orderItem.OrderId)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := checkItemsEditable(order); err != nil {
+		return 0, err
+	}
+
+	if orderItem.Count < 1 || orderItem.Count > 99 {
+		return 0, domain.NewValidationError("Menu items count must be greater than 0")
+	}
+
+	menuItem, err := s.menuRepo.GetById(orderItem.MenuItemId)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return 0, domain.NewValidationError("Invalid menuItemId")
+		}
+		return 0, err
+	}
+
+	if menuItem.RestaurantId != order.RestaurantId {
+		return 0, domain.NewForeignMenuItemError("Menu item %d is not on the menu of restaurant %d",
+			menuItem.Id, order.RestaurantId)
+	}
+
+	orderItemId, err := s.repo.CreateItem(orderItem)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return 0, domain.NewValidationError("Invalid menuItemId")
+		}
+		return 0, err
+	}
+
+	return orderItemId, s.pricer.reprice(order.Id)
+}
+
+func (s *OrderService) GetItemById(clientId int, clientType string, orderId, orderItemId int) (*domain.OrderItem, error) {
+	if _, err := s.getItemsOrder(clientId, clientType, actionRead, orderId); err != nil {
+		return nil, err
+	}
+
+	return s.getItem(orderId, orderItemId)
+}
+
+func (s *OrderService) UpdateItem(clientId int, clientType string, orderId, orderItemId, menuItemsCount int) error {
+	order, err := s.getItemsOrder(clientId, clientType, actionUpdate, orderId)
 	if err != nil {
 		return err
 	}
 
-	if orderItem.OrderId != orderId {
-		return errors.New("No such orderItem for this order")
+	if err := checkItemsEditable(order); err != nil {
+		return err
+	}
+
+	if _, err := s.getItem(orderId, orderItemId); err != nil {
+		return err
 	}
 
 	if menuItemsCount < 1 || menuItemsCount > 99 {
-		return errors.New("Menu items count must be greater than 0")
+		return domain.NewValidationError("Menu items count must be greater than 0")
+	}
+
+	if err := s.repo.UpdateItem(orderId, orderItemId, menuItemsCount); err != nil {
+		return err
 	}
 
-	return s.repo.UpdateItem(orderItemId, menuItemsCount)
+	return s.pricer.reprice(orderId)
 }
 
 func (s *OrderService) DeleteItem(clientId int, clientType string, orderId int, orderItemId int) error {
-	order, err := s.repo.GetById(orderId)
+	order, err := s.getItemsOrder(clientId, clientType, actionDelete, orderId)
 	if err != nil {
 		return err
 	}
 
-	if !(clientType == userType && order.UserId == clientId) {
-		errMessage := fmt.Sprintf("Forbidden for %s", clientType)
-		return errors.New(errMessage)
+	// the restaurant removes an item it can't make, the user gets its price back
+	if clientType == restaurantType {
+		if order.Status != consts.OrderPaid && order.Status != consts.OrderPreparing {
+			return domain.NewOrderNotEditableError("Order %d is %s, the restaurant can only remove items from a paid order",
+				order.Id, orderStatusNames[order.Status])
+		}
+
+		return s.refunds.RefundItem(clientId, clientType, order, orderItemId)
+	}
+
+	if err := checkItemsEditable(order); err != nil {
+		return err
+	}
+
+	if _, err := s.getItem(orderId, orderItemId); err != nil {
+		return err
 	}
 
-	return s.repo.DeleteItem(orderId, orderItemId)
+	if err := s.repo.DeleteItem(orderId, orderItemId); err != nil {
+		return err
+	}
+
+	return s.pricer.reprice(orderId)
+}
+
+// getItemsOrder returns the order whose items the client acts on.
+func (s *OrderService) getItemsOrder(clientId int, clientType string, act action, orderId int) (*domain.Order, error) {
+	order, err := s.repo.GetById(orderId)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, domain.NewNotFoundError("Order not found")
+		}
+		return nil, err
+	}
+
+	if err := authorize(clientId, clientType, resourceOrderItem, act, orderTarget(order)); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+func (s *OrderService) getItem(orderId, orderItemId int) (*domain.OrderItem, error) {
+	orderItem, err := s.repo.GetItemById(orderItemId)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, domain.NewNotFoundError("No such orderItem for this order")
+		}
+		return nil, err
+	}
+
+	if orderItem.OrderId != orderId {
+		return nil, domain.NewNotFoundError("No such orderItem for this order")
+	}
+
+	return orderItem, nil
+}
+
+// checkItemsEditable checks that the user may still change the items,
+// which is only until the order is paid.
+func checkItemsEditable(order *domain.Order) error {
+	if order.Status != consts.OrderCreated {
+		return domain.NewOrderNotEditableError("Order %d is %s, its items can only be changed before it is paid",
+			order.Id, orderStatusNames[order.Status])
+	}
+
+	return nil
 }
 
 func (s *OrderService) GetActiveCourierOrder(clientId int, clientType string, courierId int) (*domain.Order, error) {
-	if !(clientType == courierType && courierId == clientId) {
-		errMessage := fmt.Sprintf("Forbidden for %s", clientType)
-		return nil, errors.New(errMessage)
+	if err := authorize(clientId, clientType, resourceOrder, actionList, ownedBy(courierType, courierId)); err != nil {
+		return nil, err
 	}
 
 	return s.repo.GetActiveCourierOrder(courierId)
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"github.com/MAVIKE/yad-backend/internal/consts"
+	"github.com/MAVIKE/yad-backend/internal/domain"
+)
+
+var orderStatusNames = map[int]string{
+	consts.OrderCreated:              "created",
+	consts.OrderPaid:                 "paid",
+	consts.OrderPreparing:            "preparing",
+	consts.OrderWaitingForCourier:    "waiting_for_courier",
+	consts.OrderEnRoute:              "en_route",
+	consts.OrderDelivered:            "delivered",
+	consts.OrderCancelledByUser:      "cancelled_by_user",
+	consts.OrderRejectedByRestaurant: "rejected_by_restaurant",
+	consts.OrderDeliveryFailed:       "delivery_failed",
+	consts.OrderRefunded:             "refunded",
+}
+
+// orderTransition is an edge of the order state machine. The policy action
+// decides who may take it, the guard whether the order allows it right now.
+type orderTransition struct {
+	to     int
+	action action
+	guard  func(order *domain.Order) bool
+}
+
+var orderTransitions = map[int][]orderTransition{
+	consts.OrderCreated: {
+		{to: consts.OrderPaid, action: actionPay},
+		{to: consts.OrderCancelledByUser, action: actionCancel},
+	},
+	consts.OrderPaid: {
+		{to: consts.OrderPreparing, action: actionPrepare},
+		{to: consts.OrderCancelledByUser, action: actionCancel},
+		{to: consts.OrderRejectedByRestaurant, action: actionReject},
+	},
+	consts.OrderPreparing: {
+		{to: consts.OrderWaitingForCourier, action: actionPrepare},
+		{to: consts.OrderRejectedByRestaurant, action: actionReject},
+	},
+	consts.OrderWaitingForCourier: {
+		{to: consts.OrderEnRoute, action: actionDeliver},
+	},
+	consts.OrderEnRoute: {
+		{to: consts.OrderDelivered, action: actionDeliver},
+		{to: consts.OrderDeliveryFailed, action: actionDeliver},
+	},
+	consts.OrderCancelledByUser: {
+		{to: consts.OrderRefunded, action: actionRefund, guard: isPaid},
+	},
+	consts.OrderRejectedByRestaurant: {
+		{to: consts.OrderRefunded, action: actionRefund, guard: isPaid},
+	},
+	consts.OrderDeliveryFailed: {
+		{to: consts.OrderRefunded, action: actionRefund, guard: isPaid},
+	},
+}
+
+func isPaid(order *domain.Order) bool {
+	return order.Paid != nil
+}
+
+// checkOrderTransition checks that the order may be moved to status
+// and that the client is the one allowed to do it.
+func checkOrderTransition(clientId int, clientType string, order *domain.Order, status int) error {
+	if _, ok := orderStatusNames[status]; !ok {
+		return domain.NewValidationError("Order status input error")
+	}
+
+	for _, transition := range orderTransitions[order.Status] {
+		if transition.to != status {
+			continue
+		}
+
+		if transition.guard != nil && !transition.guard(order) {
+			break
+		}
+
+		return authorize(clientId, clientType, resourceOrder, transition.action, orderTarget(order))
+	}
+
+	return domain.NewInvalidStateTransitionError("order can't go from %s to %s",
+		orderStatusNames[order.Status], orderStatusNames[status])
+}
+
+// allowedOrderTransitions lists the statuses the client may move the order to.
+func allowedOrderTransitions(clientId int, clientType string, order *domain.Order) []*domain.OrderTransition {
+	transitions := make([]*domain.OrderTransition, 0)
+
+	for _, transition := range orderTransitions[order.Status] {
+		if transition.guard != nil && !transition.guard(order) {
+			continue
+		}
+
+		if authorize(clientId, clientType, resourceOrder, transition.action, orderTarget(order)) != nil {
+			continue
+		}
+
+		transitions = append(transitions, &domain.OrderTransition{
+			Status: transition.to,
+			Name:   orderStatusNames[transition.to],
+		})
+	}
+
+	return transitions
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MAVIKE/yad-backend/internal/consts"
+	"github.com/MAVIKE/yad-backend/internal/domain"
+)
+
+func TestOrderTransitions(t *testing.T) {
+	paid := time.Now()
+
+	tests := []struct {
+		name       string
+		from       int
+		to         int
+		clientType string
+		paid       *time.Time
+		err        string
+	}{
+		{"user can't mark paid", consts.OrderCreated, consts.OrderPaid, userType, nil, domain.CodeForbidden},
+		{"user cancels created", consts.OrderCreated, consts.OrderCancelledByUser, userType, nil, ""},
+		{"user cancels paid", consts.OrderPaid, consts.OrderCancelledByUser, userType, &paid, ""},
+		{"user can't cancel preparing", consts.OrderPreparing, consts.OrderCancelledByUser, userType, &paid, domain.CodeInvalidStateTransition},
+		{"restaurant prepares", consts.OrderPaid, consts.OrderPreparing, restaurantType, &paid, ""},
+		{"restaurant rejects paid", consts.OrderPaid, consts.OrderRejectedByRestaurant, restaurantType, &paid, ""},
+		{"restaurant rejects preparing", consts.OrderPreparing, consts.OrderRejectedByRestaurant, restaurantType, &paid, ""},
+		{"restaurant can't reject en route", consts.OrderEnRoute, consts.OrderRejectedByRestaurant, restaurantType, &paid, domain.CodeInvalidStateTransition},
+		{"restaurant can't cancel", consts.OrderPaid, consts.OrderCancelledByUser, restaurantType, &paid, domain.CodeForbidden},
+		{"courier picks up", consts.OrderWaitingForCourier, consts.OrderEnRoute, courierType, &paid, ""},
+		{"courier delivers", consts.OrderEnRoute, consts.OrderDelivered, courierType, &paid, ""},
+		{"courier fails delivery", consts.OrderEnRoute, consts.OrderDeliveryFailed, courierType, &paid, ""},
+		{"user can't mark delivered", consts.OrderEnRoute, consts.OrderDelivered, userType, &paid, domain.CodeForbidden},
+		{"admin can't refund failed delivery", consts.OrderDeliveryFailed, consts.OrderRefunded, adminType, &paid, domain.CodeForbidden},
+		{"admin can't refund rejected", consts.OrderRejectedByRestaurant, consts.OrderRefunded, adminType, &paid, domain.CodeForbidden},
+		{"unpaid order isn't refunded", consts.OrderCancelledByUser, consts.OrderRefunded, adminType, nil, domain.CodeInvalidStateTransition},
+		{"user can't refund", consts.OrderCancelledByUser, consts.OrderRefunded, userType, &paid, domain.CodeForbidden},
+		{"delivered is final", consts.OrderDelivered, consts.OrderRefunded, adminType, &paid, domain.CodeInvalidStateTransition},
+		{"no skipping", consts.OrderPaid, consts.OrderEnRoute, courierType, &paid, domain.CodeInvalidStateTransition},
+		{"no going back", consts.OrderEnRoute, consts.OrderPreparing, restaurantType, &paid, domain.CodeInvalidStateTransition},
+		{"unknown status", consts.OrderPaid, 42, userType, &paid, domain.CodeValidation},
+	}
+
+	for _, tt := range tests {
+		order := &domain.Order{
+			UserId:       ownClientId,
+			CourierId:    ownClientId,
+			RestaurantId: ownClientId,
+			Status:       tt.from,
+			Paid:         tt.paid,
+		}
+
+		err := checkOrderTransition(ownClientId, tt.clientType, order, tt.to)
+		if code := domain.ErrorCode(err); code != tt.err || (tt.err == "" && err != nil) {
+			t.Errorf("%s: expected %q, got %v", tt.name, tt.err, err)
+		}
+	}
+}
+
+func TestAllowedOrderTransitions(t *testing.T) {
+	order := &domain.Order{
+		UserId:       ownClientId,
+		CourierId:    ownClientId,
+		RestaurantId: ownClientId,
+		Status:       consts.OrderPaid,
+	}
+
+	expected := map[string][]int{
+		adminType:      {},
+		userType:       {consts.OrderCancelledByUser},
+		courierType:    {},
+		restaurantType: {consts.OrderPreparing, consts.OrderRejectedByRestaurant},
+	}
+
+	for clientType, statuses := range expected {
+		transitions := allowedOrderTransitions(ownClientId, clientType, order)
+		if len(transitions) != len(statuses) {
+			t.Errorf("%s: expected %v, got %d transitions", clientType, statuses, len(transitions))
+			continue
+		}
+
+		for i, transition := range transitions {
+			if transition.Status != statuses[i] {
+				t.Errorf("%s: expected %v, got status %d at %d", clientType, statuses, transition.Status, i)
+			}
+		}
+	}
+}
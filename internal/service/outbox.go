@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/MAVIKE/yad-backend/internal/repository"
+)
+
+const (
+	// outboxBatch is how many messages are relayed per pass.
+	outboxBatch = 100
+	// outboxLease is how long a claimed message is hidden from other relays.
+	outboxLease = time.Minute
+	// outboxBackoff is the delay before a failed message is relayed again,
+	// doubled for every next failure up to maxOutboxBackoff.
+	outboxBackoff    = 5 * time.Second
+	maxOutboxBackoff = 10 * time.Minute
+)
+
+// OutboxSink receives the events relayed from the outbox. Delivery is
+// at least once: a sink may get an event again if it or another sink has
+// failed, so it should drop duplicates by Event.Id.
+type OutboxSink interface {
+	Handle(event *domain.Event) error
+}
+
+// OutboxService relays the events written to the outbox together with the
+// changes they describe to every sink. A message stays in the outbox until
+// all sinks have handled it.
+type OutboxService struct {
+	repo      repository.Outbox
+	sinks     []OutboxSink
+	retention time.Duration
+}
+
+// NewOutboxService relays to the given sinks. Published messages are
+// kept for retention; zero keeps them forever.
+func NewOutboxService(repo repository.Outbox, retention time.Duration, sinks ...OutboxSink) *OutboxService {
+	return &OutboxService{
+		repo:      repo,
+		sinks:     sinks,
+		retention: retention,
+	}
+}
+
+// Run relays the outbox every interval until the context is done.
+func (s *OutboxService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Relay(); err != nil {
+			log.Printf("outbox: %s", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *OutboxService) Relay() error {
+	messages, err := s.repo.Claim(outboxBatch, outboxLease)
+	if err != nil {
+		return err
+	}
+
+	for _, message := range messages {
+		if err := s.relay(message); err != nil {
+			log.Printf("outbox: event %s: %s", message.EventId, err.Error())
+
+			nextAttemptAt := time.Now().Add(backoff(outboxBackoff, maxOutboxBackoff, message.Attempts+1))
+			if err := s.repo.MarkFailed(message.Id, err.Error(), nextAttemptAt); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := s.repo.MarkPublished(message.Id); err != nil {
+			return err
+		}
+	}
+
+	if s.retention == 0 {
+		return nil
+	}
+
+	return s.repo.DeletePublished(time.Now().Add(-s.retention))
+}
+
+func (s *OutboxService) relay(message *domain.OutboxMessage) error {
+	event := new(domain.Event)
+	if err := json.Unmarshal([]byte(message.Payload), event); err != nil {
+		return fmt.Errorf("invalid payload: %s", err.Error())
+	}
+
+	for _, sink := range s.sinks {
+		if err := sink.Handle(event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,347 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/MAVIKE/yad-backend/internal/consts"
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/MAVIKE/yad-backend/internal/repository"
+	"github.com/MAVIKE/yad-backend/pkg/payment"
+)
+
+// PaymentService takes the money for orders through the payment provider.
+// The user creates a payment for the total price of the order and confirms
+// it. Once the provider authorizes it, synchronously or with a callback,
+// the money is captured and the order is moved to paid. A payment that can
+// no longer pay its order is cancelled before capture, or refunded if the
+// money was captured meanwhile.
+type PaymentService struct {
+	repo      repository.Payment
+	orderRepo repository.Order
+	provider  payment.Provider
+	pricing   DeliveryPricing
+	dispatch  Dispatch
+	hours     *openingHours
+}
+
+func NewPaymentService(repo repository.Payment, orderRepo repository.Order, restaurantRepo repository.Restaurant,
+	provider payment.Provider, pricing DeliveryPricing, dispatch Dispatch) *PaymentService {
+	return &PaymentService{
+		repo:      repo,
+		orderRepo: orderRepo,
+		provider:  provider,
+		pricing:   pricing,
+		dispatch:  dispatch,
+		hours:     &openingHours{repo: restaurantRepo},
+	}
+}
+
+func (s *PaymentService) Create(clientId int, clientType string, orderId int) (*domain.Payment, error) {
+	order, err := s.getOrder(clientId, clientType, actionCreate, orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.Status != consts.OrderCreated {
+		return nil, domain.NewConflictError("Only a new order can be paid")
+	}
+
+	if err := s.pricing.CheckMinOrder(itemsPrice(order)); err != nil {
+		return nil, err
+	}
+
+	if err := s.hours.check(order.RestaurantId); err != nil {
+		return nil, err
+	}
+
+	if err := s.cancelOpen(orderId); err != nil {
+		return nil, err
+	}
+
+	intent, err := s.provider.CreateIntent(orderId, order.TotalPrice)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.repo.Create(&domain.Payment{
+		OrderId:  orderId,
+		Provider: s.provider.Name(),
+		IntentId: intent.Id,
+		Amount:   intent.Amount,
+		Status:   consts.PaymentPending,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetLatest(orderId)
+}
+
+func (s *PaymentService) Get(clientId int, clientType string, orderId int) (*domain.Payment, error) {
+	if _, err := s.getOrder(clientId, clientType, actionRead, orderId); err != nil {
+		return nil, err
+	}
+
+	return s.getLatest(orderId)
+}
+
+func (s *PaymentService) Confirm(clientId int, clientType string, orderId int) (*domain.Payment, error) {
+	order, err := s.getOrder(clientId, clientType, actionUpdate, orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := s.getLatest(orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Status != consts.PaymentPending {
+		return nil, domain.NewConflictError("Payment is already %s", p.Status)
+	}
+
+	if p.Amount != order.TotalPrice {
+		return nil, domain.NewConflictError("Order total has changed, create a new payment")
+	}
+
+	if err := s.hours.check(order.RestaurantId); err != nil {
+		return nil, err
+	}
+
+	intent, err := s.provider.Confirm(p.IntentId)
+	if err != nil {
+		return nil, providerError(err)
+	}
+
+	if err := s.apply(p, intent.Status); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetLatest(orderId)
+}
+
+// HandleCallback applies the status change the provider tells about.
+// Callbacks may come more than once and in any order.
+func (s *PaymentService) HandleCallback(header http.Header, body []byte) error {
+	callback, err := s.provider.ParseCallback(header, body)
+	if err != nil {
+		if errors.Is(err, payment.ErrInvalidSignature) {
+			return domain.NewValidationError("Invalid callback signature")
+		}
+		if errors.Is(err, payment.ErrStaleCallback) {
+			return domain.NewValidationError("Stale callback")
+		}
+		return domain.NewValidationError("Invalid callback: %s", err.Error())
+	}
+
+	p, err := s.repo.GetByIntentId(s.provider.Name(), callback.IntentId)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return domain.NewNotFoundError("Payment not found")
+		}
+		return err
+	}
+
+	return s.apply(p, callback.Status)
+}
+
+// apply moves the payment to the status of its intent. An authorized intent
+// is captured right away if it still pays the order, a captured one makes
+// the order paid.
+func (s *PaymentService) apply(p *domain.Payment, status string) error {
+	switch {
+	case p.Status == consts.PaymentCancelled || p.Status == consts.PaymentRefunded:
+		return nil
+	case p.Status == consts.PaymentCaptured:
+		// the order may not have been marked paid when the payment was captured
+		return s.markPaid(p)
+	case status == payment.StatusAuthorized:
+		order, err := s.orderRepo.GetById(p.OrderId)
+		if err != nil {
+			return err
+		}
+
+		if !pays(p, order) {
+			return s.cancel(p)
+		}
+
+		intent, err := s.provider.Capture(p.IntentId)
+		if err != nil {
+			return providerError(err)
+		}
+
+		if intent.Status != payment.StatusCaptured {
+			return s.repo.UpdateStatus(p.Id, consts.PaymentAuthorized)
+		}
+
+		return s.capture(p)
+	case status == payment.StatusCaptured:
+		return s.capture(p)
+	case status == payment.StatusFailed:
+		return s.repo.UpdateStatus(p.Id, consts.PaymentFailed)
+	}
+
+	return nil
+}
+
+func (s *PaymentService) capture(p *domain.Payment) error {
+	if err := s.repo.UpdateStatus(p.Id, consts.PaymentCaptured); err != nil {
+		return err
+	}
+	p.Status = consts.PaymentCaptured
+
+	return s.markPaid(p)
+}
+
+// markPaid moves the order of the captured payment to paid and queues it
+// for dispatch. The money is returned if the order was cancelled or changed
+// while it was captured.
+func (s *PaymentService) markPaid(p *domain.Payment) error {
+	order, err := s.orderRepo.GetById(p.OrderId)
+	if err != nil {
+		return err
+	}
+
+	// already paid, a cancelled paid order is refunded by the order service
+	if order.Paid != nil {
+		return nil
+	}
+
+	if !pays(p, order) {
+		return s.refund(p, order)
+	}
+
+	now := time.Now()
+	oldStatus := order.Status
+	order.Status = consts.OrderPaid
+	order.Paid = &now
+
+	// the user is the actor, the provider only confirms the payment
+	err = s.orderRepo.MarkPaid(order.Id, p.Amount, now, &domain.OrderEvent{
+		ActorId:   order.UserId,
+		ActorType: userType,
+		OldStatus: &oldStatus,
+		NewStatus: order.Status,
+	}, orderStatusEvents(order)...)
+	if errors.Is(err, domain.ErrConflict) {
+		// a concurrent callback has marked the order paid, or the order
+		// has changed since it was read
+		if order, err = s.orderRepo.GetById(p.OrderId); err != nil {
+			return err
+		}
+		if order.Paid != nil {
+			return nil
+		}
+		return s.refund(p, order)
+	}
+	if err != nil {
+		return err
+	}
+
+	// the courier is found by dispatch once the order is paid
+	s.dispatch.Notify()
+
+	return nil
+}
+
+// pays reports whether capturing the payment pays the order: the order is
+// still new and the payment is for its total.
+func pays(p *domain.Payment, order *domain.Order) bool {
+	return order.Status == consts.OrderCreated && p.Amount == order.TotalPrice
+}
+
+// cancel voids an authorized payment that can no longer pay its order.
+func (s *PaymentService) cancel(p *domain.Payment) error {
+	if _, err := s.provider.Cancel(p.IntentId); err != nil {
+		return providerError(err)
+	}
+
+	if err := s.repo.UpdateStatus(p.Id, consts.PaymentCancelled); err != nil {
+		return err
+	}
+
+	return domain.NewConflictError("Order has changed, create a new payment")
+}
+
+// refund returns the money of a captured payment that didn't pay its order.
+func (s *PaymentService) refund(p *domain.Payment, order *domain.Order) error {
+	log.Printf("payments: payment %d of %d captured for order %d in status %d with total %d, refunding",
+		p.Id, p.Amount, order.Id, order.Status, order.TotalPrice)
+
+	if _, err := s.provider.Refund(p.IntentId, p.Amount, fmt.Sprintf("payment-%d", p.Id)); err != nil {
+		return providerError(err)
+	}
+
+	if err := s.repo.UpdateStatus(p.Id, consts.PaymentRefunded); err != nil {
+		return err
+	}
+
+	return domain.NewConflictError("Order can no longer be paid, the payment is refunded")
+}
+
+// cancelOpen voids the earlier payments of the order that the payer could
+// still confirm, so that only the newest one can be captured. An intent
+// that has moved on at the provider is left to its callback.
+func (s *PaymentService) cancelOpen(orderId int) error {
+	payments, err := s.repo.GetOpen(orderId)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range payments {
+		if _, err := s.provider.Cancel(p.IntentId); err != nil {
+			if errors.Is(err, payment.ErrInvalidStatus) {
+				continue
+			}
+			return err
+		}
+
+		if err := s.repo.UpdateStatus(p.Id, consts.PaymentCancelled); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// providerError reports an intent that has moved on at the provider, e.g.
+// confirmed by a concurrent request, as a conflict.
+func providerError(err error) error {
+	if errors.Is(err, payment.ErrInvalidStatus) {
+		return domain.NewConflictError("Payment is already being processed")
+	}
+
+	return err
+}
+
+func (s *PaymentService) getOrder(clientId int, clientType string, act action, orderId int) (*domain.Order, error) {
+	order, err := s.orderRepo.GetById(orderId)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, domain.NewNotFoundError("Order not found")
+		}
+		return nil, err
+	}
+
+	if err := authorize(clientId, clientType, resourcePayment, act, orderTarget(order)); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+func (s *PaymentService) getLatest(orderId int) (*domain.Payment, error) {
+	p, err := s.repo.GetLatest(orderId)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, domain.NewNotFoundError("Payment not found")
+		}
+		return nil, err
+	}
+
+	return p, nil
+}
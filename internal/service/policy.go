@@ -0,0 +1,354 @@
+package service
+
+import (
+	"github.com/MAVIKE/yad-backend/internal/domain"
+)
+
+var errForbidden = domain.ErrForbidden
+
+type resource string
+
+const (
+	resourceUser       resource = "user"
+	resourceCourier    resource = "courier"
+	resourceRestaurant resource = "restaurant"
+	resourceCategory   resource = "category"
+	resourceMenuItem   resource = "menu_item"
+	resourceOrder      resource = "order"
+	resourceOrderItem  resource = "order_item"
+	resourceSession    resource = "session"
+	resourceRevocation resource = "revocation"
+	resourceDispatch   resource = "dispatch"
+
+	resourceCourierOffer    resource = "courier_offer"
+	resourceCourierLocation resource = "courier_location"
+	resourceWebhook         resource = "webhook"
+	resourcePayment         resource = "payment"
+	resourceRefund          resource = "refund"
+	resourceCart            resource = "cart"
+	resourceSchedule        resource = "schedule"
+	resourceDeliveryZone    resource = "delivery_zone"
+	resourceReview          resource = "review"
+)
+
+type action string
+
+const (
+	actionCreate action = "create"
+	actionRead   action = "read"
+	actionList   action = "list"
+	actionUpdate action = "update"
+	actionDelete action = "delete"
+
+	// Order status changes are split by who drives them. Nobody may pay
+	// or refund an order directly: it is paid once the payment provider
+	// confirms and refunded once the money is returned.
+	actionPay     action = "pay"
+	actionCancel  action = "cancel"
+	actionPrepare action = "prepare"
+	actionReject  action = "reject"
+	actionDeliver action = "deliver"
+	actionRefund  action = "refund"
+
+	// Restaurants reply to the reviews of their orders, moderators hide
+	// the abusive ones.
+	actionReply    action = "reply"
+	actionModerate action = "moderate"
+)
+
+// target describes whom a resource belongs to. Only the ids that make sense
+// for the resource are set, e.g. an order has a user, a restaurant and a courier.
+type target struct {
+	AdminId      int
+	UserId       int
+	CourierId    int
+	RestaurantId int
+
+	// Orders are the active orders of a user, they let the restaurant
+	// and the courier serving the user read the user profile.
+	Orders []*domain.Order
+}
+
+func ownedBy(clientType string, clientId int) target {
+	var t target
+
+	switch clientType {
+	case adminType:
+		t.AdminId = clientId
+	case userType:
+		t.UserId = clientId
+	case courierType:
+		t.CourierId = clientId
+	case restaurantType:
+		t.RestaurantId = clientId
+	}
+
+	return t
+}
+
+func orderTarget(order *domain.Order) target {
+	return target{
+		UserId:       order.UserId,
+		CourierId:    order.CourierId,
+		RestaurantId: order.RestaurantId,
+	}
+}
+
+func (t target) ownerId(clientType string) int {
+	switch clientType {
+	case adminType:
+		return t.AdminId
+	case userType:
+		return t.UserId
+	case courierType:
+		return t.CourierId
+	case restaurantType:
+		return t.RestaurantId
+	}
+
+	return 0
+}
+
+type predicate func(clientId int, clientType string, t target) bool
+
+func anyone(int, string, target) bool {
+	return true
+}
+
+// owner holds when the client is the one the resource belongs to,
+// checked against the id of the client's own role.
+func owner(clientId int, clientType string, t target) bool {
+	return clientId != 0 && t.ownerId(clientType) == clientId
+}
+
+// servesUser holds when the client is the restaurant or the courier of one
+// of the user's active orders.
+func servesUser(clientId int, clientType string, t target) bool {
+	for _, order := range t.Orders {
+		if owner(clientId, clientType, orderTarget(order)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type rules map[resource]map[action]predicate
+
+// policy lists what every role may do. Anything not listed is forbidden.
+var policy = map[string]rules{
+	adminType: {
+		resourceCourier: {
+			actionCreate: anyone,
+			actionUpdate: anyone,
+		},
+		resourceRestaurant: {
+			actionCreate: anyone,
+			actionUpdate: anyone,
+		},
+		resourceSchedule: {
+			actionRead:   anyone,
+			actionUpdate: anyone,
+		},
+		resourceDeliveryZone: {
+			actionRead:   anyone,
+			actionUpdate: anyone,
+		},
+		resourceOrder: {
+			actionRead: anyone,
+		},
+		resourcePayment: {
+			actionRead: anyone,
+		},
+		resourceRefund: {
+			actionCreate: anyone,
+			actionList:   anyone,
+		},
+		resourceSession: {
+			actionList:   anyone,
+			actionDelete: anyone,
+		},
+		resourceRevocation: {
+			actionCreate: anyone,
+		},
+		resourceDispatch: {
+			actionList: anyone,
+		},
+		resourceReview: {
+			actionRead:     anyone,
+			actionList:     anyone,
+			actionModerate: anyone,
+		},
+	},
+	userType: {
+		resourceUser: {
+			actionRead:   owner,
+			actionUpdate: owner,
+		},
+		resourceCourier: {
+			actionRead: anyone,
+		},
+		resourceRestaurant: {
+			actionRead: anyone,
+			actionList: anyone,
+		},
+		resourceSchedule: {
+			actionRead: anyone,
+		},
+		resourceDeliveryZone: {
+			actionRead: anyone,
+		},
+		resourceCategory: {
+			actionRead: anyone,
+			actionList: anyone,
+		},
+		resourceMenuItem: {
+			actionRead: anyone,
+			actionList: anyone,
+		},
+		resourceOrder: {
+			actionCreate: anyone,
+			actionRead:   owner,
+			actionList:   owner,
+			actionDelete: owner,
+			actionCancel: owner,
+		},
+		resourcePayment: {
+			actionCreate: owner,
+			actionRead:   owner,
+			actionUpdate: owner,
+		},
+		resourceRefund: {
+			actionList: owner,
+		},
+		resourceCourierLocation: {
+			actionRead: owner,
+		},
+		resourceOrderItem: {
+			actionCreate: owner,
+			actionRead:   owner,
+			actionList:   owner,
+			actionUpdate: owner,
+			actionDelete: owner,
+		},
+		resourceCart: {
+			actionRead:   owner,
+			actionUpdate: owner,
+			actionDelete: owner,
+		},
+		resourceReview: {
+			actionCreate: owner,
+			actionRead:   owner,
+			actionList:   anyone,
+		},
+		resourceSession: {
+			actionList:   owner,
+			actionDelete: owner,
+		},
+	},
+	courierType: {
+		resourceUser: {
+			actionRead: servesUser,
+		},
+		resourceCourier: {
+			actionRead:   owner,
+			actionUpdate: owner,
+		},
+		resourceOrder: {
+			actionRead:    owner,
+			actionList:    owner,
+			actionDeliver: owner,
+		},
+		resourceCourierOffer: {
+			actionList:   owner,
+			actionUpdate: owner,
+		},
+		resourceCourierLocation: {
+			actionCreate: owner,
+		},
+		resourceReview: {
+			actionRead: owner,
+		},
+		resourceOrderItem: {
+			actionRead: owner,
+			actionList: owner,
+		},
+		resourceSession: {
+			actionList:   owner,
+			actionDelete: owner,
+		},
+	},
+	restaurantType: {
+		resourceUser: {
+			actionRead: servesUser,
+		},
+		resourceCourier: {
+			actionRead: anyone,
+		},
+		resourceRestaurant: {
+			actionRead:   owner,
+			actionUpdate: owner,
+		},
+		resourceSchedule: {
+			actionRead:   owner,
+			actionUpdate: owner,
+		},
+		resourceDeliveryZone: {
+			actionRead:   owner,
+			actionUpdate: owner,
+		},
+		resourceCategory: {
+			actionCreate: owner,
+			actionRead:   owner,
+			actionList:   owner,
+			actionUpdate: owner,
+			actionDelete: owner,
+		},
+		resourceMenuItem: {
+			actionCreate: owner,
+			actionRead:   owner,
+			actionList:   owner,
+			actionUpdate: owner,
+			actionDelete: owner,
+		},
+		resourceOrder: {
+			actionRead:    owner,
+			actionList:    owner,
+			actionPrepare: owner,
+			actionReject:  owner,
+		},
+		resourceWebhook: {
+			actionCreate: owner,
+			actionRead:   owner,
+			actionList:   owner,
+			actionDelete: owner,
+		},
+		resourceOrderItem: {
+			actionRead:   owner,
+			actionList:   owner,
+			actionDelete: owner,
+		},
+		resourceRefund: {
+			actionList: owner,
+		},
+		resourceReview: {
+			actionRead:  owner,
+			actionList:  owner,
+			actionReply: owner,
+		},
+		resourceSession: {
+			actionList:   owner,
+			actionDelete: owner,
+		},
+	},
+}
+
+// authorize returns errForbidden unless the policy lets the client
+// perform the action on the resource described by t.
+func authorize(clientId int, clientType string, res resource, act action, t target) error {
+	if allowed, ok := policy[clientType][res][act]; ok && allowed(clientId, clientType, t) {
+		return nil
+	}
+
+	return errForbidden
+}
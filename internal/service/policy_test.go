@@ -0,0 +1,173 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+)
+
+const (
+	ownClientId     = 1
+	foreignClientId = 2
+)
+
+var roles = []string{adminType, userType, courierType, restaurantType}
+
+// ownTarget belongs to client 1 whatever its role, foreignTarget to client 2.
+var (
+	ownTarget = target{
+		AdminId:      ownClientId,
+		UserId:       ownClientId,
+		CourierId:    ownClientId,
+		RestaurantId: ownClientId,
+		Orders: []*domain.Order{
+			{UserId: ownClientId, CourierId: ownClientId, RestaurantId: ownClientId},
+		},
+	}
+	foreignTarget = target{
+		AdminId:      foreignClientId,
+		UserId:       foreignClientId,
+		CourierId:    foreignClientId,
+		RestaurantId: foreignClientId,
+		Orders: []*domain.Order{
+			{UserId: foreignClientId, CourierId: foreignClientId, RestaurantId: foreignClientId},
+		},
+	}
+)
+
+func TestPolicy(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		resource resource
+		action   action
+		// roles allowed when the target belongs to the client
+		own []string
+		// roles allowed when the target belongs to someone else
+		foreign []string
+	}{
+		{"POST /couriers/sign-up", resourceCourier, actionCreate, []string{adminType}, []string{adminType}},
+		{"GET /couriers/:id", resourceCourier, actionRead, []string{userType, courierType, restaurantType}, []string{userType, restaurantType}},
+		{"PUT /couriers/:id", resourceCourier, actionUpdate, []string{adminType, courierType}, []string{adminType}},
+
+		{"POST /restaurants/sign-up", resourceRestaurant, actionCreate, []string{adminType}, []string{adminType}},
+		{"GET /restaurants/", resourceRestaurant, actionList, []string{userType}, []string{userType}},
+		{"GET /restaurants/search", resourceRestaurant, actionList, []string{userType}, []string{userType}},
+		{"GET /restaurants/:rid", resourceRestaurant, actionRead, []string{userType, restaurantType}, []string{userType}},
+		{"PUT /restaurants/:rid", resourceRestaurant, actionUpdate, []string{adminType, restaurantType}, []string{adminType}},
+		{"GET /restaurants/:rid/hours", resourceSchedule, actionRead, []string{adminType, userType, restaurantType}, []string{adminType, userType}},
+		{"PUT /restaurants/:rid/hours", resourceSchedule, actionUpdate, []string{adminType, restaurantType}, []string{adminType}},
+		{"GET /restaurants/:rid/zone", resourceDeliveryZone, actionRead, []string{adminType, userType, restaurantType}, []string{adminType, userType}},
+		{"PUT /restaurants/:rid/zone", resourceDeliveryZone, actionUpdate, []string{adminType, restaurantType}, []string{adminType}},
+
+		{"GET /users/:uid", resourceUser, actionRead, []string{userType, courierType, restaurantType}, nil},
+		{"PUT /users/:uid", resourceUser, actionUpdate, []string{userType}, nil},
+
+		{"POST /restaurants/:rid/categories/", resourceCategory, actionCreate, []string{restaurantType}, nil},
+		{"GET /restaurants/:rid/categories/", resourceCategory, actionList, []string{userType, restaurantType}, []string{userType}},
+		{"GET /restaurants/:rid/categories/:id", resourceCategory, actionRead, []string{userType, restaurantType}, []string{userType}},
+		{"PUT /restaurants/:rid/categories/:cid", resourceCategory, actionUpdate, []string{restaurantType}, nil},
+		{"DELETE /restaurants/:rid/categories/:cid", resourceCategory, actionDelete, []string{restaurantType}, nil},
+
+		{"POST /restaurants/:rid/menu/", resourceMenuItem, actionCreate, []string{restaurantType}, nil},
+		{"GET /restaurants/:rid/menu/", resourceMenuItem, actionList, []string{userType, restaurantType}, []string{userType}},
+		{"GET /restaurants/:rid/menu/:id", resourceMenuItem, actionRead, []string{userType, restaurantType}, []string{userType}},
+		{"PUT /restaurants/:rid/menu/:id", resourceMenuItem, actionUpdate, []string{restaurantType}, nil},
+		{"DELETE /restaurants/:rid/menu/:id", resourceMenuItem, actionDelete, []string{restaurantType}, nil},
+
+		{"POST /orders/", resourceOrder, actionCreate, []string{userType}, []string{userType}},
+		{"GET /orders/:oid{,/timeline,/receipt}", resourceOrder, actionRead, []string{adminType, userType, courierType, restaurantType}, []string{adminType}},
+		{"GET /{users,couriers,restaurants}/:id/orders", resourceOrder, actionList, []string{userType, courierType, restaurantType}, nil},
+		{"DELETE /orders/:oid", resourceOrder, actionDelete, []string{userType}, nil},
+		{"PUT /orders/:oid paid", resourceOrder, actionPay, nil, nil},
+		{"PUT /orders/:oid preparing", resourceOrder, actionPrepare, []string{restaurantType}, nil},
+		{"PUT /orders/:oid en route", resourceOrder, actionDeliver, []string{courierType}, nil},
+		{"PUT /orders/:oid cancelled", resourceOrder, actionCancel, []string{userType}, nil},
+		{"PUT /orders/:oid rejected", resourceOrder, actionReject, []string{restaurantType}, nil},
+		{"PUT /orders/:oid refunded", resourceOrder, actionRefund, nil, nil},
+
+		{"POST /orders/:oid/items/", resourceOrderItem, actionCreate, []string{userType}, nil},
+		{"GET /orders/:oid/items/", resourceOrderItem, actionList, []string{userType, courierType, restaurantType}, nil},
+		{"GET /orders/:oid/items/:id", resourceOrderItem, actionRead, []string{userType, courierType, restaurantType}, nil},
+		{"PUT /orders/:oid/items/:id", resourceOrderItem, actionUpdate, []string{userType}, nil},
+		{"DELETE /orders/:oid/items/:id", resourceOrderItem, actionDelete, []string{userType, restaurantType}, nil},
+
+		{"GET /users/:uid/sessions", resourceSession, actionList, roles, []string{adminType}},
+		{"DELETE /users/:uid/sessions/:sid", resourceSession, actionDelete, roles, []string{adminType}},
+
+		{"POST /admins/revocations", resourceRevocation, actionCreate, []string{adminType}, []string{adminType}},
+
+		{"GET /admins/dispatch", resourceDispatch, actionList, []string{adminType}, []string{adminType}},
+		{"GET /couriers/:cid/offers", resourceCourierOffer, actionList, []string{courierType}, nil},
+		{"POST /couriers/:cid/offers/:id/{accept,decline}", resourceCourierOffer, actionUpdate, []string{courierType}, nil},
+
+		{"POST /couriers/:cid/location", resourceCourierLocation, actionCreate, []string{courierType}, nil},
+		{"GET /orders/:oid/courier-location", resourceCourierLocation, actionRead, []string{userType}, nil},
+
+		{"POST /restaurants/:rid/webhooks", resourceWebhook, actionCreate, []string{restaurantType}, nil},
+		{"GET /restaurants/:rid/webhooks", resourceWebhook, actionList, []string{restaurantType}, nil},
+		{"GET /restaurants/:rid/webhooks/:wid/deliveries", resourceWebhook, actionRead, []string{restaurantType}, nil},
+		{"DELETE /restaurants/:rid/webhooks/:wid", resourceWebhook, actionDelete, []string{restaurantType}, nil},
+
+		{"POST /orders/:oid/payment", resourcePayment, actionCreate, []string{userType}, nil},
+		{"GET /orders/:oid/payment", resourcePayment, actionRead, []string{adminType, userType}, []string{adminType}},
+		{"POST /orders/:oid/payment/confirm", resourcePayment, actionUpdate, []string{userType}, nil},
+
+		{"POST /orders/:oid/refunds", resourceRefund, actionCreate, []string{adminType}, []string{adminType}},
+		{"GET /orders/:oid/refunds", resourceRefund, actionList, []string{adminType, userType, restaurantType}, []string{adminType}},
+
+		{"GET /users/:uid/cart", resourceCart, actionRead, []string{userType}, nil},
+		{"{POST,PUT,DELETE} /users/:uid/cart/items", resourceCart, actionUpdate, []string{userType}, nil},
+		{"DELETE /users/:uid/cart", resourceCart, actionDelete, []string{userType}, nil},
+
+		{"POST /orders/:oid/reviews", resourceReview, actionCreate, []string{userType}, nil},
+		{"GET /orders/:oid/reviews", resourceReview, actionRead, []string{adminType, userType, courierType, restaurantType}, []string{adminType}},
+		{"GET /restaurants/:rid/reviews", resourceReview, actionList, []string{adminType, userType, restaurantType}, []string{adminType, userType}},
+		{"PUT /restaurants/:rid/reviews/:id/reply", resourceReview, actionReply, []string{restaurantType}, nil},
+		{"PUT /reviews/:id/moderation", resourceReview, actionModerate, []string{adminType}, []string{adminType}},
+	}
+
+	for _, tt := range tests {
+		for _, role := range roles {
+			checkPolicy(t, tt.endpoint, role, "own", tt.resource, tt.action, ownTarget, contains(tt.own, role))
+			checkPolicy(t, tt.endpoint, role, "foreign", tt.resource, tt.action, foreignTarget, contains(tt.foreign, role))
+		}
+	}
+}
+
+func TestPolicy_UnknownRole(t *testing.T) {
+	if err := authorize(ownClientId, "guest", resourceOrder, actionRead, ownTarget); err != errForbidden {
+		t.Errorf("expected errForbidden for an unknown role, got %v", err)
+	}
+}
+
+func TestPolicy_ServesUserNeedsActiveOrder(t *testing.T) {
+	user := ownedBy(userType, foreignClientId)
+
+	for _, role := range []string{courierType, restaurantType} {
+		if err := authorize(ownClientId, role, resourceUser, actionRead, user); err != errForbidden {
+			t.Errorf("%s without an order must not read the user, got %v", role, err)
+		}
+	}
+}
+
+func checkPolicy(t *testing.T, endpoint, role, ownership string, res resource, act action, tg target, allowed bool) {
+	t.Helper()
+
+	err := authorize(ownClientId, role, res, act, tg)
+	if allowed && err != nil {
+		t.Errorf("%s: %s on %s target must be allowed, got %v", endpoint, role, ownership, err)
+	}
+	if !allowed && err != errForbidden {
+		t.Errorf("%s: %s on %s target must be forbidden", endpoint, role, ownership)
+	}
+}
+
+func contains(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
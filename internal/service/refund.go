@@ -0,0 +1,339 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/MAVIKE/yad-backend/internal/consts"
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/MAVIKE/yad-backend/internal/repository"
+	"github.com/MAVIKE/yad-backend/pkg/payment"
+)
+
+const (
+	// refundBatch is how many pending refunds are made per pass.
+	refundBatch = 20
+	// refundLease is how long a refund being made is hidden from the other
+	// instances.
+	refundLease = time.Minute
+	// refundBackoff is the delay before a failed refund is made again,
+	// doubled for every next failure up to maxRefundBackoff.
+	refundBackoff    = 30 * time.Second
+	maxRefundBackoff = time.Hour
+	// maxRefundAttempts is how many times a refund is tried before it fails.
+	maxRefundAttempts = 8
+)
+
+// RefundService returns captured money to the user. A refund is recorded
+// as pending first, which holds its amount, then made at the payment
+// provider and taken off the order total once the provider succeeds, so
+// the total of a paid order is always the captured amount minus the refunds.
+// A refund the provider fails is retried by Run.
+type RefundService struct {
+	repo        repository.Refund
+	orderRepo   repository.Order
+	paymentRepo repository.Payment
+	provider    payment.Provider
+}
+
+func NewRefundService(repo repository.Refund, orderRepo repository.Order, paymentRepo repository.Payment,
+	provider payment.Provider) *RefundService {
+	return &RefundService{
+		repo:        repo,
+		orderRepo:   orderRepo,
+		paymentRepo: paymentRepo,
+		provider:    provider,
+	}
+}
+
+// Create makes a goodwill refund of a part of the order.
+func (s *RefundService) Create(clientId int, clientType string, orderId int, input *domain.Refund) (int, error) {
+	order, err := s.getOrder(clientId, clientType, actionCreate, orderId)
+	if err != nil {
+		return 0, err
+	}
+
+	if input.Amount <= 0 {
+		return 0, domain.NewValidationError("Refund amount must be greater than 0")
+	}
+
+	p, err := s.captured(order)
+	if err != nil {
+		return 0, err
+	}
+
+	if p == nil {
+		return 0, domain.NewConflictError("Order has no captured payment")
+	}
+
+	return s.refund(order, p, &domain.Refund{
+		Kind:      consts.RefundGoodwill,
+		Amount:    input.Amount,
+		Reason:    input.Reason,
+		ActorId:   clientId,
+		ActorType: clientType,
+	})
+}
+
+func (s *RefundService) GetAll(clientId int, clientType string, orderId int) ([]*domain.Refund, error) {
+	if _, err := s.getOrder(clientId, clientType, actionList, orderId); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetAll(orderId)
+}
+
+// RefundOrder moves the paid order to the status of the event, cancelled
+// or rejected, and returns what is left of its payment. The refund is
+// recorded together with the status, so it is made even if the provider
+// fails now. Orders paid without a payment have nothing to return.
+func (s *RefundService) RefundOrder(order *domain.Order, event *domain.OrderEvent) error {
+	p, err := s.captured(order)
+	if err != nil {
+		return err
+	}
+
+	if p == nil {
+		return s.orderRepo.Update(order.Id, &domain.Order{Status: order.Status}, event, orderStatusEvents(order)...)
+	}
+
+	refund := &domain.Refund{
+		OrderId:       order.Id,
+		PaymentId:     p.Id,
+		Kind:          consts.RefundOrder,
+		Reason:        orderStatusNames[order.Status],
+		ActorId:       event.ActorId,
+		ActorType:     event.ActorType,
+		NextAttemptAt: time.Now().Add(refundLease),
+	}
+
+	refundId, err := s.repo.CreateForOrder(refund, event, orderStatusEvents(order)...)
+	if err != nil {
+		return err
+	}
+
+	if refundId == 0 {
+		return s.settle(event.ActorId, event.ActorType, order, p)
+	}
+	refund.Id = refundId
+
+	if err := s.process(order, p, refund); err != nil {
+		log.Printf("refunds: refund %d of order %d: %s, retrying later", refundId, order.Id, err.Error())
+	}
+
+	return nil
+}
+
+// RefundItem removes the item from a paid order and returns the price
+// the user paid for it. The item stays in the order if the provider fails
+// to refund it, and removing it again is a conflict while that refund is
+// pending.
+func (s *RefundService) RefundItem(actorId int, actorType string, order *domain.Order, orderItemId int) error {
+	item, err := s.orderRepo.GetItemById(orderItemId)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return domain.NewNotFoundError("No such orderItem for this order")
+		}
+		return err
+	}
+
+	if item.OrderId != order.Id {
+		return domain.NewNotFoundError("No such orderItem for this order")
+	}
+
+	p, err := s.captured(order)
+	if err != nil {
+		return err
+	}
+
+	if p == nil {
+		return domain.NewConflictError("Order has no captured payment")
+	}
+
+	_, err = s.refund(order, p, &domain.Refund{
+		Kind:        consts.RefundItem,
+		Amount:      item.Price * item.Count,
+		Reason:      fmt.Sprintf("%d x %s removed", item.Count, item.Title),
+		OrderItemId: &item.Id,
+		ActorId:     actorId,
+		ActorType:   actorType,
+	})
+
+	return err
+}
+
+// refund records the refund and makes it at once. A refund the provider
+// fails stays pending until Run makes it.
+func (s *RefundService) refund(order *domain.Order, p *domain.Payment, refund *domain.Refund) (int, error) {
+	refund.OrderId = order.Id
+	refund.PaymentId = p.Id
+	refund.NextAttemptAt = time.Now().Add(refundLease)
+
+	refundId, err := s.repo.Create(refund)
+	if err != nil {
+		return 0, err
+	}
+	refund.Id = refundId
+
+	if err := s.process(order, p, refund); err != nil {
+		log.Printf("refunds: refund %d of order %d: %s, retrying later", refundId, order.Id, err.Error())
+	}
+
+	return refundId, nil
+}
+
+// Run makes the pending refunds every interval until the context is done.
+func (s *RefundService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.ProcessPending(); err != nil {
+			log.Printf("refunds: %s", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessPending makes the pending refunds that are due: those the provider
+// has failed and those left behind by a crash.
+func (s *RefundService) ProcessPending() error {
+	refunds, err := s.repo.ClaimPending(refundBatch, refundLease)
+	if err != nil {
+		return err
+	}
+
+	for _, refund := range refunds {
+		order, err := s.orderRepo.GetById(refund.OrderId)
+		if err != nil {
+			return err
+		}
+
+		p, err := s.paymentRepo.GetById(refund.PaymentId)
+		if err != nil {
+			return err
+		}
+
+		if err := s.process(order, p, refund); err != nil {
+			log.Printf("refunds: refund %d of order %d: %s", refund.Id, order.Id, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// process makes the pending refund at the provider. The refund id is the
+// idempotency key, so a retry of a refund the provider has made but that
+// wasn't recorded doesn't return the money twice. A failed refund is
+// retried with a backoff, maxRefundAttempts times in all.
+func (s *RefundService) process(order *domain.Order, p *domain.Payment, refund *domain.Refund) error {
+	providerRefund, err := s.provider.Refund(p.IntentId, refund.Amount, fmt.Sprintf("refund-%d", refund.Id))
+	if err != nil {
+		attempts := refund.Attempts + 1
+		if attempts >= maxRefundAttempts {
+			if err := s.repo.Fail(refund.Id, err.Error()); err != nil {
+				return err
+			}
+			return fmt.Errorf("failed after %d attempts: %s", attempts, err.Error())
+		}
+
+		nextAttemptAt := time.Now().Add(backoff(refundBackoff, maxRefundBackoff, attempts))
+		if err := s.repo.Retry(refund.Id, err.Error(), nextAttemptAt); err != nil {
+			return err
+		}
+		return err
+	}
+
+	if err := s.repo.Complete(refund, providerRefund.Id); err != nil {
+		return err
+	}
+
+	return s.settle(refund.ActorId, refund.ActorType, order, p)
+}
+
+// settle moves a cancelled, rejected or failed order to refunded once
+// nothing of its payment is left.
+func (s *RefundService) settle(actorId int, actorType string, order *domain.Order, p *domain.Payment) error {
+	if !refundable(order) {
+		return nil
+	}
+
+	refunded, err := s.repo.GetRefundedAmount(p.Id)
+	if err != nil {
+		return err
+	}
+
+	if refunded < p.Amount {
+		return nil
+	}
+
+	oldStatus := order.Status
+	order.Status = consts.OrderRefunded
+
+	err = s.orderRepo.Update(order.Id, &domain.Order{Status: order.Status}, &domain.OrderEvent{
+		ActorId:   actorId,
+		ActorType: actorType,
+		OldStatus: &oldStatus,
+		NewStatus: order.Status,
+	}, orderStatusEvents(order)...)
+	// a concurrent refund has already moved the order
+	if errors.Is(err, domain.ErrConflict) {
+		return nil
+	}
+
+	return err
+}
+
+// refundable reports whether the order state machine lets the order
+// become refunded.
+func refundable(order *domain.Order) bool {
+	for _, transition := range orderTransitions[order.Status] {
+		if transition.to == consts.OrderRefunded {
+			return transition.guard == nil || transition.guard(order)
+		}
+	}
+
+	return false
+}
+
+// captured returns the captured payment of the order, or nil if the order
+// has none.
+func (s *RefundService) captured(order *domain.Order) (*domain.Payment, error) {
+	p, err := s.paymentRepo.GetLatest(order.Id)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if p.Status != consts.PaymentCaptured && p.Status != consts.PaymentRefunded {
+		return nil, nil
+	}
+
+	return p, nil
+}
+
+func (s *RefundService) getOrder(clientId int, clientType string, act action, orderId int) (*domain.Order, error) {
+	order, err := s.orderRepo.GetById(orderId)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, domain.NewNotFoundError("Order not found")
+		}
+		return nil, err
+	}
+
+	if err := authorize(clientId, clientType, resourceRefund, act, orderTarget(order)); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
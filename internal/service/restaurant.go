@@ -1,69 +1,145 @@
 package service
 
 import (
+	"context"
 	"errors"
-	"github.com/MAVIKE/yad-backend/internal/consts"
+	"log"
 	"time"
 
+	"github.com/MAVIKE/yad-backend/internal/consts"
+
 	"github.com/MAVIKE/yad-backend/internal/domain"
 	"github.com/MAVIKE/yad-backend/internal/repository"
-	"github.com/MAVIKE/yad-backend/pkg/auth"
+	"github.com/MAVIKE/yad-backend/pkg/hash"
 )
 
 type RestaurantService struct {
-	repo           repository.Restaurant
-	tokenManager   auth.TokenManager
-	accessTokenTTL time.Duration
+	repo     repository.Restaurant
+	hasher   hash.PasswordHasher
+	sessions Session
+	hours    *openingHours
 }
 
-func NewRestaurantService(repo repository.Restaurant, tokenManager auth.TokenManager, accessTokenTTL time.Duration) *RestaurantService {
+func NewRestaurantService(repo repository.Restaurant, hasher hash.PasswordHasher, sessions Session) *RestaurantService {
 	return &RestaurantService{
-		repo:           repo,
-		tokenManager:   tokenManager,
-		accessTokenTTL: accessTokenTTL,
+		repo:     repo,
+		hasher:   hasher,
+		sessions: sessions,
+		hours:    &openingHours{repo: repo},
 	}
 }
 
 func (s *RestaurantService) SignIn(phone, password string) (*Tokens, error) {
-	restaurant, err := s.repo.GetByCredentials(phone, password)
+	restaurant, err := s.repo.GetByPhone(phone)
 	if err != nil {
-		return nil, err
+		return nil, credentialsError(err)
 	}
-	token, err := s.tokenManager.NewJWT(restaurant.Id, restaurantType, s.accessTokenTTL)
 
+	newHash, err := checkPassword(s.hasher, restaurant.Password, password)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Tokens{AccessToken: token}, nil
+	if newHash != "" {
+		if err := s.repo.UpdatePassword(restaurant.Id, newHash); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.sessions.Create(restaurant.Id, restaurantType)
 }
 
 func (s *RestaurantService) SignUp(restaurant *domain.Restaurant, clientType string) (int, error) {
-	if clientType != adminType {
-		return 0, errors.New("forbidden")
+	if err := authorize(0, clientType, resourceRestaurant, actionCreate, target{}); err != nil {
+		return 0, err
 	}
+
+	passwordHash, err := s.hasher.Hash(restaurant.Password)
+	if err != nil {
+		return 0, err
+	}
+	restaurant.Password = passwordHash
+
 	return s.repo.Create(restaurant)
 }
 
-func (s *RestaurantService) GetAll(clientId int, clientType string) ([]*domain.Restaurant, error) {
-	if clientType != userType {
-		return nil, errors.New("Forbidden")
+// maxRestaurantBatches caps the batches read for one filtered page. A page
+// that isn't full by then is returned short, with the cursor to go on.
+const maxRestaurantBatches = 10
+
+// GetAll returns a page of the restaurants delivering to the user. The
+// working status follows the opening hours and is only known once they are
+// applied, so a filtered page is filled batch by batch.
+func (s *RestaurantService) GetAll(clientId int, clientType string, filter *domain.RestaurantFilter) (*domain.RestaurantPage, error) {
+	if err := authorize(clientId, clientType, resourceRestaurant, actionList, target{}); err != nil {
+		return nil, err
+	}
+
+	if err := validateRestaurantFilter(filter); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	params := filter.ListParams
+	params.Limit = domain.MaxListLimit
+	page := &domain.RestaurantPage{Items: make([]*domain.Restaurant, 0, filter.Limit)}
+
+	for i := 0; i < maxRestaurantBatches; i++ {
+		batch, err := s.repo.GetAll(clientId, &params)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := s.hours.apply(now, batch.Items...); err != nil {
+			return nil, err
+		}
+
+		for j, restaurant := range batch.Items {
+			if filter.WorkingStatus != 0 && restaurant.WorkingStatus != filter.WorkingStatus {
+				continue
+			}
+
+			page.Items = append(page.Items, restaurant)
+			if len(page.Items) == filter.Limit {
+				// the next page starts after the last restaurant returned
+				if j < len(batch.Items)-1 || batch.NextCursor != "" {
+					page.NextCursor = batch.Cursors[j]
+				}
+				return page, nil
+			}
+		}
+
+		page.NextCursor = batch.NextCursor
+		if page.NextCursor == "" {
+			return page, nil
+		}
+		params.Cursor = page.NextCursor
 	}
 
-	return s.repo.GetAll(clientId)
+	// a short page, the client goes on with its cursor
+	return page, nil
 }
 
 func (s *RestaurantService) GetById(clientId int, clientType string, restaurantId int) (*domain.Restaurant, error) {
-	if !(clientType == userType || clientType == restaurantType && restaurantId == clientId) {
-		return nil, errors.New("Forbidden")
+	if err := authorize(clientId, clientType, resourceRestaurant, actionRead, ownedBy(restaurantType, restaurantId)); err != nil {
+		return nil, err
 	}
 
-	return s.repo.GetById(restaurantId)
+	restaurant, err := s.repo.GetById(restaurantId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.hours.apply(time.Now(), restaurant); err != nil {
+		return nil, err
+	}
+
+	return restaurant, nil
 }
 
 func (s *RestaurantService) UpdateImage(clientId int, clientType string, restaurantId int, image string) (*domain.Restaurant, error) {
-	if clientType != restaurantType || restaurantId != clientId {
-		return nil, errors.New("Forbidden")
+	if err := authorize(clientId, clientType, resourceRestaurant, actionUpdate, ownedBy(restaurantType, restaurantId)); err != nil {
+		return nil, err
 	}
 
 	if err := s.repo.UpdateImage(restaurantId, image); err != nil {
@@ -73,16 +149,152 @@ func (s *RestaurantService) UpdateImage(clientId int, clientType string, restaur
 	return s.repo.GetById(restaurantId)
 }
 
+// Update changes the restaurant; a zero working status keeps the current
+// one. The working status of a restaurant with opening hours can't be set
+// by hand.
 func (s *RestaurantService) Update(clientId int, clientType string, restaurantId int, input *domain.Restaurant) error {
 	switch input.WorkingStatus {
-	case consts.RestaurantUnable, consts.RestaurantWorking:
+	case 0, consts.RestaurantUnable, consts.RestaurantWorking:
 		break
 	default:
-		return errors.New("working_status input error")
+		return domain.NewValidationError("working_status input error")
+	}
+	if err := authorize(clientId, clientType, resourceRestaurant, actionUpdate, ownedBy(restaurantType, restaurantId)); err != nil {
+		return err
 	}
-	if !(clientType == restaurantType && restaurantId == clientId || clientType == adminType) {
-		return errors.New("forbidden")
+
+	if input.Password != "" {
+		passwordHash, err := s.hasher.Hash(input.Password)
+		if err != nil {
+			return err
+		}
+		input.Password = passwordHash
+	}
+
+	restaurant, err := s.repo.GetById(restaurantId)
+	if err != nil {
+		return err
+	}
+
+	schedule, err := s.getSchedule(restaurantId)
+	if err != nil {
+		return err
+	}
+
+	if input.WorkingStatus != 0 && len(schedule.Hours) > 0 {
+		return domain.NewValidationError("Working status follows the opening hours, remove them to set it by hand")
+	}
+
+	var events []*domain.Event
+	if input.WorkingStatus != 0 && input.WorkingStatus != restaurant.WorkingStatus {
+		events = append(events,
+			domain.NewRestaurantEvent(domain.EventRestaurantStatusChanged, restaurantId, input.WorkingStatus))
+	}
+
+	return s.repo.Update(restaurantId, input, events...)
+}
+
+func (s *RestaurantService) GetSchedule(clientId int, clientType string, restaurantId int) (*domain.Schedule, error) {
+	if err := authorize(clientId, clientType, resourceSchedule, actionRead, ownedBy(restaurantType, restaurantId)); err != nil {
+		return nil, err
+	}
+
+	return s.getSchedule(restaurantId)
+}
+
+// UpdateSchedule replaces the opening hours of the restaurant. From then on
+// they set its working status; a restaurant without hours sets it by hand.
+func (s *RestaurantService) UpdateSchedule(clientId int, clientType string, restaurantId int, schedule *domain.Schedule) error {
+	if err := authorize(clientId, clientType, resourceSchedule, actionUpdate, ownedBy(restaurantType, restaurantId)); err != nil {
+		return err
+	}
+
+	if err := validateSchedule(schedule); err != nil {
+		return err
+	}
+
+	if _, err := s.getSchedule(restaurantId); err != nil {
+		return err
+	}
+
+	if err := s.repo.UpdateSchedule(restaurantId, schedule); err != nil {
+		return err
+	}
+
+	return s.hours.sync(time.Now(), restaurantId)
+}
+
+// Run stores the working status the opening hours give the restaurants
+// every interval until the context is done.
+func (s *RestaurantService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.SyncWorkingStatus(); err != nil {
+			log.Printf("opening hours: %s", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *RestaurantService) SyncWorkingStatus() error {
+	return s.hours.sync(time.Now())
+}
+
+func (s *RestaurantService) getSchedule(restaurantId int) (*domain.Schedule, error) {
+	schedules, err := s.repo.GetSchedules([]int{restaurantId})
+	if err != nil {
+		return nil, err
+	}
+
+	schedule, ok := schedules[restaurantId]
+	if !ok {
+		return nil, domain.NewNotFoundError("Restaurant not found")
+	}
+
+	return schedule, nil
+}
+
+func (s *RestaurantService) GetDeliveryZone(clientId int, clientType string, restaurantId int) (*domain.DeliveryZone, error) {
+	if err := authorize(clientId, clientType, resourceDeliveryZone, actionRead, ownedBy(restaurantType, restaurantId)); err != nil {
+		return nil, err
+	}
+
+	return s.getDeliveryZone(restaurantId)
+}
+
+// UpdateDeliveryZone replaces the delivery zone of the restaurant. Users
+// outside of it no longer see the restaurant nor can order from it.
+func (s *RestaurantService) UpdateDeliveryZone(clientId int, clientType string, restaurantId int, zone *domain.DeliveryZone) error {
+	if err := authorize(clientId, clientType, resourceDeliveryZone, actionUpdate, ownedBy(restaurantType, restaurantId)); err != nil {
+		return err
+	}
+
+	if err := validateDeliveryZone(zone); err != nil {
+		return err
+	}
+
+	if _, err := s.getDeliveryZone(restaurantId); err != nil {
+		return err
+	}
+
+	return s.repo.UpdateDeliveryZone(restaurantId, zone)
+}
+
+func (s *RestaurantService) getDeliveryZone(restaurantId int) (*domain.DeliveryZone, error) {
+	zone, err := s.repo.GetDeliveryZone(restaurantId)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, domain.NewNotFoundError("Restaurant not found")
+		}
+		return nil, err
 	}
 
-	return s.repo.Update(restaurantId, input)
+	return zone, nil
 }
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/MAVIKE/yad-backend/internal/consts"
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/MAVIKE/yad-backend/internal/repository"
+)
+
+// restaurantRepo lists the restaurants by id, the cursor being the id of
+// the last one, and has no opening hours.
+type restaurantRepo struct {
+	repository.Restaurant
+	restaurants []*domain.Restaurant
+	calls       int
+}
+
+func (r *restaurantRepo) GetAll(_ int, params *domain.ListParams) (*domain.RestaurantPage, error) {
+	r.calls++
+
+	after := 0
+	if params.Cursor != "" {
+		after, _ = strconv.Atoi(params.Cursor)
+	}
+
+	page := &domain.RestaurantPage{}
+	for _, restaurant := range r.restaurants {
+		if restaurant.Id <= after {
+			continue
+		}
+
+		if len(page.Items) == params.Limit {
+			page.NextCursor = page.Cursors[len(page.Cursors)-1]
+			break
+		}
+
+		copied := *restaurant
+		page.Items = append(page.Items, &copied)
+		page.Cursors = append(page.Cursors, strconv.Itoa(restaurant.Id))
+	}
+
+	return page, nil
+}
+
+func (r *restaurantRepo) GetSchedules([]int) (map[int]*domain.Schedule, error) {
+	return map[int]*domain.Schedule{}, nil
+}
+
+// newRestaurantRepo returns count restaurants, those whose id is a multiple
+// of every working and the others unable.
+func newRestaurantRepo(count, every int) *restaurantRepo {
+	repo := &restaurantRepo{}
+	for id := 1; id <= count; id++ {
+		status := consts.RestaurantUnable
+		if id%every == 0 {
+			status = consts.RestaurantWorking
+		}
+		repo.restaurants = append(repo.restaurants, &domain.Restaurant{Id: id, WorkingStatus: status})
+	}
+
+	return repo
+}
+
+func TestRestaurantService_GetAllFiltered(t *testing.T) {
+	repo := newRestaurantRepo(1000, 7)
+	s := NewRestaurantService(repo, nil, nil)
+
+	filter := &domain.RestaurantFilter{
+		ListParams:    domain.ListParams{Limit: 20},
+		WorkingStatus: consts.RestaurantWorking,
+	}
+
+	var ids []int
+	for pages := 0; ; pages++ {
+		if pages > 100 {
+			t.Fatal("the pages don't end")
+		}
+
+		repo.calls = 0
+		page, err := s.GetAll(ownClientId, userType, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// a batch holds 14 working restaurants, a page of 20 takes two
+		if repo.calls > 2 {
+			t.Errorf("page %d read %d batches", pages, repo.calls)
+		}
+
+		for _, restaurant := range page.Items {
+			ids = append(ids, restaurant.Id)
+		}
+
+		if page.NextCursor == "" {
+			break
+		}
+		filter.Cursor = page.NextCursor
+	}
+
+	if len(ids) != 1000/7 {
+		t.Fatalf("expected %d restaurants, got %d", 1000/7, len(ids))
+	}
+
+	for i, id := range ids {
+		if id != (i+1)*7 {
+			t.Fatalf("restaurant %d: expected id %d, got %d", i, (i+1)*7, id)
+		}
+	}
+}
+
+func TestRestaurantService_GetAllSparse(t *testing.T) {
+	repo := newRestaurantRepo(5000, 2000)
+	s := NewRestaurantService(repo, nil, nil)
+
+	filter := &domain.RestaurantFilter{
+		ListParams:    domain.ListParams{Limit: 20},
+		WorkingStatus: consts.RestaurantWorking,
+	}
+
+	page, err := s.GetAll(ownClientId, userType, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if repo.calls != maxRestaurantBatches {
+		t.Errorf("expected %d batches, got %d", maxRestaurantBatches, repo.calls)
+	}
+
+	// the first working restaurant is out of reach, the cursor goes on
+	if len(page.Items) != 0 || page.NextCursor != strconv.Itoa(maxRestaurantBatches*domain.MaxListLimit) {
+		t.Errorf("expected a short page up to %d, got %d items and cursor %q",
+			maxRestaurantBatches*domain.MaxListLimit, len(page.Items), page.NextCursor)
+	}
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/MAVIKE/yad-backend/internal/consts"
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/MAVIKE/yad-backend/internal/repository"
+)
+
+// maxReviewText is the length of a comment or a reply in characters.
+const maxReviewText = 1000
+
+// ReviewService lets users rate the restaurant and the courier of a
+// delivered order once each. The rating of a restaurant is the average of
+// its visible reviews and is kept up to date by the database.
+type ReviewService struct {
+	repo      repository.Review
+	orderRepo repository.Order
+}
+
+func NewReviewService(repo repository.Review, orderRepo repository.Order) *ReviewService {
+	return &ReviewService{
+		repo:      repo,
+		orderRepo: orderRepo,
+	}
+}
+
+// Create reviews the subject of the order, which is rated by its user once
+// it is delivered.
+func (s *ReviewService) Create(clientId int, clientType string, orderId int, input *domain.Review) (int, error) {
+	order, err := s.getOrder(clientId, clientType, actionCreate, orderId)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := validateReview(input); err != nil {
+		return 0, err
+	}
+
+	if order.Status != consts.OrderDelivered {
+		return 0, domain.NewConflictError("Only delivered orders can be reviewed")
+	}
+
+	review := &domain.Review{
+		OrderId: order.Id,
+		UserId:  order.UserId,
+		Subject: input.Subject,
+		Rating:  input.Rating,
+		Comment: input.Comment,
+	}
+
+	switch input.Subject {
+	case consts.ReviewRestaurant:
+		review.SubjectId = order.RestaurantId
+	case consts.ReviewCourier:
+		if order.CourierId == 0 {
+			return 0, domain.NewConflictError("Order has no courier to review")
+		}
+		review.SubjectId = order.CourierId
+	}
+
+	reviewId, err := s.repo.Create(review)
+	if errors.Is(err, domain.ErrConflict) {
+		return 0, domain.NewConflictError("The %s of the order is already reviewed", input.Subject)
+	}
+
+	return reviewId, err
+}
+
+// GetByOrder returns the reviews of the order. The restaurant and the
+// courier see the reviews about them, hidden reviews are only seen by
+// their author and the moderators.
+func (s *ReviewService) GetByOrder(clientId int, clientType string, orderId int) ([]*domain.Review, error) {
+	if _, err := s.getOrder(clientId, clientType, actionRead, orderId); err != nil {
+		return nil, err
+	}
+
+	reviews, err := s.repo.GetByOrder(orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := make([]*domain.Review, 0, len(reviews))
+	for _, review := range reviews {
+		switch {
+		case clientType == restaurantType && (review.Subject != consts.ReviewRestaurant || review.Hidden):
+		case clientType == courierType && (review.Subject != consts.ReviewCourier || review.Hidden):
+		default:
+			visible = append(visible, review)
+		}
+	}
+
+	return visible, nil
+}
+
+// GetRestaurantReviews returns a page of the reviews of the restaurant.
+// Moderators see the hidden reviews too.
+func (s *ReviewService) GetRestaurantReviews(clientId int, clientType string, restaurantId int,
+	filter *domain.ReviewFilter) (*domain.ReviewPage, error) {
+	if err := authorize(clientId, clientType, resourceReview, actionList, ownedBy(restaurantType, restaurantId)); err != nil {
+		return nil, err
+	}
+
+	if err := validateListParams(&filter.ListParams); err != nil {
+		return nil, err
+	}
+
+	filter.RestaurantId = restaurantId
+	filter.WithHidden = clientType == adminType
+
+	return s.repo.GetAll(filter)
+}
+
+// Reply sets the public reply of the restaurant to a review about it.
+func (s *ReviewService) Reply(clientId int, clientType string, restaurantId, reviewId int, reply string) error {
+	if err := authorize(clientId, clientType, resourceReview, actionReply, ownedBy(restaurantType, restaurantId)); err != nil {
+		return err
+	}
+
+	reply = strings.TrimSpace(reply)
+	if reply == "" || utf8.RuneCountInString(reply) > maxReviewText {
+		return domain.NewValidationError("Reply must be from 1 to %d characters", maxReviewText)
+	}
+
+	review, err := s.repo.GetById(reviewId)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return err
+	}
+
+	if err != nil || review.Subject != consts.ReviewRestaurant || review.SubjectId != restaurantId {
+		return domain.NewNotFoundError("Review not found")
+	}
+
+	return s.repo.Reply(reviewId, reply)
+}
+
+// Moderate hides an abusive review or shows it again.
+func (s *ReviewService) Moderate(clientId int, clientType string, reviewId int, hidden bool) error {
+	if err := authorize(clientId, clientType, resourceReview, actionModerate, target{}); err != nil {
+		return err
+	}
+
+	if _, err := s.repo.GetById(reviewId); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return domain.NewNotFoundError("Review not found")
+		}
+		return err
+	}
+
+	return s.repo.SetHidden(reviewId, hidden)
+}
+
+func (s *ReviewService) getOrder(clientId int, clientType string, act action, orderId int) (*domain.Order, error) {
+	order, err := s.orderRepo.GetById(orderId)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, domain.NewNotFoundError("Order not found")
+		}
+		return nil, err
+	}
+
+	if err := authorize(clientId, clientType, resourceReview, act, orderTarget(order)); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+func validateReview(review *domain.Review) error {
+	if review.Subject != consts.ReviewRestaurant && review.Subject != consts.ReviewCourier {
+		return domain.NewValidationError("Review subject must be %q or %q", consts.ReviewRestaurant, consts.ReviewCourier)
+	}
+
+	if review.Rating < 1 || review.Rating > 5 {
+		return domain.NewValidationError("Rating must be from 1 to 5")
+	}
+
+	review.Comment = strings.TrimSpace(review.Comment)
+	if utf8.RuneCountInString(review.Comment) > maxReviewText {
+		return domain.NewValidationError("Comment must be at most %d characters", maxReviewText)
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/MAVIKE/yad-backend/internal/consts"
+	"github.com/MAVIKE/yad-backend/internal/domain"
+)
+
+func TestValidateReview(t *testing.T) {
+	tests := []struct {
+		name   string
+		review domain.Review
+		valid  bool
+	}{
+		{"restaurant", domain.Review{Subject: consts.ReviewRestaurant, Rating: 5, Comment: "Вкусно"}, true},
+		{"courier without a comment", domain.Review{Subject: consts.ReviewCourier, Rating: 1}, true},
+		{"longest comment", domain.Review{Subject: consts.ReviewCourier, Rating: 3,
+			Comment: strings.Repeat("ж", maxReviewText)}, true},
+		{"unknown subject", domain.Review{Subject: "user", Rating: 5}, false},
+		{"no rating", domain.Review{Subject: consts.ReviewRestaurant}, false},
+		{"rating too high", domain.Review{Subject: consts.ReviewRestaurant, Rating: 6}, false},
+		{"comment too long", domain.Review{Subject: consts.ReviewRestaurant, Rating: 4,
+			Comment: strings.Repeat("ж", maxReviewText+1)}, false},
+	}
+
+	for _, tt := range tests {
+		err := validateReview(&tt.review)
+		if tt.valid && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+
+		if !tt.valid && domain.ErrorCode(err) != domain.CodeValidation {
+			t.Errorf("%s: expected a validation error, got %v", tt.name, err)
+		}
+	}
+}
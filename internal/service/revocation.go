@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/MAVIKE/yad-backend/internal/repository"
+	"github.com/MAVIKE/yad-backend/pkg/auth"
+)
+
+type revokedClientKey struct {
+	id         int
+	clientType string
+}
+
+// defaultRevocationCacheTTL is used when no cache TTL is configured, so the
+// deny list isn't reloaded on every request.
+const defaultRevocationCacheTTL = 30 * time.Second
+
+// RevocationService keeps an in-process copy of the deny list and reloads it
+// from the database once it is older than cacheTTL, so revocations made by
+// other instances are picked up without a query per request. A reload only
+// adds to the copy, so it never undoes a revocation made meanwhile.
+type RevocationService struct {
+	repo           repository.Revocation
+	sessionRepo    repository.Session
+	accessTokenTTL time.Duration
+	cacheTTL       time.Duration
+
+	reloadMu sync.Mutex
+	mu       sync.RWMutex
+	loadedAt time.Time
+	tokens   map[string]time.Time
+	clients  map[revokedClientKey]time.Time
+}
+
+func NewRevocationService(repo repository.Revocation, sessionRepo repository.Session, accessTokenTTL, cacheTTL time.Duration) *RevocationService {
+	if cacheTTL == 0 {
+		cacheTTL = defaultRevocationCacheTTL
+	}
+
+	return &RevocationService{
+		repo:           repo,
+		sessionRepo:    sessionRepo,
+		accessTokenTTL: accessTokenTTL,
+		cacheTTL:       cacheTTL,
+		tokens:         make(map[string]time.Time),
+		clients:        make(map[revokedClientKey]time.Time),
+	}
+}
+
+func (s *RevocationService) IsRevoked(claims *auth.Claims) (bool, error) {
+	if err := s.reloadIfStale(); err != nil {
+		return false, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.tokens[claims.TokenId]; ok {
+		return true, nil
+	}
+
+	revokedBefore, ok := s.clients[revokedClientKey{claims.ClientId, claims.ClientType}]
+	if ok && claims.IssuedAt.Before(revokedBefore) {
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (s *RevocationService) RevokeToken(tokenId string, expiresAt time.Time) error {
+	if err := s.repo.RevokeToken(tokenId, expiresAt); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.tokens[tokenId] = expiresAt
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *RevocationService) RevokeClient(clientId int, clientType string, targetId int, targetType string) error {
+	if err := authorize(clientId, clientType, resourceRevocation, actionCreate, target{}); err != nil {
+		return err
+	}
+
+	switch targetType {
+	case adminType, userType, courierType, restaurantType:
+		break
+	default:
+		return domain.NewValidationError("client_type input error")
+	}
+
+	// Tokens carry the issue time in whole seconds, so every token issued
+	// up to the end of the current second is revoked.
+	revokedBefore := time.Now().Truncate(time.Second).Add(time.Second)
+	if err := s.repo.RevokeClient(targetId, targetType, revokedBefore); err != nil {
+		return err
+	}
+
+	if err := s.sessionRepo.DeleteAll(targetId, targetType); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.revokeClient(revokedClientKey{targetId, targetType}, revokedBefore)
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Run drops the expired revocations from the database and the cache every
+// interval until the context is done.
+func (s *RevocationService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.DeleteExpired(); err != nil {
+			log.Printf("revocations: %s", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeleteExpired drops the revocations of the access tokens that have
+// expired anyway.
+func (s *RevocationService) DeleteExpired() error {
+	now := time.Now()
+
+	// Access tokens issued before this moment have already expired,
+	// so client revocations older than that can be dropped.
+	clientsRevokedBefore := now.Add(-s.accessTokenTTL)
+	if err := s.repo.DeleteExpired(clientsRevokedBefore); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for tokenId, expiresAt := range s.tokens {
+		if !expiresAt.After(now) {
+			delete(s.tokens, tokenId)
+		}
+	}
+
+	for key, revokedBefore := range s.clients {
+		if revokedBefore.Before(clientsRevokedBefore) {
+			delete(s.clients, key)
+		}
+	}
+
+	return nil
+}
+
+func (s *RevocationService) reloadIfStale() error {
+	if s.fresh() {
+		return nil
+	}
+
+	// one request reloads, the others wait for it and use its result
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	if s.fresh() {
+		return nil
+	}
+
+	revokedTokens, err := s.repo.GetRevokedTokens()
+	if err != nil {
+		return err
+	}
+
+	revokedClients, err := s.repo.GetRevokedClients()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, token := range revokedTokens {
+		s.tokens[token.TokenId] = token.ExpiresAt
+	}
+
+	for _, client := range revokedClients {
+		s.revokeClient(revokedClientKey{client.ClientId, client.ClientType}, client.RevokedBefore)
+	}
+
+	s.loadedAt = time.Now()
+
+	return nil
+}
+
+func (s *RevocationService) fresh() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return time.Since(s.loadedAt) < s.cacheTTL
+}
+
+// revokeClient keeps the latest revocation of the client. The caller must
+// hold mu.
+func (s *RevocationService) revokeClient(key revokedClientKey, revokedBefore time.Time) {
+	if revokedBefore.After(s.clients[key]) {
+		s.clients[key] = revokedBefore
+	}
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/MAVIKE/yad-backend/internal/repository"
+	"github.com/MAVIKE/yad-backend/pkg/auth"
+)
+
+// revocationRepo serves a fixed deny list, as if another instance had
+// written it, and drops the writes of this instance.
+type revocationRepo struct {
+	repository.Revocation
+	tokens  []*domain.RevokedToken
+	clients []*domain.RevokedClient
+}
+
+func (r *revocationRepo) RevokeToken(string, time.Time) error {
+	return nil
+}
+
+func (r *revocationRepo) RevokeClient(int, string, time.Time) error {
+	return nil
+}
+
+func (r *revocationRepo) GetRevokedTokens() ([]*domain.RevokedToken, error) {
+	return r.tokens, nil
+}
+
+func (r *revocationRepo) GetRevokedClients() ([]*domain.RevokedClient, error) {
+	return r.clients, nil
+}
+
+type sessionRepo struct {
+	repository.Session
+}
+
+func (r *sessionRepo) DeleteAll(int, string) error {
+	return nil
+}
+
+func TestRevocationService_SameSecond(t *testing.T) {
+	s := NewRevocationService(&revocationRepo{}, &sessionRepo{}, time.Hour, time.Hour)
+
+	issuedAt := time.Unix(time.Now().Unix(), 0)
+	if err := s.RevokeClient(1, adminType, ownClientId, userType); err != nil {
+		t.Fatal(err)
+	}
+
+	// the token was issued in the second of the revocation, possibly before it
+	revoked, err := s.IsRevoked(&auth.Claims{ClientId: ownClientId, ClientType: userType, IssuedAt: issuedAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !revoked {
+		t.Errorf("token issued in the second of the revocation must be revoked")
+	}
+
+	revoked, err = s.IsRevoked(&auth.Claims{ClientId: ownClientId, ClientType: userType, IssuedAt: issuedAt.Add(2 * time.Second)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revoked {
+		t.Errorf("token issued after the revocation must not be revoked")
+	}
+}
+
+func TestRevocationService_ReloadKeepsRevocations(t *testing.T) {
+	repo := &revocationRepo{
+		tokens: []*domain.RevokedToken{{TokenId: "other", ExpiresAt: time.Now().Add(time.Hour)}},
+	}
+	s := NewRevocationService(repo, &sessionRepo{}, time.Hour, time.Hour)
+
+	// revoked here after the database has been read by a reload
+	if err := s.RevokeToken("own", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tokenId := range []string{"own", "other"} {
+		revoked, err := s.IsRevoked(&auth.Claims{TokenId: tokenId, IssuedAt: time.Now()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !revoked {
+			t.Errorf("token %q must be revoked", tokenId)
+		}
+	}
+}
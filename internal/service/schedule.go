@@ -0,0 +1,291 @@
+package service
+
+import (
+	"errors"
+	"time"
+	// the zone database is built in so that restaurant time zones load
+	// on hosts without one
+	_ "time/tzdata"
+
+	"github.com/MAVIKE/yad-backend/internal/consts"
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/MAVIKE/yad-backend/internal/repository"
+)
+
+const (
+	clockLayout = "15:04"
+	dateLayout  = "2006-01-02"
+
+	// scheduleHorizon is how many days ahead the next opening is looked for.
+	scheduleHorizon = 14
+)
+
+// openingHours keeps the working status of restaurants with opening hours
+// in line with them; restaurants without hours keep the status set by hand.
+// The status is applied on every read and stored by sync, which tells
+// clients about the changes. The order, cart and payment services use it to
+// refuse orders outside the opening hours.
+type openingHours struct {
+	repo repository.Restaurant
+}
+
+// apply sets the working status of the restaurants from their opening
+// hours at now, and the time they open next if they are closed.
+func (o *openingHours) apply(now time.Time, restaurants ...*domain.Restaurant) error {
+	if len(restaurants) == 0 {
+		return nil
+	}
+
+	restaurantIds := make([]int, 0, len(restaurants))
+	for _, restaurant := range restaurants {
+		restaurantIds = append(restaurantIds, restaurant.Id)
+	}
+
+	schedules, err := o.repo.GetSchedules(restaurantIds)
+	if err != nil {
+		return err
+	}
+
+	for _, restaurant := range restaurants {
+		schedule, ok := schedules[restaurant.Id]
+		if !ok || len(schedule.Hours) == 0 {
+			continue
+		}
+
+		open, next, err := scheduleState(schedule, now)
+		if err != nil {
+			return err
+		}
+
+		restaurant.NextOpening = next
+		if open {
+			restaurant.WorkingStatus = consts.RestaurantWorking
+		} else {
+			restaurant.WorkingStatus = consts.RestaurantUnable
+		}
+	}
+
+	return nil
+}
+
+// sync stores the working status the opening hours give the restaurants
+// at now and writes an EventRestaurantStatusChanged for every change. With
+// no ids it syncs every restaurant with opening hours.
+func (o *openingHours) sync(now time.Time, restaurantIds ...int) error {
+	if len(restaurantIds) == 0 {
+		var err error
+		if restaurantIds, err = o.repo.GetScheduledIds(); err != nil {
+			return err
+		}
+	}
+
+	restaurants := make([]*domain.Restaurant, 0, len(restaurantIds))
+	for _, restaurantId := range restaurantIds {
+		restaurants = append(restaurants, &domain.Restaurant{Id: restaurantId})
+	}
+
+	if err := o.apply(now, restaurants...); err != nil {
+		return err
+	}
+
+	for _, restaurant := range restaurants {
+		// no opening hours
+		if restaurant.WorkingStatus == 0 {
+			continue
+		}
+
+		changed := domain.NewRestaurantEvent(domain.EventRestaurantStatusChanged, restaurant.Id, restaurant.WorkingStatus)
+		if err := o.repo.SetWorkingStatus(restaurant.Id, restaurant.WorkingStatus, changed); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// check returns an error if the restaurant is closed, by hand or by its
+// opening hours.
+func (o *openingHours) check(restaurantId int) error {
+	restaurant, err := o.repo.GetById(restaurantId)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return domain.NewValidationError("Invalid restaurantId")
+		}
+		return err
+	}
+
+	if err := o.apply(time.Now(), restaurant); err != nil {
+		return err
+	}
+
+	if restaurant.WorkingStatus == consts.RestaurantWorking {
+		return nil
+	}
+
+	if restaurant.NextOpening == nil {
+		return domain.NewRestaurantClosedError("Restaurant is closed")
+	}
+
+	return domain.NewRestaurantClosedError("Restaurant is closed until %s", restaurant.NextOpening.Format(time.RFC3339))
+}
+
+// scheduleState reports whether the schedule has the restaurant open at
+// now and, if it is closed, when it opens next within the horizon.
+func scheduleState(schedule *domain.Schedule, now time.Time) (bool, *time.Time, error) {
+	loc, err := time.LoadLocation(schedule.TimeZone)
+	if err != nil {
+		return false, nil, err
+	}
+
+	holidays := make(map[string]*domain.Holiday, len(schedule.Holidays))
+	for _, holiday := range schedule.Holidays {
+		holidays[holiday.Date] = holiday
+	}
+
+	local := now.In(loc)
+	var next *time.Time
+
+	// the hours of the day before may last past midnight
+	for d := -1; d <= scheduleHorizon; d++ {
+		day := time.Date(local.Year(), local.Month(), local.Day()+d, 0, 0, 0, 0, loc)
+
+		intervals, err := dayHours(schedule, holidays, day)
+		if err != nil {
+			return false, nil, err
+		}
+
+		for _, interval := range intervals {
+			opens, closes := interval[0], interval[1]
+			if !now.Before(opens) && now.Before(closes) {
+				return true, nil, nil
+			}
+
+			if opens.After(now) && (next == nil || opens.Before(*next)) {
+				opening := opens
+				next = &opening
+			}
+		}
+
+		if next != nil && d >= 0 {
+			break
+		}
+	}
+
+	return false, next, nil
+}
+
+// dayHours returns the opening and closing times of the day, the holiday
+// on the day replacing its weekly hours.
+func dayHours(schedule *domain.Schedule, holidays map[string]*domain.Holiday, day time.Time) ([][2]time.Time, error) {
+	var intervals [][2]time.Time
+
+	if holiday, ok := holidays[day.Format(dateLayout)]; ok {
+		if holiday.OpensAt == nil || holiday.ClosesAt == nil {
+			return nil, nil
+		}
+
+		interval, err := openInterval(day, *holiday.OpensAt, *holiday.ClosesAt)
+		if err != nil {
+			return nil, err
+		}
+
+		return append(intervals, interval), nil
+	}
+
+	for _, hours := range schedule.Hours {
+		if hours.Weekday != isoWeekday(day) {
+			continue
+		}
+
+		interval, err := openInterval(day, hours.OpensAt, hours.ClosesAt)
+		if err != nil {
+			return nil, err
+		}
+
+		intervals = append(intervals, interval)
+	}
+
+	return intervals, nil
+}
+
+func openInterval(day time.Time, opensAt, closesAt string) ([2]time.Time, error) {
+	opensClock, err := time.Parse(clockLayout, opensAt)
+	if err != nil {
+		return [2]time.Time{}, err
+	}
+
+	closesClock, err := time.Parse(clockLayout, closesAt)
+	if err != nil {
+		return [2]time.Time{}, err
+	}
+
+	opens := time.Date(day.Year(), day.Month(), day.Day(), opensClock.Hour(), opensClock.Minute(), 0, 0, day.Location())
+	closes := time.Date(day.Year(), day.Month(), day.Day(), closesClock.Hour(), closesClock.Minute(), 0, 0, day.Location())
+	if !closes.After(opens) {
+		closes = time.Date(day.Year(), day.Month(), day.Day()+1, closesClock.Hour(), closesClock.Minute(), 0, 0, day.Location())
+	}
+
+	return [2]time.Time{opens, closes}, nil
+}
+
+// isoWeekday numbers the days of the week from Monday, 1, to Sunday, 7.
+func isoWeekday(day time.Time) int {
+	if day.Weekday() == time.Sunday {
+		return 7
+	}
+
+	return int(day.Weekday())
+}
+
+// validateSchedule checks the schedule before it replaces the one of the
+// restaurant.
+func validateSchedule(schedule *domain.Schedule) error {
+	if schedule.TimeZone == "" || schedule.TimeZone == "Local" {
+		return domain.NewValidationError("Invalid time_zone")
+	}
+
+	if _, err := time.LoadLocation(schedule.TimeZone); err != nil {
+		return domain.NewValidationError("Invalid time_zone")
+	}
+
+	for _, hours := range schedule.Hours {
+		if hours.Weekday < 1 || hours.Weekday > 7 {
+			return domain.NewValidationError("Weekday must be from 1 to 7")
+		}
+
+		if !validClock(hours.OpensAt) || !validClock(hours.ClosesAt) {
+			return domain.NewValidationError("Opening hours must be in the HH:MM format")
+		}
+	}
+
+	dates := make(map[string]bool, len(schedule.Holidays))
+	for _, holiday := range schedule.Holidays {
+		if _, err := time.Parse(dateLayout, holiday.Date); err != nil {
+			return domain.NewValidationError("Holiday date must be in the YYYY-MM-DD format")
+		}
+
+		if dates[holiday.Date] {
+			return domain.NewValidationError("Holiday %s is given twice", holiday.Date)
+		}
+		dates[holiday.Date] = true
+
+		if (holiday.OpensAt == nil) != (holiday.ClosesAt == nil) {
+			return domain.NewValidationError("Holiday hours need both opens_at and closes_at")
+		}
+
+		if holiday.OpensAt != nil && (!validClock(*holiday.OpensAt) || !validClock(*holiday.ClosesAt)) {
+			return domain.NewValidationError("Opening hours must be in the HH:MM format")
+		}
+
+		if len(holiday.Reason) > 100 {
+			return domain.NewValidationError("Holiday reason must be at most 100 characters")
+		}
+	}
+
+	return nil
+}
+
+func validClock(value string) bool {
+	_, err := time.Parse(clockLayout, value)
+	return err == nil
+}
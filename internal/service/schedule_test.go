@@ -0,0 +1,133 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+)
+
+func clock(value string) *string {
+	return &value
+}
+
+func TestScheduleState(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 1 January 2024 is a Monday
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2024, time.January, day, hour, min, 0, 0, moscow)
+	}
+
+	weekdays := &domain.Schedule{
+		TimeZone: "Europe/Moscow",
+		Hours: []*domain.OpeningHours{
+			{Weekday: 1, OpensAt: "10:00", ClosesAt: "22:00"},
+			{Weekday: 2, OpensAt: "10:00", ClosesAt: "22:00"},
+			{Weekday: 5, OpensAt: "18:00", ClosesAt: "02:00"},
+		},
+		Holidays: []*domain.Holiday{
+			{Date: "2024-01-02"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		schedule *domain.Schedule
+		now      time.Time
+		open     bool
+		next     *time.Time
+	}{
+		{"open", weekdays, at(1, 12, 0), true, nil},
+		{"opens at the hour", weekdays, at(1, 10, 0), true, nil},
+		{"closes at the hour", weekdays, at(1, 22, 0), false, timePtr(at(5, 18, 0))},
+		{"before opening", weekdays, at(1, 9, 59), false, timePtr(at(1, 10, 0))},
+		{"closed on a holiday", weekdays, at(2, 12, 0), false, timePtr(at(5, 18, 0))},
+		{"past midnight", weekdays, at(6, 1, 30), true, nil},
+		{"after the night", weekdays, at(6, 2, 0), false, timePtr(at(8, 10, 0))},
+		{"in another time zone", weekdays, time.Date(2024, time.January, 1, 8, 0, 0, 0, time.UTC), true, nil},
+		{"holiday hours", &domain.Schedule{
+			TimeZone: "Europe/Moscow",
+			Hours:    weekdays.Hours,
+			Holidays: []*domain.Holiday{{Date: "2024-01-02", OpensAt: clock("12:00"), ClosesAt: clock("14:00")}},
+		}, at(2, 11, 0), false, timePtr(at(2, 12, 0))},
+		{"open all day", &domain.Schedule{
+			TimeZone: "UTC",
+			Hours:    []*domain.OpeningHours{{Weekday: 1, OpensAt: "00:00", ClosesAt: "00:00"}},
+		}, time.Date(2024, time.January, 1, 23, 59, 0, 0, time.UTC), true, nil},
+		{"never opens", &domain.Schedule{
+			TimeZone: "UTC",
+			Holidays: []*domain.Holiday{{Date: "2024-01-01"}},
+		}, at(1, 12, 0), false, nil},
+	}
+
+	for _, tt := range tests {
+		open, next, err := scheduleState(tt.schedule, tt.now)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+
+		if open != tt.open {
+			t.Errorf("%s: expected open %t, got %t", tt.name, tt.open, open)
+		}
+
+		if (next == nil) != (tt.next == nil) || next != nil && !next.Equal(*tt.next) {
+			t.Errorf("%s: expected next opening %v, got %v", tt.name, tt.next, next)
+		}
+	}
+}
+
+func TestValidateSchedule(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule *domain.Schedule
+		valid    bool
+	}{
+		{"valid", &domain.Schedule{
+			TimeZone: "Asia/Novosibirsk",
+			Hours:    []*domain.OpeningHours{{Weekday: 7, OpensAt: "09:30", ClosesAt: "23:00"}},
+			Holidays: []*domain.Holiday{{Date: "2024-12-31", OpensAt: clock("10:00"), ClosesAt: clock("18:00")}},
+		}, true},
+		{"no time zone", &domain.Schedule{}, false},
+		{"unknown time zone", &domain.Schedule{TimeZone: "Mars/Olympus"}, false},
+		{"bad weekday", &domain.Schedule{
+			TimeZone: "UTC",
+			Hours:    []*domain.OpeningHours{{Weekday: 0, OpensAt: "09:00", ClosesAt: "18:00"}},
+		}, false},
+		{"bad time", &domain.Schedule{
+			TimeZone: "UTC",
+			Hours:    []*domain.OpeningHours{{Weekday: 1, OpensAt: "24:00", ClosesAt: "18:00"}},
+		}, false},
+		{"bad date", &domain.Schedule{
+			TimeZone: "UTC",
+			Holidays: []*domain.Holiday{{Date: "31.12.2024"}},
+		}, false},
+		{"same date twice", &domain.Schedule{
+			TimeZone: "UTC",
+			Holidays: []*domain.Holiday{{Date: "2024-12-31"}, {Date: "2024-12-31"}},
+		}, false},
+		{"holiday without closing time", &domain.Schedule{
+			TimeZone: "UTC",
+			Holidays: []*domain.Holiday{{Date: "2024-12-31", OpensAt: clock("10:00")}},
+		}, false},
+	}
+
+	for _, tt := range tests {
+		err := validateSchedule(tt.schedule)
+		if tt.valid && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+
+		if !tt.valid && domain.ErrorCode(err) != domain.CodeValidation {
+			t.Errorf("%s: expected a validation error, got %v", tt.name, err)
+		}
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
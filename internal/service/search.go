@@ -0,0 +1,48 @@
+package service
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+)
+
+const maxSearchQuery = 100
+
+// Search returns a page of the restaurants delivering to the user that match
+// the search, with their matching dishes.
+func (s *RestaurantService) Search(clientId int, clientType string, filter *domain.SearchFilter) (*domain.SearchPage, error) {
+	if err := authorize(clientId, clientType, resourceRestaurant, actionList, target{}); err != nil {
+		return nil, err
+	}
+
+	if err := validateSearchFilter(filter); err != nil {
+		return nil, err
+	}
+
+	page, err := s.repo.Search(clientId, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	restaurants := make([]*domain.Restaurant, 0, len(page.Items))
+	for _, result := range page.Items {
+		restaurants = append(restaurants, result.Restaurant)
+	}
+
+	if err := s.hours.apply(time.Now(), restaurants...); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+func validateSearchFilter(filter *domain.SearchFilter) error {
+	filter.Query = strings.TrimSpace(filter.Query)
+	if filter.Query == "" || utf8.RuneCountInString(filter.Query) > maxSearchQuery {
+		return domain.NewValidationError("Search query must be from 1 to %d characters", maxSearchQuery)
+	}
+
+	return validateListParams(&filter.ListParams)
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+)
+
+func TestValidateSearchFilter(t *testing.T) {
+	page := domain.ListParams{Limit: domain.DefaultListLimit}
+
+	tests := []struct {
+		name  string
+		query string
+		valid bool
+	}{
+		{"word", "pizza", true},
+		{"phrase", `"пицца маргарита" -острая`, true},
+		{"longest", strings.Repeat("ж", maxSearchQuery), true},
+		{"empty", "", false},
+		{"blank", "  \t", false},
+		{"too long", strings.Repeat("ж", maxSearchQuery+1), false},
+	}
+
+	for _, tt := range tests {
+		err := validateSearchFilter(&domain.SearchFilter{ListParams: page, Query: tt.query})
+		if tt.valid && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+
+		if !tt.valid && domain.ErrorCode(err) != domain.CodeValidation {
+			t.Errorf("%s: expected a validation error, got %v", tt.name, err)
+		}
+	}
+}
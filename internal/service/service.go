@@ -1,11 +1,16 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"net/http"
 	"time"
 
 	"github.com/MAVIKE/yad-backend/internal/domain"
 	"github.com/MAVIKE/yad-backend/internal/repository"
 	"github.com/MAVIKE/yad-backend/pkg/auth"
+	"github.com/MAVIKE/yad-backend/pkg/hash"
+	"github.com/MAVIKE/yad-backend/pkg/payment"
 )
 
 const (
@@ -15,8 +20,39 @@ const (
 	restaurantType = "restaurant"
 )
 
+var errInvalidCredentials = domain.NewUnauthorizedError("Invalid credentials")
+
 type Tokens struct {
-	AccessToken string `json:"token"`
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// checkPassword compares the password with the stored hash. When the stored
+// value is a legacy plaintext password or was hashed with another cost,
+// a fresh hash is returned so that the caller can save it.
+func checkPassword(hasher hash.PasswordHasher, passwordHash, password string) (string, error) {
+	ok, err := hasher.Compare(passwordHash, password)
+	if err != nil {
+		return "", err
+	}
+
+	if !ok {
+		return "", errInvalidCredentials
+	}
+
+	if !hasher.NeedsRehash(passwordHash) {
+		return "", nil
+	}
+
+	return hasher.Hash(password)
+}
+
+func credentialsError(err error) error {
+	if errors.Is(err, domain.ErrNotFound) {
+		return errInvalidCredentials
+	}
+
+	return err
 }
 
 type Admin interface {
@@ -26,19 +62,26 @@ type Admin interface {
 type User interface {
 	SignIn(phone, password string) (*Tokens, error)
 	SignUp(user *domain.User) (int, error)
-	GetAllOrders(clientId int, clientType string, userId int, activeOrdersFlag bool) ([]*domain.Order, error)
+	GetAllOrders(clientId int, clientType string, userId int, filter *domain.OrderFilter) (*domain.OrderPage, error)
 	Update(clientId int, clientType string, userId int, input *domain.User) error
 	GetById(clientId int, clientType string, userId int) (*domain.User, error)
 }
 
 type Restaurant interface {
-	GetAll(clientId int, clientType string) ([]*domain.Restaurant, error)
+	GetAll(clientId int, clientType string, filter *domain.RestaurantFilter) (*domain.RestaurantPage, error)
+	Search(clientId int, clientType string, filter *domain.SearchFilter) (*domain.SearchPage, error)
 	GetById(clientId int, clientType string, restaurantId int) (*domain.Restaurant, error)
 	SignIn(phone, password string) (*Tokens, error)
-	GetMenu(clientId int, clientType string, restaurantId int) ([]*domain.MenuItem, error)
+	GetMenu(clientId int, clientType string, restaurantId int, filter *domain.MenuFilter) (*domain.MenuPage, error)
 	SignUp(restaurant *domain.Restaurant, clientType string) (int, error)
 	UpdateImage(clientId int, clientType string, restaurantId int, image string) (*domain.Restaurant, error)
 	Update(clientId int, clientType string, restaurantId int, input *domain.Restaurant) error
+	GetSchedule(clientId int, clientType string, restaurantId int) (*domain.Schedule, error)
+	UpdateSchedule(clientId int, clientType string, restaurantId int, schedule *domain.Schedule) error
+	Run(ctx context.Context, interval time.Duration)
+	SyncWorkingStatus() error
+	GetDeliveryZone(clientId int, clientType string, restaurantId int) (*domain.DeliveryZone, error)
+	UpdateDeliveryZone(clientId int, clientType string, restaurantId int, zone *domain.DeliveryZone) error
 }
 
 type Courier interface {
@@ -62,7 +105,10 @@ type Order interface {
 	GetById(clientId int, clientType string, orderId int) (*domain.Order, error)
 	Delete(clientId int, clientType string, orderId int) error
 	Update(clientId int, clientType string, orderId int, status *domain.Order) error
-	GetActiveRestaurantOrders(clientId int, clientType string, restaurantId int) ([]*domain.Order, error)
+	GetTransitions(clientId int, clientType string, orderId int) ([]*domain.OrderTransition, error)
+	GetTimeline(clientId int, clientType string, orderId int) ([]*domain.OrderEvent, error)
+	GetReceipt(clientId int, clientType string, orderId int) (*domain.Receipt, error)
+	GetRestaurantOrders(clientId int, clientType string, restaurantId int, filter *domain.OrderFilter) (*domain.OrderPage, error)
 	CreateItem(clientId int, clientType string, orderItem *domain.OrderItem) (int, error)
 	GetAllItems(clientId int, clientType string, orderId int) ([]*domain.OrderItem, error)
 	GetItemById(clientId int, clientType string, orderId, orderItemId int) (*domain.OrderItem, error)
@@ -79,6 +125,98 @@ type MenuItem interface {
 	Delete(clientId int, clientType string, restaurantId int, menuItemId int) error
 }
 
+type Session interface {
+	Create(clientId int, clientType string) (*Tokens, error)
+	Refresh(clientType, refreshToken string) (*Tokens, error)
+	SignOut(clientId int, clientType string, refreshToken string) error
+	GetAll(clientId int, clientType string, ownerId int, ownerType string) ([]*domain.Session, error)
+	Delete(clientId int, clientType string, ownerId int, ownerType string, sessionId int) error
+}
+
+type Revocation interface {
+	IsRevoked(claims *auth.Claims) (bool, error)
+	RevokeToken(tokenId string, expiresAt time.Time) error
+	RevokeClient(clientId int, clientType string, targetId int, targetType string) error
+	Run(ctx context.Context, interval time.Duration)
+	DeleteExpired() error
+}
+
+type Dispatch interface {
+	Run(ctx context.Context, interval time.Duration)
+	Notify()
+	AssignPending() error
+	GetPending(clientId int, clientType string) ([]*domain.PendingOrder, error)
+	GetOffers(clientId int, clientType string, courierId int) ([]*domain.CourierOffer, error)
+	AcceptOffer(clientId int, clientType string, courierId, offerId int) error
+	DeclineOffer(clientId int, clientType string, courierId, offerId int) error
+}
+
+type Events interface {
+	Subscribe(clientId int, clientType string) (<-chan *domain.Event, func())
+	Publish(event *domain.Event)
+	Run(ctx context.Context)
+}
+
+type EventTicket interface {
+	Create(claims *auth.Claims) (*domain.EventTicket, error)
+	Redeem(ticket string) (*auth.Claims, error)
+}
+
+type Webhook interface {
+	Create(clientId int, clientType string, webhook *domain.Webhook) (int, error)
+	GetAll(clientId int, clientType string, restaurantId int) ([]*domain.Webhook, error)
+	Delete(clientId int, clientType string, restaurantId, webhookId int) error
+	GetDeliveries(clientId int, clientType string, restaurantId, webhookId int) ([]*domain.WebhookDelivery, error)
+	Enqueue(event *domain.Event) error
+	Run(ctx context.Context, interval time.Duration)
+	DeliverPending() error
+}
+
+type Payment interface {
+	Create(clientId int, clientType string, orderId int) (*domain.Payment, error)
+	Get(clientId int, clientType string, orderId int) (*domain.Payment, error)
+	Confirm(clientId int, clientType string, orderId int) (*domain.Payment, error)
+	HandleCallback(header http.Header, body []byte) error
+}
+
+type Cart interface {
+	Get(clientId int, clientType string, userId int) (*domain.Cart, error)
+	AddItem(clientId int, clientType string, userId int, item *domain.CartItem, replace bool) (int, error)
+	UpdateItem(clientId int, clientType string, userId, cartItemId, count int) error
+	DeleteItem(clientId int, clientType string, userId, cartItemId int) error
+	Delete(clientId int, clientType string, userId int) error
+	Checkout(clientId int, clientType string, userId int) (int, error)
+	Run(ctx context.Context, interval time.Duration)
+	Expire() error
+}
+
+type Refund interface {
+	Create(clientId int, clientType string, orderId int, input *domain.Refund) (int, error)
+	GetAll(clientId int, clientType string, orderId int) ([]*domain.Refund, error)
+	RefundOrder(order *domain.Order, event *domain.OrderEvent) error
+	RefundItem(actorId int, actorType string, order *domain.Order, orderItemId int) error
+	Run(ctx context.Context, interval time.Duration)
+	ProcessPending() error
+}
+
+type Review interface {
+	Create(clientId int, clientType string, orderId int, input *domain.Review) (int, error)
+	GetByOrder(clientId int, clientType string, orderId int) ([]*domain.Review, error)
+	GetRestaurantReviews(clientId int, clientType string, restaurantId int, filter *domain.ReviewFilter) (*domain.ReviewPage, error)
+	Reply(clientId int, clientType string, restaurantId, reviewId int, reply string) error
+	Moderate(clientId int, clientType string, reviewId int, hidden bool) error
+}
+
+type Outbox interface {
+	Run(ctx context.Context, interval time.Duration)
+	Relay() error
+}
+
+type Tracking interface {
+	AddLocation(clientId int, clientType string, courierId int, location *domain.Location) error
+	GetOrderCourierLocation(clientId int, clientType string, orderId int) (*domain.CourierLocation, error)
+}
+
 type Service struct {
 	Admin
 	User
@@ -87,22 +225,70 @@ type Service struct {
 	Category
 	Order
 	MenuItem
+	Session
+	Revocation
+	Dispatch
+	Tracking
+	Events
+	EventTicket
+	Webhook
+	Outbox
+	Payment
+	Refund
+	Cart
+	Review
 }
 
 type Deps struct {
-	Repos          *repository.Repository
-	TokenManager   auth.TokenManager
-	AccessTokenTTL time.Duration
+	Repos           *repository.Repository
+	EventListener   repository.EventListener
+	TokenManager    auth.TokenManager
+	Hasher          hash.PasswordHasher
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	RevocationCacheTTL time.Duration
+	DeliveryPricing    DeliveryPricing
+
+	DispatchOfferTimeout  time.Duration
+	DispatchOfferCooldown time.Duration
+	DispatchEscalateAfter time.Duration
+	LocationRetention     time.Duration
+	Webhooks              WebhookConfig
+	OutboxRetention       time.Duration
+	PaymentProvider       payment.Provider
+	CartTTL               time.Duration
 }
 
 func NewService(deps Deps) *Service {
+	sessionService := NewSessionService(deps.Repos.Session, deps.TokenManager, deps.AccessTokenTTL, deps.RefreshTokenTTL)
+	events := NewEventBus(deps.EventListener)
+	webhookService := NewWebhookService(deps.Repos.Webhook, deps.Webhooks)
+	dispatchService := NewDispatchService(deps.Repos.Dispatch, deps.Repos.Order,
+		deps.DispatchOfferTimeout, deps.DispatchOfferCooldown, deps.DispatchEscalateAfter)
+	paymentService := NewPaymentService(deps.Repos.Payment, deps.Repos.Order, deps.Repos.Restaurant,
+		deps.PaymentProvider, deps.DeliveryPricing, dispatchService)
+	refundService := NewRefundService(deps.Repos.Refund, deps.Repos.Order, deps.Repos.Payment, deps.PaymentProvider)
+
 	return &Service{
-		Admin:      NewAdminService(deps.Repos.Admin, deps.TokenManager, deps.AccessTokenTTL),
-		User:       NewUserService(deps.Repos.User, deps.TokenManager, deps.AccessTokenTTL),
-		Courier:    NewCourierService(deps.Repos.Courier, deps.Repos.Order, deps.TokenManager, deps.AccessTokenTTL),
-		Restaurant: NewRestaurantService(deps.Repos.Restaurant, deps.TokenManager, deps.AccessTokenTTL),
-		Category:   NewCategoryService(deps.Repos.Category),
-		Order:      NewOrderService(deps.Repos.Order),
-		MenuItem:   NewMenuItemService(deps.Repos.MenuItem, deps.Repos.Category),
+		Admin:       NewAdminService(deps.Repos.Admin, deps.Hasher, sessionService),
+		User:        NewUserService(deps.Repos.User, deps.Repos.Order, deps.Hasher, sessionService, deps.DeliveryPricing),
+		Courier:     NewCourierService(deps.Repos.Courier, deps.Repos.Order, deps.Hasher, sessionService, dispatchService),
+		Restaurant:  NewRestaurantService(deps.Repos.Restaurant, deps.Hasher, sessionService),
+		Category:    NewCategoryService(deps.Repos.Category),
+		Order:       NewOrderService(deps.Repos.Order, deps.Repos.MenuItem, deps.Repos.Restaurant, deps.DeliveryPricing, refundService),
+		MenuItem:    NewMenuItemService(deps.Repos.MenuItem, deps.Repos.Category),
+		Session:     sessionService,
+		Revocation:  NewRevocationService(deps.Repos.Revocation, deps.Repos.Session, deps.AccessTokenTTL, deps.RevocationCacheTTL),
+		Dispatch:    dispatchService,
+		Tracking:    NewTrackingService(deps.Repos.Tracking, deps.Repos.Order, deps.LocationRetention),
+		Events:      events,
+		EventTicket: NewEventTicketService(deps.Repos.EventTicket, deps.TokenManager),
+		Webhook:     webhookService,
+		Outbox:      NewOutboxService(deps.Repos.Outbox, deps.OutboxRetention, webhookService),
+		Payment:     paymentService,
+		Refund:      refundService,
+		Cart:        NewCartService(deps.Repos.Cart, deps.Repos.MenuItem, deps.Repos.Order, deps.Repos.Restaurant, deps.DeliveryPricing, deps.CartTTL),
+		Review:      NewReviewService(deps.Repos.Review, deps.Repos.Order),
 	}
 }
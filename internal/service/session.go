@@ -0,0 +1,145 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/MAVIKE/yad-backend/internal/repository"
+	"github.com/MAVIKE/yad-backend/pkg/auth"
+)
+
+var errInvalidRefreshToken = domain.NewUnauthorizedError("Invalid refresh token")
+
+type SessionService struct {
+	repo            repository.Session
+	tokenManager    auth.TokenManager
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+}
+
+func NewSessionService(repo repository.Session, tokenManager auth.TokenManager, accessTokenTTL, refreshTokenTTL time.Duration) *SessionService {
+	return &SessionService{
+		repo:            repo,
+		tokenManager:    tokenManager,
+		accessTokenTTL:  accessTokenTTL,
+		refreshTokenTTL: refreshTokenTTL,
+	}
+}
+
+func (s *SessionService) Create(clientId int, clientType string) (*Tokens, error) {
+	refreshToken, err := s.tokenManager.NewRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	session := &domain.Session{
+		ClientId:     clientId,
+		ClientType:   clientType,
+		RefreshToken: hashRefreshToken(refreshToken),
+		ExpiresAt:    time.Now().Add(s.refreshTokenTTL),
+	}
+
+	if _, err := s.repo.Create(session); err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.tokenManager.NewJWT(clientId, clientType, s.accessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tokens{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+func (s *SessionService) Refresh(clientType, refreshToken string) (*Tokens, error) {
+	session, err := s.repo.GetByRefreshToken(hashRefreshToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, errInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	if session.ClientType != clientType {
+		return nil, errInvalidRefreshToken
+	}
+
+	if session.ExpiresAt.Before(time.Now()) {
+		if err := s.repo.Delete(session.Id); err != nil {
+			return nil, err
+		}
+		return nil, errInvalidRefreshToken
+	}
+
+	newRefreshToken, err := s.tokenManager.NewRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.Rotate(session.Id, session.RefreshToken, hashRefreshToken(newRefreshToken),
+		time.Now().Add(s.refreshTokenTTL))
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, errInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	accessToken, err := s.tokenManager.NewJWT(session.ClientId, session.ClientType, s.accessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tokens{AccessToken: accessToken, RefreshToken: newRefreshToken}, nil
+}
+
+func (s *SessionService) SignOut(clientId int, clientType string, refreshToken string) error {
+	session, err := s.repo.GetByRefreshToken(hashRefreshToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return errInvalidRefreshToken
+		}
+		return err
+	}
+
+	if !(session.ClientId == clientId && session.ClientType == clientType) {
+		return errForbidden
+	}
+
+	return s.repo.Delete(session.Id)
+}
+
+func (s *SessionService) GetAll(clientId int, clientType string, ownerId int, ownerType string) ([]*domain.Session, error) {
+	if err := authorize(clientId, clientType, resourceSession, actionList, ownedBy(ownerType, ownerId)); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetAll(ownerId, ownerType)
+}
+
+func (s *SessionService) Delete(clientId int, clientType string, ownerId int, ownerType string, sessionId int) error {
+	if err := authorize(clientId, clientType, resourceSession, actionDelete, ownedBy(ownerType, ownerId)); err != nil {
+		return err
+	}
+
+	session, err := s.repo.GetById(sessionId)
+	if err != nil {
+		return err
+	}
+
+	if !(session.ClientId == ownerId && session.ClientType == ownerType) {
+		return domain.NewNotFoundError("no such session for this client")
+	}
+
+	return s.repo.Delete(sessionId)
+}
+
+// Only a digest of the refresh token is stored, so a leaked sessions table
+// can't be used to mint new access tokens.
+func hashRefreshToken(refreshToken string) string {
+	sum := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(sum[:])
+}
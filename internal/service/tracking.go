@@ -0,0 +1,66 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/MAVIKE/yad-backend/internal/consts"
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/MAVIKE/yad-backend/internal/repository"
+)
+
+// TrackingService keeps the recent positions of couriers. Positions older
+// than retention are dropped as new ones arrive.
+type TrackingService struct {
+	repo      repository.Tracking
+	orderRepo repository.Order
+	retention time.Duration
+}
+
+func NewTrackingService(repo repository.Tracking, orderRepo repository.Order, retention time.Duration) *TrackingService {
+	return &TrackingService{
+		repo:      repo,
+		orderRepo: orderRepo,
+		retention: retention,
+	}
+}
+
+func (s *TrackingService) AddLocation(clientId int, clientType string, courierId int, location *domain.Location) error {
+	if err := authorize(clientId, clientType, resourceCourierLocation, actionCreate, ownedBy(courierType, courierId)); err != nil {
+		return err
+	}
+
+	var keepAfter time.Time
+	if s.retention > 0 {
+		keepAfter = time.Now().Add(-s.retention)
+	}
+
+	return s.repo.AddLocation(courierId, location, keepAfter)
+}
+
+// GetOrderCourierLocation returns the latest position of the courier
+// delivering the order. It is only shared while the order is en route.
+func (s *TrackingService) GetOrderCourierLocation(clientId int, clientType string, orderId int) (*domain.CourierLocation, error) {
+	order, err := s.orderRepo.GetById(orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorize(clientId, clientType, resourceCourierLocation, actionRead, orderTarget(order)); err != nil {
+		return nil, err
+	}
+
+	if order.Status != consts.OrderEnRoute {
+		return nil, domain.NewConflictError("Order is not en route")
+	}
+
+	location, err := s.repo.GetLatestLocation(order.CourierId)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, domain.NewNotFoundError("Courier location not found")
+		}
+		return nil, err
+	}
+
+	return location, nil
+}
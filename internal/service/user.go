@@ -1,95 +1,112 @@
 package service
 
 import (
-	"errors"
 	"github.com/MAVIKE/yad-backend/internal/domain"
-	"time"
 
 	"github.com/MAVIKE/yad-backend/internal/repository"
-	"github.com/MAVIKE/yad-backend/pkg/auth"
+	"github.com/MAVIKE/yad-backend/pkg/hash"
 )
 
 type UserService struct {
-	repo           repository.User
-	tokenManager   auth.TokenManager
-	accessTokenTTL time.Duration
+	repo      repository.User
+	orderRepo repository.Order
+	hasher    hash.PasswordHasher
+	sessions  Session
+	pricer    *deliveryPricer
 }
 
-func NewUserService(repo repository.User, tokenManager auth.TokenManager, accessTokenTTL time.Duration) *UserService {
+func NewUserService(repo repository.User, orderRepo repository.Order, hasher hash.PasswordHasher,
+	sessions Session, pricing DeliveryPricing) *UserService {
 	return &UserService{
-		repo:           repo,
-		tokenManager:   tokenManager,
-		accessTokenTTL: accessTokenTTL,
+		repo:      repo,
+		orderRepo: orderRepo,
+		hasher:    hasher,
+		sessions:  sessions,
+		pricer:    &deliveryPricer{repo: orderRepo, pricing: pricing},
 	}
 }
 
 func (s *UserService) SignUp(user *domain.User) (int, error) {
+	passwordHash, err := s.hasher.Hash(user.Password)
+	if err != nil {
+		return 0, err
+	}
+	user.Password = passwordHash
+
 	return s.repo.Create(user)
 }
 
 func (s *UserService) SignIn(phone, password string) (*Tokens, error) {
-	user, err := s.repo.GetByCredentials(phone, password)
+	user, err := s.repo.GetByPhone(phone)
 	if err != nil {
-		return nil, err
+		return nil, credentialsError(err)
 	}
 
-	token, err := s.tokenManager.NewJWT(user.Id, userType, s.accessTokenTTL)
+	newHash, err := checkPassword(s.hasher, user.Password, password)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Tokens{AccessToken: token}, nil
+	if newHash != "" {
+		if err := s.repo.UpdatePassword(user.Id, newHash); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.sessions.Create(user.Id, userType)
 }
 
-func (s UserService) GetAllOrders(clientId int, clientType string, userId int, activeOrdersFlag bool) ([]*domain.Order, error) {
-	if clientType != userType || clientId != userId {
-		return nil, errors.New("Forbidden")
+// GetAllOrders returns a page of the orders of the user.
+func (s *UserService) GetAllOrders(clientId int, clientType string, userId int, filter *domain.OrderFilter) (*domain.OrderPage, error) {
+	if err := authorize(clientId, clientType, resourceOrder, actionList, ownedBy(userType, userId)); err != nil {
+		return nil, err
+	}
+
+	if err := validateOrderFilter(filter); err != nil {
+		return nil, err
 	}
+	filter.UserId = userId
 
-	return s.repo.GetAllOrders(clientId, activeOrdersFlag)
+	return s.orderRepo.GetAll(filter)
 }
 
 func (s *UserService) Update(clientId int, clientType string, userId int, input *domain.User) error {
+	if err := authorize(clientId, clientType, resourceUser, actionUpdate, ownedBy(userType, userId)); err != nil {
+		return err
+	}
+
+	if input.Password != "" {
+		passwordHash, err := s.hasher.Hash(input.Password)
+		if err != nil {
+			return err
+		}
+		input.Password = passwordHash
+	}
 
-	if !(clientType == userType && userId == clientId) {
-		return errors.New("forbidden")
+	if err := s.repo.Update(userId, input); err != nil {
+		return err
 	}
 
-	return s.repo.Update(userId, input)
+	// The delivery price of unpaid orders depends on the address.
+	if input.Address != nil && input.Address.Latitude != 0 && input.Address.Longitude != 0 {
+		return s.pricer.repriceUserOrders(userId)
+	}
+
+	return nil
 }
 
 func (s *UserService) GetById(clientId int, clientType string, userId int) (*domain.User, error) {
-switchCheck:
-	switch clientType {
-	case userType:
-		if userId == clientId {
-			break switchCheck
-		}
-		return nil, errors.New("forbidden")
-	case restaurantType:
-		userOrders, err := s.repo.GetAllOrders(userId, true)
+	t := ownedBy(userType, userId)
+	if clientType == restaurantType || clientType == courierType {
+		orders, err := s.repo.GetAllOrders(userId, true)
 		if err != nil {
-			return nil, errors.New("forbidden")
-		}
-		for _, order := range userOrders {
-			if order.RestaurantId == clientId {
-				break switchCheck
-			}
+			return nil, err
 		}
-		return nil, errors.New("forbidden")
-	case courierType:
-		userOrders, err := s.repo.GetAllOrders(userId, true)
-		if err != nil {
-			return nil, errors.New("forbidden")
-		}
-		for _, order := range userOrders {
-			if order.CourierId == clientId {
-				break switchCheck
-			}
-		}
-		return nil, errors.New("forbidden")
-	default:
-		return nil, errors.New("forbidden")
+		t.Orders = orders
+	}
+
+	if err := authorize(clientId, clientType, resourceUser, actionRead, t); err != nil {
+		return nil, err
 	}
 
 	return s.repo.GetById(userId)
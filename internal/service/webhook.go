@@ -0,0 +1,288 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MAVIKE/yad-backend/internal/consts"
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/MAVIKE/yad-backend/internal/repository"
+	"github.com/MAVIKE/yad-backend/pkg/webhook"
+)
+
+const (
+	// webhookBatch is how many due deliveries are sent per pass.
+	webhookBatch = 100
+	// maxWebhookBackoff caps the delay between two attempts.
+	maxWebhookBackoff = 6 * time.Hour
+	// defaultWebhookTimeout is used when no timeout is configured.
+	defaultWebhookTimeout = 10 * time.Second
+)
+
+// webhookEvents are the events restaurants may subscribe to.
+var webhookEvents = map[string]bool{
+	domain.EventOrderCreated:   true,
+	domain.EventOrderPaid:      true,
+	domain.EventOrderCancelled: true,
+}
+
+// WebhookConfig tells how deliveries are sent. A failed delivery is retried
+// after Backoff, then after twice as long and so on, MaxAttempts times in all.
+// AllowPrivate lets webhooks reach the internal network, for development.
+type WebhookConfig struct {
+	Timeout      time.Duration
+	Backoff      time.Duration
+	MaxAttempts  int
+	AllowPrivate bool
+}
+
+type WebhookService struct {
+	repo   repository.Webhook
+	config WebhookConfig
+	client *http.Client
+}
+
+func NewWebhookService(repo repository.Webhook, config WebhookConfig) *WebhookService {
+	if config.MaxAttempts < 1 {
+		config.MaxAttempts = 1
+	}
+
+	if config.Timeout == 0 {
+		config.Timeout = defaultWebhookTimeout
+	}
+
+	return &WebhookService{
+		repo:   repo,
+		config: config,
+		client: webhook.NewClient(config.Timeout, config.AllowPrivate),
+	}
+}
+
+func (s *WebhookService) Create(clientId int, clientType string, hook *domain.Webhook) (int, error) {
+	if err := authorize(clientId, clientType, resourceWebhook, actionCreate, ownedBy(restaurantType, hook.RestaurantId)); err != nil {
+		return 0, err
+	}
+
+	if len(hook.Events) == 0 {
+		return 0, domain.NewValidationError("Webhook must subscribe to at least one event")
+	}
+
+	for _, event := range hook.Events {
+		if !webhookEvents[event] {
+			return 0, domain.NewValidationError("Unknown webhook event %s", event)
+		}
+	}
+
+	return s.repo.Create(hook)
+}
+
+func (s *WebhookService) GetAll(clientId int, clientType string, restaurantId int) ([]*domain.Webhook, error) {
+	if err := authorize(clientId, clientType, resourceWebhook, actionList, ownedBy(restaurantType, restaurantId)); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetAll(restaurantId)
+}
+
+func (s *WebhookService) Delete(clientId int, clientType string, restaurantId, webhookId int) error {
+	if _, err := s.getWebhook(clientId, clientType, actionDelete, restaurantId, webhookId); err != nil {
+		return err
+	}
+
+	return s.repo.Delete(webhookId)
+}
+
+func (s *WebhookService) GetDeliveries(clientId int, clientType string, restaurantId, webhookId int) ([]*domain.WebhookDelivery, error) {
+	if _, err := s.getWebhook(clientId, clientType, actionRead, restaurantId, webhookId); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetDeliveries(webhookId)
+}
+
+// Enqueue creates a delivery of the event for every webhook of the
+// restaurant subscribed to it. The worker sends them.
+func (s *WebhookService) Enqueue(event *domain.Event) error {
+	hooks, err := s.repo.GetSubscribed(event.RestaurantId, event.Type)
+	if err != nil {
+		return err
+	}
+
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	for _, hook := range hooks {
+		_, err := s.repo.CreateDelivery(&domain.WebhookDelivery{
+			WebhookId: hook.Id,
+			Event:     event.Type,
+			EventId:   event.Id,
+			Payload:   string(payload),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Handle enqueues the events relayed from the outbox. An event relayed
+// again isn't delivered twice.
+func (s *WebhookService) Handle(event *domain.Event) error {
+	if !webhookEvents[event.Type] {
+		return nil
+	}
+
+	return s.Enqueue(event)
+}
+
+// Run sends due deliveries every interval until the context is done.
+func (s *WebhookService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.DeliverPending(); err != nil {
+			log.Printf("webhooks: %s", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *WebhookService) DeliverPending() error {
+	// the batch is claimed until every delivery in it may have timed out
+	deliveries, err := s.repo.ClaimDueDeliveries(webhookBatch, webhookBatch*s.config.Timeout)
+	if err != nil {
+		return err
+	}
+
+	hooks := make(map[int]*domain.Webhook)
+	for _, delivery := range deliveries {
+		hook, ok := hooks[delivery.WebhookId]
+		if !ok {
+			hook, err = s.repo.GetById(delivery.WebhookId)
+			if err != nil {
+				return err
+			}
+			hooks[hook.Id] = hook
+		}
+
+		s.deliver(hook, delivery)
+
+		if err := s.repo.UpdateDelivery(delivery); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deliver makes one attempt and records its outcome in the delivery.
+func (s *WebhookService) deliver(hook *domain.Webhook, delivery *domain.WebhookDelivery) {
+	now := time.Now()
+	delivery.Attempts++
+
+	statusCode, err := s.post(hook, delivery, now)
+	if statusCode != 0 {
+		delivery.ResponseCode = &statusCode
+	}
+
+	if err == nil {
+		delivery.Status = consts.DeliveryDelivered
+		delivery.DeliveredAt = &now
+		delivery.LastError = nil
+		return
+	}
+
+	// the error is shown to the restaurant, so it mustn't tell anything
+	// about the network the request was sent from
+	log.Printf("webhooks: delivery %d: %s", delivery.Id, err.Error())
+	lastError := deliveryError(statusCode, err)
+	delivery.LastError = &lastError
+
+	if delivery.Attempts >= s.config.MaxAttempts {
+		delivery.Status = consts.DeliveryFailed
+		return
+	}
+
+	delivery.NextAttemptAt = now.Add(backoff(s.config.Backoff, maxWebhookBackoff, delivery.Attempts))
+}
+
+func (s *WebhookService) post(hook *domain.Webhook, delivery *domain.WebhookDelivery, now time.Time) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := now.Unix()
+
+	req, err := http.NewRequest(http.MethodPost, hook.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhook.EventHeader, delivery.Event)
+	req.Header.Set(webhook.DeliveryHeader, strconv.Itoa(delivery.Id))
+	req.Header.Set(webhook.TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(webhook.SignatureHeader, webhook.Sign(hook.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+func deliveryError(statusCode int, err error) string {
+	var netErr net.Error
+
+	switch {
+	case statusCode != 0:
+		return fmt.Sprintf("unexpected response status %d", statusCode)
+	case errors.Is(err, webhook.ErrForbiddenAddress):
+		return "url resolves to a forbidden address"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "request timed out"
+	default:
+		return "request failed"
+	}
+}
+
+func (s *WebhookService) getWebhook(clientId int, clientType string, act action, restaurantId, webhookId int) (*domain.Webhook, error) {
+	if err := authorize(clientId, clientType, resourceWebhook, act, ownedBy(restaurantType, restaurantId)); err != nil {
+		return nil, err
+	}
+
+	hook, err := s.repo.GetById(webhookId)
+	if err != nil {
+		return nil, err
+	}
+
+	if hook.RestaurantId != restaurantId {
+		return nil, domain.NewNotFoundError("Webhook not found")
+	}
+
+	return hook, nil
+}
@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// DefaultKeyId is the id of the key used to verify tokens issued
+// before tokens carried a kid header.
+const DefaultKeyId = "default"
+
+type Key struct {
+	Id     string
+	Method jwt.SigningMethod
+
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// ParseKey builds a key for the given algorithm. For HS256 data is the shared
+// secret, for RS256 and EdDSA it is a PEM encoded private or public key.
+// Keys built from a public key can only verify tokens.
+func ParseKey(id, algorithm string, data []byte) (*Key, error) {
+	if id == "" {
+		return nil, errors.New("empty key id")
+	}
+
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty key %q", id)
+	}
+
+	key := &Key{Id: id}
+
+	switch algorithm {
+	case jwt.SigningMethodHS256.Alg():
+		key.Method = jwt.SigningMethodHS256
+		key.signKey = data
+		key.verifyKey = data
+	case jwt.SigningMethodRS256.Alg():
+		key.Method = jwt.SigningMethodRS256
+		if privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+			key.signKey = privateKey
+			key.verifyKey = &privateKey.PublicKey
+		} else if publicKey, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+			key.verifyKey = publicKey
+		} else {
+			return nil, fmt.Errorf("key %q: %s", id, err.Error())
+		}
+	case SigningMethodEdDSA.Alg():
+		key.Method = SigningMethodEdDSA
+		if err := key.parseEd25519(data); err != nil {
+			return nil, fmt.Errorf("key %q: %s", id, err.Error())
+		}
+	default:
+		return nil, fmt.Errorf("key %q: unsupported algorithm %q", id, algorithm)
+	}
+
+	return key, nil
+}
+
+func (k *Key) parseEd25519(data []byte) error {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return errors.New("key must be PEM encoded")
+	}
+
+	if privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		edKey, ok := privateKey.(ed25519.PrivateKey)
+		if !ok {
+			return errors.New("not an Ed25519 private key")
+		}
+
+		k.signKey = edKey
+		k.verifyKey = edKey.Public()
+		return nil
+	}
+
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return err
+	}
+
+	edKey, ok := publicKey.(ed25519.PublicKey)
+	if !ok {
+		return errors.New("not an Ed25519 public key")
+	}
+
+	k.verifyKey = edKey
+	return nil
+}
+
+func (k *Key) CanSign() bool {
+	return k.signKey != nil
+}
+
+// Keyring holds every key tokens may be verified with and the one new tokens
+// are signed with. A key is rotated by adding the new key, making it active
+// and keeping the old one until the tokens it signed have expired.
+type Keyring struct {
+	active *Key
+	keys   map[string]*Key
+}
+
+func NewKeyring(activeId string, keys ...*Key) (*Keyring, error) {
+	keyring := &Keyring{
+		keys: make(map[string]*Key, len(keys)),
+	}
+
+	for _, key := range keys {
+		if _, ok := keyring.keys[key.Id]; ok {
+			return nil, fmt.Errorf("duplicate key id %q", key.Id)
+		}
+		keyring.keys[key.Id] = key
+	}
+
+	active, ok := keyring.keys[activeId]
+	if !ok {
+		return nil, fmt.Errorf("active key %q not found", activeId)
+	}
+
+	if !active.CanSign() {
+		return nil, fmt.Errorf("active key %q has no private key", activeId)
+	}
+
+	keyring.active = active
+	return keyring, nil
+}
+
+func (r *Keyring) Active() *Key {
+	return r.active
+}
+
+func (r *Keyring) Get(id string) (*Key, bool) {
+	key, ok := r.keys[id]
+	return key, ok
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public part of every asymmetric key. HMAC keys are
+// shared secrets and are never published.
+func (r *Keyring) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+
+	for _, key := range r.keys {
+		jwk := JWK{
+			Kid: key.Id,
+			Alg: key.Method.Alg(),
+			Use: "sig",
+		}
+
+		switch publicKey := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = jwt.EncodeSegment(publicKey.N.Bytes())
+			jwk.E = jwt.EncodeSegment(bigEndian(publicKey.E))
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = jwt.EncodeSegment(publicKey)
+		default:
+			continue
+		}
+
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	sort.Slice(jwks.Keys, func(i, j int) bool {
+		return jwks.Keys[i].Kid < jwks.Keys[j].Kid
+	})
+
+	return jwks
+}
+
+func bigEndian(n int) []byte {
+	var b []byte
+	for ; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+	return b
+}
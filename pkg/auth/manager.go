@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"time"
 
@@ -9,11 +11,26 @@ import (
 
 type TokenManager interface {
 	NewJWT(id int, clientType string, ttl time.Duration) (string, error)
-	Parse(accessToken string) (int, string, error)
+	Parse(accessToken string) (*Claims, error)
+	NewRefreshToken() (string, error)
+	JWKS() JWKS
 }
 
+const (
+	refreshTokenLength = 32
+	tokenIdLength      = 16
+)
+
 type Manager struct {
-	signingKey string
+	keyring *Keyring
+}
+
+type Claims struct {
+	ClientId   int
+	ClientType string
+	TokenId    string
+	IssuedAt   time.Time
+	ExpiresAt  time.Time
 }
 
 type tokenClaims struct {
@@ -22,43 +39,85 @@ type tokenClaims struct {
 	ClientType string `json:"client_type"`
 }
 
-func NewManager(signingKey string) (*Manager, error) {
-	if signingKey == "" {
-		return nil, errors.New("empty signing key")
+func NewManager(keyring *Keyring) (*Manager, error) {
+	if keyring == nil {
+		return nil, errors.New("empty keyring")
 	}
 
-	return &Manager{signingKey: signingKey}, nil
+	return &Manager{keyring: keyring}, nil
 }
 
 func (m *Manager) NewJWT(id int, clientType string, ttl time.Duration) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &tokenClaims{
+	tokenId, err := randomHex(tokenIdLength)
+	if err != nil {
+		return "", err
+	}
+
+	key := m.keyring.Active()
+	token := jwt.NewWithClaims(key.Method, &tokenClaims{
 		jwt.StandardClaims{
+			Id:        tokenId,
 			ExpiresAt: time.Now().Add(ttl).Unix(),
 			IssuedAt:  time.Now().Unix(),
 		},
 		id,
 		clientType,
 	})
+	token.Header["kid"] = key.Id
 
-	return token.SignedString([]byte(m.signingKey))
+	return token.SignedString(key.signKey)
 }
 
-func (m *Manager) Parse(accessToken string) (int, string, error) {
+func (m *Manager) Parse(accessToken string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(accessToken, &tokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			kid = DefaultKeyId
+		}
+
+		key, ok := m.keyring.Get(kid)
+		if !ok {
+			return nil, errors.New("unknown signing key")
+		}
+
+		if token.Method.Alg() != key.Method.Alg() {
 			return nil, errors.New("invalid signing method")
 		}
 
-		return []byte(m.signingKey), nil
+		return key.verifyKey, nil
 	})
 	if err != nil {
-		return 0, "", err
+		return nil, err
 	}
 
 	claims, ok := token.Claims.(*tokenClaims)
 	if !ok {
-		return 0, "", errors.New("token claims are not of type *tokenClaims")
+		return nil, errors.New("token claims are not of type *tokenClaims")
+	}
+
+	return &Claims{
+		ClientId:   claims.Id,
+		ClientType: claims.ClientType,
+		TokenId:    claims.StandardClaims.Id,
+		IssuedAt:   time.Unix(claims.IssuedAt, 0),
+		ExpiresAt:  time.Unix(claims.ExpiresAt, 0),
+	}, nil
+}
+
+func (m *Manager) NewRefreshToken() (string, error) {
+	return randomHex(refreshTokenLength)
+}
+
+func (m *Manager) JWKS() JWKS {
+	return m.keyring.JWKS()
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
 	}
 
-	return claims.Id, claims.ClientType, nil
+	return hex.EncodeToString(b), nil
 }
@@ -0,0 +1,71 @@
+package hash
+
+import (
+	"crypto/subtle"
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Compare(hash, password string) (bool, error)
+	NeedsRehash(hash string) bool
+}
+
+type BCryptHasher struct {
+	cost int
+}
+
+func NewBCryptHasher(cost int) (*BCryptHasher, error) {
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return nil, errors.New("invalid bcrypt cost")
+	}
+
+	return &BCryptHasher{cost: cost}, nil
+}
+
+func (h *BCryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// Compare also accepts legacy rows that still hold the password in plaintext,
+// so they can be rehashed after the next successful sign in.
+func (h *BCryptHasher) Compare(hash, password string) (bool, error) {
+	if !isBCryptHash(hash) {
+		return subtle.ConstantTimeCompare([]byte(hash), []byte(password)) == 1, nil
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (h *BCryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return true
+	}
+
+	return cost != h.cost
+}
+
+func isBCryptHash(hash string) bool {
+	_, err := bcrypt.Cost([]byte(hash))
+	return err == nil
+}
@@ -0,0 +1,88 @@
+package hash
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestBCryptHasher_Compare(t *testing.T) {
+	h, err := NewBCryptHasher(bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := h.Hash("qwerty")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(hash) > 100 {
+		t.Errorf("expected the hash to fit the password_hash column, got %d bytes", len(hash))
+	}
+
+	tests := []struct {
+		name     string
+		hash     string
+		password string
+		match    bool
+	}{
+		{"bcrypt", hash, "qwerty", true},
+		{"bcrypt, wrong password", hash, "qwertz", false},
+		{"legacy plaintext", "qwerty", "qwerty", true},
+		{"legacy plaintext, wrong password", "qwerty", "qwertz", false},
+		{"legacy plaintext, prefix", "qwerty", "qwert", false},
+	}
+
+	for _, tt := range tests {
+		match, err := h.Compare(tt.hash, tt.password)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+
+		if match != tt.match {
+			t.Errorf("%s: expected match %t, got %t", tt.name, tt.match, match)
+		}
+	}
+}
+
+func TestBCryptHasher_NeedsRehash(t *testing.T) {
+	h, err := NewBCryptHasher(bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := h.Hash("qwerty")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stronger, err := NewBCryptHasher(bcrypt.MinCost + 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if h.NeedsRehash(hash) {
+		t.Error("a hash of the current cost must not be rehashed")
+	}
+
+	if !stronger.NeedsRehash(hash) {
+		t.Error("a hash of another cost must be rehashed")
+	}
+
+	if !h.NeedsRehash("qwerty") {
+		t.Error("a legacy plaintext password must be rehashed")
+	}
+}
+
+func TestNewBCryptHasher(t *testing.T) {
+	if _, err := NewBCryptHasher(bcrypt.MaxCost + 1); err == nil {
+		t.Error("expected a cost above the maximum to be rejected")
+	}
+
+	h, err := NewBCryptHasher(0)
+	if err != nil || h.cost != bcrypt.DefaultCost {
+		t.Errorf("expected the default cost, got %+v, %v", h, err)
+	}
+}
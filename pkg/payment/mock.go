@@ -0,0 +1,243 @@
+package payment
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/MAVIKE/yad-backend/pkg/webhook"
+)
+
+const (
+	MockName = "mock"
+
+	TimestampHeader = "X-Payment-Timestamp"
+	SignatureHeader = "X-Payment-Signature"
+)
+
+// MockProvider is an in-memory provider for local runs and tests. It never
+// talks to a bank: every intent is authorized on confirmation unless it has
+// been declined with Decline, and every refund is made unless the refunds
+// of the intent have been declined with DeclineRefunds. Ids are sequential,
+// so runs are reproducible. Callbacks are signed like webhooks with the
+// given secret.
+type MockProvider struct {
+	secret string
+
+	mu       sync.Mutex
+	intents  map[string]*Intent
+	declined map[string]bool
+	noRefund map[string]bool
+	refunds  map[string]*Refund
+	intentNo int
+	refundNo int
+}
+
+func NewMockProvider(secret string) *MockProvider {
+	return &MockProvider{
+		secret:   secret,
+		intents:  make(map[string]*Intent),
+		declined: make(map[string]bool),
+		noRefund: make(map[string]bool),
+		refunds:  make(map[string]*Refund),
+	}
+}
+
+func (p *MockProvider) Name() string {
+	return MockName
+}
+
+func (p *MockProvider) CreateIntent(orderId, amount int) (*Intent, error) {
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.intentNo++
+	intent := &Intent{
+		Id:      fmt.Sprintf("mock_pi_%d", p.intentNo),
+		OrderId: orderId,
+		Amount:  amount,
+		Status:  StatusPending,
+	}
+	p.intents[intent.Id] = intent
+
+	return p.copy(intent), nil
+}
+
+// Decline makes the confirmation of the intent fail.
+func (p *MockProvider) Decline(intentId string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.declined[intentId] = true
+}
+
+// DeclineRefunds makes the refunds of the intent fail, or succeed again
+// when declined is false.
+func (p *MockProvider) DeclineRefunds(intentId string, declined bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.noRefund[intentId] = declined
+}
+
+func (p *MockProvider) Confirm(intentId string) (*Intent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	intent, err := p.get(intentId, StatusPending)
+	if err != nil {
+		return nil, err
+	}
+
+	intent.Status = StatusAuthorized
+	if p.declined[intentId] {
+		intent.Status = StatusFailed
+	}
+
+	return p.copy(intent), nil
+}
+
+func (p *MockProvider) Capture(intentId string) (*Intent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	intent, err := p.get(intentId, StatusAuthorized)
+	if err != nil {
+		return nil, err
+	}
+
+	intent.Status = StatusCaptured
+
+	return p.copy(intent), nil
+}
+
+func (p *MockProvider) Cancel(intentId string) (*Intent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	intent, err := p.get(intentId, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if intent.Status != StatusPending && intent.Status != StatusAuthorized {
+		return nil, ErrInvalidStatus
+	}
+
+	intent.Status = StatusCancelled
+
+	return p.copy(intent), nil
+}
+
+func (p *MockProvider) Refund(intentId string, amount int, key string) (*Refund, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if refund, ok := p.refunds[key]; ok {
+		copied := *refund
+		return &copied, nil
+	}
+
+	intent, err := p.get(intentId, StatusCaptured)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.noRefund[intentId] {
+		return nil, ErrRefundDeclined
+	}
+
+	if amount <= 0 || intent.Refunded+amount > intent.Amount {
+		return nil, ErrInvalidAmount
+	}
+
+	intent.Refunded += amount
+	if intent.Refunded == intent.Amount {
+		intent.Status = StatusRefunded
+	}
+
+	p.refundNo++
+	refund := &Refund{
+		Id:       fmt.Sprintf("mock_re_%d", p.refundNo),
+		IntentId: intentId,
+		Amount:   amount,
+	}
+	p.refunds[key] = refund
+
+	copied := *refund
+	return &copied, nil
+}
+
+// Callback returns a signed callback with the current status of the intent,
+// as the provider would send it.
+func (p *MockProvider) Callback(intentId string) (http.Header, []byte, error) {
+	p.mu.Lock()
+	intent, err := p.get(intentId, "")
+	p.mu.Unlock()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	body, err := json.Marshal(&Callback{
+		IntentId: intent.Id,
+		Status:   intent.Status,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	timestamp := time.Now().Unix()
+	header := make(http.Header)
+	header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	header.Set(SignatureHeader, webhook.Sign(p.secret, timestamp, body))
+
+	return header, body, nil
+}
+
+func (p *MockProvider) ParseCallback(header http.Header, body []byte) (*Callback, error) {
+	timestamp, err := strconv.ParseInt(header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+
+	if !webhook.Verify(p.secret, timestamp, body, header.Get(SignatureHeader)) {
+		return nil, ErrInvalidSignature
+	}
+
+	if webhook.Expired(timestamp, time.Now()) {
+		return nil, ErrStaleCallback
+	}
+
+	callback := new(Callback)
+	if err := json.Unmarshal(body, callback); err != nil {
+		return nil, err
+	}
+
+	return callback, nil
+}
+
+// get returns the intent, checking its status unless status is empty.
+func (p *MockProvider) get(intentId, status string) (*Intent, error) {
+	intent, ok := p.intents[intentId]
+	if !ok {
+		return nil, ErrIntentNotFound
+	}
+
+	if status != "" && intent.Status != status {
+		return nil, ErrInvalidStatus
+	}
+
+	return intent, nil
+}
+
+func (p *MockProvider) copy(intent *Intent) *Intent {
+	c := *intent
+	return &c
+}
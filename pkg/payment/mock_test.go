@@ -0,0 +1,150 @@
+package payment
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/MAVIKE/yad-backend/pkg/webhook"
+)
+
+func TestMockProvider_Flow(t *testing.T) {
+	p := NewMockProvider("secret")
+
+	intent, err := p.CreateIntent(1, 900)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if intent.Id != "mock_pi_1" || intent.Status != StatusPending {
+		t.Fatalf("unexpected intent %+v", intent)
+	}
+
+	if _, err := p.Capture(intent.Id); err != ErrInvalidStatus {
+		t.Errorf("expected a pending intent not to be captured, got %v", err)
+	}
+
+	if intent, err = p.Confirm(intent.Id); err != nil || intent.Status != StatusAuthorized {
+		t.Fatalf("expected authorized, got %+v, %v", intent, err)
+	}
+
+	if intent, err = p.Capture(intent.Id); err != nil || intent.Status != StatusCaptured {
+		t.Fatalf("expected captured, got %+v, %v", intent, err)
+	}
+
+	if _, err := p.Refund(intent.Id, 901, "1"); err != ErrInvalidAmount {
+		t.Errorf("expected a refund over the amount to fail, got %v", err)
+	}
+
+	refund, err := p.Refund(intent.Id, 900, "2")
+	if err != nil || refund.Amount != 900 {
+		t.Errorf("expected a full refund, got %+v, %v", refund, err)
+	}
+
+	if retried, err := p.Refund(intent.Id, 900, "2"); err != nil || retried.Id != refund.Id {
+		t.Errorf("expected the retried refund to be the first one, got %+v, %v", retried, err)
+	}
+}
+
+func TestMockProvider_Decline(t *testing.T) {
+	p := NewMockProvider("secret")
+
+	intent, err := p.CreateIntent(1, 900)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.Decline(intent.Id)
+
+	if intent, err = p.Confirm(intent.Id); err != nil || intent.Status != StatusFailed {
+		t.Errorf("expected failed, got %+v, %v", intent, err)
+	}
+}
+
+func TestMockProvider_DeclineRefunds(t *testing.T) {
+	p := NewMockProvider("secret")
+
+	intent, err := p.CreateIntent(1, 900)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.Confirm(intent.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.Capture(intent.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	p.DeclineRefunds(intent.Id, true)
+
+	if _, err := p.Refund(intent.Id, 200, "1"); err != ErrRefundDeclined {
+		t.Errorf("expected the refund to be declined, got %v", err)
+	}
+
+	p.DeclineRefunds(intent.Id, false)
+
+	if refund, err := p.Refund(intent.Id, 200, "1"); err != nil || refund.Amount != 200 {
+		t.Errorf("expected the retried refund to be made, got %+v, %v", refund, err)
+	}
+}
+
+func TestMockProvider_Callback(t *testing.T) {
+	p := NewMockProvider("secret")
+
+	intent, err := p.CreateIntent(1, 900)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header, body, err := p.Callback(intent.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	callback, err := p.ParseCallback(header, body)
+	if err != nil || callback.IntentId != intent.Id || callback.Status != StatusPending {
+		t.Errorf("unexpected callback %+v, %v", callback, err)
+	}
+
+	if _, err := NewMockProvider("other").ParseCallback(header, body); err != ErrInvalidSignature {
+		t.Errorf("expected a foreign callback to be rejected, got %v", err)
+	}
+}
+
+func TestMockProvider_Cancel(t *testing.T) {
+	p := NewMockProvider("secret")
+
+	intent, err := p.CreateIntent(1, 900)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if intent, err = p.Cancel(intent.Id); err != nil || intent.Status != StatusCancelled {
+		t.Fatalf("expected cancelled, got %+v, %v", intent, err)
+	}
+
+	if _, err := p.Confirm(intent.Id); err != ErrInvalidStatus {
+		t.Errorf("expected a cancelled intent not to be confirmed, got %v", err)
+	}
+
+	if _, err := p.Cancel(intent.Id); err != ErrInvalidStatus {
+		t.Errorf("expected a cancelled intent not to be cancelled again, got %v", err)
+	}
+}
+
+func TestMockProvider_StaleCallback(t *testing.T) {
+	p := NewMockProvider("secret")
+	body := []byte(`{"intent_id":"mock_pi_1","status":"authorized"}`)
+
+	timestamp := time.Now().Add(-webhook.Tolerance - time.Minute).Unix()
+	header := make(http.Header)
+	header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	header.Set(SignatureHeader, webhook.Sign("secret", timestamp, body))
+
+	if _, err := p.ParseCallback(header, body); err != ErrStaleCallback {
+		t.Errorf("expected a replayed callback to be rejected, got %v", err)
+	}
+}
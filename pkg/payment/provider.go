@@ -0,0 +1,68 @@
+package payment
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Intent statuses. A pending intent waits for the payer to confirm it,
+// an authorized one holds the money until it is captured or cancelled.
+const (
+	StatusPending    = "pending"
+	StatusAuthorized = "authorized"
+	StatusCaptured   = "captured"
+	StatusFailed     = "failed"
+	StatusRefunded   = "refunded"
+	StatusCancelled  = "cancelled"
+)
+
+var (
+	ErrIntentNotFound   = errors.New("payment intent not found")
+	ErrInvalidStatus    = errors.New("payment intent is in a wrong status")
+	ErrInvalidAmount    = errors.New("invalid payment amount")
+	ErrRefundDeclined   = errors.New("refund declined")
+	ErrInvalidSignature = errors.New("invalid callback signature")
+	ErrStaleCallback    = errors.New("callback timestamp is out of tolerance")
+)
+
+// Intent is an attempt to take the amount of an order from the payer.
+// Amounts are in the smallest currency unit.
+type Intent struct {
+	Id       string `json:"id"`
+	OrderId  int    `json:"order_id"`
+	Amount   int    `json:"amount"`
+	Refunded int    `json:"refunded"`
+	Status   string `json:"status"`
+}
+
+type Refund struct {
+	Id       string `json:"id"`
+	IntentId string `json:"intent_id"`
+	Amount   int    `json:"amount"`
+}
+
+// Callback is the provider telling that the status of an intent has changed.
+type Callback struct {
+	IntentId string `json:"intent_id"`
+	Status   string `json:"status"`
+}
+
+// Provider is a payment gateway.
+type Provider interface {
+	Name() string
+	CreateIntent(orderId, amount int) (*Intent, error)
+	// Confirm authorizes the intent on behalf of the payer. The intent
+	// may stay pending if the payer has to act on the provider's side,
+	// then a callback tells the outcome.
+	Confirm(intentId string) (*Intent, error)
+	Capture(intentId string) (*Intent, error)
+	// Cancel voids a pending or authorized intent, releasing the money
+	// it holds.
+	Cancel(intentId string) (*Intent, error)
+	// Refund returns the amount of a captured intent. A refund retried with
+	// the same key is made once: the provider returns the first one.
+	Refund(intentId string, amount int, key string) (*Refund, error)
+	// ParseCallback checks that the callback comes from the provider and
+	// is not a replay of an old one.
+	ParseCallback(header http.Header, body []byte) (*Callback, error)
+}
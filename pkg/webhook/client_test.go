@@ -0,0 +1,70 @@
+package webhook
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPublic(t *testing.T) {
+	tests := []struct {
+		ip     string
+		public bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.20.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"fd00::1", false},
+		{"fe80::1", false},
+		{"::ffff:127.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		if public := Public(net.ParseIP(tt.ip)); public != tt.public {
+			t.Errorf("%s: expected public %v, got %v", tt.ip, tt.public, public)
+		}
+	}
+}
+
+func TestNewClient_Private(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer receiver.Close()
+
+	_, err := NewClient(time.Second, false).Post(receiver.URL, "application/json", nil)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("expected %v, got %v", ErrForbiddenAddress, err)
+	}
+
+	resp, err := NewClient(time.Second, true).Post(receiver.URL, "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+}
+
+func TestNewClient_Redirect(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/", http.StatusFound)
+	}))
+	defer receiver.Close()
+
+	resp, err := NewClient(time.Second, true).Post(receiver.URL, "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusFound {
+		t.Errorf("redirect must not be followed, got status %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,46 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+const (
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+	TimestampHeader = "X-Webhook-Timestamp"
+	SignatureHeader = "X-Webhook-Signature"
+
+	signaturePrefix = "sha256="
+
+	// Tolerance is how far the timestamp of a delivery may be from the
+	// clock of the receiver before the delivery is taken for a replay.
+	Tolerance = 5 * time.Minute
+)
+
+// Sign returns the signature of a delivery: the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the webhook secret. The timestamp is
+// signed too so that receivers can reject replayed deliveries with Expired.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Expired reports whether the timestamp of a delivery is out of Tolerance
+// of now, either way.
+func Expired(timestamp int64, now time.Time) bool {
+	age := now.Sub(time.Unix(timestamp, 0))
+	return age > Tolerance || age < -Tolerance
+}
+
+// Verify reports whether the signature matches the delivery.
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
@@ -0,0 +1,58 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	// echo -n '1700000000.{"order_id":1}' | openssl dgst -sha256 -hmac secret
+	expected := "sha256=7e642bb2d66db7c3e9958a307b1e8950f2166d2aee35c076c6dfb34d1a3ded78"
+
+	if signature := Sign("secret", 1700000000, []byte(`{"order_id":1}`)); signature != expected {
+		t.Errorf("expected %s, got %s", expected, signature)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"order_id":1}`)
+	signature := Sign("secret", 1700000000, body)
+
+	if !Verify("secret", 1700000000, body, signature) {
+		t.Error("signature must verify")
+	}
+
+	if Verify("other", 1700000000, body, signature) {
+		t.Error("signature must not verify with another secret")
+	}
+
+	if Verify("secret", 1700000001, body, signature) {
+		t.Error("signature must not verify with another timestamp")
+	}
+
+	if Verify("secret", 1700000000, []byte(`{"order_id":2}`), signature) {
+		t.Error("signature must not verify with another body")
+	}
+}
+
+func TestExpired(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name      string
+		timestamp int64
+		expired   bool
+	}{
+		{"now", 1700000000, false},
+		{"within tolerance", 1700000000 - 299, false},
+		{"slightly ahead", 1700000000 + 60, false},
+		{"too old", 1700000000 - 301, true},
+		{"too far ahead", 1700000000 + 301, true},
+	}
+
+	for _, tt := range tests {
+		if expired := Expired(tt.timestamp, now); expired != tt.expired {
+			t.Errorf("%s: expected expired %v, got %v", tt.name, tt.expired, expired)
+		}
+	}
+}
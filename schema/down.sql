@@ -1,8 +1,32 @@
+DROP TRIGGER IF EXISTS restaurant_rating_update_trigger ON reviews;
+DROP FUNCTION IF EXISTS update_restaurant_rating;
 DROP TRIGGER IF EXISTS total_price_update_trigger ON order_items;
 DROP FUNCTION IF EXISTS get_total_price(int);
 DROP FUNCTION IF EXISTS update_total_price;
+DROP FUNCTION IF EXISTS search_query(text);
+DROP FUNCTION IF EXISTS in_delivery_zone(int, float, float);
+DROP FUNCTION IF EXISTS in_polygon(int, float, float);
 DROP FUNCTION IF EXISTS get_distance(float, float, float, float);
 
+DROP TABLE IF EXISTS reviews CASCADE;
+DROP TABLE IF EXISTS delivery_zone_points CASCADE;
+DROP TABLE IF EXISTS restaurant_holidays CASCADE;
+DROP TABLE IF EXISTS restaurant_hours CASCADE;
+DROP TABLE IF EXISTS cart_items CASCADE;
+DROP TABLE IF EXISTS carts CASCADE;
+DROP TABLE IF EXISTS refunds CASCADE;
+DROP TABLE IF EXISTS payments CASCADE;
+DROP TABLE IF EXISTS outbox CASCADE;
+DROP TABLE IF EXISTS webhook_deliveries CASCADE;
+DROP TABLE IF EXISTS webhooks CASCADE;
+DROP TABLE IF EXISTS courier_locations CASCADE;
+DROP TABLE IF EXISTS courier_offers CASCADE;
+DROP TABLE IF EXISTS dispatch_queue CASCADE;
+DROP TABLE IF EXISTS event_tickets CASCADE;
+DROP TABLE IF EXISTS revoked_clients CASCADE;
+DROP TABLE IF EXISTS revoked_tokens CASCADE;
+DROP TABLE IF EXISTS sessions CASCADE;
+DROP TABLE IF EXISTS order_events CASCADE;
 DROP TABLE IF EXISTS order_items CASCADE;
 DROP TABLE IF EXISTS orders CASCADE;
 DROP TABLE IF EXISTS admins CASCADE;
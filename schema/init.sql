@@ -8,7 +8,7 @@ CREATE TABLE IF NOT EXISTS users (
     id SERIAL PRIMARY KEY,
     name VARCHAR(50) NOT NULL,
     phone VARCHAR(20) UNIQUE NOT NULL,
-    password_hash VARCHAR(50) NOT NULL,
+    password_hash VARCHAR(100) NOT NULL,
     email VARCHAR(50) NOT NULL,
     address_id INT REFERENCES locations (id) ON DELETE CASCADE NOT NULL
 );
@@ -17,7 +17,7 @@ CREATE TABLE IF NOT EXISTS couriers (
     id SERIAL PRIMARY KEY,
     name VARCHAR(50) NOT NULL,
     phone VARCHAR(20) UNIQUE NOT NULL,
-    password_hash VARCHAR(50) NOT NULL,
+    password_hash VARCHAR(100) NOT NULL,
     email VARCHAR(50) NOT NULL,
     address_id INT REFERENCES locations (id) ON DELETE CASCADE NOT NULL,
     working_status INT NOT NULL CHECK (working_status BETWEEN 0 AND 2)
@@ -27,27 +27,48 @@ CREATE TABLE IF NOT EXISTS restaurants (
     id SERIAL PRIMARY KEY,
     name VARCHAR(50) NOT NULL,
     phone VARCHAR(20) UNIQUE NOT NULL,
-    password_hash VARCHAR(50) NOT NULL,
+    password_hash VARCHAR(100) NOT NULL,
     address_id INT REFERENCES locations (id) ON DELETE CASCADE NOT NULL,
     working_status INT NOT NULL,
-    image VARCHAR(100) NOT NULL DEFAULT ''
+    image VARCHAR(100) NOT NULL DEFAULT '',
+    time_zone VARCHAR(50) NOT NULL DEFAULT 'UTC',
+    delivery_radius FLOAT CHECK (delivery_radius > 0),
+    rating FLOAT NOT NULL DEFAULT 0,
+    rating_count INT NOT NULL DEFAULT 0,
+    search_vector TSVECTOR GENERATED ALWAYS AS (
+        to_tsvector('russian', name) || to_tsvector('english', name)
+    ) STORED
 );
 
+CREATE INDEX IF NOT EXISTS restaurants_search_idx ON restaurants USING GIN (search_vector);
+
 CREATE TABLE IF NOT EXISTS menu_items (
     id SERIAL PRIMARY KEY,
     restaurant_id INT REFERENCES restaurants (id) ON DELETE CASCADE NOT NULL,
     title VARCHAR(50) NOT NULL,
     image VARCHAR(100) NOT NULL DEFAULT '',
     description TEXT,
-    price INT NOT NULL
+    price INT NOT NULL,
+    search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('russian', title), 'A') || setweight(to_tsvector('english', title), 'A') ||
+        setweight(to_tsvector('russian', COALESCE(description, '')), 'B') ||
+        setweight(to_tsvector('english', COALESCE(description, '')), 'B')
+    ) STORED
 );
 
+CREATE INDEX IF NOT EXISTS menu_items_search_idx ON menu_items USING GIN (search_vector);
+
 CREATE TABLE IF NOT EXISTS categories (
     id SERIAL PRIMARY KEY,
     restaurant_id INT REFERENCES restaurants (id) ON DELETE CASCADE NOT NULL,
-    title VARCHAR(50) NOT NULL
+    title VARCHAR(50) NOT NULL,
+    search_vector TSVECTOR GENERATED ALWAYS AS (
+        to_tsvector('russian', title) || to_tsvector('english', title)
+    ) STORED
 );
 
+CREATE INDEX IF NOT EXISTS categories_search_idx ON categories USING GIN (search_vector);
+
 CREATE TABLE IF NOT EXISTS category_items (
     id SERIAL PRIMARY KEY,
     category_id INT REFERENCES categories (id) ON DELETE CASCADE NOT NULL,
@@ -57,9 +78,16 @@ CREATE TABLE IF NOT EXISTS category_items (
 CREATE TABLE IF NOT EXISTS admins (
     id SERIAL PRIMARY KEY,
     name VARCHAR(50) NOT NULL,
-    password_hash VARCHAR(50) NOT NULL
+    password_hash VARCHAR(100) NOT NULL
 );
 
+-- databases created before passwords were hashed have password_hash columns
+-- too short for a bcrypt hash
+ALTER TABLE users ALTER COLUMN password_hash TYPE VARCHAR(100);
+ALTER TABLE couriers ALTER COLUMN password_hash TYPE VARCHAR(100);
+ALTER TABLE restaurants ALTER COLUMN password_hash TYPE VARCHAR(100);
+ALTER TABLE admins ALTER COLUMN password_hash TYPE VARCHAR(100);
+
 CREATE TABLE IF NOT EXISTS orders (
     id SERIAL PRIMARY KEY,
     user_id INT REFERENCES users (id) ON DELETE CASCADE NOT NULL,
@@ -68,31 +96,306 @@ CREATE TABLE IF NOT EXISTS orders (
     delivery_price INT NOT NULL DEFAULT 0 CHECK (delivery_price >= 0),
     total_price INT NOT NULL DEFAULT 0 CHECK (total_price >= 0),
     status INT NOT NULL,
-    paid TIMESTAMP
+    paid TIMESTAMP,
+    discount INT NOT NULL DEFAULT 0 CHECK (discount >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
 );
 
+CREATE INDEX IF NOT EXISTS orders_user_idx ON orders (user_id, created_at);
+CREATE INDEX IF NOT EXISTS orders_restaurant_idx ON orders (restaurant_id, created_at);
+
 CREATE TABLE IF NOT EXISTS order_items (
     id SERIAL PRIMARY KEY,
     order_id INT REFERENCES orders (id) ON DELETE CASCADE NOT NULL,
-    menu_item_id INT REFERENCES menu_items (id) ON DELETE CASCADE NOT NULL,
+    menu_item_id INT REFERENCES menu_items (id) ON DELETE SET NULL,
     count INT NULL DEFAULT 1 CHECK (count > 0 AND count < 100),
+    title VARCHAR(50) NOT NULL,
+    price INT NOT NULL CHECK (price >= 0),
     UNIQUE(order_id, menu_item_id)
 );
 
+CREATE TABLE IF NOT EXISTS order_events (
+    id SERIAL PRIMARY KEY,
+    order_id INT REFERENCES orders (id) ON DELETE CASCADE NOT NULL,
+    actor_id INT NOT NULL,
+    actor_type VARCHAR(20) NOT NULL,
+    old_status INT,
+    new_status INT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS order_events_order_idx ON order_events (order_id, created_at);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id SERIAL PRIMARY KEY,
+    client_id INT NOT NULL,
+    client_type VARCHAR(20) NOT NULL,
+    refresh_token_hash VARCHAR(64) UNIQUE NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    refreshed_at TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS sessions_client_idx ON sessions (client_id, client_type);
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    token_id VARCHAR(64) PRIMARY KEY,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS revoked_clients (
+    client_id INT NOT NULL,
+    client_type VARCHAR(20) NOT NULL,
+    revoked_before TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (client_id, client_type)
+);
+
+CREATE TABLE IF NOT EXISTS event_tickets (
+    ticket_hash VARCHAR(64) PRIMARY KEY,
+    client_id INT NOT NULL,
+    client_type VARCHAR(20) NOT NULL,
+    token_id VARCHAR(64) NOT NULL,
+    token_issued_at TIMESTAMPTZ NOT NULL,
+    token_expires_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS dispatch_queue (
+    order_id INT PRIMARY KEY REFERENCES orders (id) ON DELETE CASCADE,
+    queued_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    escalated_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS courier_offers (
+    id SERIAL PRIMARY KEY,
+    order_id INT REFERENCES orders (id) ON DELETE CASCADE NOT NULL,
+    courier_id INT REFERENCES couriers (id) ON DELETE CASCADE NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    responded_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS courier_offers_pending_idx ON courier_offers (order_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS courier_offers_courier_idx ON courier_offers (courier_id, status);
+
+CREATE TABLE IF NOT EXISTS courier_locations (
+    id SERIAL PRIMARY KEY,
+    courier_id INT REFERENCES couriers (id) ON DELETE CASCADE NOT NULL,
+    latitude DOUBLE PRECISION NOT NULL CHECK (ABS(latitude) <= 90),
+    longitude DOUBLE PRECISION NOT NULL CHECK (ABS(longitude) <= 180),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS courier_locations_courier_idx ON courier_locations (courier_id, created_at);
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    id SERIAL PRIMARY KEY,
+    restaurant_id INT REFERENCES restaurants (id) ON DELETE CASCADE NOT NULL,
+    url VARCHAR(2048) NOT NULL,
+    secret VARCHAR(100) NOT NULL,
+    events VARCHAR(50)[] NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS webhooks_restaurant_idx ON webhooks (restaurant_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id SERIAL PRIMARY KEY,
+    webhook_id INT REFERENCES webhooks (id) ON DELETE CASCADE NOT NULL,
+    event VARCHAR(50) NOT NULL,
+    event_id VARCHAR(50) NOT NULL,
+    payload TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    response_code INT,
+    last_error TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    delivered_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_idx ON webhook_deliveries (webhook_id, id);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE UNIQUE INDEX IF NOT EXISTS webhook_deliveries_event_idx ON webhook_deliveries (webhook_id, event_id);
+
+CREATE TABLE IF NOT EXISTS outbox
+(
+    id BIGSERIAL PRIMARY KEY,
+    event_id VARCHAR(50) NOT NULL UNIQUE,
+    event_type VARCHAR(50) NOT NULL,
+    payload TEXT NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    published_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox (next_attempt_at, id) WHERE published_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS payments
+(
+    id SERIAL PRIMARY KEY,
+    order_id INT REFERENCES orders (id) ON DELETE CASCADE NOT NULL,
+    provider VARCHAR(50) NOT NULL,
+    intent_id VARCHAR(100) NOT NULL,
+    amount INT NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (provider, intent_id)
+);
+
+CREATE INDEX IF NOT EXISTS payments_order_idx ON payments (order_id, id);
+
+CREATE TABLE IF NOT EXISTS refunds
+(
+    id SERIAL PRIMARY KEY,
+    order_id INT REFERENCES orders (id) ON DELETE CASCADE NOT NULL,
+    payment_id INT REFERENCES payments (id) ON DELETE CASCADE NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    amount INT NOT NULL CHECK (amount > 0),
+    reason TEXT NOT NULL DEFAULT '',
+    order_item_id INT,
+    status VARCHAR(20) NOT NULL,
+    provider_refund_id VARCHAR(100),
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    actor_id INT NOT NULL,
+    actor_type VARCHAR(20) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS refunds_order_idx ON refunds (order_id, id);
+CREATE INDEX IF NOT EXISTS refunds_pending_idx ON refunds (next_attempt_at) WHERE status = 'pending';
+CREATE UNIQUE INDEX IF NOT EXISTS refunds_item_idx ON refunds (order_item_id) WHERE status <> 'failed';
+
+CREATE TABLE IF NOT EXISTS carts
+(
+    id SERIAL PRIMARY KEY,
+    user_id INT REFERENCES users (id) ON DELETE CASCADE NOT NULL UNIQUE,
+    restaurant_id INT REFERENCES restaurants (id) ON DELETE CASCADE NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS carts_updated_idx ON carts (updated_at);
+
+CREATE TABLE IF NOT EXISTS cart_items
+(
+    id SERIAL PRIMARY KEY,
+    cart_id INT REFERENCES carts (id) ON DELETE CASCADE NOT NULL,
+    menu_item_id INT REFERENCES menu_items (id) ON DELETE CASCADE NOT NULL,
+    count INT NOT NULL DEFAULT 1 CHECK (count > 0 AND count < 100),
+    UNIQUE (cart_id, menu_item_id)
+);
+
+CREATE TABLE IF NOT EXISTS restaurant_hours
+(
+    id SERIAL PRIMARY KEY,
+    restaurant_id INT REFERENCES restaurants (id) ON DELETE CASCADE NOT NULL,
+    weekday INT NOT NULL CHECK (weekday >= 1 AND weekday <= 7),
+    opens_at TIME NOT NULL,
+    closes_at TIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS restaurant_hours_restaurant_idx ON restaurant_hours (restaurant_id, weekday);
+
+CREATE TABLE IF NOT EXISTS restaurant_holidays
+(
+    id SERIAL PRIMARY KEY,
+    restaurant_id INT REFERENCES restaurants (id) ON DELETE CASCADE NOT NULL,
+    date DATE NOT NULL,
+    opens_at TIME,
+    closes_at TIME,
+    reason VARCHAR(100) NOT NULL DEFAULT '',
+    UNIQUE (restaurant_id, date),
+    CHECK ((opens_at IS NULL) = (closes_at IS NULL))
+);
+
+CREATE TABLE IF NOT EXISTS delivery_zone_points
+(
+    id SERIAL PRIMARY KEY,
+    restaurant_id INT REFERENCES restaurants (id) ON DELETE CASCADE NOT NULL,
+    seq INT NOT NULL,
+    latitude FLOAT NOT NULL,
+    longitude FLOAT NOT NULL,
+    UNIQUE (restaurant_id, seq)
+);
+
+CREATE TABLE IF NOT EXISTS reviews
+(
+    id SERIAL PRIMARY KEY,
+    order_id INT REFERENCES orders (id) ON DELETE CASCADE NOT NULL,
+    user_id INT REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+    subject VARCHAR(20) NOT NULL CHECK (subject IN ('restaurant', 'courier')),
+    subject_id INT NOT NULL,
+    rating INT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    comment VARCHAR(1000) NOT NULL DEFAULT '',
+    reply VARCHAR(1000),
+    replied_at TIMESTAMPTZ,
+    hidden BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (order_id, subject)
+);
+
+CREATE INDEX IF NOT EXISTS reviews_subject_idx ON reviews (subject, subject_id, created_at);
+
 CREATE OR REPLACE FUNCTION get_distance(lat1 float, lon1 float, lat2 float, lon2 float)
 RETURNS float AS $$
 	SELECT 2*6371*asin(sqrt(power(sin(radians((lat2 - lat1)/2)), 2) + 
 		cos(radians(lat1))*cos(radians(lat2))*power(sin(radians((lon2 - lon1)/2)), 2)))
 $$ LANGUAGE SQL;
 
+-- in_polygon casts a ray east from the point and counts the edges of the
+-- delivery zone polygon it crosses
+CREATE OR REPLACE FUNCTION in_polygon(cur_restaurant_id int, lat float, lon float)
+RETURNS boolean AS $$
+	SELECT COUNT(*) % 2 = 1 FROM
+	(
+		SELECT latitude AS lat1, longitude AS lon1,
+			COALESCE(lag(latitude) OVER w, last_value(latitude) OVER w_all) AS lat2,
+			COALESCE(lag(longitude) OVER w, last_value(longitude) OVER w_all) AS lon2
+		FROM delivery_zone_points
+		WHERE restaurant_id = cur_restaurant_id
+		WINDOW w AS (ORDER BY seq),
+			w_all AS (ORDER BY seq ROWS BETWEEN UNBOUNDED PRECEDING AND UNBOUNDED FOLLOWING)
+	) AS edges
+	WHERE (lat1 > lat) <> (lat2 > lat)
+		AND lon < CASE WHEN lat1 = lat2 THEN lon1 ELSE (lon2 - lon1) * (lat - lat1) / (lat2 - lat1) + lon1 END
+$$ LANGUAGE SQL STABLE;
+
+-- in_delivery_zone reports whether the restaurant delivers to the point,
+-- a restaurant without a radius or a polygon delivers anywhere
+CREATE OR REPLACE FUNCTION in_delivery_zone(cur_restaurant_id int, lat float, lon float)
+RETURNS boolean AS $$
+	SELECT CASE
+		WHEN EXISTS (SELECT 1 FROM delivery_zone_points WHERE restaurant_id = cur_restaurant_id)
+			THEN in_polygon(cur_restaurant_id, lat, lon)
+		WHEN r.delivery_radius IS NOT NULL
+			THEN get_distance(l.latitude, l.longitude, lat, lon) <= r.delivery_radius
+		ELSE TRUE
+	END
+	FROM restaurants AS r
+		INNER JOIN locations AS l ON r.address_id = l.id
+	WHERE r.id = cur_restaurant_id
+$$ LANGUAGE SQL STABLE;
+
+-- search_query matches the words of a search by their Russian or English
+-- stems, the menus are bilingual
+CREATE OR REPLACE FUNCTION search_query(query text)
+RETURNS tsquery AS $$
+	SELECT websearch_to_tsquery('russian', query) || websearch_to_tsquery('english', query)
+$$ LANGUAGE SQL STABLE;
+
 CREATE OR REPLACE FUNCTION get_total_price(cur_order_id int)
 RETURNS bigint AS $$
-	SELECT SUM(tmp.mul) + (SELECT delivery_price FROM orders WHERE id = cur_order_id) FROM 
+	SELECT COALESCE(SUM(tmp.mul), 0) + (SELECT delivery_price - discount FROM orders WHERE id = cur_order_id) FROM 
 	(
-		SELECT count * 
-			(
-				SELECT price FROM menu_items WHERE id = oi.menu_item_id
-			) AS mul
+		SELECT count * price AS mul
 		FROM order_items AS oi 
 		WHERE order_id = cur_order_id
 	) AS tmp
@@ -116,4 +419,35 @@ $$ LANGUAGE PLPGSQL;
 
 CREATE TRIGGER total_price_update_trigger
 AFTER INSERT OR UPDATE OR DELETE ON order_items 
-FOR EACH ROW EXECUTE PROCEDURE update_total_price ();
\ No newline at end of file
+FOR EACH ROW EXECUTE PROCEDURE update_total_price ();
+
+-- update_restaurant_rating keeps the rating of a restaurant in step with its
+-- reviews, the hidden ones aside. The restaurant is locked first, so that
+-- concurrent reviews aggregate one after another and each sees the other
+CREATE OR REPLACE FUNCTION update_restaurant_rating()
+RETURNS trigger AS $$
+DECLARE
+	review reviews;
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		review := OLD;
+	ELSE
+		review := NEW;
+	END IF;
+
+	IF review.subject = 'restaurant' THEN
+		PERFORM 1 FROM restaurants WHERE id = review.subject_id FOR UPDATE;
+
+		UPDATE restaurants SET (rating, rating_count) = (
+			SELECT COALESCE(ROUND(AVG(rv.rating), 2), 0), COUNT(*)
+			FROM reviews AS rv
+			WHERE rv.subject = 'restaurant' AND rv.subject_id = review.subject_id AND NOT rv.hidden
+		) WHERE id = review.subject_id;
+	END IF;
+RETURN NULL;
+END;
+$$ LANGUAGE PLPGSQL;
+
+CREATE TRIGGER restaurant_rating_update_trigger
+AFTER INSERT OR UPDATE OR DELETE ON reviews
+FOR EACH ROW EXECUTE PROCEDURE update_restaurant_rating ();
@@ -3,35 +3,35 @@
 INSERT INTO orders (user_id, restaurant_id, courier_id, delivery_price, total_price, status)
 VALUES (1, 1, NULL, 100, 900, 0);
 
-INSERT INTO order_items (order_id, menu_item_id, count)
-VALUES (1, 1, 2);
+INSERT INTO order_items (order_id, menu_item_id, count, title, price)
+VALUES (1, 1, 2, 'Title1', 100);
 
-INSERT INTO order_items (order_id, menu_item_id, count)
-VALUES (1, 2, 3);
+INSERT INTO order_items (order_id, menu_item_id, count, title, price)
+VALUES (1, 2, 3, 'Title2', 200);
 
 -- Order 2
 INSERT INTO orders (user_id, restaurant_id, courier_id, delivery_price, total_price, status)
 VALUES (1, 2, 1, 200, 650, 5);
 
-INSERT INTO order_items (order_id, menu_item_id, count)
-VALUES (2, 4, 3);
+INSERT INTO order_items (order_id, menu_item_id, count, title, price)
+VALUES (2, 4, 3, 'Title4', 150);
 
 -- user2 orders
 -- Order 3
 INSERT INTO orders (user_id, restaurant_id, courier_id, delivery_price, total_price, status)
 VALUES (2, 2, 2, 100, 800, 1);
 
-INSERT INTO order_items (order_id, menu_item_id, count)
-VALUES (3, 4, 3);
+INSERT INTO order_items (order_id, menu_item_id, count, title, price)
+VALUES (3, 4, 3, 'Title4', 150);
 
-INSERT INTO order_items (order_id, menu_item_id, count)
-VALUES (3, 5, 1);
+INSERT INTO order_items (order_id, menu_item_id, count, title, price)
+VALUES (3, 5, 1, 'Title5', 250);
 
 -- user3 orders
 -- Order 4
 INSERT INTO orders (user_id, restaurant_id, courier_id, delivery_price, total_price, status)
 VALUES (3, 1, 3, 100, 700, 3);
 
-INSERT INTO order_items (order_id, menu_item_id, count)
-VALUES (4, 2, 3);
+INSERT INTO order_items (order_id, menu_item_id, count, title, price)
+VALUES (4, 2, 3, 'Title2', 200);
 
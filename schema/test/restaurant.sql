@@ -2,17 +2,17 @@
 INSERT INTO locations (latitude, longitude) VALUES (52, 85);
 
 INSERT INTO restaurants (name, phone, password_hash, address_id, working_status, image)
-VALUES ('Restaurant1', '71234567891', 'password', 9, 1, 'img/image1.jpg');
+VALUES ('Restaurant1', '71234567891', 'password', 9, 2, 'img/image1.jpg');
 
 INSERT INTO locations (latitude, longitude) VALUES (55, 85);
 
 INSERT INTO restaurants (name, phone, password_hash, address_id, working_status, image)
-VALUES ('Restaurant2', '71234567892', 'password', 10, 1, 'img/image1.jpg');
+VALUES ('Restaurant2', '71234567892', 'password', 10, 2, 'img/image1.jpg');
 
 INSERT INTO locations (latitude, longitude) VALUES (56, 87);
 
 INSERT INTO restaurants (name, phone, password_hash, address_id, working_status, image)
-VALUES ('Restaurant2', '71234567893', 'password', 11, 2, 'img/image1.jpg');
+VALUES ('Restaurant2', '71234567893', 'password', 11, 1, 'img/image1.jpg');
 
 -- Menu items for restaurant 1
 INSERT INTO menu_items (restaurant_id, title, image, description, price)
@@ -1,3 +1,22 @@
+TRUNCATE reviews RESTART IDENTITY CASCADE;
+TRUNCATE delivery_zone_points RESTART IDENTITY CASCADE;
+TRUNCATE restaurant_holidays RESTART IDENTITY CASCADE;
+TRUNCATE restaurant_hours RESTART IDENTITY CASCADE;
+TRUNCATE cart_items RESTART IDENTITY CASCADE;
+TRUNCATE carts RESTART IDENTITY CASCADE;
+TRUNCATE refunds RESTART IDENTITY CASCADE;
+TRUNCATE payments RESTART IDENTITY CASCADE;
+TRUNCATE outbox RESTART IDENTITY CASCADE;
+TRUNCATE webhook_deliveries RESTART IDENTITY CASCADE;
+TRUNCATE webhooks RESTART IDENTITY CASCADE;
+TRUNCATE courier_locations RESTART IDENTITY CASCADE;
+TRUNCATE courier_offers RESTART IDENTITY CASCADE;
+TRUNCATE dispatch_queue RESTART IDENTITY CASCADE;
+TRUNCATE event_tickets RESTART IDENTITY CASCADE;
+TRUNCATE revoked_clients RESTART IDENTITY CASCADE;
+TRUNCATE revoked_tokens RESTART IDENTITY CASCADE;
+TRUNCATE sessions RESTART IDENTITY CASCADE;
+TRUNCATE order_events RESTART IDENTITY CASCADE;
 TRUNCATE order_items RESTART IDENTITY CASCADE;
 TRUNCATE orders RESTART IDENTITY CASCADE;
 TRUNCATE admins RESTART IDENTITY CASCADE;
@@ -0,0 +1,159 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/MAVIKE/yad-backend/internal/consts"
+	"github.com/MAVIKE/yad-backend/internal/domain"
+)
+
+func (s *APITestSuite) addCartItem(userId int, reqBody string, replace bool) int {
+	url := fmt.Sprintf("/api/v1/users/%d/cart/items", userId)
+	if replace {
+		url += "?replace=true"
+	}
+
+	return s.clientRequest("POST", url, userId, userType, reqBody).Result().StatusCode
+}
+
+func (s *APITestSuite) getCart(userId int) *domain.Cart {
+	resp := s.clientRequest("GET", fmt.Sprintf("/api/v1/users/%d/cart", userId), userId, userType, "")
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	var cart domain.Cart
+	respData, err := ioutil.ReadAll(resp.Body)
+	s.NoError(err)
+
+	err = json.Unmarshal(respData, &cart)
+	s.NoError(err)
+
+	return &cart
+}
+
+func (s *APITestSuite) TestUserCartCheckoutOk() {
+	s.Require().Equal(http.StatusOK, s.addCartItem(1, `{"menu_item_id":1,"count":1}`, false))
+	s.Require().Equal(http.StatusOK, s.addCartItem(1, `{"menu_item_id":1,"count":1}`, false))
+	s.Require().Equal(http.StatusOK, s.addCartItem(1, `{"menu_item_id":2,"count":1}`, false))
+
+	cart := s.getCart(1)
+	s.Require().Equal(1, cart.RestaurantId)
+	s.Require().Len(cart.Items, 2)
+	s.Require().Equal(2, cart.Items[0].Count)
+	s.Require().Equal(400, cart.ItemsPrice)
+	s.Require().Equal(cart.ItemsPrice+cart.DeliveryPrice, cart.TotalPrice)
+
+	resp := s.clientRequest("POST", "/api/v1/users/1/cart/checkout", 1, userType, "")
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	var order struct {
+		Id int `json:"id"`
+	}
+	respData, err := ioutil.ReadAll(resp.Body)
+	s.NoError(err)
+
+	err = json.Unmarshal(respData, &order)
+	s.NoError(err)
+
+	created, err := s.repos.Order.GetById(order.Id)
+	s.NoError(err)
+	s.Require().Equal(consts.OrderCreated, created.Status)
+	s.Require().Equal(1, created.RestaurantId)
+	s.Require().Equal(cart.TotalPrice, created.TotalPrice)
+	s.requireOrderItems(order.Id, 2)
+
+	// the cart is emptied by checkout
+	s.Require().Empty(s.getCart(1).Items)
+}
+
+func (s *APITestSuite) TestUserCartUpdateOk() {
+	s.Require().Equal(http.StatusOK, s.addCartItem(1, `{"menu_item_id":1,"count":1}`, false))
+	s.Require().Equal(http.StatusOK, s.addCartItem(1, `{"menu_item_id":2,"count":1}`, false))
+
+	cart := s.getCart(1)
+
+	url := fmt.Sprintf("/api/v1/users/1/cart/items/%d", cart.Items[0].Id)
+	resp := s.clientRequest("PUT", url, 1, userType, `{"count":3}`)
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	url = fmt.Sprintf("/api/v1/users/1/cart/items/%d", cart.Items[1].Id)
+	resp = s.clientRequest("DELETE", url, 1, userType, "")
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	cart = s.getCart(1)
+	s.Require().Len(cart.Items, 1)
+	s.Require().Equal(300, cart.ItemsPrice)
+
+	resp = s.clientRequest("DELETE", url, 1, userType, "")
+	s.Require().Equal(http.StatusNotFound, resp.Result().StatusCode)
+}
+
+func (s *APITestSuite) TestUserCartSwitchRestaurant() {
+	s.Require().Equal(http.StatusOK, s.addCartItem(1, `{"menu_item_id":1,"count":1}`, false))
+
+	// dishes of another restaurant need a confirmation
+	s.Require().Equal(http.StatusConflict, s.addCartItem(1, `{"menu_item_id":4,"count":1}`, false))
+	s.Require().Equal(1, s.getCart(1).RestaurantId)
+
+	s.Require().Equal(http.StatusOK, s.addCartItem(1, `{"menu_item_id":4,"count":1}`, true))
+
+	cart := s.getCart(1)
+	s.Require().Equal(2, cart.RestaurantId)
+	s.Require().Len(cart.Items, 1)
+	s.Require().Equal(4, cart.Items[0].MenuItemId)
+}
+
+func (s *APITestSuite) TestUserCartExpired() {
+	s.Require().Equal(http.StatusOK, s.addCartItem(1, `{"menu_item_id":1,"count":1}`, false))
+	s.Require().Equal(http.StatusOK, s.addCartItem(2, `{"menu_item_id":1,"count":1}`, false))
+	s.db.MustExec(`UPDATE carts SET updated_at = now() - interval '2 hours' WHERE user_id = 1`)
+
+	s.NoError(s.services.Cart.Expire())
+
+	s.Require().Empty(s.getCart(1).Items)
+	s.Require().Len(s.getCart(2).Items, 1)
+}
+
+func (s *APITestSuite) TestUserCartCheckoutError_PriceChanged() {
+	s.Require().Equal(http.StatusOK, s.addCartItem(1, `{"menu_item_id":1,"count":2}`, false))
+	cart := s.getCart(1)
+
+	// the price changes between the preview and the checkout transaction
+	s.db.MustExec(`UPDATE menu_items SET price = price + 50 WHERE id = 1`)
+
+	order := &domain.Order{
+		UserId:        1,
+		RestaurantId:  cart.RestaurantId,
+		DeliveryPrice: cart.DeliveryPrice,
+		TotalPrice:    cart.TotalPrice,
+		Status:        consts.OrderCreated,
+	}
+	_, err := s.repos.Cart.Checkout(cart, order, &domain.OrderEvent{
+		ActorId:   1,
+		ActorType: userType,
+		NewStatus: order.Status,
+	})
+	s.Require().True(errors.Is(err, domain.ErrConflict))
+
+	// the cart is kept for the user to review
+	s.Require().Len(s.getCart(1).Items, 1)
+}
+
+func (s *APITestSuite) TestUserCartCheckoutError_Empty() {
+	resp := s.clientRequest("POST", "/api/v1/users/1/cart/checkout", 1, userType, "")
+	s.Require().Equal(http.StatusConflict, resp.Result().StatusCode)
+}
+
+func (s *APITestSuite) TestUserCartError_Forbidden() {
+	resp := s.clientRequest("GET", "/api/v1/users/1/cart", 2, userType, "")
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
+
+	resp = s.clientRequest("POST", "/api/v1/users/1/cart/items", 2, userType, `{"menu_item_id":1,"count":1}`)
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
+
+	resp = s.clientRequest("POST", "/api/v1/users/1/cart/checkout", 1, restaurantType, "")
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
+}
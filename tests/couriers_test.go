@@ -68,7 +68,7 @@ func (s *APITestSuite) TestCourierSignUpError_NotAdmin() {
 	resp := httptest.NewRecorder()
 	s.app.ServeHTTP(resp, req)
 
-	s.Require().Equal(http.StatusInternalServerError, resp.Result().StatusCode)
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
 }
 
 func (s *APITestSuite) TestCourierSignUpError_NoUniquePhone() {
@@ -92,7 +92,7 @@ func (s *APITestSuite) TestCourierSignUpError_NoUniquePhone() {
 	resp := httptest.NewRecorder()
 	s.app.ServeHTTP(resp, req)
 
-	s.Require().Equal(http.StatusInternalServerError, resp.Result().StatusCode)
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
 }
 
 func (s *APITestSuite) TestCourierSignUpError_EmptyRequiredFields() {
@@ -214,7 +214,26 @@ func (s *APITestSuite) TestCourierGetError_WrongId() {
 	resp := httptest.NewRecorder()
 	s.app.ServeHTTP(resp, req)
 
-	s.Require().Equal(http.StatusInternalServerError, resp.Result().StatusCode)
+	s.Require().Equal(http.StatusNotFound, resp.Result().StatusCode)
+}
+
+func (s *APITestSuite) TestCourierGetError_OtherCourier() {
+	userId := 2
+	clientType := courierType
+
+	jwt, err := s.getJWT(userId, clientType)
+	s.NoError(err)
+
+	req, err := http.NewRequest("GET", "/api/v1/couriers/1", nil)
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
 }
 
 func (s *APITestSuite) TestCourierUpdateOk() {
@@ -224,7 +243,7 @@ func (s *APITestSuite) TestCourierUpdateOk() {
 	jwt, err := s.getJWT(userId, clientType)
 	s.NoError(err)
 
-	working_status, address := consts.CourierWorking, "{\"latitude\":45,\"longitude\":42}"
+	working_status, address := consts.CourierWaiting, "{\"latitude\":45,\"longitude\":42}"
 	reqBody := fmt.Sprintf(`{"working_status":%d,"address":%s}`, working_status, address)
 
 	req, err := http.NewRequest("PUT", "/api/v1/couriers/5", bytes.NewBuffer([]byte(reqBody)))
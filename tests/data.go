@@ -65,7 +65,7 @@ var (
 			Name:     "Restaurant1",
 			Phone:    "71234567891",
 			Password: "password",
-			WorkingStatus: 1,
+			WorkingStatus: 2,
 			Address: &domain.Location{
 				Latitude:  52,
 				Longitude: 85,
@@ -77,7 +77,7 @@ var (
 			Name:     "Restaurant2",
 			Phone:    "71234567892",
 			Password: "password",
-			WorkingStatus: 1,
+			WorkingStatus: 2,
 			Address: &domain.Location{
 				Latitude:  55,
 				Longitude: 85,
@@ -89,7 +89,7 @@ var (
 			Name:     "Restaurant2",
 			Phone:    "71234567893",
 			Password: "password",
-			WorkingStatus: 2,
+			WorkingStatus: 1,
 			Address: &domain.Location{
 				Latitude:  56,
 				Longitude: 87,
@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+)
+
+// squareZone has user 1 inside and users 2 and 3 outside.
+const squareZone = `{"polygon":[{"latitude":49,"longitude":86},{"latitude":49,"longitude":88},
+	{"latitude":50.5,"longitude":88},{"latitude":50.5,"longitude":86}]}`
+
+func (s *APITestSuite) getRestaurantIds(userId int) []int {
+	resp := s.clientRequest("GET", "/api/v1/restaurants/", userId, userType, "")
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	var page domain.RestaurantPage
+	respData, err := ioutil.ReadAll(resp.Body)
+	s.NoError(err)
+
+	err = json.Unmarshal(respData, &page)
+	s.NoError(err)
+
+	ids := make([]int, 0, len(page.Items))
+	for _, restaurant := range page.Items {
+		ids = append(ids, restaurant.Id)
+	}
+
+	return ids
+}
+
+func (s *APITestSuite) TestGetAllRestaurantsRadiusZone() {
+	// user 1 is about 260 km away from restaurant 1
+	resp := s.clientRequest("PUT", "/api/v1/restaurants/1/zone", 1, restaurantType, `{"radius":100}`)
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+	s.Require().Equal([]int{2, 3}, s.getRestaurantIds(1))
+
+	resp = s.clientRequest("PUT", "/api/v1/restaurants/1/zone", 1, adminType, `{"radius":300}`)
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+	s.Require().Equal([]int{1, 2, 3}, s.getRestaurantIds(1))
+
+	// an empty zone delivers anywhere
+	resp = s.clientRequest("PUT", "/api/v1/restaurants/1/zone", 1, restaurantType, `{}`)
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+	s.Require().Equal([]int{1, 2, 3}, s.getRestaurantIds(2))
+}
+
+func (s *APITestSuite) TestGetAllRestaurantsPolygonZone() {
+	resp := s.clientRequest("PUT", "/api/v1/restaurants/2/zone", 2, restaurantType, squareZone)
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	s.Require().Equal([]int{1, 2, 3}, s.getRestaurantIds(1))
+	s.Require().Equal([]int{1, 3}, s.getRestaurantIds(2))
+
+	resp = s.clientRequest("GET", "/api/v1/restaurants/2/zone", 1, userType, "")
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	var zone domain.DeliveryZone
+	respData, err := ioutil.ReadAll(resp.Body)
+	s.NoError(err)
+
+	err = json.Unmarshal(respData, &zone)
+	s.NoError(err)
+
+	s.Require().Nil(zone.Radius)
+	s.Require().Len(zone.Polygon, 4)
+	s.Require().Equal(50.5, zone.Polygon[2].Latitude)
+}
+
+func (s *APITestSuite) TestUserCreateOrderError_OutsideDeliveryZone() {
+	resp := s.clientRequest("PUT", "/api/v1/restaurants/2/zone", 2, restaurantType, squareZone)
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	resp = s.clientRequest("POST", "/api/v1/orders/", 2, userType, `{"restaurant_id":2}`)
+	s.requireErrorCode(resp, http.StatusUnprocessableEntity, domain.CodeOutsideDeliveryZone)
+
+	s.addCartItem(2, `{"menu_item_id":4,"count":1}`, false)
+	resp = s.clientRequest("POST", "/api/v1/users/2/cart/checkout", 2, userType, "")
+	s.requireErrorCode(resp, http.StatusUnprocessableEntity, domain.CodeOutsideDeliveryZone)
+
+	resp = s.clientRequest("POST", "/api/v1/orders/", 1, userType, `{"restaurant_id":2}`)
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+}
+
+func (s *APITestSuite) TestUpdateDeliveryZoneError() {
+	tests := []struct {
+		name       string
+		clientId   int
+		clientType string
+		reqBody    string
+		status     int
+	}{
+		{"foreign restaurant", 2, restaurantType, `{"radius":5}`, http.StatusForbidden},
+		{"user", 1, userType, `{"radius":5}`, http.StatusForbidden},
+		{"radius and polygon", 1, restaurantType, `{"radius":5,"polygon":[{"latitude":49,"longitude":86},
+			{"latitude":49,"longitude":88},{"latitude":50,"longitude":88}]}`, http.StatusUnprocessableEntity},
+		{"negative radius", 1, restaurantType, `{"radius":-5}`, http.StatusUnprocessableEntity},
+		{"two points", 1, restaurantType, `{"polygon":[{"latitude":49,"longitude":86},{"latitude":49,"longitude":88}]}`,
+			http.StatusUnprocessableEntity},
+		{"bad point", 1, restaurantType, `{"polygon":[{"latitude":149,"longitude":86},{"latitude":49,"longitude":88},
+			{"latitude":50,"longitude":88}]}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		resp := s.clientRequest("PUT", "/api/v1/restaurants/1/zone", tt.clientId, tt.clientType, tt.reqBody)
+		s.Require().Equal(tt.status, resp.Result().StatusCode, tt.name)
+	}
+
+	resp := s.clientRequest("PUT", "/api/v1/restaurants/100/zone", 1, adminType, `{"radius":5}`)
+	s.Require().Equal(http.StatusNotFound, resp.Result().StatusCode)
+}
+
+func (s *APITestSuite) TestInPolygon() {
+	// a C opening east: a notch between latitudes 1 and 2 east of longitude 1
+	resp := s.clientRequest("PUT", "/api/v1/restaurants/2/zone", 2, restaurantType, `{"polygon":[
+		{"latitude":0,"longitude":0},{"latitude":0,"longitude":3},{"latitude":1,"longitude":3},
+		{"latitude":1,"longitude":1},{"latitude":2,"longitude":1},{"latitude":2,"longitude":3},
+		{"latitude":3,"longitude":3},{"latitude":3,"longitude":0}]}`)
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	tests := []struct {
+		name      string
+		latitude  float64
+		longitude float64
+		inside    bool
+	}{
+		{"lower arm", 0.5, 2, true},
+		{"spine", 1.5, 0.5, true},
+		{"notch", 1.5, 2, false},
+		{"west", 1.5, -1, false},
+		{"east", 1.5, 4, false},
+		{"south", -1, 1, false},
+		{"ray along the notch edge", 1, 0.5, true},
+		{"ray along the top edge", 3, -1, false},
+	}
+
+	for _, tt := range tests {
+		var inside bool
+		err := s.db.Get(&inside, `SELECT in_polygon(2, $1, $2)`, tt.latitude, tt.longitude)
+		s.NoError(err)
+		s.Require().Equal(tt.inside, inside, tt.name)
+	}
+}
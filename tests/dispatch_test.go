@@ -0,0 +1,237 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/MAVIKE/yad-backend/internal/consts"
+	"github.com/MAVIKE/yad-backend/internal/domain"
+)
+
+func (s *APITestSuite) payOrder(orderId, userId int) {
+	s.createPayment(orderId, userId)
+
+	payment := s.confirmPayment(orderId, userId)
+	s.Require().Equal(consts.PaymentCaptured, payment.Status)
+}
+
+func (s *APITestSuite) getCourierOffers(courierId int) []*domain.CourierOffer {
+	jwt, err := s.getJWT(courierId, courierType)
+	s.NoError(err)
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("/api/v1/couriers/%d/offers", courierId), nil)
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	var offers []*domain.CourierOffer
+	respData, err := ioutil.ReadAll(resp.Body)
+	s.NoError(err)
+
+	err = json.Unmarshal(respData, &offers)
+	s.NoError(err)
+
+	return offers
+}
+
+func (s *APITestSuite) answerOffer(courierId, offerId int, answer string) int {
+	jwt, err := s.getJWT(courierId, courierType)
+	s.NoError(err)
+
+	url := fmt.Sprintf("/api/v1/couriers/%d/offers/%d/%s", courierId, offerId, answer)
+	req, err := http.NewRequest("POST", url, nil)
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	return resp.Result().StatusCode
+}
+
+func (s *APITestSuite) TestCourierAcceptOfferOk() {
+	s.payOrder(1, 1)
+	s.NoError(s.services.Dispatch.AssignPending())
+
+	// courier 4 is the only waiting one
+	offers := s.getCourierOffers(4)
+	s.Require().Len(offers, 1)
+	s.Require().Equal(1, offers[0].OrderId)
+
+	s.Require().Equal(http.StatusOK, s.answerOffer(4, offers[0].Id, "accept"))
+
+	var order domain.Order
+	err := s.db.Get(&order, `SELECT courier_id FROM orders WHERE id = 1`)
+	s.NoError(err)
+	s.Require().Equal(4, order.CourierId)
+
+	var workingStatus int
+	err = s.db.Get(&workingStatus, `SELECT working_status FROM couriers WHERE id = 4`)
+	s.NoError(err)
+	s.Require().Equal(consts.CourierWorking, workingStatus)
+
+	pending, err := s.repos.Dispatch.GetPending()
+	s.NoError(err)
+	s.Require().Empty(pending)
+}
+
+func (s *APITestSuite) TestCourierDeclineOfferOk_NextCourier() {
+	s.db.MustExec(`UPDATE couriers SET working_status = $1 WHERE id = 1`, consts.CourierWaiting)
+
+	s.payOrder(1, 1)
+	s.NoError(s.services.Dispatch.AssignPending())
+
+	// courier 1 lives nearer to user 1 than courier 4
+	offers := s.getCourierOffers(1)
+	s.Require().Len(offers, 1)
+	s.Require().Empty(s.getCourierOffers(4))
+
+	s.Require().Equal(http.StatusOK, s.answerOffer(1, offers[0].Id, "decline"))
+	s.NoError(s.services.Dispatch.AssignPending())
+
+	s.Require().Empty(s.getCourierOffers(1))
+	s.Require().Len(s.getCourierOffers(4), 1)
+
+	// a declined offer can't be accepted afterwards
+	s.Require().Equal(http.StatusConflict, s.answerOffer(1, offers[0].Id, "accept"))
+}
+
+func (s *APITestSuite) TestCourierDeclineOfferOk_OfferedAgainAfterCooldown() {
+	s.db.MustExec(`UPDATE couriers SET working_status = $1 WHERE id = 4`, consts.CourierUnable)
+	s.db.MustExec(`UPDATE couriers SET working_status = $1 WHERE id = 1`, consts.CourierWaiting)
+
+	s.payOrder(1, 1)
+	s.NoError(s.services.Dispatch.AssignPending())
+
+	offers := s.getCourierOffers(1)
+	s.Require().Len(offers, 1)
+	s.Require().Equal(http.StatusOK, s.answerOffer(1, offers[0].Id, "decline"))
+
+	// courier 1 is the only free one, but has just declined
+	s.NoError(s.services.Dispatch.AssignPending())
+	s.Require().Empty(s.getCourierOffers(1))
+
+	s.db.MustExec(`UPDATE courier_offers SET responded_at = now() - interval '10 minutes' WHERE id = $1`, offers[0].Id)
+	s.NoError(s.services.Dispatch.AssignPending())
+	s.Require().Len(s.getCourierOffers(1), 1)
+}
+
+func (s *APITestSuite) TestCourierOfferExpiredOk_NextCourier() {
+	s.db.MustExec(`UPDATE couriers SET working_status = $1 WHERE id = 1`, consts.CourierWaiting)
+
+	s.payOrder(1, 1)
+	s.NoError(s.services.Dispatch.AssignPending())
+
+	offers := s.getCourierOffers(1)
+	s.Require().Len(offers, 1)
+
+	s.db.MustExec(`UPDATE courier_offers SET expires_at = now() WHERE id = $1`, offers[0].Id)
+	s.NoError(s.services.Dispatch.AssignPending())
+
+	s.Require().Len(s.getCourierOffers(4), 1)
+	s.Require().Equal(http.StatusConflict, s.answerOffer(1, offers[0].Id, "accept"))
+}
+
+func (s *APITestSuite) TestCourierAcceptOfferError_OtherCourier() {
+	s.payOrder(1, 1)
+	s.NoError(s.services.Dispatch.AssignPending())
+
+	offers := s.getCourierOffers(4)
+	s.Require().Len(offers, 1)
+
+	jwt, err := s.getJWT(1, courierType)
+	s.NoError(err)
+
+	url := fmt.Sprintf("/api/v1/couriers/4/offers/%d/accept", offers[0].Id)
+	req, err := http.NewRequest("POST", url, nil)
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
+}
+
+func (s *APITestSuite) TestDispatchQueueOk_NoFreeCourier() {
+	// courier 4 is the only free one, take it off duty
+	s.db.MustExec(`UPDATE couriers SET working_status = $1 WHERE id = 4`, consts.CourierUnable)
+
+	s.payOrder(1, 1)
+	s.NoError(s.services.Dispatch.AssignPending())
+
+	adminJWT, err := s.getJWT(1, adminType)
+	s.NoError(err)
+
+	req, err := http.NewRequest("GET", "/api/v1/admins/dispatch", nil)
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+adminJWT)
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	var pending []*domain.PendingOrder
+	respData, err := ioutil.ReadAll(resp.Body)
+	s.NoError(err)
+
+	err = json.Unmarshal(respData, &pending)
+	s.NoError(err)
+
+	s.Require().Len(pending, 1)
+	s.Require().Equal(1, pending[0].OrderId)
+	s.Require().False(pending[0].Offered)
+}
+
+func (s *APITestSuite) TestDispatchEscalatedOk_NoFreeCourier() {
+	s.db.MustExec(`UPDATE couriers SET working_status = $1 WHERE id = 4`, consts.CourierUnable)
+
+	s.payOrder(1, 1)
+	s.db.MustExec(`UPDATE dispatch_queue SET queued_at = now() - interval '2 hours' WHERE order_id = 1`)
+
+	// the order is escalated once however many passes see it
+	s.NoError(s.services.Dispatch.AssignPending())
+	s.NoError(s.services.Dispatch.AssignPending())
+
+	var escalated int
+	err := s.db.Get(&escalated, `SELECT count(*) FROM outbox WHERE event_type = $1`, domain.EventOrderEscalated)
+	s.NoError(err)
+	s.Require().Equal(1, escalated)
+
+	var pending []*domain.PendingOrder
+	s.getPage("/api/v1/admins/dispatch", 1, adminType, &pending)
+	s.Require().Len(pending, 1)
+	s.Require().NotNil(pending[0].EscalatedAt)
+}
+
+func (s *APITestSuite) TestGetDispatchQueueError_Forbidden() {
+	jwt, err := s.getJWT(1, userType)
+	s.NoError(err)
+
+	req, err := http.NewRequest("GET", "/api/v1/admins/dispatch", nil)
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
+}
@@ -249,15 +249,37 @@ func (s *APITestSuite) TestE2EOk() {
 	order.TotalPrice = 800
 	testGetOrder(s, jwt, &order)
 
-	// User set status 1
-	reqBody = `{"status":1}`
-	req, err = http.NewRequest("PUT", "/api/v1/orders/5", bytes.NewBuffer([]byte(reqBody)))
+	// User pays the order
+	for _, url := range []string{"/api/v1/orders/5/payment", "/api/v1/orders/5/payment/confirm"} {
+		req, err = http.NewRequest("POST", url, nil)
+		if err != nil {
+			s.FailNow("Failed to build request", err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+jwt)
+
+		resp = httptest.NewRecorder()
+		s.app.ServeHTTP(resp, req)
+
+		s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+	}
+
+	// Get order
+	order.Status = 1
+	testGetOrder(s, jwt, &order)
+
+	// Courier 4 accepts the offer
+	s.NoError(s.services.Dispatch.AssignPending())
+
+	courierJWT, err := s.getJWT(4, courierType)
+	s.NoError(err)
+
+	req, err = http.NewRequest("POST", "/api/v1/couriers/4/offers/1/accept", nil)
 	if err != nil {
 		s.FailNow("Failed to build request", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+jwt)
-	req.Header.Set("Content-type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+courierJWT)
 
 	resp = httptest.NewRecorder()
 	s.app.ServeHTTP(resp, req)
@@ -266,7 +288,6 @@ func (s *APITestSuite) TestE2EOk() {
 
 	// Get order
 	order.CourierId = 4
-	order.Status = 1
 	testGetOrder(s, jwt, &order)
 
 	// Restaurant set status 2
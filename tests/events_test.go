@@ -0,0 +1,161 @@
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/labstack/echo/v4"
+)
+
+// readEvent returns the next event of the stream, skipping comments.
+func (s *APITestSuite) readEvent(stream *bufio.Reader) (string, *domain.Event) {
+	var eventType string
+
+	for {
+		line, err := stream.ReadString('\n')
+		s.Require().NoError(err)
+		line = strings.TrimSuffix(line, "\n")
+
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			eventType = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			var event domain.Event
+			err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event)
+			s.Require().NoError(err)
+			return eventType, &event
+		}
+	}
+}
+
+// createEventTicket returns a ticket to open the event stream of the client with.
+func (s *APITestSuite) createEventTicket(clientId int, clientType string) string {
+	resp := s.clientRequest("POST", "/api/v1/events/tickets", clientId, clientType, "")
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	var ticket domain.EventTicket
+	s.NoError(json.Unmarshal(resp.Body.Bytes(), &ticket))
+	s.Require().NotEmpty(ticket.Ticket)
+
+	return ticket.Ticket
+}
+
+func (s *APITestSuite) TestUserStreamEventsOk() {
+	server := httptest.NewServer(s.app)
+	defer server.Close()
+
+	streamResp, err := http.Get(server.URL + "/api/v1/events?ticket=" + s.createEventTicket(2, userType))
+	s.Require().NoError(err)
+	defer streamResp.Body.Close()
+
+	s.Require().Equal(http.StatusOK, streamResp.StatusCode)
+	s.Require().Equal("text/event-stream", streamResp.Header.Get("Content-Type"))
+
+	stream := bufio.NewReader(streamResp.Body)
+	line, err := stream.ReadString('\n')
+	s.Require().NoError(err)
+	s.Require().Equal(": connected\n", line)
+
+	restaurantJWT, err := s.getJWT(2, restaurantType)
+	s.NoError(err)
+
+	reqBody := `{"status":2}`
+	req, err := http.NewRequest("PUT", "/api/v1/orders/3", bytes.NewBuffer([]byte(reqBody)))
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+restaurantJWT)
+	req.Header.Set("Content-type", "application/json")
+
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	// the event is published on commit, without waiting for the outbox relay
+	eventType, event := s.readEvent(stream)
+	s.Require().Equal(domain.EventOrderStatusChanged, eventType)
+	s.Require().Equal(3, event.OrderId)
+	s.Require().Equal(2, event.Status)
+	s.Require().NotEmpty(event.Id)
+}
+
+func (s *APITestSuite) TestStreamEventsEndOnShutdown() {
+	// a server of its own, the suite's one keeps serving the other tests
+	app := echo.New()
+	app.HideBanner = true
+	app.HidePort = true
+	s.handlers.Init(app)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().NoError(err)
+	app.Listener = listener
+
+	go func() {
+		_ = app.Start("")
+	}()
+
+	streamResp, err := http.Get("http://" + listener.Addr().String() + "/api/v1/events?ticket=" +
+		s.createEventTicket(2, userType))
+	s.Require().NoError(err)
+	defer streamResp.Body.Close()
+	s.Require().Equal(http.StatusOK, streamResp.StatusCode)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// the open stream doesn't hold the shutdown up until the timeout
+	s.Require().NoError(app.Shutdown(ctx))
+
+	_, err = ioutil.ReadAll(streamResp.Body)
+	s.Require().NoError(err)
+}
+
+func (s *APITestSuite) TestStreamEventsError_NoToken() {
+	req, err := http.NewRequest("GET", "/api/v1/events", nil)
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusUnauthorized, resp.Result().StatusCode)
+}
+
+func (s *APITestSuite) TestStreamEventsError_Ticket() {
+	server := httptest.NewServer(s.app)
+	defer server.Close()
+
+	ticket := s.createEventTicket(2, userType)
+	streamResp, err := http.Get(server.URL + "/api/v1/events?ticket=" + ticket)
+	s.Require().NoError(err)
+	s.Require().Equal(http.StatusOK, streamResp.StatusCode)
+	streamResp.Body.Close()
+
+	jwt, err := s.getJWT(2, userType)
+	s.NoError(err)
+
+	// a ticket is used once and the access token is no longer read from the url
+	for _, query := range []string{"ticket=" + ticket, "ticket=unknown", "access_token=" + jwt} {
+		req, err := http.NewRequest("GET", "/api/v1/events?"+query, nil)
+		if err != nil {
+			s.FailNow("Failed to build request", err)
+		}
+
+		resp := httptest.NewRecorder()
+		s.app.ServeHTTP(resp, req)
+
+		s.Require().Equal(http.StatusUnauthorized, resp.Result().StatusCode, query)
+	}
+}
@@ -0,0 +1,84 @@
+package tests
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/MAVIKE/yad-backend/pkg/auth"
+	"github.com/dgrijalva/jwt-go"
+)
+
+func (s *APITestSuite) TestGetJWKSOk() {
+	req, err := http.NewRequest("GET", "/api/v1/.well-known/jwks.json", nil)
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	var jwks auth.JWKS
+	respData, err := ioutil.ReadAll(resp.Body)
+	s.NoError(err)
+
+	err = json.Unmarshal(respData, &jwks)
+	s.NoError(err)
+
+	s.Require().Len(jwks.Keys, 1)
+	s.Require().Equal(signingKeyId, jwks.Keys[0].Kid)
+	s.Require().Equal("OKP", jwks.Keys[0].Kty)
+	s.Require().Equal("EdDSA", jwks.Keys[0].Alg)
+	s.Require().NotEmpty(jwks.Keys[0].X)
+}
+
+func (s *APITestSuite) TestLegacyTokenWithoutKidOk() {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"jti":         "legacy",
+		"iat":         time.Now().Unix(),
+		"exp":         time.Now().Add(time.Minute).Unix(),
+		"id":          1,
+		"client_type": userType,
+	})
+
+	legacyJWT, err := token.SignedString([]byte(signingKey))
+	s.NoError(err)
+
+	req, err := http.NewRequest("GET", "/api/v1/users/1", nil)
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+legacyJWT)
+
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+}
+
+func (s *APITestSuite) TestUnknownKidError() {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"exp":         time.Now().Add(time.Minute).Unix(),
+		"id":          1,
+		"client_type": userType,
+	})
+	token.Header["kid"] = "unknown"
+
+	unknownJWT, err := token.SignedString([]byte(signingKey))
+	s.NoError(err)
+
+	req, err := http.NewRequest("GET", "/api/v1/users/1", nil)
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+unknownJWT)
+
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusUnauthorized, resp.Result().StatusCode)
+}
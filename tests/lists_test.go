@@ -0,0 +1,185 @@
+package tests
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+)
+
+// getPage requests a page of a list and decodes it into page.
+func (s *APITestSuite) getPage(path string, clientId int, clientType string, page interface{}) {
+	resp := s.clientRequest("GET", path, clientId, clientType, "")
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode, path)
+
+	respData, err := ioutil.ReadAll(resp.Body)
+	s.NoError(err)
+
+	err = json.Unmarshal(respData, page)
+	s.NoError(err)
+}
+
+// getRestaurantPages walks the pages of the restaurants from the query and
+// returns the ids of each page.
+func (s *APITestSuite) getRestaurantPages(query string) [][]int {
+	var pages [][]int
+	cursor := ""
+
+	for {
+		var page domain.RestaurantPage
+		s.getPage("/api/v1/restaurants/?"+query+"&cursor="+url.QueryEscape(cursor), 1, userType, &page)
+
+		ids := make([]int, 0, len(page.Items))
+		for _, restaurant := range page.Items {
+			ids = append(ids, restaurant.Id)
+		}
+		pages = append(pages, ids)
+
+		if page.NextCursor == "" {
+			return pages
+		}
+		cursor = page.NextCursor
+	}
+}
+
+func (s *APITestSuite) TestGetAllRestaurantsPages() {
+	s.Require().Equal([][]int{{1, 2}, {3}}, s.getRestaurantPages("limit=2"))
+	s.Require().Equal([][]int{{3, 2}, {1}}, s.getRestaurantPages("limit=2&sort=-name"))
+
+	// a full page can't tell the rest is filtered out, the last page is empty
+	s.Require().Equal([][]int{{1}, {2}, {}}, s.getRestaurantPages("limit=1&working_status=2"))
+
+	// the page is filled past the restaurants filtered out
+	s.Require().Equal([][]int{{3}}, s.getRestaurantPages("limit=1&working_status=1"))
+}
+
+// getAllIds follows the cursors of the list from its first page and returns
+// the ids of all its items.
+func (s *APITestSuite) getAllIds(path string, clientId int, clientType string) []int {
+	var ids []int
+	cursor := ""
+
+	for {
+		var page struct {
+			Items []struct {
+				Id int `json:"id"`
+			} `json:"items"`
+			NextCursor string `json:"next_cursor"`
+		}
+		s.getPage(path+"&cursor="+url.QueryEscape(cursor), clientId, clientType, &page)
+
+		for _, item := range page.Items {
+			ids = append(ids, item.Id)
+		}
+
+		if page.NextCursor == "" {
+			return ids
+		}
+		cursor = page.NextCursor
+	}
+}
+
+func (s *APITestSuite) TestListCursorRoundTrip() {
+	// the sort key of every column type goes through the cursor and back,
+	// ties included: the restaurants are all unrated
+	lists := []struct {
+		path       string
+		clientId   int
+		clientType string
+		sorts      []string
+	}{
+		{"/api/v1/restaurants/?", 1, userType, []string{"distance", "name", "rating"}},
+		{"/api/v1/restaurants/1/menu/?", 1, userType, []string{"id", "title", "price"}},
+		{"/api/v1/restaurants/2/orders/?status=1,5", 2, restaurantType, []string{"created_at", "total_price"}},
+	}
+
+	for _, list := range lists {
+		for _, sort := range list.sorts {
+			for _, sort := range []string{sort, "-" + sort} {
+				path := list.path + "&sort=" + sort
+				all := s.getAllIds(path+"&limit=100", list.clientId, list.clientType)
+				s.Require().NotEmpty(all, path)
+				s.Require().Equal(all, s.getAllIds(path+"&limit=1", list.clientId, list.clientType), path)
+			}
+		}
+	}
+}
+
+func (s *APITestSuite) TestGetRestaurantMenuFilter() {
+	var page domain.MenuPage
+	s.getPage("/api/v1/restaurants/1/menu/?sort=-price&limit=2", 1, userType, &page)
+
+	s.Require().Len(page.Items, 2)
+	s.Require().Equal(3, page.Items[0].Id)
+	s.Require().Equal(2, page.Items[1].Id)
+	s.Require().NotEmpty(page.NextCursor)
+
+	var next domain.MenuPage
+	s.getPage("/api/v1/restaurants/1/menu/?sort=-price&limit=2&cursor="+page.NextCursor, 1, userType, &next)
+
+	s.Require().Len(next.Items, 1)
+	s.Require().Equal(1, next.Items[0].Id)
+	s.Require().Empty(next.NextCursor)
+
+	var filtered domain.MenuPage
+	s.getPage("/api/v1/restaurants/1/menu/?min_price=150&max_price=250", 1, userType, &filtered)
+
+	s.Require().Len(filtered.Items, 1)
+	s.Require().Equal(200, filtered.Items[0].Price)
+}
+
+func (s *APITestSuite) TestGetOrdersFilter() {
+	// a restaurant gets its paid orders unless it asks for other statuses
+	var page domain.OrderPage
+	s.getPage("/api/v1/restaurants/2/orders/", 2, restaurantType, &page)
+	s.Require().Len(page.Items, 1)
+	s.Require().Equal(3, page.Items[0].Id)
+
+	s.getPage("/api/v1/restaurants/2/orders/?status=1,5&sort=total_price", 2, restaurantType, &page)
+	s.Require().Len(page.Items, 2)
+	s.Require().Equal(2, page.Items[0].Id)
+	s.Require().Equal(3, page.Items[1].Id)
+
+	s.getPage("/api/v1/users/1/orders?status=5", 1, userType, &page)
+	s.Require().Len(page.Items, 1)
+	s.Require().Equal(2, page.Items[0].Id)
+	s.Require().False(page.Items[0].CreatedAt.IsZero())
+
+	s.getPage("/api/v1/users/1/orders?status=active", 1, userType, &page)
+	s.Require().Empty(page.Items)
+
+	s.getPage("/api/v1/users/1/orders?from=2000-01-01T00:00:00Z&to=2000-01-02T00:00:00Z", 1, userType, &page)
+	s.Require().Empty(page.Items)
+}
+
+func (s *APITestSuite) TestListError() {
+	var page domain.MenuPage
+	s.getPage("/api/v1/restaurants/1/menu/?sort=price&limit=1", 1, userType, &page)
+
+	tests := []struct {
+		name   string
+		url    string
+		status int
+	}{
+		{"bad limit", "/api/v1/restaurants/?limit=ten", http.StatusBadRequest},
+		{"limit too big", "/api/v1/restaurants/?limit=1000", http.StatusUnprocessableEntity},
+		{"unknown sort", "/api/v1/restaurants/?sort=password_hash", http.StatusUnprocessableEntity},
+		{"bad cursor", "/api/v1/restaurants/?cursor=abc", http.StatusUnprocessableEntity},
+		{"cursor of another sort", "/api/v1/restaurants/1/menu/?sort=title&cursor=" + page.NextCursor,
+			http.StatusUnprocessableEntity},
+		{"bad working status", "/api/v1/restaurants/?working_status=7", http.StatusUnprocessableEntity},
+		{"inverted price range", "/api/v1/restaurants/1/menu/?min_price=300&max_price=100", http.StatusUnprocessableEntity},
+		{"bad status", "/api/v1/users/1/orders?status=paid", http.StatusBadRequest},
+		{"unknown status", "/api/v1/users/1/orders?status=42", http.StatusUnprocessableEntity},
+		{"bad date", "/api/v1/users/1/orders?from=2021-01-01", http.StatusBadRequest},
+		{"inverted dates", "/api/v1/users/1/orders?from=2021-01-02T00:00:00Z&to=2021-01-01T00:00:00Z",
+			http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		resp := s.clientRequest("GET", tt.url, 1, userType, "")
+		s.Require().Equal(tt.status, resp.Result().StatusCode, tt.name)
+	}
+}
@@ -1,6 +1,11 @@
 package tests
 
 import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
 	"github.com/labstack/echo/v4/middleware"
 	"io/ioutil"
 	"net/http"
@@ -13,11 +18,14 @@ import (
 	"github.com/MAVIKE/yad-backend/internal/repository"
 	"github.com/MAVIKE/yad-backend/internal/service"
 	"github.com/MAVIKE/yad-backend/pkg/auth"
+	"github.com/MAVIKE/yad-backend/pkg/hash"
+	"github.com/MAVIKE/yad-backend/pkg/payment"
 	"github.com/jmoiron/sqlx"
 	"github.com/labstack/echo/v4"
 	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
 	"github.com/stretchr/testify/suite"
+	"golang.org/x/crypto/bcrypt"
 )
 
 const (
@@ -28,11 +36,15 @@ const (
 	portDB     = "5433"
 	sslmodeDB  = "disable"
 
-	signingKey     = "test"
-	accessTokenTTL = 720
+	signingKey      = "test"
+	signingKeyId    = "test-ed25519"
+	accessTokenTTL  = 720
+	refreshTokenTTL = 720
 
 	schemaDir = "../schema/"
 
+	paymentCallbackSecret = "test"
+
 	adminType   = "admin"
 	userType       = "user"
 	courierType    = "courier"
@@ -49,7 +61,11 @@ type APITestSuite struct {
 
 	app *echo.Echo
 
+	eventListener *repository.EventListenerPg
+	stopEvents    context.CancelFunc
+
 	tokenManager *auth.Manager
+	payments     *payment.MockProvider
 
 	pool     *dockertest.Pool
 	resource *dockertest.Resource
@@ -112,35 +128,76 @@ func (s *APITestSuite) initPool() {
 
 func (s *APITestSuite) initApp() {
 	var err error
+	dbConfig := repository.Config{
+		Host:     hostDB,
+		Port:     portDB,
+		Username: userDB,
+		DBName:   nameDB,
+		SSLMode:  sslmodeDB,
+		Password: passwordDB,
+	}
+
 	err = s.pool.Retry(func() error {
-		s.db, err = repository.NewPostgresDB(repository.Config{
-			Host:     hostDB,
-			Port:     portDB,
-			Username: userDB,
-			DBName:   nameDB,
-			SSLMode:  sslmodeDB,
-			Password: passwordDB,
-		})
+		s.db, err = repository.NewPostgresDB(dbConfig)
 		return err
 	})
 	if err != nil {
 		s.FailNow("Failed to initialize db", err)
 	}
 
+	s.eventListener, err = repository.NewEventListenerPg(dbConfig)
+	if err != nil {
+		s.FailNow("Failed to listen for events", err)
+	}
+
 	s.repos = repository.NewRepository(s.db)
 
-	s.tokenManager, err = auth.NewManager(signingKey)
+	keyring, err := newTestKeyring()
+	if err != nil {
+		s.FailNow("Failed to initialize token keys", err)
+	}
+
+	s.tokenManager, err = auth.NewManager(keyring)
 	if err != nil {
 		s.FailNow("Failed to initialize token manager", err)
 	}
 
+	hasher, err := hash.NewBCryptHasher(bcrypt.MinCost)
+	if err != nil {
+		s.FailNow("Failed to initialize password hasher", err)
+	}
+
+	s.payments = payment.NewMockProvider(paymentCallbackSecret)
+
 	deps := service.Deps{
 		Repos:          s.repos,
+		EventListener:  s.eventListener,
 		TokenManager:   s.tokenManager,
-		AccessTokenTTL: time.Duration(accessTokenTTL) * time.Hour,
+		Hasher:         hasher,
+		AccessTokenTTL:  time.Duration(accessTokenTTL) * time.Hour,
+		RefreshTokenTTL: time.Duration(refreshTokenTTL) * time.Hour,
+
+		DispatchOfferTimeout:  time.Minute,
+		DispatchOfferCooldown: 5 * time.Minute,
+		DispatchEscalateAfter: time.Hour,
+		Webhooks: service.WebhookConfig{
+			Timeout:     5 * time.Second,
+			Backoff:     time.Minute,
+			MaxAttempts: 3,
+
+			// the receivers of the tests listen on localhost
+			AllowPrivate: true,
+		},
+		PaymentProvider: s.payments,
+		CartTTL:         time.Hour,
 	}
 
 	s.services = service.NewService(deps)
+
+	var ctx context.Context
+	ctx, s.stopEvents = context.WithCancel(context.Background())
+	go s.services.Events.Run(ctx)
+
 	s.handlers = handler.NewHandler(s.services, s.tokenManager)
 
 	s.app = echo.New()
@@ -169,6 +226,9 @@ func (s *APITestSuite) downDB() error {
 }
 
 func (s *APITestSuite) TearDownSuite() {
+	s.stopEvents()
+	_ = s.eventListener.Close()
+
 	if err := s.pool.Purge(s.resource); err != nil {
 		s.FailNow("Failed to purge resource", err)
 	}
@@ -213,6 +273,32 @@ func (s *APITestSuite) SetupTest() {
 	}
 }
 
+// newTestKeyring signs with a generated Ed25519 key and keeps signingKey as
+// the legacy HS256 key, the same layout as a server in the middle of a rotation.
+func newTestKeyring() (*auth.Keyring, error) {
+	legacyKey, err := auth.ParseKey(auth.DefaultKeyId, "HS256", []byte(signingKey))
+	if err != nil {
+		return nil, err
+	}
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := auth.ParseKey(signingKeyId, "EdDSA", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		return nil, err
+	}
+
+	return auth.NewKeyring(signingKeyId, legacyKey, key)
+}
+
 func (s *APITestSuite) getJWT(clientId int, clientType string) (string, error) {
-	return s.tokenManager.NewJWT(clientId, clientType, accessTokenTTL)
+	return s.tokenManager.NewJWT(clientId, clientType, time.Duration(accessTokenTTL)*time.Hour)
 }
@@ -26,13 +26,15 @@ func (s *APITestSuite) TestRestaurantGetAllMenuItemsOk() {
 
 	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
 
-	var respMenu []*domain.MenuItem
+	var page domain.MenuPage
 	respData, err := ioutil.ReadAll(resp.Body)
 	s.NoError(err)
 
-	err = json.Unmarshal(respData, &respMenu)
+	err = json.Unmarshal(respData, &page)
 	s.NoError(err)
 
+	respMenu := page.Items
+
 	s.Require().Equal(len(menuItems), len(respMenu))
 	for i := 0; i < len(menuItems); i++ {
 		s.Require().Equal(menuItems[i].Id, respMenu[i].Id)
@@ -59,7 +61,7 @@ func (s *APITestSuite) TestRestaurantGetAllMenuItemsForbidden() {
 	resp := httptest.NewRecorder()
 	s.app.ServeHTTP(resp, req)
 
-	s.Require().Equal(http.StatusInternalServerError, resp.Result().StatusCode)
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
 }
 
 
@@ -110,7 +112,7 @@ func (s *APITestSuite) TestRestaurantGetMenuItemByIdForbidden() {
 	resp := httptest.NewRecorder()
 	s.app.ServeHTTP(resp, req)
 
-	s.Require().Equal(http.StatusInternalServerError, resp.Result().StatusCode)
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
 }
 
 
@@ -131,13 +133,15 @@ func (s *APITestSuite) TestRestaurantGetAllCategoryMenuItemsOk() {
 
 	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
 
-	var respMenu []*domain.MenuItem
+	var page domain.MenuPage
 	respData, err := ioutil.ReadAll(resp.Body)
 	s.NoError(err)
 
-	err = json.Unmarshal(respData, &respMenu)
+	err = json.Unmarshal(respData, &page)
 	s.NoError(err)
 
+	respMenu := page.Items
+
 	s.Require().Equal(2, len(respMenu))
 	for i := 0; i < len(respMenu); i++ {
 		s.Require().Equal(menuItems[i].Id, respMenu[i].Id)
@@ -164,5 +168,5 @@ func (s *APITestSuite) TestRestaurantGetAllCategoryItemsForbidden() {
 	resp := httptest.NewRecorder()
 	s.app.ServeHTTP(resp, req)
 
-	s.Require().Equal(http.StatusInternalServerError, resp.Result().StatusCode)
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
 }
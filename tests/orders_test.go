@@ -96,7 +96,36 @@ func (s *APITestSuite) TestUserGetOrderError_Forbidden() {
 	req.Header.Set("Authorization", "Bearer "+jwt)
 	resp := httptest.NewRecorder()
 	s.app.ServeHTTP(resp, req)
-	s.Require().Equal(http.StatusInternalServerError, resp.Result().StatusCode)
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
+}
+
+func (s *APITestSuite) TestUserGetOrderError_NotFound() {
+	clientId := 1
+	clientType := userType
+	jwt, err := s.getJWT(clientId, clientType)
+	s.NoError(err)
+
+	req, err := http.NewRequest("GET", "/api/v1/orders/100", nil)
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+	s.Require().Equal(http.StatusNotFound, resp.Result().StatusCode)
+
+	var respBody struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	respData, err := ioutil.ReadAll(resp.Body)
+	s.NoError(err)
+
+	err = json.Unmarshal(respData, &respBody)
+	s.NoError(err)
+
+	s.Require().Equal(domain.CodeNotFound, respBody.Code)
 }
 
 func (s *APITestSuite) TestCourierGetOrderError_Forbidden() {
@@ -114,7 +143,7 @@ func (s *APITestSuite) TestCourierGetOrderError_Forbidden() {
 	resp := httptest.NewRecorder()
 	s.app.ServeHTTP(resp, req)
 
-	s.Require().Equal(http.StatusInternalServerError, resp.Result().StatusCode)
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
 }
 
 func (s *APITestSuite) TestUserGetOrdersOk() {
@@ -123,7 +152,7 @@ func (s *APITestSuite) TestUserGetOrdersOk() {
 	jwt, err := s.getJWT(clientId, clientType)
 	s.NoError(err)
 
-	req, err := http.NewRequest("GET", "/api/v1/users/1/orders", nil)
+	req, err := http.NewRequest("GET", "/api/v1/users/1/orders?sort=created_at", nil)
 	if err != nil {
 		s.FailNow("Failed to build request", err)
 	}
@@ -134,14 +163,16 @@ func (s *APITestSuite) TestUserGetOrdersOk() {
 
 	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
 
-	var respOrders = []*domain.Order{}
+	var page domain.OrderPage
 	respData, err := ioutil.ReadAll(resp.Body)
 	s.NoError(err)
 
-	err = json.Unmarshal(respData, &respOrders)
+	err = json.Unmarshal(respData, &page)
 	s.NoError(err)
 
+	respOrders := page.Items
 	clientOrders := orders[:2]
+	s.Require().Len(respOrders, len(clientOrders))
 
 	for i := 0; i < len(clientOrders); i++ {
 		s.Require().Equal(clientOrders[i].Id, respOrders[i].Id)
@@ -206,7 +237,7 @@ func (s *APITestSuite) TestUserGetOrdersError_Forbidden() {
 	resp := httptest.NewRecorder()
 	s.app.ServeHTTP(resp, req)
 
-	s.Require().Equal(http.StatusInternalServerError, resp.Result().StatusCode)
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
 }
 
 func (s *APITestSuite) TestCourierGetOrdersError_Forbidden() {
@@ -224,7 +255,7 @@ func (s *APITestSuite) TestCourierGetOrdersError_Forbidden() {
 	resp := httptest.NewRecorder()
 	s.app.ServeHTTP(resp, req)
 
-	s.Require().Equal(http.StatusInternalServerError, resp.Result().StatusCode)
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
 }
 
 func (s *APITestSuite) TestUserCreateOrderOk() {
@@ -277,7 +308,7 @@ func (s *APITestSuite) TestUserCreateOrderError_Forbidden() {
 	resp := httptest.NewRecorder()
 	s.app.ServeHTTP(resp, req)
 
-	s.Require().Equal(http.StatusInternalServerError, resp.Result().StatusCode)
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
 }
 
 func (s *APITestSuite) TestUserCreateOrderError_WrongRestaurantId() {
@@ -298,7 +329,7 @@ func (s *APITestSuite) TestUserCreateOrderError_WrongRestaurantId() {
 	resp := httptest.NewRecorder()
 	s.app.ServeHTTP(resp, req)
 
-	s.Require().Equal(http.StatusInternalServerError, resp.Result().StatusCode)
+	s.Require().Equal(http.StatusUnprocessableEntity, resp.Result().StatusCode)
 }
 
 func (s *APITestSuite) TestUserUpdateOrderOk() {
@@ -307,7 +338,7 @@ func (s *APITestSuite) TestUserUpdateOrderOk() {
 	jwt, err := s.getJWT(clientId, clientType)
 	s.NoError(err)
 
-	reqBody := `{"status":1}`
+	reqBody := `{"status":6}`
 	req, err := http.NewRequest("PUT", "/api/v1/orders/1", bytes.NewBuffer([]byte(reqBody)))
 	if err != nil {
 		s.FailNow("Failed to build request", err)
@@ -370,7 +401,7 @@ func (s *APITestSuite) TestUserUpdateOrderError_WrongStatus() {
 	jwt, err := s.getJWT(clientId, clientType)
 	s.NoError(err)
 
-	reqBody := `{"status":6}`
+	reqBody := `{"status":10}`
 	req, err := http.NewRequest("PUT", "/api/v1/orders/1", bytes.NewBuffer([]byte(reqBody)))
 	if err != nil {
 		s.FailNow("Failed to build request", err)
@@ -403,7 +434,7 @@ func (s *APITestSuite) TestCourierUpdateOrderError_ReducedStatus() {
 	resp := httptest.NewRecorder()
 	s.app.ServeHTTP(resp, req)
 
-	s.Require().Equal(http.StatusInternalServerError, resp.Result().StatusCode)
+	s.Require().Equal(http.StatusConflict, resp.Result().StatusCode)
 }
 
 func (s *APITestSuite) TestUserUpdateOrderError_OrderNotFound() {
@@ -424,7 +455,7 @@ func (s *APITestSuite) TestUserUpdateOrderError_OrderNotFound() {
 	resp := httptest.NewRecorder()
 	s.app.ServeHTTP(resp, req)
 
-	s.Require().Equal(http.StatusInternalServerError, resp.Result().StatusCode)
+	s.Require().Equal(http.StatusNotFound, resp.Result().StatusCode)
 }
 
 func (s *APITestSuite) TestUserUpdateOrderError_Forbidden() {
@@ -433,6 +464,27 @@ func (s *APITestSuite) TestUserUpdateOrderError_Forbidden() {
 	jwt, err := s.getJWT(clientId, clientType)
 	s.NoError(err)
 
+	reqBody := `{"status":6}`
+	req, err := http.NewRequest("PUT", "/api/v1/orders/1", bytes.NewBuffer([]byte(reqBody)))
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Content-type", "application/json")
+
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
+}
+
+func (s *APITestSuite) TestUserUpdateOrderError_PaidWithoutPayment() {
+	clientId := 1
+	clientType := userType
+	jwt, err := s.getJWT(clientId, clientType)
+	s.NoError(err)
+
 	reqBody := `{"status":1}`
 	req, err := http.NewRequest("PUT", "/api/v1/orders/1", bytes.NewBuffer([]byte(reqBody)))
 	if err != nil {
@@ -445,7 +497,7 @@ func (s *APITestSuite) TestUserUpdateOrderError_Forbidden() {
 	resp := httptest.NewRecorder()
 	s.app.ServeHTTP(resp, req)
 
-	s.Require().Equal(http.StatusInternalServerError, resp.Result().StatusCode)
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
 }
 
 func (s *APITestSuite) TestCourierUpdateOrderError_Forbidden() {
@@ -466,7 +518,7 @@ func (s *APITestSuite) TestCourierUpdateOrderError_Forbidden() {
 	resp := httptest.NewRecorder()
 	s.app.ServeHTTP(resp, req)
 
-	s.Require().Equal(http.StatusInternalServerError, resp.Result().StatusCode)
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
 }
 
 func (s *APITestSuite) TestRestaurantUpdateOrderError_Forbidden() {
@@ -487,7 +539,7 @@ func (s *APITestSuite) TestRestaurantUpdateOrderError_Forbidden() {
 	resp := httptest.NewRecorder()
 	s.app.ServeHTTP(resp, req)
 
-	s.Require().Equal(http.StatusInternalServerError, resp.Result().StatusCode)
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
 }
 
 func (s *APITestSuite) TestUserDeleteOrderOk() {
@@ -523,7 +575,7 @@ func (s *APITestSuite) TestUserDeleteOrderError_OrderNotFound() {
 	resp := httptest.NewRecorder()
 	s.app.ServeHTTP(resp, req)
 
-	s.Require().Equal(http.StatusInternalServerError, resp.Result().StatusCode)
+	s.Require().Equal(http.StatusNotFound, resp.Result().StatusCode)
 }
 
 func (s *APITestSuite) TestUserDeleteOrderError_Forbidden() {
@@ -541,7 +593,7 @@ func (s *APITestSuite) TestUserDeleteOrderError_Forbidden() {
 	resp := httptest.NewRecorder()
 	s.app.ServeHTTP(resp, req)
 
-	s.Require().Equal(http.StatusInternalServerError, resp.Result().StatusCode)
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
 }
 
 func (s *APITestSuite) TestUserDeleteOrderError_PaidOrder() {
@@ -558,5 +610,284 @@ func (s *APITestSuite) TestUserDeleteOrderError_PaidOrder() {
 
 	resp := httptest.NewRecorder()
 	s.app.ServeHTTP(resp, req)
-	s.Require().Equal(http.StatusInternalServerError, resp.Result().StatusCode)
+	s.Require().Equal(http.StatusConflict, resp.Result().StatusCode)
+}
+
+func (s *APITestSuite) TestUserCancelOrderOk() {
+	clientId := 2
+	clientType := userType
+	jwt, err := s.getJWT(clientId, clientType)
+	s.NoError(err)
+
+	reqBody := `{"status":6}`
+	req, err := http.NewRequest("PUT", "/api/v1/orders/3", bytes.NewBuffer([]byte(reqBody)))
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Content-type", "application/json")
+
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+}
+
+func (s *APITestSuite) TestUserCancelOrderError_WaitingForCourier() {
+	clientId := 3
+	clientType := userType
+	jwt, err := s.getJWT(clientId, clientType)
+	s.NoError(err)
+
+	reqBody := `{"status":6}`
+	req, err := http.NewRequest("PUT", "/api/v1/orders/4", bytes.NewBuffer([]byte(reqBody)))
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Content-type", "application/json")
+
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusConflict, resp.Result().StatusCode)
+}
+
+func (s *APITestSuite) TestRestaurantRejectOrderOk() {
+	clientId := 2
+	clientType := restaurantType
+	jwt, err := s.getJWT(clientId, clientType)
+	s.NoError(err)
+
+	reqBody := `{"status":7}`
+	req, err := http.NewRequest("PUT", "/api/v1/orders/3", bytes.NewBuffer([]byte(reqBody)))
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Content-type", "application/json")
+
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+}
+
+func (s *APITestSuite) TestRestaurantGetOrderTransitionsOk() {
+	clientId := 2
+	clientType := restaurantType
+	jwt, err := s.getJWT(clientId, clientType)
+	s.NoError(err)
+
+	req, err := http.NewRequest("GET", "/api/v1/orders/3/transitions", nil)
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	var transitions []*domain.OrderTransition
+	respData, err := ioutil.ReadAll(resp.Body)
+	s.NoError(err)
+
+	err = json.Unmarshal(respData, &transitions)
+	s.NoError(err)
+
+	s.Require().Equal([]*domain.OrderTransition{
+		{Status: 2, Name: "preparing"},
+		{Status: 7, Name: "rejected_by_restaurant"},
+	}, transitions)
+}
+
+func (s *APITestSuite) TestRestaurantGetOrderTimelineOk() {
+	clientId := 2
+	clientType := restaurantType
+	jwt, err := s.getJWT(clientId, clientType)
+	s.NoError(err)
+
+	reqBody := `{"status":2}`
+	req, err := http.NewRequest("PUT", "/api/v1/orders/3", bytes.NewBuffer([]byte(reqBody)))
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Content-type", "application/json")
+
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	req, err = http.NewRequest("GET", "/api/v1/orders/3/timeline", nil)
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	resp = httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	var events []*domain.OrderEvent
+	respData, err := ioutil.ReadAll(resp.Body)
+	s.NoError(err)
+
+	err = json.Unmarshal(respData, &events)
+	s.NoError(err)
+
+	s.Require().Len(events, 1)
+	s.Require().Equal(clientId, events[0].ActorId)
+	s.Require().Equal(clientType, events[0].ActorType)
+	s.Require().NotNil(events[0].OldStatus)
+	s.Require().Equal(1, *events[0].OldStatus)
+	s.Require().Equal(2, events[0].NewStatus)
+}
+
+func (s *APITestSuite) TestUserGetOrderTimelineError_Forbidden() {
+	clientId := 1
+	clientType := userType
+	jwt, err := s.getJWT(clientId, clientType)
+	s.NoError(err)
+
+	req, err := http.NewRequest("GET", "/api/v1/orders/3/timeline", nil)
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
+}
+
+func (s *APITestSuite) TestUserGetOrderReceiptOk() {
+	// the order keeps the price the item had when it was added
+	s.db.MustExec(`UPDATE menu_items SET price = 1000 WHERE id = 1`)
+
+	resp := s.clientRequest("POST", "/api/v1/orders/1/items/", 1, userType, `{"menu_item_id":3,"count":1}`)
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	resp = s.clientRequest("GET", "/api/v1/orders/1/receipt", 1, userType, "")
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	var receipt domain.Receipt
+	respData, err := ioutil.ReadAll(resp.Body)
+	s.NoError(err)
+
+	err = json.Unmarshal(respData, &receipt)
+	s.NoError(err)
+
+	s.Require().Len(receipt.Items, 3)
+	s.Require().Equal("Title1", receipt.Items[0].Title)
+	s.Require().Equal(100, receipt.Items[0].Price)
+	s.Require().Equal(200, receipt.Items[0].Amount)
+	s.Require().Equal(300, receipt.Items[2].Price)
+	s.Require().Equal(1100, receipt.ItemsPrice)
+	s.Require().Equal(receipt.ItemsPrice+receipt.DeliveryPrice, receipt.TotalPrice)
+}
+
+func (s *APITestSuite) TestDeleteMenuItemKeepsOrderItems() {
+	resp := s.clientRequest("DELETE", "/api/v1/restaurants/1/menu/2", 1, restaurantType, "")
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	// the lines of the dish stay in the orders with their snapshot
+	s.requireOrderItems(4, 1)
+
+	var receipt domain.Receipt
+	s.getPage("/api/v1/orders/4/receipt", 3, userType, &receipt)
+	s.Require().Len(receipt.Items, 1)
+	s.Require().Equal(0, receipt.Items[0].MenuItemId)
+	s.Require().Equal("Title2", receipt.Items[0].Title)
+	s.Require().Equal(600, receipt.Items[0].Amount)
+	s.Require().Equal(700, receipt.TotalPrice)
+}
+
+func (s *APITestSuite) TestUserGetOrderReceiptError_Forbidden() {
+	resp := s.clientRequest("GET", "/api/v1/orders/3/receipt", 1, userType, "")
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
+}
+
+func (s *APITestSuite) requireOrderItems(orderId int, count int) {
+	var items int
+	err := s.db.Get(&items, `SELECT COUNT(*) FROM order_items WHERE order_id = $1`, orderId)
+	s.NoError(err)
+	s.Require().Equal(count, items, "order %d items", orderId)
+}
+
+func (s *APITestSuite) TestOrderItemsIntegrity() {
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		clientId   int
+		clientType string
+		reqBody    string
+		status     int
+		code       string
+	}{
+		{"add to a foreign order", "POST", "/api/v1/orders/1/items/", 2, userType, `{"menu_item_id":3,"count":1}`,
+			http.StatusForbidden, domain.CodeForbidden},
+		{"add a dish of another restaurant", "POST", "/api/v1/orders/1/items/", 1, userType, `{"menu_item_id":4,"count":1}`,
+			http.StatusUnprocessableEntity, domain.CodeForeignMenuItem},
+		{"add a missing dish", "POST", "/api/v1/orders/1/items/", 1, userType, `{"menu_item_id":100,"count":1}`,
+			http.StatusUnprocessableEntity, domain.CodeValidation},
+		{"add a dish twice", "POST", "/api/v1/orders/1/items/", 1, userType, `{"menu_item_id":1,"count":1}`,
+			http.StatusConflict, domain.CodeConflict},
+		{"add to a delivered order", "POST", "/api/v1/orders/2/items/", 1, userType, `{"menu_item_id":4,"count":1}`,
+			http.StatusConflict, domain.CodeOrderNotEditable},
+		{"add to a missing order", "POST", "/api/v1/orders/100/items/", 1, userType, `{"menu_item_id":1,"count":1}`,
+			http.StatusNotFound, domain.CodeNotFound},
+		{"courier adds", "POST", "/api/v1/orders/4/items/", 3, courierType, `{"menu_item_id":1,"count":1}`,
+			http.StatusForbidden, domain.CodeForbidden},
+		{"restaurant adds", "POST", "/api/v1/orders/1/items/", 1, restaurantType, `{"menu_item_id":3,"count":1}`,
+			http.StatusForbidden, domain.CodeForbidden},
+		{"update a foreign order", "PUT", "/api/v1/orders/1/items/1", 2, userType, `{"count":1}`,
+			http.StatusForbidden, domain.CodeForbidden},
+		{"update an item of another order", "PUT", "/api/v1/orders/1/items/3", 1, userType, `{"count":1}`,
+			http.StatusNotFound, domain.CodeNotFound},
+		{"update a delivered order", "PUT", "/api/v1/orders/2/items/3", 1, userType, `{"count":1}`,
+			http.StatusConflict, domain.CodeOrderNotEditable},
+		{"delete from a foreign order", "DELETE", "/api/v1/orders/1/items/1", 2, userType, "",
+			http.StatusForbidden, domain.CodeForbidden},
+		{"delete a missing item", "DELETE", "/api/v1/orders/1/items/100", 1, userType, "",
+			http.StatusNotFound, domain.CodeNotFound},
+		{"delete from a delivered order", "DELETE", "/api/v1/orders/2/items/3", 1, userType, "",
+			http.StatusConflict, domain.CodeOrderNotEditable},
+		{"restaurant deletes from an unpaid order", "DELETE", "/api/v1/orders/1/items/1", 1, restaurantType, "",
+			http.StatusConflict, domain.CodeOrderNotEditable},
+		{"restaurant deletes from an order waiting for courier", "DELETE", "/api/v1/orders/4/items/6", 1, restaurantType, "",
+			http.StatusConflict, domain.CodeOrderNotEditable},
+		{"restaurant deletes from a foreign order", "DELETE", "/api/v1/orders/1/items/1", 2, restaurantType, "",
+			http.StatusForbidden, domain.CodeForbidden},
+	}
+
+	for _, tt := range tests {
+		resp := s.clientRequest(tt.method, tt.url, tt.clientId, tt.clientType, tt.reqBody)
+		s.Require().Equal(tt.status, resp.Result().StatusCode, tt.name)
+
+		var body struct {
+			Code string `json:"code"`
+		}
+		respData, err := ioutil.ReadAll(resp.Body)
+		s.NoError(err)
+
+		err = json.Unmarshal(respData, &body)
+		s.NoError(err)
+		s.Require().Equal(tt.code, body.Code, tt.name)
+	}
+
+	// none of the requests has changed the orders
+	s.requireOrderItems(1, 2)
+	s.requireOrderItems(2, 1)
+	s.requireOrderItems(4, 1)
 }
@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/MAVIKE/yad-backend/internal/consts"
+	"github.com/MAVIKE/yad-backend/internal/domain"
+)
+
+func (s *APITestSuite) getOutboxEventTypes() []string {
+	var eventTypes []string
+	err := s.db.Select(&eventTypes, `SELECT event_type FROM outbox ORDER BY id`)
+	s.NoError(err)
+
+	return eventTypes
+}
+
+func (s *APITestSuite) TestOutboxWrittenWithOrderOk() {
+	s.payOrder(1, 1)
+
+	s.Require().Equal([]string{
+		domain.EventOrderStatusChanged,
+		domain.EventOrderIncoming,
+		domain.EventOrderPaid,
+	}, s.getOutboxEventTypes())
+
+	s.NoError(s.services.Outbox.Relay())
+
+	var pending int
+	err := s.db.Get(&pending, `SELECT count(*) FROM outbox WHERE published_at IS NULL`)
+	s.NoError(err)
+	s.Require().Equal(0, pending)
+}
+
+func (s *APITestSuite) TestOutboxNotWrittenError_OrderUnchanged() {
+	jwt, err := s.getJWT(1, userType)
+	s.NoError(err)
+
+	// order 1 can't be delivered before it is paid
+	reqBody := `{"status":5}`
+	req, err := http.NewRequest("PUT", "/api/v1/orders/1", bytes.NewBuffer([]byte(reqBody)))
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Content-type", "application/json")
+
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().NotEqual(http.StatusOK, resp.Result().StatusCode)
+	s.Require().Empty(s.getOutboxEventTypes())
+}
+
+func (s *APITestSuite) TestOutboxRelayedAgainOk_NoDuplicateDelivery() {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer receiver.Close()
+
+	webhookId := s.createWebhook(1, receiver.URL, `["order.paid"]`)
+
+	s.payOrder(1, 1)
+	s.NoError(s.services.Outbox.Relay())
+
+	// the relay crashed before marking the events as published
+	s.db.MustExec(`UPDATE outbox SET published_at = NULL, next_attempt_at = now()`)
+	s.NoError(s.services.Outbox.Relay())
+
+	deliveries := s.getWebhookDeliveries(1, webhookId)
+	s.Require().Len(deliveries, 1)
+	s.Require().Equal(consts.DeliveryPending, deliveries[0].Status)
+}
+
+func (s *APITestSuite) TestOutboxWrittenWithCourierOk() {
+	jwt, err := s.getJWT(5, courierType)
+	s.NoError(err)
+
+	reqBody := `{"working_status":1}`
+	req, err := http.NewRequest("PUT", "/api/v1/couriers/5", bytes.NewBuffer([]byte(reqBody)))
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Content-type", "application/json")
+
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+	s.Require().Equal([]string{domain.EventCourierStatusChanged}, s.getOutboxEventTypes())
+}
@@ -0,0 +1,255 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/MAVIKE/yad-backend/internal/consts"
+	"github.com/MAVIKE/yad-backend/internal/domain"
+)
+
+func (s *APITestSuite) paymentRequest(method string, orderId, userId int, action string) *httptest.ResponseRecorder {
+	jwt, err := s.getJWT(userId, userType)
+	s.NoError(err)
+
+	url := fmt.Sprintf("/api/v1/orders/%d/payment%s", orderId, action)
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	return resp
+}
+
+func (s *APITestSuite) decodePayment(resp *httptest.ResponseRecorder) *domain.Payment {
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	var payment domain.Payment
+	respData, err := ioutil.ReadAll(resp.Body)
+	s.NoError(err)
+
+	err = json.Unmarshal(respData, &payment)
+	s.NoError(err)
+
+	return &payment
+}
+
+func (s *APITestSuite) createPayment(orderId, userId int) *domain.Payment {
+	return s.decodePayment(s.paymentRequest("POST", orderId, userId, ""))
+}
+
+func (s *APITestSuite) confirmPayment(orderId, userId int) *domain.Payment {
+	return s.decodePayment(s.paymentRequest("POST", orderId, userId, "/confirm"))
+}
+
+func (s *APITestSuite) getOrderStatus(orderId int) int {
+	var status int
+	err := s.db.Get(&status, `SELECT status FROM orders WHERE id = $1`, orderId)
+	s.NoError(err)
+
+	return status
+}
+
+func (s *APITestSuite) TestUserPayOrderOk() {
+	payment := s.createPayment(1, 1)
+	s.Require().Equal(consts.PaymentPending, payment.Status)
+	s.Require().Equal(900, payment.Amount)
+	s.Require().Equal(consts.OrderCreated, s.getOrderStatus(1))
+
+	payment = s.confirmPayment(1, 1)
+	s.Require().Equal(consts.PaymentCaptured, payment.Status)
+	s.Require().Equal(consts.OrderPaid, s.getOrderStatus(1))
+
+	pending, err := s.repos.Dispatch.GetPending()
+	s.NoError(err)
+	s.Require().Len(pending, 1)
+}
+
+func (s *APITestSuite) TestUserPayOrderError_Declined() {
+	payment := s.createPayment(1, 1)
+	s.payments.Decline(payment.IntentId)
+
+	payment = s.confirmPayment(1, 1)
+	s.Require().Equal(consts.PaymentFailed, payment.Status)
+	s.Require().Equal(consts.OrderCreated, s.getOrderStatus(1))
+
+	// a failed payment can't be confirmed again, a new one can be created
+	s.Require().Equal(http.StatusConflict, s.paymentRequest("POST", 1, 1, "/confirm").Result().StatusCode)
+	s.createPayment(1, 1)
+}
+
+func (s *APITestSuite) TestUserPayOrderError_TotalChanged() {
+	s.createPayment(1, 1)
+	s.db.MustExec(`UPDATE orders SET total_price = total_price + 100 WHERE id = 1`)
+
+	s.Require().Equal(http.StatusConflict, s.paymentRequest("POST", 1, 1, "/confirm").Result().StatusCode)
+	s.Require().Equal(consts.OrderCreated, s.getOrderStatus(1))
+}
+
+func (s *APITestSuite) TestPaymentCaptureError_TotalChangedMeanwhile() {
+	payment := s.createPayment(1, 1)
+
+	// an item was added after the service had checked the payment pays the order
+	s.db.MustExec(`UPDATE orders SET total_price = total_price + 100 WHERE id = 1`)
+
+	oldStatus := consts.OrderCreated
+	err := s.repos.Order.MarkPaid(1, payment.Amount, time.Now(), &domain.OrderEvent{
+		ActorId:   1,
+		ActorType: userType,
+		OldStatus: &oldStatus,
+		NewStatus: consts.OrderPaid,
+	})
+	s.Require().True(errors.Is(err, domain.ErrConflict), err)
+	s.Require().Equal(consts.OrderCreated, s.getOrderStatus(1))
+
+	pending, err := s.repos.Dispatch.GetPending()
+	s.NoError(err)
+	s.Require().Empty(pending)
+}
+
+func (s *APITestSuite) TestPaymentCallbackOk() {
+	payment := s.createPayment(1, 1)
+
+	// the user has confirmed the payment on the provider's side
+	_, err := s.payments.Confirm(payment.IntentId)
+	s.NoError(err)
+
+	header, body, err := s.payments.Callback(payment.IntentId)
+	s.NoError(err)
+
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest("POST", "/api/v1/payments/callback", bytes.NewBuffer(body))
+		if err != nil {
+			s.FailNow("Failed to build request", err)
+		}
+
+		req.Header = header.Clone()
+		req.Header.Set("Content-type", "application/json")
+
+		resp := httptest.NewRecorder()
+		s.app.ServeHTTP(resp, req)
+
+		s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+	}
+
+	s.Require().Equal(consts.OrderPaid, s.getOrderStatus(1))
+	payment = s.decodePayment(s.paymentRequest("GET", 1, 1, ""))
+	s.Require().Equal(consts.PaymentCaptured, payment.Status)
+}
+
+func (s *APITestSuite) TestPaymentCallbackError_InvalidSignature() {
+	payment := s.createPayment(1, 1)
+
+	header, body, err := s.payments.Callback(payment.IntentId)
+	s.NoError(err)
+
+	req, err := http.NewRequest("POST", "/api/v1/payments/callback",
+		bytes.NewBuffer(bytes.Replace(body, []byte("pending"), []byte("captured"), 1)))
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+
+	req.Header = header.Clone()
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusBadRequest, resp.Result().StatusCode)
+	s.Require().Equal(consts.OrderCreated, s.getOrderStatus(1))
+}
+
+func (s *APITestSuite) TestUserCreatePaymentError_Forbidden() {
+	s.Require().Equal(http.StatusForbidden, s.paymentRequest("POST", 1, 2, "").Result().StatusCode)
+}
+
+func (s *APITestSuite) sendPaymentCallback(intentId string) *httptest.ResponseRecorder {
+	header, body, err := s.payments.Callback(intentId)
+	s.NoError(err)
+
+	req, err := http.NewRequest("POST", "/api/v1/payments/callback", bytes.NewBuffer(body))
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+
+	req.Header = header
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	return resp
+}
+
+func (s *APITestSuite) getPaymentStatus(paymentId int) string {
+	var status string
+	err := s.db.Get(&status, `SELECT status FROM payments WHERE id = $1`, paymentId)
+	s.NoError(err)
+
+	return status
+}
+
+func (s *APITestSuite) TestPaymentCallbackError_OrderCancelled() {
+	payment := s.createPayment(1, 1)
+
+	_, err := s.payments.Confirm(payment.IntentId)
+	s.NoError(err)
+	s.db.MustExec(`UPDATE orders SET status = $1 WHERE id = 1`, consts.OrderCancelledByUser)
+
+	// the authorized money is released instead of captured
+	resp := s.sendPaymentCallback(payment.IntentId)
+	s.Require().Equal(http.StatusConflict, resp.Result().StatusCode)
+	s.Require().Equal(consts.PaymentCancelled, s.getPaymentStatus(payment.Id))
+	s.Require().Equal(consts.OrderCancelledByUser, s.getOrderStatus(1))
+
+	resp = s.sendPaymentCallback(payment.IntentId)
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+}
+
+func (s *APITestSuite) TestPaymentCallbackError_CapturedAfterCancel() {
+	payment := s.createPayment(1, 1)
+
+	// the provider has captured the money on its own
+	_, err := s.payments.Confirm(payment.IntentId)
+	s.NoError(err)
+	_, err = s.payments.Capture(payment.IntentId)
+	s.NoError(err)
+	s.db.MustExec(`UPDATE orders SET status = $1 WHERE id = 1`, consts.OrderCancelledByUser)
+
+	resp := s.sendPaymentCallback(payment.IntentId)
+	s.Require().Equal(http.StatusConflict, resp.Result().StatusCode)
+	s.Require().Equal(consts.PaymentRefunded, s.getPaymentStatus(payment.Id))
+
+	_, err = s.payments.Refund(payment.IntentId, 1, "test")
+	s.Require().Error(err, "the payment must be refunded in full")
+}
+
+func (s *APITestSuite) TestUserCreatePaymentCancelsOpen() {
+	first := s.createPayment(1, 1)
+	second := s.createPayment(1, 1)
+
+	s.Require().Equal(consts.PaymentCancelled, s.getPaymentStatus(first.Id))
+	_, err := s.payments.Confirm(first.IntentId)
+	s.Require().Error(err)
+
+	payment := s.confirmPayment(1, 1)
+	s.Require().Equal(second.Id, payment.Id)
+	s.Require().Equal(consts.PaymentCaptured, payment.Status)
+}
+
+func (s *APITestSuite) TestUserPayOrderError_ConcurrentConfirm() {
+	payment := s.createPayment(1, 1)
+
+	// another request has confirmed the intent meanwhile
+	_, err := s.payments.Confirm(payment.IntentId)
+	s.NoError(err)
+
+	resp := s.paymentRequest("POST", 1, 1, "/confirm")
+	s.requireErrorCode(resp, http.StatusConflict, domain.CodeConflict)
+}
@@ -0,0 +1,242 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/MAVIKE/yad-backend/internal/consts"
+	"github.com/MAVIKE/yad-backend/internal/domain"
+)
+
+func (s *APITestSuite) clientRequest(method, url string, clientId int, clientType string, reqBody string) *httptest.ResponseRecorder {
+	jwt, err := s.getJWT(clientId, clientType)
+	s.NoError(err)
+
+	req, err := http.NewRequest(method, url, bytes.NewBuffer([]byte(reqBody)))
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Content-type", "application/json")
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	return resp
+}
+
+func (s *APITestSuite) getRefunds(orderId, clientId int, clientType string) []*domain.Refund {
+	resp := s.clientRequest("GET", fmt.Sprintf("/api/v1/orders/%d/refunds", orderId), clientId, clientType, "")
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	var refunds []*domain.Refund
+	respData, err := ioutil.ReadAll(resp.Body)
+	s.NoError(err)
+
+	err = json.Unmarshal(respData, &refunds)
+	s.NoError(err)
+
+	return refunds
+}
+
+// requireReconciled checks that the order total is what is left of the
+// captured payment after the refunds.
+func (s *APITestSuite) requireReconciled(orderId int, total int) {
+	var order struct {
+		TotalPrice int `db:"total_price"`
+		Captured   int `db:"captured"`
+		Refunded   int `db:"refunded"`
+	}
+
+	err := s.db.Get(&order, `SELECT o.total_price,
+			(SELECT amount FROM payments WHERE order_id = o.id AND status IN ($2, $3)) AS captured,
+			(SELECT COALESCE(SUM(amount), 0) FROM refunds WHERE order_id = o.id AND status = $4) AS refunded
+		FROM orders AS o WHERE o.id = $1`,
+		orderId, consts.PaymentCaptured, consts.PaymentRefunded, consts.RefundSucceeded)
+	s.NoError(err)
+
+	s.Require().Equal(total, order.TotalPrice)
+	s.Require().Equal(order.Captured-order.Refunded, order.TotalPrice)
+}
+
+func (s *APITestSuite) TestRestaurantRejectOrderRefundOk() {
+	s.payOrder(1, 1)
+
+	resp := s.clientRequest("PUT", "/api/v1/orders/1", 1, restaurantType, `{"status":7}`)
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	s.Require().Equal(consts.OrderRefunded, s.getOrderStatus(1))
+	s.requireReconciled(1, 0)
+
+	refunds := s.getRefunds(1, 1, userType)
+	s.Require().Len(refunds, 1)
+	s.Require().Equal(consts.RefundOrder, refunds[0].Kind)
+	s.Require().Equal(consts.RefundSucceeded, refunds[0].Status)
+	s.Require().Equal(900, refunds[0].Amount)
+
+	payment := s.decodePayment(s.paymentRequest("GET", 1, 1, ""))
+	s.Require().Equal(consts.PaymentRefunded, payment.Status)
+}
+
+func (s *APITestSuite) TestRejectOrderRefundOk_MadeAfterCrash() {
+	s.payOrder(1, 1)
+	payment := s.decodePayment(s.paymentRequest("GET", 1, 1, ""))
+
+	// the order was rejected, then the instance crashed before calling the provider
+	oldStatus := consts.OrderPaid
+	refundId, err := s.repos.Refund.CreateForOrder(&domain.Refund{
+		OrderId:       1,
+		PaymentId:     payment.Id,
+		Kind:          consts.RefundOrder,
+		ActorId:       1,
+		ActorType:     restaurantType,
+		NextAttemptAt: time.Now(),
+	}, &domain.OrderEvent{
+		ActorId:   1,
+		ActorType: restaurantType,
+		OldStatus: &oldStatus,
+		NewStatus: consts.OrderRejectedByRestaurant,
+	})
+	s.NoError(err)
+	s.Require().NotZero(refundId)
+	s.Require().Equal(consts.OrderRejectedByRestaurant, s.getOrderStatus(1))
+
+	s.NoError(s.services.Refund.ProcessPending())
+
+	s.Require().Equal(consts.OrderRefunded, s.getOrderStatus(1))
+	s.requireReconciled(1, 0)
+
+	refunds := s.getRefunds(1, 1, userType)
+	s.Require().Len(refunds, 1)
+	s.Require().Equal(consts.RefundSucceeded, refunds[0].Status)
+	s.Require().Equal(900, refunds[0].Amount)
+}
+
+func (s *APITestSuite) TestRestaurantRemoveOrderItemRefundOk() {
+	s.payOrder(1, 1)
+
+	resp := s.clientRequest("DELETE", "/api/v1/orders/1/items/1", 1, restaurantType, "")
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	s.Require().Equal(consts.OrderPaid, s.getOrderStatus(1))
+	s.requireReconciled(1, 700)
+
+	refunds := s.getRefunds(1, 1, restaurantType)
+	s.Require().Len(refunds, 1)
+	s.Require().Equal(consts.RefundItem, refunds[0].Kind)
+	s.Require().Equal(200, refunds[0].Amount)
+	s.Require().Equal(1, *refunds[0].OrderItemId)
+
+	// the rest of the order is refunded once it is cancelled
+	resp = s.clientRequest("PUT", "/api/v1/orders/1", 1, userType, `{"status":6}`)
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	s.Require().Equal(consts.OrderRefunded, s.getOrderStatus(1))
+	s.requireReconciled(1, 0)
+	s.Require().Len(s.getRefunds(1, 1, userType), 2)
+}
+
+func (s *APITestSuite) TestRestaurantRemoveOrderItemRefundError_AlreadyRefunding() {
+	s.payOrder(1, 1)
+	payment := s.decodePayment(s.paymentRequest("GET", 1, 1, ""))
+	s.payments.DeclineRefunds(payment.IntentId, true)
+	defer s.payments.DeclineRefunds(payment.IntentId, false)
+
+	// the provider fails, the item stays in the order with its refund pending
+	resp := s.clientRequest("DELETE", "/api/v1/orders/1/items/1", 1, restaurantType, "")
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	resp = s.clientRequest("DELETE", "/api/v1/orders/1/items/1", 1, restaurantType, "")
+	s.Require().Equal(http.StatusConflict, resp.Result().StatusCode)
+
+	refunds := s.getRefunds(1, 1, restaurantType)
+	s.Require().Len(refunds, 1)
+	s.Require().Equal(consts.RefundPending, refunds[0].Status)
+	s.Require().Equal(1, *refunds[0].OrderItemId)
+	s.requireReconciled(1, 900)
+}
+
+func (s *APITestSuite) TestUserUpdateOrderItemError_Paid() {
+	s.payOrder(1, 1)
+
+	resp := s.clientRequest("DELETE", "/api/v1/orders/1/items/1", 1, userType, "")
+	s.Require().Equal(http.StatusConflict, resp.Result().StatusCode)
+
+	resp = s.clientRequest("PUT", "/api/v1/orders/1/items/1", 1, userType, `{"count":1}`)
+	s.Require().Equal(http.StatusConflict, resp.Result().StatusCode)
+
+	s.requireReconciled(1, 900)
+}
+
+func (s *APITestSuite) TestUserUpdateOrderItemError_PaidMeanwhile() {
+	s.payOrder(1, 1)
+
+	// the order was paid after the service had checked that it was editable
+	_, err := s.repos.Order.CreateItem(&domain.OrderItem{OrderId: 1, MenuItemId: 1, Count: 1})
+	s.Require().True(errors.Is(err, domain.ErrOrderNotEditable), err)
+
+	err = s.repos.Order.UpdateItem(1, 1, 5)
+	s.Require().True(errors.Is(err, domain.ErrOrderNotEditable), err)
+
+	err = s.repos.Order.DeleteItem(1, 1)
+	s.Require().True(errors.Is(err, domain.ErrOrderNotEditable), err)
+
+	s.requireReconciled(1, 900)
+}
+
+func (s *APITestSuite) TestRepriceOrderError_PaidMeanwhile() {
+	s.payOrder(1, 1)
+
+	// the order was paid after the service had checked that it was new
+	s.NoError(s.repos.Order.UpdateDeliveryPrice(1, 500))
+
+	s.requireReconciled(1, 900)
+}
+
+func (s *APITestSuite) TestAdminGoodwillRefundOk() {
+	s.payOrder(1, 1)
+
+	resp := s.clientRequest("POST", "/api/v1/orders/1/refunds", 1, adminType, `{"amount":150,"reason":"late delivery"}`)
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	s.Require().Equal(consts.OrderPaid, s.getOrderStatus(1))
+	s.requireReconciled(1, 750)
+
+	// no more than what is left of the payment can be refunded
+	resp = s.clientRequest("POST", "/api/v1/orders/1/refunds", 1, adminType, `{"amount":800,"reason":"late delivery"}`)
+	s.Require().Equal(http.StatusConflict, resp.Result().StatusCode)
+	s.requireReconciled(1, 750)
+
+	resp = s.clientRequest("PUT", "/api/v1/orders/1", 1, restaurantType, `{"status":7}`)
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	s.Require().Equal(consts.OrderRefunded, s.getOrderStatus(1))
+	s.requireReconciled(1, 0)
+
+	refunds := s.getRefunds(1, 1, adminType)
+	s.Require().Len(refunds, 2)
+	s.Require().Equal(consts.RefundGoodwill, refunds[0].Kind)
+	s.Require().Equal(consts.RefundOrder, refunds[1].Kind)
+	s.Require().Equal(750, refunds[1].Amount)
+}
+
+func (s *APITestSuite) TestAdminGoodwillRefundError_NotPaid() {
+	resp := s.clientRequest("POST", "/api/v1/orders/1/refunds", 1, adminType, `{"amount":150,"reason":"late delivery"}`)
+	s.Require().Equal(http.StatusConflict, resp.Result().StatusCode)
+}
+
+func (s *APITestSuite) TestUserGoodwillRefundError_Forbidden() {
+	s.payOrder(1, 1)
+
+	resp := s.clientRequest("POST", "/api/v1/orders/1/refunds", 1, userType, `{"amount":150,"reason":"late delivery"}`)
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
+
+	resp = s.clientRequest("GET", "/api/v1/orders/1/refunds", 2, userType, "")
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
+}
@@ -29,10 +29,13 @@ func (s *APITestSuite) TestGetAllRestaurantsOk() {
 	respData, err := ioutil.ReadAll(resp.Body)
 	s.NoError(err)
 
-	var respRestaurant []*domain.Restaurant
-	err = json.Unmarshal(respData, &respRestaurant)
+	var page domain.RestaurantPage
+	err = json.Unmarshal(respData, &page)
 	s.NoError(err)
 
+	respRestaurant := page.Items
+	s.Require().Empty(page.NextCursor)
+
 	s.Require().Equal(len(restaurants), len(respRestaurant))
 	for i := 0; i < len(respRestaurant); i++ {
 		s.Require().Equal(restaurants[i].Id, respRestaurant[i].Id)
@@ -60,7 +63,7 @@ func (s *APITestSuite) TestGetAllRestaurantsForbidden() {
 	resp := httptest.NewRecorder()
 	s.app.ServeHTTP(resp, req)
 
-	s.Require().Equal(http.StatusInternalServerError, resp.Result().StatusCode)
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
 }
 
 func (s *APITestSuite) TestGetRestaurantByIdOk() {
@@ -112,5 +115,5 @@ func (s *APITestSuite) TestGetRestaurantByIdForbidden() {
 	resp := httptest.NewRecorder()
 	s.app.ServeHTTP(resp, req)
 
-	s.Require().Equal(http.StatusInternalServerError, resp.Result().StatusCode)
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
 }
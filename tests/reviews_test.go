@@ -0,0 +1,198 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+)
+
+// createReview reviews the delivered order 2 of user 1 from restaurant 2
+// with courier 1.
+func (s *APITestSuite) createReview(reqBody string) {
+	resp := s.clientRequest("POST", "/api/v1/orders/2/reviews", 1, userType, reqBody)
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+}
+
+// getRating returns the rating of restaurant 2 and the number of its reviews.
+func (s *APITestSuite) getRating() (float64, int) {
+	var restaurant domain.Restaurant
+	s.getPage("/api/v1/restaurants/2", 1, userType, &restaurant)
+
+	return restaurant.Rating, restaurant.RatingCount
+}
+
+func (s *APITestSuite) TestCreateReview() {
+	s.createReview(`{"subject":"restaurant","rating":4,"comment":"Вкусно, но долго"}`)
+	s.createReview(`{"subject":"courier","rating":5}`)
+
+	rating, count := s.getRating()
+	s.Require().Equal(4.0, rating)
+	s.Require().Equal(1, count)
+
+	// the best rated restaurant first, the unrated ones by id
+	s.Require().Equal([][]int{{2, 3, 1}}, s.getRestaurantPages("sort=-rating"))
+
+	resp := s.clientRequest("POST", "/api/v1/orders/2/reviews", 1, userType, `{"subject":"restaurant","rating":1}`)
+	s.requireErrorCode(resp, http.StatusConflict, domain.CodeConflict)
+
+	// the user sees both reviews, the restaurant and the courier the ones about them
+	var reviews []*domain.Review
+	s.getPage("/api/v1/orders/2/reviews", 1, userType, &reviews)
+	s.Require().Len(reviews, 2)
+
+	s.getPage("/api/v1/orders/2/reviews", 2, restaurantType, &reviews)
+	s.Require().Len(reviews, 1)
+	s.Require().Equal(2, reviews[0].SubjectId)
+	s.Require().Equal("Вкусно, но долго", reviews[0].Comment)
+
+	s.getPage("/api/v1/orders/2/reviews", 1, courierType, &reviews)
+	s.Require().Len(reviews, 1)
+	s.Require().Equal(1, reviews[0].SubjectId)
+	s.Require().Equal(5, reviews[0].Rating)
+}
+
+func (s *APITestSuite) TestReplyToReview() {
+	s.createReview(`{"subject":"restaurant","rating":3}`)
+
+	resp := s.clientRequest("PUT", "/api/v1/restaurants/2/reviews/1/reply", 2, restaurantType, `{"reply":"Спасибо!"}`)
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	var page domain.ReviewPage
+	s.getPage("/api/v1/restaurants/2/reviews", 3, userType, &page)
+	s.Require().Len(page.Items, 1)
+	s.Require().NotNil(page.Items[0].Reply)
+	s.Require().Equal("Спасибо!", *page.Items[0].Reply)
+	s.Require().NotNil(page.Items[0].RepliedAt)
+	s.Require().Empty(page.NextCursor)
+
+	// a restaurant replies only to the reviews about it
+	resp = s.clientRequest("PUT", "/api/v1/restaurants/1/reviews/1/reply", 1, restaurantType, `{"reply":"Спасибо!"}`)
+	s.requireErrorCode(resp, http.StatusNotFound, domain.CodeNotFound)
+
+	resp = s.clientRequest("PUT", "/api/v1/restaurants/2/reviews/1/reply", 1, restaurantType, `{"reply":"Спасибо!"}`)
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
+}
+
+func (s *APITestSuite) TestModerateReview() {
+	s.createReview(`{"subject":"restaurant","rating":1,"comment":"abusive"}`)
+
+	resp := s.clientRequest("PUT", "/api/v1/reviews/1/moderation", 1, adminType, `{"hidden":true}`)
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	rating, count := s.getRating()
+	s.Require().Equal(0.0, rating)
+	s.Require().Equal(0, count)
+
+	var page domain.ReviewPage
+	s.getPage("/api/v1/restaurants/2/reviews", 1, userType, &page)
+	s.Require().Empty(page.Items)
+
+	s.getPage("/api/v1/restaurants/2/reviews", 1, adminType, &page)
+	s.Require().Len(page.Items, 1)
+	s.Require().True(page.Items[0].Hidden)
+
+	// the author still sees the hidden review, the restaurant doesn't
+	var reviews []*domain.Review
+	s.getPage("/api/v1/orders/2/reviews", 1, userType, &reviews)
+	s.Require().Len(reviews, 1)
+
+	s.getPage("/api/v1/orders/2/reviews", 2, restaurantType, &reviews)
+	s.Require().Empty(reviews)
+
+	resp = s.clientRequest("PUT", "/api/v1/reviews/1/moderation", 1, adminType, `{"hidden":false}`)
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	rating, count = s.getRating()
+	s.Require().Equal(1.0, rating)
+	s.Require().Equal(1, count)
+}
+
+// moderateReview hides or shows the review.
+func (s *APITestSuite) moderateReview(reviewId int, hidden bool) {
+	resp := s.clientRequest("PUT", fmt.Sprintf("/api/v1/reviews/%d/moderation", reviewId), 1, adminType,
+		fmt.Sprintf(`{"hidden":%t}`, hidden))
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+}
+
+func (s *APITestSuite) TestRestaurantRatingFollowsModeration() {
+	s.createReview(`{"subject":"restaurant","rating":4}`)
+	s.createReview(`{"subject":"courier","rating":1}`)
+
+	// a second review of restaurant 2, on order 3 of user 2
+	s.db.MustExec(`INSERT INTO reviews (order_id, user_id, subject, subject_id, rating)
+		VALUES (3, 2, 'restaurant', 2, 1)`)
+
+	tests := []struct {
+		name     string
+		reviewId int
+		hidden   bool
+		rating   float64
+		count    int
+	}{
+		{"the second hidden", 3, true, 4.0, 1},
+		{"both hidden", 1, true, 0.0, 0},
+		{"the second shown", 3, false, 1.0, 1},
+		{"both shown", 1, false, 2.5, 2},
+		{"the courier review hidden", 2, true, 2.5, 2},
+	}
+
+	for _, tt := range tests {
+		s.moderateReview(tt.reviewId, tt.hidden)
+
+		rating, count := s.getRating()
+		s.Require().Equal(tt.rating, rating, tt.name)
+		s.Require().Equal(tt.count, count, tt.name)
+	}
+
+	s.db.MustExec(`DELETE FROM reviews WHERE id = 1`)
+
+	rating, count := s.getRating()
+	s.Require().Equal(1.0, rating)
+	s.Require().Equal(1, count)
+}
+
+func (s *APITestSuite) TestReviewError() {
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		clientId   int
+		clientType string
+		reqBody    string
+		status     int
+	}{
+		{"undelivered order", "POST", "/api/v1/orders/1/reviews", 1, userType, `{"subject":"restaurant","rating":5}`,
+			http.StatusConflict},
+		{"foreign order", "POST", "/api/v1/orders/2/reviews", 2, userType, `{"subject":"restaurant","rating":5}`,
+			http.StatusForbidden},
+		{"restaurant reviews", "POST", "/api/v1/orders/2/reviews", 2, restaurantType, `{"subject":"courier","rating":5}`,
+			http.StatusForbidden},
+		{"unknown order", "POST", "/api/v1/orders/100/reviews", 1, userType, `{"subject":"restaurant","rating":5}`,
+			http.StatusNotFound},
+		{"unknown subject", "POST", "/api/v1/orders/2/reviews", 1, userType, `{"subject":"user","rating":5}`,
+			http.StatusBadRequest},
+		{"rating too high", "POST", "/api/v1/orders/2/reviews", 1, userType, `{"subject":"restaurant","rating":6}`,
+			http.StatusUnprocessableEntity},
+		{"comment too long", "POST", "/api/v1/orders/2/reviews", 1, userType,
+			`{"subject":"restaurant","rating":5,"comment":"` + strings.Repeat("ж", 1001) + `"}`,
+			http.StatusUnprocessableEntity},
+		{"foreign order reviews", "GET", "/api/v1/orders/2/reviews", 2, userType, "", http.StatusForbidden},
+		{"foreign restaurant reviews", "GET", "/api/v1/restaurants/2/reviews", 1, restaurantType, "", http.StatusForbidden},
+		{"unknown review sort", "GET", "/api/v1/restaurants/2/reviews?sort=comment", 1, userType, "",
+			http.StatusUnprocessableEntity},
+		{"empty reply", "PUT", "/api/v1/restaurants/2/reviews/1/reply", 2, restaurantType, `{"reply":""}`,
+			http.StatusBadRequest},
+		{"unknown review", "PUT", "/api/v1/reviews/100/moderation", 1, adminType, `{"hidden":true}`,
+			http.StatusNotFound},
+		{"no hidden", "PUT", "/api/v1/reviews/1/moderation", 1, adminType, `{}`, http.StatusBadRequest},
+		{"user moderates", "PUT", "/api/v1/reviews/1/moderation", 1, userType, `{"hidden":true}`,
+			http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		resp := s.clientRequest(tt.method, tt.url, tt.clientId, tt.clientType, tt.reqBody)
+		s.Require().Equal(tt.status, resp.Result().StatusCode, tt.name)
+	}
+}
@@ -0,0 +1,194 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/MAVIKE/yad-backend/internal/consts"
+	"github.com/MAVIKE/yad-backend/internal/domain"
+)
+
+// allDay keeps a restaurant open round the clock, holidays aside.
+const allDay = `[{"weekday":1,"opens_at":"00:00","closes_at":"00:00"},{"weekday":2,"opens_at":"00:00","closes_at":"00:00"},
+	{"weekday":3,"opens_at":"00:00","closes_at":"00:00"},{"weekday":4,"opens_at":"00:00","closes_at":"00:00"},
+	{"weekday":5,"opens_at":"00:00","closes_at":"00:00"},{"weekday":6,"opens_at":"00:00","closes_at":"00:00"},
+	{"weekday":7,"opens_at":"00:00","closes_at":"00:00"}]`
+
+// closeToday gives the restaurant opening hours that have it closed for
+// the rest of the UTC day and returns the time it opens again.
+func (s *APITestSuite) closeToday(restaurantId int) time.Time {
+	now := time.Now().UTC()
+	reqBody := fmt.Sprintf(`{"time_zone":"UTC","hours":%s,"holidays":[{"date":"%s","reason":"inventory"}]}`,
+		allDay, now.Format("2006-01-02"))
+
+	resp := s.clientRequest("PUT", fmt.Sprintf("/api/v1/restaurants/%d/hours", restaurantId), restaurantId, restaurantType, reqBody)
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+}
+
+func (s *APITestSuite) requireErrorCode(resp *httptest.ResponseRecorder, status int, code string) {
+	s.Require().Equal(status, resp.Result().StatusCode)
+
+	var body struct {
+		Code string `json:"code"`
+	}
+	respData, err := ioutil.ReadAll(resp.Body)
+	s.NoError(err)
+
+	err = json.Unmarshal(respData, &body)
+	s.NoError(err)
+	s.Require().Equal(code, body.Code)
+}
+
+func (s *APITestSuite) TestRestaurantUpdateScheduleOk() {
+	reqBody := fmt.Sprintf(`{"time_zone":"Asia/Novosibirsk","hours":%s,
+		"holidays":[{"date":"2099-12-31","opens_at":"10:00","closes_at":"16:00","reason":"New Year's Eve"}]}`, allDay)
+
+	resp := s.clientRequest("PUT", "/api/v1/restaurants/1/hours", 1, restaurantType, reqBody)
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	resp = s.clientRequest("GET", "/api/v1/restaurants/1/hours", 1, userType, "")
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	var schedule domain.Schedule
+	respData, err := ioutil.ReadAll(resp.Body)
+	s.NoError(err)
+
+	err = json.Unmarshal(respData, &schedule)
+	s.NoError(err)
+
+	s.Require().Equal("Asia/Novosibirsk", schedule.TimeZone)
+	s.Require().Len(schedule.Hours, 7)
+	s.Require().Equal("00:00", schedule.Hours[0].OpensAt)
+	s.Require().Len(schedule.Holidays, 1)
+	s.Require().Equal("2099-12-31", schedule.Holidays[0].Date)
+	s.Require().Equal("16:00", *schedule.Holidays[0].ClosesAt)
+
+	// the opening hours replace the working status set by hand
+	resp = s.clientRequest("GET", "/api/v1/restaurants/1", 1, userType, "")
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	var restaurant domain.Restaurant
+	respData, err = ioutil.ReadAll(resp.Body)
+	s.NoError(err)
+
+	err = json.Unmarshal(respData, &restaurant)
+	s.NoError(err)
+
+	s.Require().Equal(consts.RestaurantWorking, restaurant.WorkingStatus)
+	s.Require().Nil(restaurant.NextOpening)
+}
+
+func (s *APITestSuite) TestGetAllRestaurantsNextOpening() {
+	opening := s.closeToday(1)
+
+	resp := s.clientRequest("GET", "/api/v1/restaurants/", 1, userType, "")
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	var page domain.RestaurantPage
+	respData, err := ioutil.ReadAll(resp.Body)
+	s.NoError(err)
+
+	err = json.Unmarshal(respData, &page)
+	s.NoError(err)
+
+	for _, restaurant := range page.Items {
+		if restaurant.Id != 1 {
+			s.Require().Nil(restaurant.NextOpening)
+			continue
+		}
+
+		s.Require().Equal(consts.RestaurantUnable, restaurant.WorkingStatus)
+		s.Require().NotNil(restaurant.NextOpening)
+		s.Require().True(opening.Equal(*restaurant.NextOpening))
+	}
+}
+
+func (s *APITestSuite) TestUserOrderError_RestaurantClosed() {
+	s.addCartItem(1, `{"menu_item_id":1,"count":1}`, false)
+	s.closeToday(1)
+
+	resp := s.clientRequest("POST", "/api/v1/orders/", 1, userType, `{"restaurant_id":1}`)
+	s.requireErrorCode(resp, http.StatusConflict, domain.CodeRestaurantClosed)
+
+	resp = s.clientRequest("POST", "/api/v1/users/1/cart/checkout", 1, userType, "")
+	s.requireErrorCode(resp, http.StatusConflict, domain.CodeRestaurantClosed)
+
+	resp = s.paymentRequest("POST", 1, 1, "")
+	s.requireErrorCode(resp, http.StatusConflict, domain.CodeRestaurantClosed)
+
+	// other restaurants still take orders
+	resp = s.clientRequest("POST", "/api/v1/orders/", 1, userType, `{"restaurant_id":2}`)
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+}
+
+func (s *APITestSuite) TestUserPayOrderError_RestaurantClosed() {
+	s.createPayment(1, 1)
+	s.closeToday(1)
+
+	resp := s.paymentRequest("POST", 1, 1, "/confirm")
+	s.requireErrorCode(resp, http.StatusConflict, domain.CodeRestaurantClosed)
+	s.Require().Equal(consts.OrderCreated, s.getOrderStatus(1))
+}
+
+func (s *APITestSuite) TestRestaurantScheduleStatusChangedOk() {
+	s.closeToday(1)
+
+	var status int
+	err := s.db.Get(&status, `SELECT working_status FROM restaurants WHERE id = 1`)
+	s.NoError(err)
+	s.Require().Equal(consts.RestaurantUnable, status)
+
+	// the status is stored and announced once per change
+	s.NoError(s.services.Restaurant.SyncWorkingStatus())
+	s.Require().Equal([]string{domain.EventRestaurantStatusChanged}, s.getOutboxEventTypes())
+}
+
+func (s *APITestSuite) TestUserOrderError_RestaurantUnable() {
+	// restaurant 3 has no opening hours and is closed by hand
+	resp := s.clientRequest("POST", "/api/v1/orders/", 1, userType, `{"restaurant_id":3}`)
+	s.requireErrorCode(resp, http.StatusConflict, domain.CodeRestaurantClosed)
+}
+
+func (s *APITestSuite) TestRestaurantUpdateError_ScheduledStatus() {
+	resp := s.clientRequest("PUT", "/api/v1/restaurants/1/hours", 1, restaurantType,
+		fmt.Sprintf(`{"time_zone":"UTC","hours":%s}`, allDay))
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	resp = s.clientRequest("PUT", "/api/v1/restaurants/1", 1, restaurantType, `{"working_status":1}`)
+	s.requireErrorCode(resp, http.StatusUnprocessableEntity, domain.CodeValidation)
+
+	resp = s.clientRequest("PUT", "/api/v1/restaurants/1", 1, restaurantType, `{"name":"Restaurant1"}`)
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+}
+
+func (s *APITestSuite) TestUpdateScheduleError() {
+	tests := []struct {
+		name       string
+		clientId   int
+		clientType string
+		reqBody    string
+		status     int
+	}{
+		{"foreign restaurant", 2, restaurantType, `{"time_zone":"UTC"}`, http.StatusForbidden},
+		{"user", 1, userType, `{"time_zone":"UTC"}`, http.StatusForbidden},
+		{"unknown time zone", 1, restaurantType, `{"time_zone":"Mars/Olympus"}`, http.StatusUnprocessableEntity},
+		{"bad weekday", 1, restaurantType,
+			`{"time_zone":"UTC","hours":[{"weekday":8,"opens_at":"10:00","closes_at":"18:00"}]}`, http.StatusBadRequest},
+		{"bad time", 1, restaurantType,
+			`{"time_zone":"UTC","hours":[{"weekday":1,"opens_at":"10 am","closes_at":"18:00"}]}`, http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		resp := s.clientRequest("PUT", "/api/v1/restaurants/1/hours", tt.clientId, tt.clientType, tt.reqBody)
+		s.Require().Equal(tt.status, resp.Result().StatusCode, tt.name)
+	}
+
+	resp := s.clientRequest("PUT", "/api/v1/restaurants/100/hours", 1, adminType, `{"time_zone":"UTC"}`)
+	s.Require().Equal(http.StatusNotFound, resp.Result().StatusCode)
+}
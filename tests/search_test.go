@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+)
+
+// searchDishes returns the dish ids of each restaurant found by the query,
+// the best match first.
+func (s *APITestSuite) searchDishes(query string) map[int][]int {
+	var page domain.SearchPage
+	s.getPage("/api/v1/restaurants/search?q="+url.QueryEscape(query), 1, userType, &page)
+
+	results := make(map[int][]int, len(page.Items))
+	for _, result := range page.Items {
+		ids := make([]int, 0, len(result.Dishes))
+		for _, dish := range result.Dishes {
+			ids = append(ids, dish.Id)
+		}
+		results[result.Restaurant.Id] = ids
+	}
+
+	return results
+}
+
+func (s *APITestSuite) TestSearchRestaurants() {
+	_, err := s.db.Exec(`UPDATE menu_items SET title = 'Пицца Маргарита', description = 'Томаты и моцарелла' WHERE id = 1`)
+	s.NoError(err)
+	_, err = s.db.Exec(`UPDATE menu_items SET title = 'Pizza Pepperoni' WHERE id = 5`)
+	s.NoError(err)
+	_, err = s.db.Exec(`UPDATE categories SET title = 'Пиццы' WHERE id = 5`)
+	s.NoError(err)
+
+	// Russian stemming matches the dish and the category of dishes
+	s.Require().Equal(map[int][]int{1: {1}, 3: {7, 8}}, s.searchDishes("пицца"))
+	s.Require().Equal(map[int][]int{1: {1}}, s.searchDishes("моцареллой"))
+
+	// English stemming
+	s.Require().Equal(map[int][]int{2: {5}}, s.searchDishes("pizzas"))
+
+	// a restaurant matching by its name has no dishes to show
+	s.Require().Equal(map[int][]int{2: {}, 3: {}}, s.searchDishes("restaurant2"))
+
+	// the nearer and better match comes first
+	var page domain.SearchPage
+	path := "/api/v1/restaurants/search?limit=1&q=" + url.QueryEscape("пицца")
+	s.getPage(path, 1, userType, &page)
+	s.Require().Len(page.Items, 1)
+	s.Require().Equal(1, page.Items[0].Restaurant.Id)
+	s.Require().NotEmpty(page.NextCursor)
+
+	s.getPage(path+"&cursor="+page.NextCursor, 1, userType, &page)
+	s.Require().Len(page.Items, 1)
+	s.Require().Equal(3, page.Items[0].Restaurant.Id)
+	s.Require().Empty(page.NextCursor)
+}
+
+func (s *APITestSuite) TestSearchRestaurantsOutsideDeliveryZone() {
+	resp := s.clientRequest("PUT", "/api/v1/restaurants/2/zone", 2, restaurantType, squareZone)
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	s.Require().Equal(map[int][]int{2: {}, 3: {}}, s.searchDishes("restaurant2"))
+
+	var page domain.SearchPage
+	s.getPage("/api/v1/restaurants/search?q=restaurant2", 2, userType, &page)
+	s.Require().Len(page.Items, 1)
+	s.Require().Equal(3, page.Items[0].Restaurant.Id)
+}
+
+func (s *APITestSuite) TestSearchRestaurantsError() {
+	tests := []struct {
+		name       string
+		url        string
+		clientType string
+		status     int
+	}{
+		{"no query", "/api/v1/restaurants/search", userType, http.StatusUnprocessableEntity},
+		{"blank query", "/api/v1/restaurants/search?q=%20%20", userType, http.StatusUnprocessableEntity},
+		{"query too long", "/api/v1/restaurants/search?q=" + strings.Repeat("a", 101), userType,
+			http.StatusUnprocessableEntity},
+		{"unknown sort", "/api/v1/restaurants/search?q=pizza&sort=name", userType, http.StatusUnprocessableEntity},
+		{"courier", "/api/v1/restaurants/search?q=pizza", courierType, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		resp := s.clientRequest("GET", tt.url, 1, tt.clientType, "")
+		s.Require().Equal(tt.status, resp.Result().StatusCode, tt.name)
+	}
+}
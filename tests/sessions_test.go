@@ -0,0 +1,236 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/MAVIKE/yad-backend/internal/domain"
+)
+
+type tokensBody struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (s *APITestSuite) signIn(path, reqBody string) tokensBody {
+	req, err := http.NewRequest("POST", path, bytes.NewBuffer([]byte(reqBody)))
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+	req.Header.Set("Content-type", "application/json")
+
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	var tokens tokensBody
+	respData, err := ioutil.ReadAll(resp.Body)
+	s.NoError(err)
+
+	err = json.Unmarshal(respData, &tokens)
+	s.NoError(err)
+
+	s.Require().NotEmpty(tokens.AccessToken)
+	s.Require().NotEmpty(tokens.RefreshToken)
+
+	return tokens
+}
+
+func (s *APITestSuite) refresh(path, refreshToken string) *httptest.ResponseRecorder {
+	reqBody := `{"refresh_token":"` + refreshToken + `"}`
+	req, err := http.NewRequest("POST", path, bytes.NewBuffer([]byte(reqBody)))
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+	req.Header.Set("Content-type", "application/json")
+
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	return resp
+}
+
+func (s *APITestSuite) TestUserRefreshOk() {
+	tokens := s.signIn("/api/v1/users/sign-in", `{"phone":"71234567890","password":"password"}`)
+
+	resp := s.refresh("/api/v1/users/refresh", tokens.RefreshToken)
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	var newTokens tokensBody
+	respData, err := ioutil.ReadAll(resp.Body)
+	s.NoError(err)
+
+	err = json.Unmarshal(respData, &newTokens)
+	s.NoError(err)
+
+	s.Require().NotEqual(tokens.RefreshToken, newTokens.RefreshToken)
+
+	resp = s.refresh("/api/v1/users/refresh", tokens.RefreshToken)
+	s.Require().Equal(http.StatusUnauthorized, resp.Result().StatusCode)
+}
+
+func (s *APITestSuite) TestUserRefreshError_WrongClientType() {
+	tokens := s.signIn("/api/v1/users/sign-in", `{"phone":"71234567890","password":"password"}`)
+
+	resp := s.refresh("/api/v1/couriers/refresh", tokens.RefreshToken)
+	s.Require().Equal(http.StatusUnauthorized, resp.Result().StatusCode)
+}
+
+func (s *APITestSuite) TestUserSignOutOk() {
+	tokens := s.signIn("/api/v1/users/sign-in", `{"phone":"71234567890","password":"password"}`)
+
+	reqBody := `{"refresh_token":"` + tokens.RefreshToken + `"}`
+	req, err := http.NewRequest("POST", "/api/v1/users/sign-out", bytes.NewBuffer([]byte(reqBody)))
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	req.Header.Set("Content-type", "application/json")
+
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	resp = s.refresh("/api/v1/users/refresh", tokens.RefreshToken)
+	s.Require().Equal(http.StatusUnauthorized, resp.Result().StatusCode)
+}
+
+func (s *APITestSuite) TestUserGetSessionsOk() {
+	tokens := s.signIn("/api/v1/users/sign-in", `{"phone":"71234567890","password":"password"}`)
+	s.signIn("/api/v1/users/sign-in", `{"phone":"71234567890","password":"password"}`)
+
+	req, err := http.NewRequest("GET", "/api/v1/users/1/sessions", nil)
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	var sessions []*domain.Session
+	respData, err := ioutil.ReadAll(resp.Body)
+	s.NoError(err)
+
+	err = json.Unmarshal(respData, &sessions)
+	s.NoError(err)
+
+	s.Require().Len(sessions, 2)
+
+	req, err = http.NewRequest("DELETE", "/api/v1/users/1/sessions/1", nil)
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+
+	resp = httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	resp = s.refresh("/api/v1/users/refresh", tokens.RefreshToken)
+	s.Require().Equal(http.StatusUnauthorized, resp.Result().StatusCode)
+}
+
+func (s *APITestSuite) TestUserGetSessionsError_Forbidden() {
+	jwt, err := s.getJWT(2, userType)
+	s.NoError(err)
+
+	req, err := http.NewRequest("GET", "/api/v1/users/1/sessions", nil)
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
+}
+
+func (s *APITestSuite) TestUserSignOut_RevokesAccessToken() {
+	tokens := s.signIn("/api/v1/users/sign-in", `{"phone":"71234567890","password":"password"}`)
+
+	reqBody := `{"refresh_token":"` + tokens.RefreshToken + `"}`
+	req, err := http.NewRequest("POST", "/api/v1/users/sign-out", bytes.NewBuffer([]byte(reqBody)))
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	req.Header.Set("Content-type", "application/json")
+
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	req, err = http.NewRequest("GET", "/api/v1/users/1", nil)
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+
+	resp = httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusUnauthorized, resp.Result().StatusCode)
+}
+
+func (s *APITestSuite) TestAdminRevokeClientOk() {
+	tokens := s.signIn("/api/v1/users/sign-in", `{"phone":"71234567890","password":"password"}`)
+
+	adminJWT, err := s.getJWT(1, adminType)
+	s.NoError(err)
+
+	reqBody := `{"client_id":1,"client_type":"user"}`
+	req, err := http.NewRequest("POST", "/api/v1/admins/revocations", bytes.NewBuffer([]byte(reqBody)))
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+adminJWT)
+	req.Header.Set("Content-type", "application/json")
+
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	req, err = http.NewRequest("GET", "/api/v1/users/1", nil)
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+
+	resp = httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusUnauthorized, resp.Result().StatusCode)
+
+	resp = s.refresh("/api/v1/users/refresh", tokens.RefreshToken)
+	s.Require().Equal(http.StatusUnauthorized, resp.Result().StatusCode)
+}
+
+func (s *APITestSuite) TestAdminRevokeClientError_Forbidden() {
+	jwt, err := s.getJWT(1, userType)
+	s.NoError(err)
+
+	reqBody := `{"client_id":2,"client_type":"user"}`
+	req, err := http.NewRequest("POST", "/api/v1/admins/revocations", bytes.NewBuffer([]byte(reqBody)))
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Content-type", "application/json")
+
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
+}
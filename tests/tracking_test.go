@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/MAVIKE/yad-backend/internal/consts"
+	"github.com/MAVIKE/yad-backend/internal/domain"
+)
+
+func (s *APITestSuite) addCourierLocation(courierId int, reqBody string) int {
+	jwt, err := s.getJWT(courierId, courierType)
+	s.NoError(err)
+
+	url := fmt.Sprintf("/api/v1/couriers/%d/location", courierId)
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer([]byte(reqBody)))
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Content-type", "application/json")
+
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	return resp.Result().StatusCode
+}
+
+func (s *APITestSuite) TestUserGetCourierLocationOk() {
+	s.db.MustExec(`UPDATE orders SET status = $1 WHERE id = 4`, consts.OrderEnRoute)
+
+	s.Require().Equal(http.StatusOK, s.addCourierLocation(3, `{"latitude":53.1,"longitude":89.9}`))
+	s.Require().Equal(http.StatusOK, s.addCourierLocation(3, `{"latitude":53.2,"longitude":89.8}`))
+
+	jwt, err := s.getJWT(3, userType)
+	s.NoError(err)
+
+	req, err := http.NewRequest("GET", "/api/v1/orders/4/courier-location", nil)
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	var location domain.CourierLocation
+	respData, err := ioutil.ReadAll(resp.Body)
+	s.NoError(err)
+
+	err = json.Unmarshal(respData, &location)
+	s.NoError(err)
+
+	s.Require().Equal(3, location.CourierId)
+	s.Require().Equal(53.2, location.Latitude)
+	s.Require().Equal(89.8, location.Longitude)
+}
+
+func (s *APITestSuite) TestUserGetCourierLocationError_NotEnRoute() {
+	s.Require().Equal(http.StatusOK, s.addCourierLocation(3, `{"latitude":53.1,"longitude":89.9}`))
+
+	jwt, err := s.getJWT(3, userType)
+	s.NoError(err)
+
+	req, err := http.NewRequest("GET", "/api/v1/orders/4/courier-location", nil)
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusConflict, resp.Result().StatusCode)
+}
+
+func (s *APITestSuite) TestUserGetCourierLocationError_OtherUser() {
+	s.db.MustExec(`UPDATE orders SET status = $1 WHERE id = 4`, consts.OrderEnRoute)
+
+	jwt, err := s.getJWT(1, userType)
+	s.NoError(err)
+
+	req, err := http.NewRequest("GET", "/api/v1/orders/4/courier-location", nil)
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
+}
+
+func (s *APITestSuite) TestAddCourierLocationError_OtherCourier() {
+	jwt, err := s.getJWT(1, courierType)
+	s.NoError(err)
+
+	reqBody := `{"latitude":53.1,"longitude":89.9}`
+	req, err := http.NewRequest("POST", "/api/v1/couriers/3/location", bytes.NewBuffer([]byte(reqBody)))
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Content-type", "application/json")
+
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
+}
+
+func (s *APITestSuite) TestDispatchUsesLatestLocationOk() {
+	s.db.MustExec(`UPDATE couriers SET working_status = $1 WHERE id = 1`, consts.CourierWaiting)
+
+	// courier 4 lives farther from user 1 than courier 1 but is now next door
+	s.Require().Equal(http.StatusOK, s.addCourierLocation(4, `{"latitude":50.01,"longitude":87.01}`))
+
+	s.payOrder(1, 1)
+	s.NoError(s.services.Dispatch.AssignPending())
+
+	s.Require().Len(s.getCourierOffers(4), 1)
+	s.Require().Empty(s.getCourierOffers(1))
+}
@@ -45,7 +45,7 @@ func (s *APITestSuite) TestUserSignUpError_NotUniquePhone() {
 	resp := httptest.NewRecorder()
 	s.app.ServeHTTP(resp, req)
 
-	s.Require().Equal(http.StatusInternalServerError, resp.Result().StatusCode)
+	s.Require().Equal(http.StatusConflict, resp.Result().StatusCode)
 }
 
 func (s *APITestSuite) TestUserSignUpError_EmptyRequiredFields() {
@@ -76,6 +76,36 @@ func (s *APITestSuite) TestUserSignInOk() {
 	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
 }
 
+func (s *APITestSuite) TestUserSignInOk_RehashLegacyPassword() {
+	reqBody := `{"phone":"71234567890","password":"password"}`
+	req, err := http.NewRequest("POST", "/api/v1/users/sign-in", bytes.NewBuffer([]byte(reqBody)))
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+	req.Header.Set("Content-type", "application/json")
+
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	var passwordHash string
+	err = s.db.Get(&passwordHash, `SELECT password_hash FROM users WHERE id = 1`)
+	s.NoError(err)
+	s.Require().NotEqual("password", passwordHash)
+
+	req, err = http.NewRequest("POST", "/api/v1/users/sign-in", bytes.NewBuffer([]byte(reqBody)))
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+	req.Header.Set("Content-type", "application/json")
+
+	resp = httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+}
+
 func (s *APITestSuite) TestUserSignInError_WrongPassword() {
 	reqBody := `{"phone":"71234567890","password":"wrong_password"}`
 	req, err := http.NewRequest("POST", "/api/v1/users/sign-in", bytes.NewBuffer([]byte(reqBody)))
@@ -87,7 +117,7 @@ func (s *APITestSuite) TestUserSignInError_WrongPassword() {
 	resp := httptest.NewRecorder()
 	s.app.ServeHTTP(resp, req)
 
-	s.Require().Equal(http.StatusInternalServerError, resp.Result().StatusCode)
+	s.Require().Equal(http.StatusUnauthorized, resp.Result().StatusCode)
 }
 
 func (s *APITestSuite) TestUserSignInError_NotExists() {
@@ -101,7 +131,7 @@ func (s *APITestSuite) TestUserSignInError_NotExists() {
 	resp := httptest.NewRecorder()
 	s.app.ServeHTTP(resp, req)
 
-	s.Require().Equal(http.StatusInternalServerError, resp.Result().StatusCode)
+	s.Require().Equal(http.StatusUnauthorized, resp.Result().StatusCode)
 }
 
 func (s *APITestSuite) TestUserGetOk() {
@@ -153,7 +183,7 @@ func (s *APITestSuite) TestUserGetError_WrongId() {
 	resp := httptest.NewRecorder()
 	s.app.ServeHTTP(resp, req)
 
-	s.Require().Equal(http.StatusInternalServerError, resp.Result().StatusCode)
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
 }
 
 func (s *APITestSuite) TestUserGetError_WrongClientType() {
@@ -172,5 +202,5 @@ func (s *APITestSuite) TestUserGetError_WrongClientType() {
 	resp := httptest.NewRecorder()
 	s.app.ServeHTTP(resp, req)
 
-	s.Require().Equal(http.StatusInternalServerError, resp.Result().StatusCode)
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
 }
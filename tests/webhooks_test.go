@@ -0,0 +1,171 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+
+	"github.com/MAVIKE/yad-backend/internal/consts"
+	"github.com/MAVIKE/yad-backend/internal/domain"
+	"github.com/MAVIKE/yad-backend/pkg/webhook"
+)
+
+const webhookSecret = "0123456789abcdef"
+
+func (s *APITestSuite) createWebhook(restaurantId int, url string, events string) int {
+	jwt, err := s.getJWT(restaurantId, restaurantType)
+	s.NoError(err)
+
+	reqBody := fmt.Sprintf(`{"url":"%s","secret":"%s","events":%s}`, url, webhookSecret, events)
+	req, err := http.NewRequest("POST", fmt.Sprintf("/api/v1/restaurants/%d/webhooks", restaurantId),
+		bytes.NewBuffer([]byte(reqBody)))
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Content-type", "application/json")
+
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	var respBody struct {
+		Id int `json:"id"`
+	}
+	respData, err := ioutil.ReadAll(resp.Body)
+	s.NoError(err)
+
+	err = json.Unmarshal(respData, &respBody)
+	s.NoError(err)
+
+	return respBody.Id
+}
+
+func (s *APITestSuite) getWebhookDeliveries(restaurantId, webhookId int) []*domain.WebhookDelivery {
+	jwt, err := s.getJWT(restaurantId, restaurantType)
+	s.NoError(err)
+
+	url := fmt.Sprintf("/api/v1/restaurants/%d/webhooks/%d/deliveries", restaurantId, webhookId)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusOK, resp.Result().StatusCode)
+
+	var deliveries []*domain.WebhookDelivery
+	respData, err := ioutil.ReadAll(resp.Body)
+	s.NoError(err)
+
+	err = json.Unmarshal(respData, &deliveries)
+	s.NoError(err)
+
+	return deliveries
+}
+
+func (s *APITestSuite) TestRestaurantWebhookDeliveredOk() {
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	defer receiver.Close()
+
+	webhookId := s.createWebhook(1, receiver.URL, `["order.paid"]`)
+
+	s.payOrder(1, 1)
+	s.NoError(s.services.Outbox.Relay())
+	s.NoError(s.services.Webhook.DeliverPending())
+
+	r := <-received
+	body := <-bodies
+
+	s.Require().Equal(domain.EventOrderPaid, r.Header.Get(webhook.EventHeader))
+
+	timestamp, err := strconv.ParseInt(r.Header.Get(webhook.TimestampHeader), 10, 64)
+	s.NoError(err)
+	s.Require().True(webhook.Verify(webhookSecret, timestamp, body, r.Header.Get(webhook.SignatureHeader)))
+
+	var event domain.Event
+	s.NoError(json.Unmarshal(body, &event))
+	s.Require().Equal(1, event.OrderId)
+	s.Require().Equal(consts.OrderPaid, event.Status)
+
+	deliveries := s.getWebhookDeliveries(1, webhookId)
+	s.Require().Len(deliveries, 1)
+	s.Require().Equal(consts.DeliveryDelivered, deliveries[0].Status)
+	s.Require().Equal(1, deliveries[0].Attempts)
+}
+
+func (s *APITestSuite) TestRestaurantWebhookRetriedOk() {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	webhookId := s.createWebhook(1, receiver.URL, `["order.paid","order.cancelled"]`)
+
+	s.payOrder(1, 1)
+	s.NoError(s.services.Outbox.Relay())
+	s.NoError(s.services.Webhook.DeliverPending())
+	// the retry isn't due yet
+	s.NoError(s.services.Webhook.DeliverPending())
+
+	deliveries := s.getWebhookDeliveries(1, webhookId)
+	s.Require().Len(deliveries, 1)
+	s.Require().Equal(consts.DeliveryPending, deliveries[0].Status)
+	s.Require().Equal(1, deliveries[0].Attempts)
+	s.Require().Equal(http.StatusInternalServerError, *deliveries[0].ResponseCode)
+	s.Require().Equal("unexpected response status 500", *deliveries[0].LastError)
+	s.Require().True(deliveries[0].NextAttemptAt.After(deliveries[0].CreatedAt))
+}
+
+func (s *APITestSuite) TestRestaurantCreateWebhookError_UnknownEvent() {
+	jwt, err := s.getJWT(1, restaurantType)
+	s.NoError(err)
+
+	reqBody := fmt.Sprintf(`{"url":"http://localhost/hook","secret":"%s","events":["order.eaten"]}`, webhookSecret)
+	req, err := http.NewRequest("POST", "/api/v1/restaurants/1/webhooks", bytes.NewBuffer([]byte(reqBody)))
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Content-type", "application/json")
+
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusBadRequest, resp.Result().StatusCode)
+}
+
+func (s *APITestSuite) TestRestaurantGetWebhookDeliveriesError_Forbidden() {
+	webhookId := s.createWebhook(1, "http://localhost/hook", `["order.created"]`)
+
+	jwt, err := s.getJWT(2, restaurantType)
+	s.NoError(err)
+
+	url := fmt.Sprintf("/api/v1/restaurants/1/webhooks/%d/deliveries", webhookId)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		s.FailNow("Failed to build request", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	resp := httptest.NewRecorder()
+	s.app.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusForbidden, resp.Result().StatusCode)
+}